@@ -0,0 +1,239 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup <src> <dest>",
+	Short: "Create a dated snapshot of a directory, hardlinking unchanged files",
+	Long: `Create a snapshot of the source directory in a new dated directory of the destination. Files whose MD5 and Blake3 values are in the previous snapshot are hardlinked to it, only new and changed files are copied, so every snapshot is a complete tree while using the space of the changes.
+
+The hashes recorded by sync info are used for files that didn't change since, the others are hashed and recorded. The files of the snapshot are recorded too, so the next backup and restore find them.`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		sourceDir, err := filepath.Abs(args[0])
+		if err != nil {
+			util.PrintError("Error getting absolute path for source: %v\n", err)
+			os.Exit(1)
+		}
+		destDir, err := filepath.Abs(args[1])
+		if err != nil {
+			util.PrintError("Error getting absolute path for destination: %v\n", err)
+			os.Exit(1)
+		}
+
+		if info, err := os.Stat(sourceDir); err != nil || !info.IsDir() {
+			util.PrintError("Source directory does not exist: %s\n", sourceDir)
+			os.Exit(1)
+		}
+
+		exitUnlessAllowed(destDir)
+
+		if err := backupDirectory(sourceDir, destDir); err != nil {
+			util.PrintError("Error during backup: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// snapshotTimeFormat names the snapshot directories after the time they were taken
+const snapshotTimeFormat = "2006-01-02_150405"
+
+// partialSnapshotSuffix marks a snapshot being written, it's renamed once complete
+const partialSnapshotSuffix = ".partial"
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+}
+
+// listSnapshots returns the names of the complete snapshots in a backup directory, oldest first
+func listSnapshots(destDir string) ([]string, error) {
+	entries, err := os.ReadDir(destDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading backup directory %s: %v", destDir, err)
+	}
+
+	var snapshots []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := time.ParseInLocation(snapshotTimeFormat, entry.Name(), time.Local); err == nil {
+			snapshots = append(snapshots, entry.Name())
+		}
+	}
+	sort.Strings(snapshots)
+	return snapshots, nil
+}
+
+// backupDirectory creates a snapshot of sourceDir in destDir, hardlinking the files found in the previous one
+func backupDirectory(sourceDir, destDir string) error {
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return fmt.Errorf("error creating backup directory %s: %v", destDir, err)
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	// Index the files of the previous snapshot by content, renamed and moved files are linked too
+	snapshots, err := listSnapshots(destDir)
+	if err != nil {
+		return err
+	}
+	previous := make(map[string]string)
+	if len(snapshots) > 0 {
+		previousDir := filepath.Join(destDir, snapshots[len(snapshots)-1])
+		var records []*data.FileInfo
+		if err := db.GetHashedFileInfos(data.DuplicateFilter{PathPrefix: previousDir}, &records); err != nil {
+			return fmt.Errorf("error getting files of snapshot %s: %v", previousDir, err)
+		}
+		for _, record := range records {
+			previous[record.Blake3+record.MD5] = record.Path
+		}
+		util.PrintProcess("Linking unchanged files to snapshot %s\n", previousDir)
+	}
+
+	name := time.Now().Format(snapshotTimeFormat)
+	snapshotDir := filepath.Join(destDir, name)
+	partialDir := snapshotDir + partialSnapshotSuffix
+	if _, err := os.Stat(snapshotDir); err == nil {
+		return fmt.Errorf("snapshot %s already exists", snapshotDir)
+	}
+	if err := os.MkdirAll(partialDir, 0755); err != nil {
+		return fmt.Errorf("error creating snapshot directory %s: %v", partialDir, err)
+	}
+	util.PrintProcess("Creating snapshot %s\n", snapshotDir)
+
+	// The records of the snapshot are saved once it's complete, with their final paths
+	var records []*data.FileInfo
+	var linked, copied int
+	var copiedSize int64
+	err = filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			util.RecordSkipped(path, err)
+			return nil
+		}
+
+		// Skip VCS and package-manager internals, and the backup itself when it's inside the source
+		if isDefaultExcluded(path, sourceDir, info) || (info.IsDir() && (path == destDir || info.Name() == util.VersionsDirName)) {
+			return filepath.SkipDir
+		}
+		// Skip the database and its journal files
+		if isWorkspaceFile(path, info) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return fmt.Errorf("error calculating relative path for %s: %v", path, err)
+		}
+		dstPath := filepath.Join(partialDir, relPath)
+
+		switch {
+		case info.IsDir():
+			if err := os.MkdirAll(dstPath, 0755); err != nil {
+				return fmt.Errorf("error creating directory %s: %v", dstPath, err)
+			}
+			return nil
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				util.RecordSkipped(path, err)
+				return nil
+			}
+			if err := os.Symlink(target, dstPath); err != nil {
+				return fmt.Errorf("error creating symlink %s: %v", dstPath, err)
+			}
+			return nil
+		case !info.Mode().IsRegular():
+			return nil
+		}
+
+		record, err := upToDateRecord(db, path, info)
+		if err != nil {
+			return err
+		}
+		// The snapshot keeps the current permissions and extended attributes of the source, a linked copy
+		// shares those of an older one and the backup's filesystem may not support extended attributes
+		record.SetPermissions(info)
+		xattrs, err := util.ReadXattrs(path)
+		if err != nil {
+			util.PrintWarning("Warning: Could not read extended attributes of %s: %v\n", path, err)
+		}
+
+		// Unchanged content is linked, a failing link (another filesystem) falls back to a copy
+		if previousPath, ok := previous[record.Blake3+record.MD5]; ok && os.Link(previousPath, dstPath) == nil {
+			linked++
+		} else {
+			util.PrintProcess("Copying %s\n", path)
+			if err := copyFile(path, dstPath); err != nil {
+				return fmt.Errorf("error copying %s to %s: %v", path, dstPath, err)
+			}
+			if err := os.Chtimes(dstPath, info.ModTime(), info.ModTime()); err != nil {
+				return fmt.Errorf("error setting modification time of %s: %v", dstPath, err)
+			}
+			copied++
+			copiedSize += info.Size()
+		}
+
+		dstInfo, err := os.Stat(dstPath)
+		if err != nil {
+			return fmt.Errorf("error getting file info for %s: %v", dstPath, err)
+		}
+		finalPath := filepath.Join(snapshotDir, relPath)
+		records = append(records, &data.FileInfo{
+			Key:      util.PathKey(finalPath),
+			Name:     filepath.Base(finalPath),
+			Path:     finalPath,
+			Status:   0, // File exists
+			MD5:      record.MD5,
+			Blake3:   record.Blake3,
+			Size:     dstInfo.Size(),
+			DiskSize: util.GetDiskUsage(dstInfo),
+			Tag:      record.Tag,
+			MTime:    dstInfo.ModTime(),
+			CTime:    util.GetCreationTime(dstInfo),
+			Mode:     record.Mode,
+			UID:      record.UID,
+			GID:      record.GID,
+			ACL:      record.ACL,
+			Xattrs:   xattrs,
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%v, the incomplete snapshot is left at %s", err, partialDir)
+	}
+
+	if err := os.Rename(partialDir, snapshotDir); err != nil {
+		return fmt.Errorf("error renaming %s to %s: %v", partialDir, snapshotDir, err)
+	}
+
+	for _, record := range records {
+		record.SetVolume()
+		if err := db.UpsertFileInfo(record); err != nil {
+			return fmt.Errorf("error upserting file info for %s: %v", record.Path, err)
+		}
+	}
+
+	util.PrintSuccess("Snapshot %s created: %d files linked, %d files copied (%s).\n", snapshotDir, linked, copied, util.FormatSize(copiedSize))
+	return nil
+}
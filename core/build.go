@@ -0,0 +1,290 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// cleanBuildCmd represents the clean build command for removing developer caches
+var cleanBuildCmd = &cobra.Command{
+	Use:               "build [folder paths...]",
+	Short:             "Find and remove reclaimable developer caches",
+	Long:              `Detect developer caches (node_modules, .venv, vendor, Cargo target, Gradle caches) by their project marker files, show the size of each project's cache, and move the selected ones to the deleted folder.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listOnly, _ := cmd.Flags().GetBool("list")
+		deletedSaveDir, _ := cmd.Flags().GetString("deleted-save-dir")
+		recycleBin, _ := cmd.Flags().GetBool("recycle-bin")
+
+		if recycleBin && !util.RecycleBinSupported() {
+			util.PrintError("Error: --recycle-bin is only supported on Windows\n")
+			os.Exit(1)
+		}
+
+		if !listOnly {
+			checkDangerousRoots(cmd, args)
+			exitUnlessAllowed(args...)
+		}
+
+		err := handleBuildCaches(args, listOnly, deletedSaveDir, recycleBin)
+		if err != nil {
+			util.PrintError("Error during build cache operation: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	cleanBuildCmd.Flags().BoolP("list", "l", false, "List developer caches only, don't delete")
+	cleanBuildCmd.Flags().StringP("deleted-save-dir", "d", "", "Directory to move deleted caches to (default is workspace/deleted)")
+	cleanBuildCmd.MarkFlagDirname("deleted-save-dir")
+	cleanBuildCmd.Flags().Bool("recycle-bin", false, "Send deleted caches to the Windows Recycle Bin instead of the deleted folder")
+	cleanCmd.AddCommand(cleanBuildCmd)
+}
+
+// buildCacheRule describes how to recognize a developer cache directory
+type buildCacheRule struct {
+	DirName      string   // Name of the cache directory
+	Kind         string   // Human readable kind of the cache
+	Markers      []string // Marker files, any of which must exist
+	MarkerInside bool     // Whether markers are inside the cache directory instead of next to it
+}
+
+// buildCacheRules contains the rules for all supported developer caches
+var buildCacheRules = []buildCacheRule{
+	{DirName: "node_modules", Kind: "Node.js dependencies", Markers: []string{"package.json"}},
+	{DirName: ".venv", Kind: "Python virtualenv", Markers: []string{"pyvenv.cfg"}, MarkerInside: true},
+	{DirName: "venv", Kind: "Python virtualenv", Markers: []string{"pyvenv.cfg"}, MarkerInside: true},
+	{DirName: "vendor", Kind: "Vendored dependencies", Markers: []string{"composer.json", "go.mod"}},
+	{DirName: "target", Kind: "Cargo build output", Markers: []string{"Cargo.toml"}},
+	{DirName: ".gradle", Kind: "Gradle cache", Markers: []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"}},
+	{DirName: "build", Kind: "Gradle build output", Markers: []string{"build.gradle", "build.gradle.kts"}},
+}
+
+// buildCache represents a detected developer cache directory
+type buildCache struct {
+	Path     string
+	Project  string
+	Kind     string
+	Size     int64
+	DiskSize int64
+}
+
+// matchBuildCacheRule returns the rule matching a directory, if it is a developer cache
+func matchBuildCacheRule(path string) (*buildCacheRule, bool) {
+	dirName := filepath.Base(path)
+	for i, rule := range buildCacheRules {
+		if rule.DirName != dirName {
+			continue
+		}
+
+		markerDir := filepath.Dir(path)
+		if rule.MarkerInside {
+			markerDir = path
+		}
+
+		for _, marker := range rule.Markers {
+			if _, err := os.Stat(filepath.Join(markerDir, marker)); err == nil {
+				return &buildCacheRules[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// findBuildCaches finds all developer caches in the specified folders
+func findBuildCaches(folderPaths []string) ([]*buildCache, error) {
+	var caches []*buildCache
+
+	for _, folderPath := range folderPaths {
+		err := filepath.Walk(folderPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				// Skip files that can't be accessed
+				util.RecordSkipped(path, err)
+				return nil
+			}
+
+			if !info.IsDir() || path == folderPath {
+				return nil
+			}
+
+			rule, ok := matchBuildCacheRule(path)
+			if !ok {
+				// Skip VCS and package-manager internals
+				if isDefaultExcluded(path, folderPath, info) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			size, diskSize, err := util.GetPathSize(path)
+			if err != nil {
+				util.PrintWarning("Warning: Could not calculate size of %s: %v\n", path, err)
+			}
+
+			caches = append(caches, &buildCache{
+				Path:     path,
+				Project:  filepath.Dir(path),
+				Kind:     rule.Kind,
+				Size:     size,
+				DiskSize: diskSize,
+			})
+
+			// Don't look for nested caches inside a cache
+			return filepath.SkipDir
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("error walking folder %s: %v", folderPath, err)
+		}
+	}
+
+	// Show the largest caches first
+	sort.Slice(caches, func(i, j int) bool {
+		return caches[i].Size > caches[j].Size
+	})
+
+	return caches, nil
+}
+
+// handleBuildCaches reports developer caches and moves the selected ones to the deleted folder
+func handleBuildCaches(folderPaths []string, listOnly bool, deletedSaveDir string, recycleBin bool) error {
+	util.PrintProcess("Searching for developer caches...\n")
+	caches, err := findBuildCaches(folderPaths)
+	if err != nil {
+		return fmt.Errorf("error finding developer caches: %v", err)
+	}
+
+	if len(caches) == 0 {
+		util.PrintSuccess("No developer caches found.\n")
+		return nil
+	}
+
+	// Display results per project
+	var totalSize, totalDiskSize int64
+	options := make([]string, len(caches))
+	for i, cache := range caches {
+		util.PrintProcess("%s | %s | %s (%s, %s on disk)\n", cache.Project, cache.Kind, filepath.Base(cache.Path), util.FormatSize(cache.Size), util.FormatSize(cache.DiskSize))
+		options[i] = fmt.Sprintf(util.T("%s | %s (%s, %s on disk)"), cache.Path, cache.Kind, util.FormatSize(cache.Size), util.FormatSize(cache.DiskSize))
+		totalSize += cache.Size
+		totalDiskSize += cache.DiskSize
+	}
+
+	util.PrintProcess("\nTotal reclaimable: %d caches (%s, %s on disk)\n", len(caches), util.FormatSize(totalSize), util.FormatSize(totalDiskSize))
+
+	// If list only, exit here
+	if listOnly {
+		util.PrintSuccess("Listing only - no caches were deleted.\n")
+		return nil
+	}
+
+	// Ask user which caches to delete
+	selectedOptions, err := util.SelectMultiple(
+		"Select caches to delete (use space to select multiple, enter to confirm):",
+		options,
+	)
+	if err != nil {
+		return fmt.Errorf("error getting user selection: %v", err)
+	}
+
+	var selectedCaches []*buildCache
+	var selectedSize int64
+	for _, selectedOption := range selectedOptions {
+		for i, option := range options {
+			if option == selectedOption {
+				selectedCaches = append(selectedCaches, caches[i])
+				selectedSize += caches[i].Size
+				break
+			}
+		}
+	}
+
+	if len(selectedCaches) == 0 {
+		util.PrintSuccess("No caches selected for deletion.\n")
+		return nil
+	}
+
+	// Ask for confirmation before deletion
+	confirmed, err := util.Confirm(fmt.Sprintf(util.T("Move %d caches (%s) to the deleted folder? (y/N)"), len(selectedCaches), util.FormatSize(selectedSize)), false)
+	if err != nil {
+		return fmt.Errorf("error getting confirmation: %v", err)
+	}
+
+	if !confirmed {
+		util.PrintSuccess("Operation cancelled by user.\n")
+		return nil
+	}
+
+	if recycleBin {
+		// Send the caches to the Recycle Bin instead of the deleted folder
+		movedCount := 0
+		for _, cache := range selectedCaches {
+			if err := util.MoveToRecycleBin(cache.Path); err != nil {
+				util.PrintError("Error moving %s to the Recycle Bin: %v\n", cache.Path, err)
+				continue
+			}
+
+			util.PrintProcess("Moved %s to the Recycle Bin\n", cache.Path)
+			movedCount++
+		}
+
+		util.PrintSuccess("Successfully moved %d developer caches to the Recycle Bin\n", movedCount)
+		return nil
+	}
+
+	// Move selected caches to deleted folder
+	deletedDir := deletedSaveDir
+	if deletedDir == "" {
+		workspaceDir, err := util.GetWorkspaceDir()
+		if err != nil {
+			return fmt.Errorf("error getting workspace directory: %v", err)
+		}
+		deletedDir = filepath.Join(workspaceDir, "deleted")
+	}
+
+	// The caches moved are recorded, so undo can put them back
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+	journal := newJournal(db, "clean build")
+
+	movedCount := 0
+	for _, cache := range selectedCaches {
+		// Preserve the relative path structure from the parent of the original folder
+		relPath, err := getRelativePathFromParent(cache.Path, folderPaths)
+		if err != nil {
+			util.PrintWarning("Warning: Could not determine relative path for %s: %v\n", cache.Path, err)
+			relPath = filepath.Base(cache.Path)
+		}
+
+		destPath := util.UniqueDestPath(filepath.Join(deletedDir, relPath), nil)
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			util.PrintError("Error creating destination directory for %s: %v\n", cache.Path, err)
+			continue
+		}
+
+		if err := os.Rename(cache.Path, destPath); err != nil {
+			util.PrintError("Error moving %s to %s: %v\n", cache.Path, destPath, err)
+			continue
+		}
+
+		if err := util.RecordQuarantine(deletedDir, destPath, cache.Path); err != nil {
+			util.PrintWarning("Warning: Could not record %s in the manifest: %v\n", destPath, err)
+		}
+		journal.recordMove(cache.Path, destPath, deletedDir, nil)
+		util.PrintProcess("Moved %s to %s\n", cache.Path, destPath)
+		movedCount++
+	}
+
+	util.PrintSuccess("Successfully moved %d developer caches to %s\n", movedCount, deletedDir)
+	return nil
+}
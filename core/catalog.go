@@ -0,0 +1,519 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// catalogCmd represents the catalog command for browsing synced volumes offline
+var catalogCmd = &cobra.Command{
+	Use:   "catalog",
+	Short: "Browse the files of synced volumes, even when they are not mounted",
+	Long:  `Commands for listing, searching and planning deduplication of the files recorded for a volume. Everything is read from the database, so the volume doesn't need to be plugged in. A volume is selected by its ID (filesystem UUID) or its label.`,
+}
+
+// catalogListCmd represents the catalog list command
+var catalogListCmd = &cobra.Command{
+	Use:   "list [volume]",
+	Short: "List known volumes, or the files recorded for a volume",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		var err error
+		if len(args) == 0 {
+			err = handleCatalogVolumes(columns)
+		} else {
+			err = handleCatalogSearch(args[0], "", columns)
+		}
+		if err != nil {
+			util.PrintError("Error reading catalog: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// catalogSearchCmd represents the catalog search command
+var catalogSearchCmd = &cobra.Command{
+	Use:   "search <volume> <pattern>",
+	Short: "Search the files recorded for a volume",
+	Long:  `Search the files recorded for a volume by matching a regular expression against their path relative to the volume's mount point.`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		err := handleCatalogSearch(args[0], args[1], columns)
+		if err != nil {
+			util.PrintError("Error searching catalog: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// catalogDedupePlanCmd represents the catalog dedupe-plan command
+var catalogDedupePlanCmd = &cobra.Command{
+	Use:   "dedupe-plan <volume>",
+	Short: "Plan which duplicate files to remove from a volume",
+	Long: `Find duplicate files recorded for a volume using their MD5 and Blake3 values and print which copy of each group to keep and which to remove, so the cleanup can be planned while the drive is shelved.
+
+By default the copy with the shortest path is kept. With --keep context, the copy in the directory holding the most files of the volume is kept, so the copy inside an organized album wins over a stray one in Downloads.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+		keep, _ := cmd.Flags().GetString("keep")
+
+		if keep != keepShortest && keep != keepContext {
+			util.PrintError("Error: invalid --keep %s, expected %s or %s\n", keep, keepShortest, keepContext)
+			os.Exit(1)
+		}
+
+		err := handleCatalogDedupePlan(args[0], keep, columns)
+		if err != nil {
+			util.PrintError("Error planning deduplication: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// catalogTypesCmd represents the catalog types command
+var catalogTypesCmd = &cobra.Command{
+	Use:   "types [volume]",
+	Short: "Summarize the recorded files by type",
+	Long:  `Print the number and total size of the recorded files of each extension, with the MIME type it implies, largest first. Without a volume every record is counted. Extensions and MIME types are recorded with the files, so this only reads the indexes.`,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		volume := ""
+		if len(args) > 0 {
+			volume = args[0]
+		}
+		if err := handleCatalogTypes(volume, columns); err != nil {
+			util.PrintError("Error reading catalog: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// catalogPermsCmd represents the catalog perms command
+var catalogPermsCmd = &cobra.Command{
+	Use:   "perms <volume>",
+	Short: "List the permissions and owners recorded for the files of a volume",
+	Long: `List the permission bits, user and group recorded for the files of a volume, or on Windows the owner of their ACL. With --world-writable only files anyone can modify are listed, with --orphaned only files whose owner no longer has an account on this machine, such as the files of a removed user. When both are given, files must match both.
+
+Permissions are recorded when a file is synced, run 'fsak sync info --force' to record them for files synced before they were tracked.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+		worldWritable, _ := cmd.Flags().GetBool("world-writable")
+		orphaned, _ := cmd.Flags().GetBool("orphaned")
+
+		err := handleCatalogPerms(args[0], worldWritable, orphaned, columns)
+		if err != nil {
+			util.PrintError("Error reading catalog: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// Columns of the catalog tables
+var (
+	catalogVolumeColumns = []string{"label", "id", "files", "size"}
+	catalogFileColumns   = []string{"path", "size", "modified", "owner"}
+	catalogPlanColumns   = []string{"group", "action", "size", "path"}
+	catalogPermsColumns  = []string{"path", "mode", "user", "group"}
+	catalogTypesColumns  = []string{"ext", "mime", "files", "size"}
+)
+
+func init() {
+	catalogListCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (volumes: label, id, files, size; files: path, size, modified, owner)")
+	catalogListCmd.RegisterFlagCompletionFunc("columns", completeColumns(append(catalogVolumeColumns, "path", "modified", "owner")))
+	catalogSearchCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (path, size, modified, owner)")
+	catalogSearchCmd.RegisterFlagCompletionFunc("columns", completeColumns(catalogFileColumns))
+	catalogDedupePlanCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (group, action, size, path)")
+	catalogDedupePlanCmd.RegisterFlagCompletionFunc("columns", completeColumns(catalogPlanColumns))
+	catalogPermsCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (path, mode, user, group)")
+	catalogPermsCmd.RegisterFlagCompletionFunc("columns", completeColumns(catalogPermsColumns))
+	catalogTypesCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (ext, mime, files, size)")
+	catalogTypesCmd.RegisterFlagCompletionFunc("columns", completeColumns(catalogTypesColumns))
+	catalogPermsCmd.Flags().Bool("world-writable", false, "Only list files anyone can modify")
+	catalogPermsCmd.Flags().Bool("orphaned", false, "Only list files whose owner no longer has an account on this machine")
+	catalogDedupePlanCmd.Flags().String("keep", keepShortest, "Copy of each group to keep: shortest (path) or context (in the directory with the most files)")
+	catalogDedupePlanCmd.RegisterFlagCompletionFunc("keep", cobra.FixedCompletions([]string{keepShortest, keepContext}, cobra.ShellCompDirectiveNoFileComp))
+	catalogCmd.AddCommand(catalogListCmd)
+	catalogCmd.AddCommand(catalogSearchCmd)
+	catalogCmd.AddCommand(catalogDedupePlanCmd)
+	catalogCmd.AddCommand(catalogPermsCmd)
+	catalogCmd.AddCommand(catalogTypesCmd)
+	rootCmd.AddCommand(catalogCmd)
+}
+
+// loadVolumeFiles loads the records of a volume, failing if the volume is unknown
+func loadVolumeFiles(db *data.DB, volume string) ([]*data.FileInfo, error) {
+	var records []*data.FileInfo
+	if err := db.GetFileInfosByVolume(volume, &records); err != nil {
+		return nil, fmt.Errorf("error getting files of volume %s: %v", volume, err)
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no files recorded for volume %s, run 'fsak catalog list' to see known volumes", volume)
+	}
+
+	return records, nil
+}
+
+// catalogPath formats the volume-relative path of a record
+func catalogPath(record *data.FileInfo) string {
+	label := record.VolumeLabel
+	if label == "" {
+		label = record.VolumeID
+	}
+	return fmt.Sprintf("%s:/%s", label, record.VolumePath)
+}
+
+// handleCatalogVolumes lists all volumes recorded in the database
+func handleCatalogVolumes(columns []string) error {
+	table := util.NewTable(catalogVolumeColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	volumes, err := db.GetVolumeSummaries()
+	if err != nil {
+		return fmt.Errorf("error getting volumes: %v", err)
+	}
+
+	if len(volumes) == 0 {
+		util.PrintSuccess("No volumes recorded yet, run 'fsak sync info' first.\n")
+		return nil
+	}
+
+	for _, volume := range volumes {
+		table.AddRow(volume.VolumeLabel, volume.VolumeID, fmt.Sprint(volume.Files), util.FormatSize(volume.Size))
+	}
+	table.Print()
+
+	util.PrintSuccess("Found %d volumes.\n", len(volumes))
+	return nil
+}
+
+// handleCatalogSearch lists the files of a volume whose volume-relative path matches pattern
+func handleCatalogSearch(volume string, pattern string, columns []string) error {
+	table := util.NewTable(catalogFileColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	var re *regexp.Regexp
+	if pattern != "" {
+		var err error
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid search pattern %s: %v", pattern, err)
+		}
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	records, err := loadVolumeFiles(db, volume)
+	if err != nil {
+		return err
+	}
+
+	var matchCount int
+	var totalSize int64
+	for _, record := range records {
+		if re != nil && !re.MatchString(record.VolumePath) {
+			continue
+		}
+
+		table.AddRow(catalogPath(record), util.FormatSize(record.Size), record.MTime.Format("2006-01-02 15:04"), record.Owner)
+		matchCount++
+		totalSize += record.Size
+	}
+
+	table.Print()
+	util.PrintSuccess("Found %d files (%s).\n", matchCount, util.FormatSize(totalSize))
+	return nil
+}
+
+// handleCatalogTypes prints the file counts and sizes per extension, of a volume when it's not empty
+func handleCatalogTypes(volume string, columns []string) error {
+	table := util.NewTable(catalogTypesColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	types, err := db.GetTypeSummaries(volume)
+	if err != nil {
+		return fmt.Errorf("error getting file types: %v", err)
+	}
+
+	var files int64
+	for _, fileType := range types {
+		ext := fileType.Ext
+		if ext == "" {
+			ext = util.T("(none)")
+		}
+		table.AddRow(ext, fileType.Mime, fmt.Sprint(fileType.Files), util.FormatSize(fileType.Size))
+		files += fileType.Files
+	}
+	table.Print()
+
+	util.PrintSuccess("Found %d files of %d types.\n", files, len(types))
+	return nil
+}
+
+// Strategies choosing the copy of a duplicate group to keep
+const (
+	keepShortest = "shortest" // The copy with the shortest path, usually the original
+	keepContext  = "context"  // The copy in the directory with the most files, such as an organized album
+)
+
+// handleCatalogDedupePlan prints which duplicates of a volume to keep and which to remove
+func handleCatalogDedupePlan(volume string, keep string, columns []string) error {
+	table := util.NewTable(catalogPlanColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	records, err := loadVolumeFiles(db, volume)
+	if err != nil {
+		return err
+	}
+
+	// Group by full hashes, the files can't be read to complete missing ones
+	groups := make(map[string][]*data.FileInfo)
+	dirFiles := make(map[string]int)
+	var unhashed int
+	for _, record := range records {
+		dirFiles[path.Dir(record.VolumePath)]++
+		if !record.HasFullHashes() {
+			unhashed++
+			continue
+		}
+		key := record.Blake3 + ":" + record.MD5
+		groups[key] = append(groups[key], record)
+	}
+
+	if unhashed > 0 {
+		util.PrintWarning("Warning: %d files have no full hashes and were skipped, run 'fsak sync info' without --quick on the volume to include them\n", unhashed)
+	}
+
+	var duplicateGroups [][]*data.FileInfo
+	for _, group := range groups {
+		if len(group) > 1 {
+			duplicateGroups = append(duplicateGroups, group)
+		}
+	}
+
+	if len(duplicateGroups) == 0 {
+		util.PrintSuccess("No duplicate files found.\n")
+		return nil
+	}
+
+	// Show the groups with the most reclaimable space first
+	sort.Slice(duplicateGroups, func(i, j int) bool {
+		sizeI := duplicateGroups[i][0].Size * int64(len(duplicateGroups[i])-1)
+		sizeJ := duplicateGroups[j][0].Size * int64(len(duplicateGroups[j])-1)
+		if sizeI != sizeJ {
+			return sizeI > sizeJ
+		}
+		return duplicateGroups[i][0].VolumePath < duplicateGroups[j][0].VolumePath
+	})
+
+	var removeCount int
+	var reclaimable int64
+	for i, group := range duplicateGroups {
+		// Keep the copy with the shortest path, it's usually the original, or the one among the most files
+		sort.Slice(group, func(a, b int) bool {
+			if keep == keepContext {
+				filesA, filesB := dirFiles[path.Dir(group[a].VolumePath)], dirFiles[path.Dir(group[b].VolumePath)]
+				if filesA != filesB {
+					return filesA > filesB
+				}
+			}
+			if len(group[a].VolumePath) != len(group[b].VolumePath) {
+				return len(group[a].VolumePath) < len(group[b].VolumePath)
+			}
+			return group[a].VolumePath < group[b].VolumePath
+		})
+
+		table.AddRow(fmt.Sprint(i+1), util.T("keep"), util.FormatSize(group[0].Size), catalogPath(group[0]))
+		for _, record := range group[1:] {
+			table.AddRow(fmt.Sprint(i+1), util.T("remove"), util.FormatSize(record.Size), catalogPath(record))
+			removeCount++
+			reclaimable += record.Size
+		}
+	}
+
+	table.Print()
+	util.PrintSuccess("Plan: remove %d files in %d groups to reclaim %s.\n", removeCount, len(duplicateGroups), util.FormatSize(reclaimable))
+	return nil
+}
+
+// accountNames resolves user and group IDs to names, remembering them since the files of a volume share few owners
+type accountNames map[string]string
+
+// user returns the name of a user ID (a SID on Windows), or "" when no account has it anymore
+func (names accountNames) user(id string) string {
+	name, ok := names["user:"+id]
+	if !ok {
+		if account, err := user.LookupId(id); err == nil {
+			name = account.Username
+		}
+		names["user:"+id] = name
+	}
+	return name
+}
+
+// group returns the name of a group ID, or "" when no group has it anymore
+func (names accountNames) group(id string) string {
+	name, ok := names["group:"+id]
+	if !ok {
+		if group, err := user.LookupGroupId(id); err == nil {
+			name = group.Name
+		}
+		names["group:"+id] = name
+	}
+	return name
+}
+
+// sddlOwner matches the owner SID of an SDDL string, well-known aliases such as BA are left out as they always exist
+var sddlOwner = regexp.MustCompile(`^O:(S-[0-9-]+)`)
+
+// sddlEveryoneRights matches the rights of the ACEs of an SDDL string allowing access to Everyone
+var sddlEveryoneRights = regexp.MustCompile(`\(A;[^;]*;([^;]*);[^;]*;[^;]*;WD\)`)
+
+// recordOwnerID returns the user ID owning a recorded file, its UID or the owner SID of its ACL
+func recordOwnerID(record *data.FileInfo) string {
+	if record.UID != nil {
+		return strconv.FormatInt(*record.UID, 10)
+	}
+	if match := sddlOwner.FindStringSubmatch(record.ACL); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// recordWorldWritable reports whether anyone can modify a recorded file, by its ACL when it has one
+func recordWorldWritable(record *data.FileInfo) bool {
+	if record.ACL == "" {
+		return *record.Mode&0002 != 0
+	}
+	for _, match := range sddlEveryoneRights.FindAllStringSubmatch(record.ACL, -1) {
+		rights := match[1]
+		// Rights are either a mask, or two-letter codes such as FA (all) and FW (write)
+		if mask, err := strconv.ParseUint(rights, 0, 32); err == nil {
+			const fileWriteData, genericAll, genericWrite = 0x2, 0x10000000, 0x40000000
+			if mask&(fileWriteData|genericAll|genericWrite) != 0 {
+				return true
+			}
+			continue
+		}
+		for i := 0; i+2 <= len(rights); i += 2 {
+			switch rights[i : i+2] {
+			case "FA", "FW", "GA", "GW":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleCatalogPerms lists the permissions and owners recorded for the files of a volume
+func handleCatalogPerms(volume string, worldWritable bool, orphaned bool, columns []string) error {
+	table := util.NewTable(catalogPermsColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	records, err := loadVolumeFiles(db, volume)
+	if err != nil {
+		return err
+	}
+
+	names := accountNames{}
+	var matchCount, unknown int
+	var totalSize int64
+	for _, record := range records {
+		if record.Mode == nil {
+			unknown++
+			continue
+		}
+		if worldWritable && !recordWorldWritable(record) {
+			continue
+		}
+
+		// Removed accounts are shown by their ID
+		ownerID := recordOwnerID(record)
+		userName := names.user(ownerID)
+		if orphaned && (ownerID == "" || userName != "") {
+			continue
+		}
+		if userName == "" {
+			userName = ownerID
+		}
+		groupName := ""
+		if record.GID != nil {
+			groupID := strconv.FormatInt(*record.GID, 10)
+			if groupName = names.group(groupID); groupName == "" {
+				groupName = groupID
+			}
+		}
+
+		table.AddRow(catalogPath(record), os.FileMode(*record.Mode).String(), userName, groupName)
+		matchCount++
+		totalSize += record.Size
+	}
+
+	if unknown > 0 {
+		util.PrintWarning("Warning: %d files have no recorded permissions and were skipped, run 'fsak sync info --force' on the volume to record them\n", unknown)
+	}
+
+	table.Print()
+	util.PrintSuccess("Found %d files (%s).\n", matchCount, util.FormatSize(totalSize))
+	return nil
+}
@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/baowuhe/go-fsak/data"
 	"github.com/baowuhe/go-fsak/util"
@@ -24,9 +28,20 @@ var cleanCmd = &cobra.Command{
 var cleanInfoCmd = &cobra.Command{
 	Use:   "info",
 	Short: "Clean file_infos table by removing records where path points to non-existent files",
-	Long:  `Traverse the file_infos table and remove records where the path field points to files that no longer exist.`,
+	Long: `Traverse the file_infos table and remove records where the path field points to files that no longer exist. Records on volumes that are not mounted are skipped, so the catalog of an unplugged drive is kept.
+
+In a catalog shared by several users or machines, only the records created by the current user@host are checked, as the files of the others may live on disks this machine can't see, unless --all-owners is given.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		err := cleanFileInfoTable()
+		allOwners, _ := cmd.Flags().GetBool("all-owners")
+		tag, _ := cmd.Flags().GetString("tag")
+		threads, _ := cmd.Flags().GetInt("threads")
+
+		if threads < 1 {
+			util.PrintError("Error: --threads must be at least 1\n")
+			os.Exit(1)
+		}
+
+		err := cleanFileInfoTable(allOwners, tag, threads)
 		if err != nil {
 			util.PrintError("Error during clean operation: %v\n", err)
 			os.Exit(1)
@@ -36,13 +51,109 @@ var cleanInfoCmd = &cobra.Command{
 
 // dupCmd represents the clean dup command for finding and removing duplicate files
 var cleanDupCmd = &cobra.Command{
-	Use:   "dup [folder paths...]",
-	Short: "Find and remove duplicate files",
-	Long:  `Find duplicate files in specified folder paths using MD5 and Blake3 values.`,
-	Args:  cobra.MinimumNArgs(1),
+	Use:               "dup [folder paths...]",
+	Short:             "Find and remove duplicate files",
+	Long:              `Find duplicate files in specified folder paths using MD5 and Blake3 values, or handle the duplicate groups found by rmlint or jdupes with --import-results.`,
+	Args:              orImportResults(orFilesFrom(cobra.MinimumNArgs(1))),
+	ValidArgsFunction: completeCatalogedDirs,
 	Run: func(cmd *cobra.Command, args []string) {
 		deletedSaveDir, _ := cmd.Flags().GetString("deleted-save-dir")
-		err := handleDuplicateFiles(args, deletedSaveDir)
+		recycleBin, _ := cmd.Flags().GetBool("recycle-bin")
+		finderTag, _ := cmd.Flags().GetString("finder-tag")
+		clone, _ := cmd.Flags().GetBool("clone")
+		skipShared, _ := cmd.Flags().GetBool("skip-shared")
+		preview, _ := cmd.Flags().GetBool("preview")
+		quick, _ := cmd.Flags().GetBool("quick")
+		maxMemoryValue, _ := cmd.Flags().GetString("max-memory")
+		emitScript, _ := cmd.Flags().GetString("emit-script")
+		namePattern, _ := cmd.Flags().GetString("name-regex")
+		importResults, _ := cmd.Flags().GetString("import-results")
+		scope, _ := cmd.Flags().GetString("scope")
+		pageSize, _ := cmd.Flags().GetInt("page-size")
+		resume, _ := cmd.Flags().GetBool("resume")
+		reviewAll, _ := cmd.Flags().GetBool("review-all")
+		diffLast, _ := cmd.Flags().GetBool("diff-last")
+
+		maxMemory, err := parseMaxMemory(maxMemoryValue)
+		if err != nil {
+			util.PrintError("Error: %v\n", err)
+			os.Exit(1)
+		}
+		exitOnInvalidScope(scope)
+		if scope != scopeAll && importResults != "" {
+			util.PrintError("Error: --scope can't be combined with --import-results\n")
+			os.Exit(1)
+		}
+
+		var nameRegex *regexp.Regexp
+		if namePattern != "" {
+			nameRegex, err = regexp.Compile(namePattern)
+			if err != nil {
+				util.PrintError("Error: invalid --name-regex %s: %v\n", namePattern, err)
+				os.Exit(1)
+			}
+		}
+
+		if recycleBin && !util.RecycleBinSupported() {
+			util.PrintError("Error: --recycle-bin is only supported on Windows\n")
+			os.Exit(1)
+		}
+		if finderTag != "" && !util.FinderTagsSupported() {
+			util.PrintError("Error: --finder-tag is only supported on macOS\n")
+			os.Exit(1)
+		}
+		if clone && !util.CloneSupported() {
+			util.PrintError("Error: --clone is only supported on macOS\n")
+			os.Exit(1)
+		}
+
+		checkDangerousRoots(cmd, args)
+		exitUnlessAllowed(args...)
+
+		listedFiles, err := readFilesFrom(cmd)
+		if err != nil {
+			util.PrintError("Error: %v\n", err)
+			os.Exit(1)
+		}
+		exitUnlessAllowed(listedFiles...)
+
+		// The groups of --import-results are only verified, the folders are hashed completely
+		if importResults == "" {
+			files, size, err := countFiles(args, nil)
+			if err != nil {
+				util.PrintError("Error counting files: %v\n", err)
+				os.Exit(1)
+			}
+			if !confirmEstimate(cmd, files+len(listedFiles), size+sizeOfFiles(listedFiles)) {
+				return
+			}
+		}
+
+		// The records have canonical absolute paths, the folders are matched against them in the same form
+		folderPaths, err := absolutePaths(args)
+		if err != nil {
+			util.PrintError("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		err = handleDuplicateFiles(folderPaths, listedFiles, dupOptions{
+			importResults:  importResults,
+			deletedSaveDir: deletedSaveDir,
+			recycleBin:     recycleBin,
+			finderTag:      finderTag,
+			clone:          clone,
+			skipShared:     skipShared,
+			preview:        preview,
+			quick:          quick,
+			maxMemory:      maxMemory,
+			emitScript:     emitScript,
+			nameRegex:      nameRegex,
+			scope:          scope,
+			pageSize:       pageSize,
+			resume:         resume,
+			reviewAll:      reviewAll,
+			diffLast:       diffLast,
+		})
 		if err != nil {
 			util.PrintError("Error during duplicate file operation: %v\n", err)
 			os.Exit(1)
@@ -52,20 +163,49 @@ var cleanDupCmd = &cobra.Command{
 
 // dirtyCmd represents the clean dirty command for removing dirty files
 var cleanDirtyCmd = &cobra.Command{
-	Use:   "dirty [folder paths...]",
-	Short: "Remove dirty files from specified folders",
-	Long:  `Remove dirty files from specified folder paths based on user selection. Dirty files are defined as: files with 0 size, files smaller than 1KB, .DS_Store files on macOS, Thumbs.db files on Windows, and empty folders.`,
-	Args:  cobra.MinimumNArgs(1),
+	Use:               "dirty [folder paths...]",
+	Short:             "Remove dirty files from specified folders",
+	Long:              `Remove dirty files from specified folder paths based on user selection. Dirty files are defined as: files with 0 size, files smaller than 1KB (configurable with --small-size), .DS_Store files on macOS, Thumbs.db files on Windows, empty folders, and regenerable build artifacts (node_modules, __pycache__, target, .cache).`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeCatalogedDirs,
 	Run: func(cmd *cobra.Command, args []string) {
 		listOnly, _ := cmd.Flags().GetBool("list")
 		deleteToDir, _ := cmd.Flags().GetString("delete-to-dir")
+		confirmEachType, _ := cmd.Flags().GetBool("confirm-each-type")
+		safeListFile, _ := cmd.Flags().GetString("safe-list")
+		smallFileThreshold, _ = cmd.Flags().GetInt64("small-size")
+		recycleBin, _ := cmd.Flags().GetBool("recycle-bin")
+		emitScript, _ := cmd.Flags().GetString("emit-script")
+		print0, _ := cmd.Flags().GetBool("print0")
+		flatten, _ := cmd.Flags().GetBool("flatten")
+
+		if print0 && !listOnly {
+			util.PrintError("Error: --print0 can only be used with --list\n")
+			os.Exit(1)
+		}
+		if recycleBin && !util.RecycleBinSupported() {
+			util.PrintError("Error: --recycle-bin is only supported on Windows\n")
+			os.Exit(1)
+		}
+
+		if deleteToDir == "" && !listOnly && !recycleBin {
+			util.PrintError("Error: --delete-to-dir (-d) flag is required when not using --list or --recycle-bin\n")
+			os.Exit(1)
+		}
 
-		if deleteToDir == "" && !listOnly {
-			util.PrintError("Error: --delete-to-dir (-d) flag is required when not using --list\n")
+		// Load safe-list patterns
+		safePatterns, err := util.ReadBlacklist(safeListFile)
+		if err != nil {
+			util.PrintError("Error reading safe-list: %v\n", err)
 			os.Exit(1)
 		}
 
-		err := handleDirtyFiles(args, listOnly, deleteToDir)
+		if !listOnly {
+			checkDangerousRoots(cmd, args)
+			exitUnlessAllowed(args...)
+		}
+
+		err = handleDirtyFiles(args, listOnly, print0, deleteToDir, confirmEachType, safePatterns, recycleBin, emitScript, flatten)
 		if err != nil {
 			util.PrintError("Error during dirty file operation: %v\n", err)
 			os.Exit(1)
@@ -74,36 +214,85 @@ var cleanDirtyCmd = &cobra.Command{
 }
 
 func init() {
+	cleanCmd.PersistentFlags().Bool("i-know-what-i-am-doing", false, "Allow cleaning the filesystem root, the home directory or the workspace")
+	cleanInfoCmd.Flags().Bool("all-owners", false, "Also check the records created by other users and machines sharing the catalog")
+	cleanInfoCmd.Flags().StringP("tag", "T", "", "Only check the records synced with this tag")
+	cleanInfoCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	cleanInfoCmd.Flags().IntP("threads", "t", 8, "Number of directories checked in parallel")
 	cleanCmd.AddCommand(cleanInfoCmd)
 	cleanDupCmd.Flags().StringP("deleted-save-dir", "d", "", "Directory to move deleted files to (default is workspace/deleted)")
 	cleanDupCmd.MarkFlagDirname("deleted-save-dir")
+	cleanDupCmd.Flags().Bool("recycle-bin", false, "Send deleted files to the Windows Recycle Bin instead of the deleted folder")
+	cleanDupCmd.Flags().String("finder-tag", "", "Mark selected duplicates with a macOS Finder tag instead of removing them (macOS only)")
+	cleanDupCmd.Flags().Lookup("finder-tag").NoOptDefVal = "fsak-duplicate"
+	cleanDupCmd.Flags().Bool("clone", false, "Replace selected duplicates with APFS clones of a kept file instead of removing them (macOS only)")
+	cleanDupCmd.Flags().String("emit-script", "", "Write the moves to a shell script (PowerShell for .ps1 files) for review instead of performing them")
+	cleanDupCmd.MarkFlagsMutuallyExclusive("recycle-bin", "finder-tag", "clone", "emit-script")
+	cleanDupCmd.Flags().Bool("skip-shared", false, "Skip duplicate groups whose files already share all extents (reflink copies)")
+	cleanDupCmd.Flags().Bool("preview", false, "Offer to open the files of each group with their default application before selecting")
+	cleanDupCmd.Flags().BoolP("quick", "q", false, "Group files by size and quick hash (first/last 1MB), selected groups are fully verified before any action")
+	cleanDupCmd.Flags().String("max-memory", "", "Memory limit (e.g. 512M, 2G), duplicate groups are moved to a temporary database when it's approached")
+	addFilesFromFlag(cleanDupCmd)
+	addYesFlag(cleanDupCmd)
+	cleanDupCmd.Flags().String("name-regex", "", "Only consider files whose names match this regular expression (e.g. '(?i)\\.(cr2|nef|arw)$')")
+	addScopeFlag(cleanDupCmd)
+	cleanDupCmd.Flags().Int("page-size", 0, "Number of groups to review before asking whether to go on, 0 reviews all groups at once")
+	cleanDupCmd.Flags().Bool("resume", false, "Skip the groups decided in earlier reviews, to go on with an interrupted review")
+	cleanDupCmd.Flags().Bool("review-all", false, "Review every group interactively, ignoring the [keep] rules of the config")
+	cleanDupCmd.Flags().Bool("diff-last", false, "Only list the groups that are new since the last run over the same folders, without changing anything")
+	cleanDupCmd.Flags().String("import-results", "", "Handle the duplicate groups of rmlint (-o json) or jdupes results instead of scanning folders")
+	cleanDupCmd.MarkFlagsMutuallyExclusive("import-results", "files-from", "files-from0")
+	cleanDupCmd.MarkFlagsMutuallyExclusive("import-results", "quick")
 	cleanCmd.AddCommand(cleanDupCmd)
 
 	// Add dirty command with its flags
 	cleanDirtyCmd.Flags().BoolP("list", "l", false, "List dirty files only, don't delete")
-	cleanDirtyCmd.Flags().StringP("delete-to-dir", "d", "", "Directory to move deleted files to (required when not using --list)")
+	cleanDirtyCmd.Flags().StringP("delete-to-dir", "d", "", "Directory to move deleted files to (required when not using --list or --recycle-bin)")
 	cleanDirtyCmd.MarkFlagDirname("delete-to-dir")
+	cleanDirtyCmd.Flags().Bool("confirm-each-type", false, "Ask for a separate confirmation for each dirty file type")
+	cleanDirtyCmd.Flags().StringP("safe-list", "S", "", "Safe-list file containing paths that are never treated as dirty (supports regex)")
+	cleanDirtyCmd.Flags().Bool("recycle-bin", false, "Send deleted files to the Windows Recycle Bin instead of the delete directory")
+	cleanDirtyCmd.Flags().Int64("small-size", 1024, "Size in bytes below which files are treated as small files (0 disables the rule)")
+	cleanDirtyCmd.Flags().String("emit-script", "", "Write the moves to a shell script (PowerShell for .ps1 files) for review instead of performing them")
+	cleanDirtyCmd.MarkFlagsMutuallyExclusive("recycle-bin", "emit-script")
+	cleanDirtyCmd.Flags().Bool("print0", false, "With --list, print the dirty paths to stdout separated by NUL bytes for xargs -0, messages go to stderr")
+	cleanDirtyCmd.Flags().Bool("flatten", false, "Move the dirty files directly into the delete directory, adding a numeric suffix to names already taken")
+	cleanDirtyCmd.MarkFlagsMutuallyExclusive("recycle-bin", "flatten")
 	cleanCmd.AddCommand(cleanDirtyCmd)
 
 	rootCmd.AddCommand(cleanCmd)
 }
 
-func cleanFileInfoTable() error {
+// checkDangerousRoots exits when a directory to clean is the filesystem root, the home directory or the
+// workspace, unless --i-know-what-i-am-doing is given
+func checkDangerousRoots(cmd *cobra.Command, dirs []string) {
+	if override, _ := cmd.Flags().GetBool("i-know-what-i-am-doing"); override {
+		return
+	}
+	for _, dir := range dirs {
+		if reason := util.DangerousRoot(dir); reason != "" {
+			util.PrintError("Error: refusing to run %s on %s, %s. Pass --i-know-what-i-am-doing if this is intended\n", cmd.CommandPath(), dir, reason)
+			os.Exit(1)
+		}
+	}
+}
+
+// cleanFileInfoTable deletes the records of files that no longer exist, checking them with threads workers
+func cleanFileInfoTable(allOwners bool, tag string, threads int) error {
 	// Connect to database
 	db, err := data.Connect()
 	if err != nil {
 		return fmt.Errorf("error connecting to database: %v", err)
 	}
-	defer func() {
-		sqlDB, _ := db.DB.DB()
-		if sqlDB != nil {
-			sqlDB.Close()
-		}
-	}()
+	defer db.Close()
 
-	// Get all file info records
+	// Get all file info records, or the ones of a tag
 	var allRecords []*data.FileInfo
-	err = db.GetAllFileInfos(&allRecords)
+	if tag != "" {
+		err = db.GetFileInfos(data.DuplicateFilter{Tag: tag}, &allRecords)
+	} else {
+		err = db.GetAllFileInfos(&allRecords)
+	}
 	if err != nil {
 		return fmt.Errorf("error getting all file info records: %v", err)
 	}
@@ -112,138 +301,626 @@ func cleanFileInfoTable() error {
 	totalRecords := len(allRecords)
 	util.PrintProcess("Found %d records in file_infos table, starting validation...\n", totalRecords)
 
-	// Check which records point to non-existent files
-	var recordsToDelete []*data.FileInfo
-	for i, record := range allRecords {
-		// Show progress
-		percentage := float64(i+1) / float64(totalRecords) * 100
-		util.PrintProcess("[ %d / %d (%.2f%%)]: Checking %s\n", i+1, totalRecords, percentage, record.Path)
+	// Mounts of the volumes by ID, none when the volume isn't mounted
+	volumes := make(map[string][]*util.VolumeInfo)
+
+	// Directories volumes are mounted in, to tell the records of unplugged drives synced without a volume
+	mountParents := util.MountParents()
+
+	// The records to check by directory, each directory is listed once
+	byDir := make(map[string][]recordCheck)
+	offlineCount, ambiguousCount, otherOwnersCount := 0, 0, 0
+	owner := util.CurrentOwner()
+	for _, record := range allRecords {
+		// The files of other users of a shared catalog may not be visible from here
+		if !allOwners && record.Owner != "" && record.Owner != owner {
+			otherOwnersCount++
+			continue
+		}
 
-		// Check if file exists
-		if _, err := os.Stat(record.Path); os.IsNotExist(err) {
-			// File doesn't exist, mark for deletion
-			recordsToDelete = append(recordsToDelete, record)
+		path := record.Path
+		if record.VolumeID != "" {
+			mounts, ok := volumes[record.VolumeID]
+			if !ok {
+				mounts, err = util.FindMountedVolumes(record.VolumeID)
+				if err != nil {
+					return fmt.Errorf("error checking whether volume %s is mounted: %v", record.VolumeID, err)
+				}
+				volumes[record.VolumeID] = mounts
+			}
+
+			// Never delete the records of an unplugged volume, nor guess which mount of a volume mounted
+			// several times the record belongs to
+			if len(mounts) == 0 {
+				offlineCount++
+				continue
+			}
+			if len(mounts) > 1 {
+				ambiguousCount++
+				continue
+			}
+
+			// The volume may be mounted at a different path than when the record was synced, the path
+			// of the record is kept when its file is still there
+			remapped := filepath.Join(mounts[0].MountPoint, filepath.FromSlash(record.VolumePath))
+			if remapped != path {
+				if _, err := os.Lstat(path); err != nil {
+					path = remapped
+				}
+			}
+		}
+
+		dir := filepath.Dir(path)
+		byDir[dir] = append(byDir[dir], recordCheck{record: record, path: path})
+	}
+
+	// Check which records point to non-existent files, a directory per worker, as the checks of a network
+	// mount wait on the network rather than the disk
+	dirCh := make(chan string, threads*2)
+	var mu sync.Mutex
+	var recordsToDelete, located []*data.FileInfo
+	var checked atomic.Int64
+	toCheck := totalRecords - offlineCount - ambiguousCount - otherOwnersCount
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for dir := range dirCh {
+				checks := byDir[dir]
+				missing, found, offline := checkUnknownVolumes(dir, checks, missingInDir(dir, checks), mountParents)
+
+				mu.Lock()
+				recordsToDelete = append(recordsToDelete, missing...)
+				located = append(located, found...)
+				offlineCount += offline
+				mu.Unlock()
+
+				// Show progress
+				done := checked.Add(int64(len(checks)))
+				percentage := float64(done) / float64(toCheck) * 100
+				util.PrintProcess("[ %d / %d (%.2f%%)]: Checked %s\n", done, toCheck, percentage, dir)
+			}
+		}()
+	}
+	for dir := range byDir {
+		dirCh <- dir
+	}
+	close(dirCh)
+	wg.Wait()
+
+	// Records synced without a volume, before volumes were recorded or when detecting it failed, get it now
+	if len(located) > 0 {
+		if err := db.SetVolumes(located); err != nil {
+			return fmt.Errorf("error recording volumes: %v", err)
 		}
+		util.PrintProcess("Recorded the volume of %d records synced without one\n", len(located))
 	}
 
 	// Print summary
+	if offlineCount > 0 {
+		util.PrintProcess("Skipped %d records on volumes that are not mounted\n", offlineCount)
+	}
+	if ambiguousCount > 0 {
+		util.PrintProcess("Skipped %d records on volumes mounted more than once\n", ambiguousCount)
+	}
+	if otherOwnersCount > 0 {
+		util.PrintProcess("Skipped %d records of other users, use --all-owners to check them\n", otherOwnersCount)
+	}
 	util.PrintProcess("Found %d records pointing to non-existent files\n", len(recordsToDelete))
 
-	// Delete the records that point to non-existent files
-	deletedCount := 0
+	// Delete the records that point to non-existent files, by batches
+	keys := make([]string, 0, len(recordsToDelete))
 	for _, record := range recordsToDelete {
 		// Print information about the record being cleaned
 		util.PrintProcess("Cleaning record ID: %d, Path: %s\n", record.ID, record.Path)
-
-		// Delete the record
-		if err := db.DeleteFileInfo(record.Key); err != nil {
-			return fmt.Errorf("error deleting record with key %s: %v", record.Key, err)
-		}
-		deletedCount++
+		keys = append(keys, record.Key)
 	}
+	if err := db.DeleteFileInfos(keys); err != nil {
+		return fmt.Errorf("error deleting records: %v", err)
+	}
+	deletedCount := len(keys)
 
 	util.PrintSuccess("Clean operation completed. %d records deleted.\n", deletedCount)
 	return nil
 }
 
-// handleDuplicateFiles finds and handles duplicate files based on MD5 and Blake3 values
-func handleDuplicateFiles(folderPaths []string, deletedSaveDir string) error {
-	// Connect to database
-	db, err := data.Connect()
-	if err != nil {
-		return fmt.Errorf("error connecting to database: %v", err)
+// recordCheck is a record whose file is checked, with the path the file is at on its volume
+type recordCheck struct {
+	record *data.FileInfo
+	path   string
+}
+
+// checkUnknownVolumes sorts out the records of a directory synced without a volume among its checks. The
+// missing ones are only deleted when the directory isn't on a volume that may be unplugged, the others are
+// counted as offline. The ones found there are returned with their volume, to record it. The missing records
+// of a known volume are returned as they are.
+func checkUnknownVolumes(dir string, checks []recordCheck, missing []*data.FileInfo, mountParents map[string]bool) ([]*data.FileInfo, []*data.FileInfo, int) {
+	gone := make(map[*data.FileInfo]bool, len(missing))
+	for _, record := range missing {
+		gone[record] = true
+	}
+
+	var deleted, found []*data.FileInfo
+	offline := 0
+	unmounted := -1 // Whether the directory may be unmounted, checked once when needed
+	for _, check := range checks {
+		record := check.record
+		switch {
+		case record.VolumeID != "":
+			if gone[record] {
+				deleted = append(deleted, record)
+			}
+		case !gone[record]:
+			if record.SetVolume(); record.VolumeID != "" {
+				found = append(found, record)
+			}
+		default:
+			if unmounted == -1 {
+				unmounted = 0
+				if util.MaybeUnmounted(dir, mountParents) {
+					unmounted = 1
+				}
+			}
+			if unmounted == 1 {
+				offline++
+			} else {
+				deleted = append(deleted, record)
+			}
+		}
+	}
+	return deleted, found, offline
+}
+
+// missingInDir returns the records of the files that don't exist among the ones of a directory. The directory
+// is listed once rather than every file being stat'ed, only the names not listed and symlinks are stat'ed,
+// since the names of a case-insensitive filesystem may be listed with another case.
+func missingInDir(dir string, checks []recordCheck) []*data.FileInfo {
+	var missing []*data.FileInfo
+	entries, err := readDirEntries(dir)
+	if os.IsNotExist(err) {
+		// Every file of a directory that's gone is gone
+		for _, check := range checks {
+			missing = append(missing, check.record)
+		}
+		return missing
 	}
-	defer func() {
-		sqlDB, _ := db.DB.DB()
-		if sqlDB != nil {
-			sqlDB.Close()
+
+	for _, check := range checks {
+		if entry, ok := entries[filepath.Base(check.path)]; ok && entry.Type()&os.ModeSymlink == 0 {
+			continue
+		}
+		if _, err := os.Stat(check.path); os.IsNotExist(err) {
+			missing = append(missing, check.record)
 		}
-	}()
+	}
+	return missing
+}
+
+// readDirEntries lists a directory by name, without sorting it
+func readDirEntries(dir string) (map[string]os.DirEntry, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	list, err := f.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]os.DirEntry, len(list))
+	for _, entry := range list {
+		entries[entry.Name()] = entry
+	}
+	return entries, nil
+}
 
+// findDuplicateGroups collects the files in the specified folders and the listed files, hashes them (reusing values stored in
+// the database) and returns the groups of files sharing the same MD5 and Blake3 values.
+// In quick mode, files are grouped by size and quick hash, so the groups are only probably identical.
+// With a maxMemory limit, the groups are moved to a temporary database when memory usage approaches it.
+// A nameRegex restricts the scan to the files whose names match it.
+func findDuplicateGroups(db *data.DB, folderPaths []string, listedFiles []string, quick bool, maxMemory int64, nameRegex *regexp.Regexp) ([][]*data.FileInfo, error) {
 	// Collect all files in the specified folders
 	var allFiles []string
 	for _, folderPath := range folderPaths {
 		files, err := getAllFilesInFolder(folderPath)
 		if err != nil {
-			return fmt.Errorf("error getting files from folder %s: %v", folderPath, err)
+			return nil, fmt.Errorf("error getting files from folder %s: %v", folderPath, err)
 		}
 		allFiles = append(allFiles, files...)
 	}
+	allFiles = append(allFiles, listedFiles...)
+
+	// Overlapping folders must not turn a file into its own duplicate
+	slices.Sort(allFiles)
+	allFiles = slices.Compact(allFiles)
 
-	// Process each file to calculate MD5 and Blake3 values
-	fileInfoMap := make(map[string]*data.FileInfo)
+	// Only hash the files whose names match the regex
+	if nameRegex != nil {
+		allFiles = slices.DeleteFunc(allFiles, func(filePath string) bool {
+			return !nameRegex.MatchString(filepath.Base(filePath))
+		})
+	}
+
+	// Group files by MD5 and Blake3 values while processing them
+	grouper := data.NewGrouper(db, maxMemory)
+	defer grouper.Close()
+
+	// Process each file to calculate MD5 and Blake3 values, tracking progress by bytes
 	totalFiles := len(allFiles)
 	util.PrintProcess("Processing %d files...\n", totalFiles)
+	progress := util.NewProgress(totalFiles, sizeOfFiles(allFiles))
 
-	for i, filePath := range allFiles {
-		// Show progress
-		percentage := float64(i+1) / float64(totalFiles) * 100
-		util.PrintProcess("[ %d / %d (%.2f%%)]: Processing %s\n", i+1, totalFiles, percentage, filePath)
-
+	for _, filePath := range allFiles {
 		// Check if file info exists in database
 		dbFileInfo, err := db.GetFileInfoByPath(filePath)
 		if err != nil && err != gorm.ErrRecordNotFound {
 			// Some other error occurred
-			return fmt.Errorf("error getting file info from database for %s: %v", filePath, err)
+			return nil, fmt.Errorf("error getting file info from database for %s: %v", filePath, err)
 		}
 
 		var fileInfo *data.FileInfo
-		if err == gorm.ErrRecordNotFound || dbFileInfo == nil {
-			// File info doesn't exist in database, calculate new values
-			blake3Val, md5Val, err := util.FileBlake3MD5(filePath)
-			if err != nil {
-				util.PrintWarning("Warning: Could not calculate hash for %s: %v\n", filePath, err)
-				continue
-			}
-
+		if err == nil && dbFileInfo != nil && ((quick && dbFileInfo.QuickHash != "") || (!quick && dbFileInfo.HasFullHashes())) {
+			// File info exists in database with the needed hashes, use it
+			fileInfo = dbFileInfo
+		} else {
 			// Get file stats
 			fileStat, err := os.Stat(filePath)
 			if err != nil {
 				util.PrintWarning("Warning: Could not get file stats for %s: %v\n", filePath, err)
+				util.RecordSkipped(filePath, err)
 				continue
 			}
 
-			// Create new FileInfo
-			fileInfo = &data.FileInfo{
-				Path:   filePath,
-				Name:   filepath.Base(filePath),
-				Key:    util.CalculateBlake3String(filePath), // Key is Blake3 of absolute path
-				MD5:    md5Val,
-				Blake3: blake3Val,
-				Size:   fileStat.Size(),
-				MTime:  fileStat.ModTime(),
-				CTime:  fileStat.ModTime(), // For now, use ModTime as CTime
-				Status: 0,                  // 0 means file exists
+			if dbFileInfo != nil {
+				// Complete the existing record with the missing hashes
+				fileInfo = dbFileInfo
+			} else {
+				// Create new FileInfo
+				fileInfo = &data.FileInfo{
+					Path:     filePath,
+					Name:     filepath.Base(filePath),
+					Key:      util.PathKey(filePath), // Key of the canonical path, set when the record is saved
+					Size:     fileStat.Size(),
+					DiskSize: util.GetDiskUsage(fileStat),
+					MTime:    fileStat.ModTime(),
+					CTime:    fileStat.ModTime(), // For now, use ModTime as CTime
+					Status:   0,                  // 0 means file exists
+				}
+				fileInfo.SetVolume()
+				fileInfo.SetPermissions(fileStat)
+			}
+
+			// Calculate new values
+			if quick {
+				fileInfo.QuickHash, err = util.FileQuickHash(filePath)
+			} else {
+				fileInfo.Blake3, fileInfo.MD5, err = util.FileBlake3MD5(filePath)
+			}
+			if err != nil {
+				util.PrintWarning("Warning: Could not calculate hash for %s: %v\n", filePath, err)
+				util.RecordSkipped(filePath, err)
+				progress.Add(fileStat.Size())
+				continue
 			}
 
 			// Insert into database
 			if err := db.UpsertFileInfo(fileInfo); err != nil {
-				return fmt.Errorf("error inserting file info into database for %s: %v", filePath, err)
+				return nil, fmt.Errorf("error inserting file info into database for %s: %v", filePath, err)
 			}
-		} else {
-			// File info exists in database, use it
-			fileInfo = dbFileInfo
 		}
+		progress.Report(fileInfo.Size, filePath)
 
-		fileInfoMap[filePath] = fileInfo
-	}
-
-	// Group files by MD5 and Blake3 values
-	groupedFiles := make(map[string][]*data.FileInfo)
-	for _, fileInfo := range fileInfoMap {
 		// Create a key combining MD5 and Blake3 to identify identical files
 		key := fileInfo.MD5 + ":" + fileInfo.Blake3
-		groupedFiles[key] = append(groupedFiles[key], fileInfo)
+		if quick {
+			// Quick hashes only identify probably identical files of the same size
+			key = fmt.Sprintf("%d:%s", fileInfo.Size, fileInfo.QuickHash)
+		}
+		if err := grouper.Add(key, fileInfo); err != nil {
+			return nil, fmt.Errorf("error moving duplicate groups to a temporary database: %v", err)
+		}
+	}
+
+	if grouper.Spilled() {
+		util.PrintProcess("Memory limit reached, grouping files in a temporary database...\n")
 	}
 
 	// Identify duplicate groups (groups with more than 1 file)
-	var duplicateGroups [][]*data.FileInfo
-	for _, group := range groupedFiles {
+	duplicateGroups, err := grouper.DuplicateGroups()
+	if err != nil {
+		return nil, fmt.Errorf("error grouping duplicate files: %v", err)
+	}
+
+	return duplicateGroups, nil
+}
+
+// Scopes of the duplicates found in several folders
+const (
+	scopeAll    = "all"    // Every duplicate group
+	scopeWithin = "within" // Copies inside the same folder, groups are split by folder
+	scopeAcross = "across" // Groups with copies in at least two folders, such as a laptop and a NAS
+)
+
+// addScopeFlag adds the --scope flag, which restricts the duplicates to the copies within a folder or across folders
+func addScopeFlag(cmd *cobra.Command) {
+	cmd.Flags().String("scope", scopeAll, "Duplicates to find: all, within (copies inside the same folder given) or across (groups with copies in different folders given)")
+	cmd.RegisterFlagCompletionFunc("scope", cobra.FixedCompletions([]string{scopeAll, scopeWithin, scopeAcross}, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// exitOnInvalidScope exits when the value of --scope is unknown
+func exitOnInvalidScope(scope string) {
+	if scope != scopeAll && scope != scopeWithin && scope != scopeAcross {
+		util.PrintError("Error: invalid --scope %s, expected %s, %s or %s\n", scope, scopeAll, scopeWithin, scopeAcross)
+		os.Exit(1)
+	}
+}
+
+// scopeDuplicateGroups restricts duplicate groups to the copies within the same folder, or to the groups with
+// copies in several folders. Files outside every folder, such as listed ones, count as one more folder.
+func scopeDuplicateGroups(groups [][]*data.FileInfo, folderPaths []string, scope string) [][]*data.FileInfo {
+	if scope == scopeAll {
+		return groups
+	}
+
+	roots := make([]string, 0, len(folderPaths))
+	for _, folderPath := range folderPaths {
+		if absPath, err := util.CanonicalPath(folderPath); err == nil {
+			roots = append(roots, absPath)
+		}
+	}
+
+	var scoped [][]*data.FileInfo
+	for _, group := range groups {
+		byFolder := make(map[int][]*data.FileInfo)
+		for _, file := range group {
+			filePath, err := util.CanonicalPath(file.Path)
+			if err != nil {
+				filePath = file.Path
+			}
+			rank := folderRank(filePath, roots)
+			byFolder[rank] = append(byFolder[rank], file)
+		}
+
+		if scope == scopeAcross {
+			if len(byFolder) > 1 {
+				scoped = append(scoped, group)
+			}
+			continue
+		}
+		for rank := 0; rank <= len(roots); rank++ {
+			if len(byFolder[rank]) > 1 {
+				scoped = append(scoped, byFolder[rank])
+			}
+		}
+	}
+
+	if len(scoped) < len(groups) {
+		util.PrintProcess("%d of %d duplicate groups are in scope %s\n", len(scoped), len(groups), scope)
+	}
+	return scoped
+}
+
+// orImportResults validates the arguments with args unless --import-results gives the duplicate groups, then
+// no folders are accepted
+func orImportResults(args cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, positional []string) error {
+		if cmd.Flags().Changed("import-results") {
+			if len(positional) > 0 {
+				return fmt.Errorf("--import-results can't be combined with folder paths")
+			}
+			return nil
+		}
+		return args(cmd, positional)
+	}
+}
+
+// importDuplicateGroups reads the duplicate groups found by rmlint or jdupes. The files keep their
+// records, files without one get a record without hashes, which are calculated when the group is verified.
+// A nameRegex restricts the groups to the files whose names match it.
+func importDuplicateGroups(db *data.DB, resultsFile string, nameRegex *regexp.Regexp) ([][]*data.FileInfo, error) {
+	paths, format, err := util.ReadDuplicateResults(resultsFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading results %s: %v", resultsFile, err)
+	}
+	util.PrintProcess("Read %d duplicate groups from %s results\n", len(paths), format)
+
+	var groups [][]*data.FileInfo
+	for _, groupPaths := range paths {
+		var group []*data.FileInfo
+		for _, path := range groupPaths {
+			absPath, err := util.CanonicalPath(path)
+			if err != nil {
+				return nil, fmt.Errorf("error getting absolute path for %s: %v", path, err)
+			}
+			if nameRegex != nil && !nameRegex.MatchString(filepath.Base(absPath)) {
+				continue
+			}
+			if err := util.CheckAllowedPath(absPath); err != nil {
+				return nil, err
+			}
+
+			// Files removed since the results were written are left out
+			fileStat, err := os.Stat(absPath)
+			if err != nil {
+				util.PrintWarning("Warning: Could not get file stats for %s: %v\n", absPath, err)
+				util.RecordSkipped(absPath, err)
+				continue
+			}
+
+			fileInfo, err := db.GetFileInfoByPath(absPath)
+			if err == gorm.ErrRecordNotFound {
+				fileInfo = &data.FileInfo{
+					Path:     absPath,
+					Name:     filepath.Base(absPath),
+					Key:      util.PathKey(absPath),
+					Size:     fileStat.Size(),
+					DiskSize: util.GetDiskUsage(fileStat),
+					MTime:    fileStat.ModTime(),
+					CTime:    util.GetCreationTime(fileStat),
+					Status:   0, // File exists
+				}
+				fileInfo.SetVolume()
+				fileInfo.SetPermissions(fileStat)
+			} else if err != nil {
+				return nil, fmt.Errorf("error getting file info from database for %s: %v", absPath, err)
+			}
+			group = append(group, fileInfo)
+		}
+
 		if len(group) > 1 {
-			duplicateGroups = append(duplicateGroups, group)
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+// verifyDuplicateGroup calculates the full MD5 and Blake3 values of a group found by quick hash,
+// storing them in the database, and reports whether all files of the group are identical
+func verifyDuplicateGroup(db *data.DB, group []*data.FileInfo) (bool, error) {
+	for _, fileInfo := range group {
+		if fileInfo.HasFullHashes() {
+			continue
+		}
+
+		util.PrintProcess("Verifying %s\n", fileInfo.Path)
+		blake3Val, md5Val, err := util.FileBlake3MD5(fileInfo.Path)
+		if err != nil {
+			return false, fmt.Errorf("error calculating hashes for %s: %v", fileInfo.Path, err)
+		}
+		fileInfo.MD5 = md5Val
+		fileInfo.Blake3 = blake3Val
+
+		if err := db.UpsertFileInfo(fileInfo); err != nil {
+			return false, fmt.Errorf("error updating file info for %s: %v", fileInfo.Path, err)
+		}
+	}
+
+	for _, fileInfo := range group[1:] {
+		if fileInfo.MD5 != group[0].MD5 || fileInfo.Blake3 != group[0].Blake3 {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// findSharedExtents maps files of a duplicate group to an earlier file of the group they already share
+// all extents with (e.g. reflink copies on Btrfs/XFS). Removing such files frees no space.
+func findSharedExtents(group []*data.FileInfo) map[string]string {
+	sharedExtents := make(map[string]string)
+	if !util.ExtentsSupported() {
+		return sharedExtents
+	}
+
+	for j := 1; j < len(group); j++ {
+		for k := 0; k < j; k++ {
+			// Only compare against files with their own storage
+			if _, ok := sharedExtents[group[k].Path]; ok {
+				continue
+			}
+
+			if shared, err := util.SharesAllExtents(group[k].Path, group[j].Path); err == nil && shared {
+				sharedExtents[group[j].Path] = group[k].Path
+				break
+			}
 		}
 	}
 
+	return sharedExtents
+}
+
+// previewDuplicateGroup lets the user open the files of a group with their default application, to look at
+// them before selecting, until they go on to the selection
+func previewDuplicateGroup(group []*data.FileInfo) error {
+	proceed := util.T("Go on to the selection")
+	options := []string{proceed}
+	for _, fileInfo := range group {
+		options = append(options, fmt.Sprintf(util.T("Open %s"), fileInfo.Path))
+	}
+
+	for {
+		choice, err := util.SelectOne("Open a file to look at it, or go on to the selection:", options)
+		if err != nil {
+			return err
+		}
+		if choice == proceed {
+			return nil
+		}
+
+		path := group[slices.Index(options, choice)-1].Path
+		if err := util.OpenWithDefaultApp(path); err != nil {
+			util.PrintWarning("Warning: Could not open %s: %v\n", path, err)
+		}
+	}
+}
+
+// dupOptions are the options of a run of clean dup
+type dupOptions struct {
+	importResults  string         // rmlint or jdupes results whose groups are handled instead of the folders
+	deletedSaveDir string         // Deleted folder the files are moved to, the one of the workspace when empty
+	recycleBin     bool           // Send the files to the Recycle Bin instead
+	finderTag      string         // Tag the files with this Finder tag instead
+	clone          bool           // Replace the files with APFS clones of the file kept instead
+	skipShared     bool           // Skip the groups whose files already share their extents
+	preview        bool           // Preview the files of a group before selecting them
+	quick          bool           // Group the files by their quick hashes
+	maxMemory      int64          // Heap size above which the groups are spilled to disk, 0 for no limit
+	emitScript     string         // Write the moves to this script instead of performing them
+	nameRegex      *regexp.Regexp // Only the files whose names match, all when nil
+	scope          string         // Groups kept by the folders of their files, one of the scope values
+	pageSize       int            // Groups reviewed before asking to go on, 0 for no pages
+	resume         bool           // Skip the groups decided in earlier reviews
+	reviewAll      bool           // Review every group, ignoring the [keep] rules of the config
+	diffLast       bool           // Only list the groups that are new since the last run
+}
+
+// stillIdentical reports whether two files have the same content, hashing again the ones changed since they
+// were recorded
+func stillIdentical(db *data.DB, path, other string) (bool, error) {
+	var records []*data.FileInfo
+	for _, file := range []string{path, other} {
+		info, err := os.Lstat(file)
+		if err != nil {
+			return false, fmt.Errorf("error getting file info for %s: %v", file, err)
+		}
+		record, err := upToDateRecord(db, file, info)
+		if err != nil {
+			return false, err
+		}
+		records = append(records, record)
+	}
+	return records[0].MD5 == records[1].MD5 && records[0].Blake3 == records[1].Blake3, nil
+}
+
+// handleDuplicateFiles finds and handles duplicate files based on MD5 and Blake3 values
+func handleDuplicateFiles(folderPaths []string, listedFiles []string, opts dupOptions) error {
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var duplicateGroups [][]*data.FileInfo
+	if opts.importResults != "" {
+		duplicateGroups, err = importDuplicateGroups(db, opts.importResults, opts.nameRegex)
+	} else {
+		duplicateGroups, err = findDuplicateGroups(db, folderPaths, listedFiles, opts.quick, opts.maxMemory, opts.nameRegex)
+	}
+	if err != nil {
+		return err
+	}
+	duplicateGroups = scopeDuplicateGroups(duplicateGroups, folderPaths, opts.scope)
+
+	// Every run records its groups, --diff-last compares them with the ones of the last run instead
+	folders := duplicateScanFolders(folderPaths, opts.importResults, opts.scope)
+	if opts.diffLast {
+		return diffDuplicateScan(db, folders, duplicateGroups)
+	}
+	recordDuplicateScan(db, folders, duplicateGroups)
+
 	if len(duplicateGroups) == 0 {
 		util.PrintSuccess("No duplicate files found.\n")
 		return nil
@@ -251,11 +928,60 @@ func handleDuplicateFiles(folderPaths []string, deletedSaveDir string) error {
 
 	util.PrintProcess("Found %d groups of duplicate files.\n", len(duplicateGroups))
 
+	// The files moved are recorded, so undo can put them back with their records
+	journal := newJournal(db, "clean dup")
+
+	// Write the moves to a script instead of performing them
+	var script *util.ScriptWriter
+	if opts.emitScript != "" {
+		script, err = util.NewScriptWriter(opts.emitScript, "clean dup")
+		if err != nil {
+			return fmt.Errorf("error creating script %s: %v", opts.emitScript, err)
+		}
+		defer script.Close()
+	}
+
+	// Destinations already used by the script, which doesn't move anything yet
+	scripted := make(map[string]bool)
+
+	// Groups decided in earlier reviews are skipped by --resume
+	var decisions map[string]*data.ReviewDecision
+	if opts.resume {
+		if decisions, err = db.GetReviewDecisions(); err != nil {
+			return fmt.Errorf("error getting the decisions of earlier reviews: %v", err)
+		}
+	}
+
 	// Process each duplicate group interactively
 	totalFilesProcessed := 0
+	reviewed, decided, pagedAt := 0, 0, 0
+	stoppedAt := -1
 
 	for i, group := range duplicateGroups {
-		util.PrintProcess("Duplicate group %d/%d (%d files):\n", i+1, len(duplicateGroups), len(group))
+		groupKey := duplicateGroupKey(group)
+		if decisions[groupKey] != nil {
+			decided++
+			continue
+		}
+
+		// After each page of groups, the review can be stopped and resumed later
+		if opts.pageSize > 0 && reviewed > pagedAt && reviewed%opts.pageSize == 0 {
+			pagedAt = reviewed
+			proceed, err := util.Confirm(fmt.Sprintf(util.T("Reviewed %d of %d groups, go on with the next page?"), i, len(duplicateGroups)), true)
+			if err != nil && !util.IsInterrupted(err) {
+				return fmt.Errorf("error getting user confirmation: %v", err)
+			}
+			if !proceed {
+				stoppedAt = i
+				break
+			}
+		}
+
+		if opts.quick {
+			util.PrintProcess("Duplicate group %d/%d (%d files, quick hash match - probabilistic):\n", i+1, len(duplicateGroups), len(group))
+		} else {
+			util.PrintProcess("Duplicate group %d/%d (%d files):\n", i+1, len(duplicateGroups), len(group))
+		}
 
 		// Prepare options for user selection - sort by absolute path but show relative paths and show in requested format
 		// Create a slice of indices to maintain the mapping after sorting
@@ -274,68 +1000,215 @@ func handleDuplicateFiles(folderPaths []string, deletedSaveDir string) error {
 
 		for j, idx := range indices {
 			sortedGroup[j] = group[idx]
+		}
+
+		// Files that already share all extents with another file of the group (e.g. reflink copies) take no extra space
+		sharedExtents := findSharedExtents(sortedGroup)
+		if opts.skipShared && len(sharedExtents) == len(sortedGroup)-1 {
+			util.PrintProcess("Skipping group %d, all files already share the same extents\n", i+1)
+			continue
+		}
+
+		// Keeping one copy, the other files with their own storage can be reclaimed
+		var reclaimable, reclaimableDisk int64
+		for j, fileInfo := range sortedGroup {
+			if _, ok := sharedExtents[fileInfo.Path]; j > 0 && !ok {
+				reclaimable += fileInfo.Size
+				reclaimableDisk += fileInfo.GetDiskSize()
+			}
+		}
+		util.PrintProcess("Reclaimable space: %s (%s on disk)\n", util.FormatSize(reclaimable), util.FormatSize(reclaimableDisk))
+
+		for j, fileInfo := range sortedGroup {
 			// Use absolute path in the display format
-			options[j] = fmt.Sprintf("%s | (%d bytes)", group[idx].Path, group[idx].Size)
+			options[j] = fmt.Sprintf(util.T("%s | (%d bytes, %d bytes on disk)"), fileInfo.Path, fileInfo.Size, fileInfo.GetDiskSize())
+			if sharedWith, ok := sharedExtents[fileInfo.Path]; ok {
+				options[j] += fmt.Sprintf(util.T(" | shares extents with %s"), sharedWith)
+			}
 		}
 
-		// Ask user which files to delete
-		selectedOptions, err := util.SelectMultiple(
-			"Select files to delete (use space to select multiple, enter to confirm):",
-			options,
-		)
-		if err != nil {
-			return fmt.Errorf("error getting user selection for group %d: %v", i+1, err)
+		// The [keep] rules of the config decide the groups they apply to, the others are reviewed
+		var selectedOptions []string
+		kept, keepRule := keptByRule(sortedGroup, opts.reviewAll)
+		if kept != nil {
+			util.PrintProcess("Keeping %s by the keep rule %s\n", kept.Path, keepRule)
+			for j, fileInfo := range sortedGroup {
+				if fileInfo != kept {
+					selectedOptions = append(selectedOptions, options[j])
+				}
+			}
+		} else {
+			if opts.preview {
+				if err := previewDuplicateGroup(sortedGroup); err != nil {
+					return fmt.Errorf("error getting user selection for group %d: %v", i+1, err)
+				}
+			}
+
+			// Ask user which files to delete, or to tag when marking with Finder tags
+			selectMessage := "Select files to delete (use space to select multiple, enter to confirm):"
+			if opts.finderTag != "" {
+				selectMessage = fmt.Sprintf(util.T("Select files to tag with %q (use space to select multiple, enter to confirm):"), opts.finderTag)
+			} else if opts.clone {
+				selectMessage = "Select files to replace with clones (use space to select multiple, enter to confirm):"
+			}
+			selectedOptions, err = util.SelectMultiple(selectMessage, options)
+			if util.IsInterrupted(err) {
+				stoppedAt = i
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("error getting user selection for group %d: %v", i+1, err)
+			}
+			reviewed++
+		}
+
+		// Quick hash matches are only probable and imported groups weren't found by fsak, verify the whole
+		// contents before acting on them
+		if (opts.quick || opts.importResults != "") && len(selectedOptions) > 0 {
+			identical, err := verifyDuplicateGroup(db, sortedGroup)
+			if err != nil {
+				return fmt.Errorf("error verifying group %d: %v", i+1, err)
+			}
+			if !identical {
+				util.PrintWarning("Warning: Files of group %d are not identical, skipping\n", i+1)
+				continue
+			}
+		}
+
+		// When cloning, the first unselected file is kept as the source of the clones
+		var survivor *data.FileInfo
+		if opts.clone && len(selectedOptions) > 0 {
+			for j, fileInfo := range sortedGroup {
+				if !slices.Contains(selectedOptions, options[j]) {
+					survivor = fileInfo
+					break
+				}
+			}
+
+			if survivor == nil {
+				util.PrintWarning("Warning: All files of group %d were selected, at least one must be kept as the clone source\n", i+1)
+				continue
+			}
 		}
 
 		// Immediately process the selected files for this group
 		if len(selectedOptions) > 0 {
-			// Move selected files to deleted folder
+			// Move selected files to deleted folder, unless they go to the Recycle Bin
 			var deletedDir string
-			if deletedSaveDir == "" {
-				workspaceDir, err := util.GetWorkspaceDir()
-				if err != nil {
-					return fmt.Errorf("error getting workspace directory: %v", err)
+			if !opts.recycleBin && opts.finderTag == "" && !opts.clone {
+				if opts.deletedSaveDir == "" {
+					workspaceDir, err := util.GetWorkspaceDir()
+					if err != nil {
+						return fmt.Errorf("error getting workspace directory: %v", err)
+					}
+					deletedDir = filepath.Join(workspaceDir, "deleted")
+				} else {
+					deletedDir = opts.deletedSaveDir
 				}
-				deletedDir = filepath.Join(workspaceDir, "deleted")
-			} else {
-				deletedDir = deletedSaveDir
-			}
 
-			if err := os.MkdirAll(deletedDir, 0755); err != nil {
-				return fmt.Errorf("error creating deleted directory: %v", err)
+				if script == nil {
+					if err := os.MkdirAll(deletedDir, 0755); err != nil {
+						return fmt.Errorf("error creating deleted directory: %v", err)
+					}
+				}
 			}
 
 			// Map selected options back to file paths and process them immediately
 			for _, selectedOption := range selectedOptions {
-				for _, fileInfo := range sortedGroup {
-					// Recreate the option string using absolute path to match what the user saw
-					option := fmt.Sprintf("%s | (%d bytes)", fileInfo.Path, fileInfo.Size)
-					if option == selectedOption {
-						// Preserve the relative path structure from the parent of the original folder (including folder name) when moving
-						relPath, err := getRelativePathFromParent(fileInfo.Path, folderPaths)
-						if err != nil {
-							util.PrintWarning("Warning: Could not determine relative path for %s: %v\n", fileInfo.Path, err)
-							relPath = filepath.Base(fileInfo.Path) // Fallback to just the filename
+				for j, fileInfo := range sortedGroup {
+					// Match the option string the user saw
+					if options[j] == selectedOption {
+						if opts.finderTag != "" {
+							// Mark the file with a Finder tag instead of removing it
+							if err := util.AddFinderTag(fileInfo.Path, opts.finderTag); err != nil {
+								return fmt.Errorf("error tagging file %s: %v", fileInfo.Path, err)
+							}
+
+							util.PrintProcess("Tagged %s with %q\n", fileInfo.Path, opts.finderTag)
+							util.EmitJSON("tagged", map[string]any{"path": fileInfo.Path, "tag": opts.finderTag})
+							totalFilesProcessed++
+							break
 						}
 
-						// Create the destination path
-						destPath := filepath.Join(deletedDir, relPath)
-
-						// Create destination directory if it doesn't exist
-						destDir := filepath.Dir(destPath)
-						if err := os.MkdirAll(destDir, 0755); err != nil {
-							return fmt.Errorf("error creating destination directory %s: %v", destDir, err)
+						if opts.clone {
+							// Recorded hashes may be stale and a clone can't be undone, only a file still identical
+							// to the survivor is replaced
+							identical, err := stillIdentical(db, survivor.Path, fileInfo.Path)
+							if err != nil {
+								return err
+							}
+							if !identical {
+								util.PrintWarning("Warning: %s changed since it was hashed, skipping\n", fileInfo.Path)
+								break
+							}
+
+							// Replace the file with an APFS clone of the survivor, sharing its storage
+							if err := util.ReplaceWithClone(survivor.Path, fileInfo.Path); err != nil {
+								return fmt.Errorf("error replacing %s with a clone of %s: %v", fileInfo.Path, survivor.Path, err)
+							}
+
+							util.PrintProcess("Replaced %s with a clone of %s\n", fileInfo.Path, survivor.Path)
+							util.EmitJSON("cloned", map[string]any{"path": fileInfo.Path, "source": survivor.Path})
+							totalFilesProcessed++
+							break
 						}
 
-						// Move the file
-						if err := os.Rename(fileInfo.Path, destPath); err != nil {
-							return fmt.Errorf("error moving file %s to %s: %v", fileInfo.Path, destPath, err)
-						}
+						if opts.recycleBin {
+							// Send the file to the Recycle Bin instead of the deleted folder
+							if err := util.MoveToRecycleBin(fileInfo.Path); err != nil {
+								return fmt.Errorf("error moving file %s to the Recycle Bin: %v", fileInfo.Path, err)
+							}
 
-						util.PrintProcess("Moved %s to %s\n", fileInfo.Path, destPath)
+							util.PrintProcess("Moved %s to the Recycle Bin\n", fileInfo.Path)
+							util.EmitJSON("recycled", map[string]any{"path": fileInfo.Path})
+						} else {
+							// Preserve the relative path structure from the parent of the original folder (including folder name) when moving
+							relPath, err := getRelativePathFromParent(fileInfo.Path, folderPaths)
+							if err != nil && (len(listedFiles) > 0 || opts.importResults != "") {
+								// Files outside the folders come from --files-from or the imported results, they keep
+								// their absolute path
+								relPath = strings.TrimPrefix(fileInfo.Path[len(filepath.VolumeName(fileInfo.Path)):], string(filepath.Separator))
+								err = nil
+							}
+							if err != nil {
+								util.PrintWarning("Warning: Could not determine relative path for %s: %v\n", fileInfo.Path, err)
+								relPath = filepath.Base(fileInfo.Path) // Fallback to just the filename
+							}
+
+							// Create the destination path, with a suffix when it's taken by an earlier run
+							destPath := util.UniqueDestPath(filepath.Join(deletedDir, relPath), scripted)
+
+							if script != nil {
+								// Leave the move, and the record, for the reviewed script
+								script.Move(fileInfo.Path, destPath)
+								scripted[destPath] = true
+								util.PrintProcess("Scripted moving %s to %s\n", fileInfo.Path, destPath)
+								util.EmitJSON("scripted", map[string]any{"path": fileInfo.Path, "to": destPath})
+								totalFilesProcessed++
+								break
+							}
+
+							// Create destination directory if it doesn't exist
+							destDir := filepath.Dir(destPath)
+							if err := os.MkdirAll(destDir, 0755); err != nil {
+								return fmt.Errorf("error creating destination directory %s: %v", destDir, err)
+							}
+
+							// Move the file
+							if err := os.Rename(fileInfo.Path, destPath); err != nil {
+								return fmt.Errorf("error moving file %s to %s: %v", fileInfo.Path, destPath, err)
+							}
+
+							if err := util.RecordQuarantine(deletedDir, destPath, fileInfo.Path); err != nil {
+								util.PrintWarning("Warning: Could not record %s in the manifest: %v\n", destPath, err)
+							}
+							journal.recordMove(fileInfo.Path, destPath, deletedDir, fileInfo)
+							util.PrintProcess("Moved %s to %s\n", fileInfo.Path, destPath)
+							util.EmitJSON("moved", map[string]any{"path": fileInfo.Path, "to": destPath})
+						}
 
 						// Delete the record from file_infos table immediately after moving the file
-						key := util.CalculateBlake3String(fileInfo.Path)
+						key := util.PathKey(fileInfo.Path)
 						if err := db.DeleteFileInfo(key); err != nil {
 							// Continue with other deletions even if one fails
 							util.PrintWarning("Warning: Could not delete record for file %s from database: %v\n", fileInfo.Path, err)
@@ -347,6 +1220,31 @@ func handleDuplicateFiles(folderPaths []string, deletedSaveDir string) error {
 				}
 			}
 		}
+
+		// The decision is kept, so --resume skips the group
+		paths := make([]string, 0, len(sortedGroup))
+		selectedPaths := []string{}
+		for j, fileInfo := range sortedGroup {
+			paths = append(paths, fileInfo.Path)
+			if slices.Contains(selectedOptions, options[j]) {
+				selectedPaths = append(selectedPaths, fileInfo.Path)
+			}
+		}
+		groupResult := map[string]any{"group": i + 1, "size": sortedGroup[0].Size, "paths": paths, "selected": selectedPaths}
+		if keepRule != nil {
+			groupResult["rule"] = keepRule.String()
+		}
+		util.EmitJSON("duplicates", groupResult)
+		if err := db.SaveReviewDecision(&data.ReviewDecision{GroupKey: groupKey, Selected: strings.Join(selectedPaths, "\n")}); err != nil {
+			util.PrintWarning("Warning: Could not record the decision of group %d: %v\n", i+1, err)
+		}
+	}
+
+	if decided > 0 {
+		util.PrintProcess("Skipped %d groups decided in earlier reviews.\n", decided)
+	}
+	if stoppedAt >= 0 {
+		util.PrintWarning("Review stopped at group %d of %d, run clean dup again with --resume to go on with the groups not decided yet.\n", stoppedAt+1, len(duplicateGroups))
 	}
 
 	if totalFilesProcessed == 0 {
@@ -354,10 +1252,78 @@ func handleDuplicateFiles(folderPaths []string, deletedSaveDir string) error {
 		return nil
 	}
 
+	if opts.finderTag != "" {
+		util.PrintSuccess("Successfully tagged %d duplicate files with %q.\n", totalFilesProcessed, opts.finderTag)
+		return nil
+	}
+
+	if opts.clone {
+		util.PrintSuccess("Successfully replaced %d duplicate files with APFS clones.\n", totalFilesProcessed)
+		return nil
+	}
+
+	if script != nil {
+		if err := script.Close(); err != nil {
+			return fmt.Errorf("error writing script %s: %v", opts.emitScript, err)
+		}
+		util.PrintSuccess("Wrote %d moves to %s, review and run it, then run clean info to update the database.\n", script.Count, opts.emitScript)
+		return nil
+	}
+
 	util.PrintSuccess("Successfully processed %d duplicate files: moved to deleted folder and removed records from database.\n", totalFilesProcessed)
 	return nil
 }
 
+// keptByRule returns the file of a duplicate group to keep by the [keep] rule of the config applying to all
+// its files, with the rule. It returns nil when no rule, or different rules, apply, or when no file is under
+// the directory of an under rule. Ties go to the first file of the group.
+func keptByRule(group []*data.FileInfo, reviewAll bool) (*data.FileInfo, *util.KeepRule) {
+	if reviewAll || len(group) == 0 {
+		return nil, nil
+	}
+	rule := util.KeepRuleFor(group[0].Name)
+	if rule == nil {
+		return nil, nil
+	}
+	for _, fileInfo := range group[1:] {
+		if util.KeepRuleFor(fileInfo.Name) != rule {
+			return nil, nil
+		}
+	}
+
+	var kept *data.FileInfo
+	for _, fileInfo := range group {
+		switch {
+		case rule.Policy == util.KeepUnder:
+			if kept == nil && rule.IsUnderDir(fileInfo.Path) {
+				kept = fileInfo
+			}
+		case kept == nil:
+			kept = fileInfo
+		case rule.Policy == util.KeepLargest && fileInfo.Size > kept.Size,
+			rule.Policy == util.KeepSmallest && fileInfo.Size < kept.Size,
+			rule.Policy == util.KeepNewest && fileInfo.MTime.After(kept.MTime),
+			rule.Policy == util.KeepOldest && fileInfo.MTime.Before(kept.MTime):
+			kept = fileInfo
+		}
+	}
+	if kept == nil {
+		return nil, nil
+	}
+	return kept, rule
+}
+
+// duplicateGroupKey identifies a duplicate group by its paths, so its decision applies until files are added
+// to it or removed from it
+func duplicateGroupKey(group []*data.FileInfo) string {
+	paths := make([]string, len(group))
+	for i, fileInfo := range group {
+		paths[i] = fileInfo.Path
+	}
+	slices.Sort(paths)
+	return util.CalculateBlake3String(strings.Join(paths, "\n"))
+}
+
 // getAllFilesInFolder recursively gets all files in a folder
 func getAllFilesInFolder(folderPath string) ([]string, error) {
 	var files []string
@@ -365,10 +1331,16 @@ func getAllFilesInFolder(folderPath string) ([]string, error) {
 	err := filepath.Walk(folderPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			// Skip files that can't be accessed
+			util.RecordSkipped(path, err)
 			return nil
 		}
 
-		if !info.IsDir() {
+		// Skip VCS and package-manager internals
+		if isDefaultExcluded(path, folderPath, info) {
+			return filepath.SkipDir
+		}
+
+		if !info.IsDir() && !isWorkspaceFile(path, info) {
 			files = append(files, path)
 		}
 
@@ -382,7 +1354,7 @@ func getAllFilesInFolder(folderPath string) ([]string, error) {
 // This includes the input folder name in the relative path
 func getRelativePathFromParent(filePath string, folderPaths []string) (string, error) {
 	for _, folderPath := range folderPaths {
-		if strings.HasPrefix(filePath, folderPath) {
+		if isSubPath(filePath, folderPath) {
 			// Get the parent directory of the folder path
 			parentDir := filepath.Dir(folderPath)
 			// Calculate relative path from the parent directory
@@ -395,6 +1367,9 @@ func getRelativePathFromParent(filePath string, folderPaths []string) (string, e
 	return "", fmt.Errorf("file %s does not belong to any of the specified folders", filePath)
 }
 
+// smallFileThreshold is the size in bytes below which files are treated as small files, 0 disables the rule
+var smallFileThreshold int64 = 1024
+
 // Dirty file types for user selection
 type DirtyFileType int
 
@@ -406,27 +1381,69 @@ const (
 	EmptyFolder
 	LinuxHiddenFile
 	OfficeTempFile
+	NodeModulesDir
+	PyCacheDir
+	BuildTargetDir
+	CacheDir
 )
 
 // String returns the string representation of a DirtyFileType
 func (d DirtyFileType) String() string {
 	switch d {
 	case EmptyFile:
-		return "Files with size 0"
+		return util.T("Files with size 0")
+	case SmallFile:
+		return fmt.Sprintf(util.T("Files smaller than %s"), util.FormatSize(smallFileThreshold))
+	case MacHiddenFile:
+		return util.T("macOS .DS_Store files")
+	case WindowsHiddenFile:
+		return util.T("Windows Thumbs.db files")
+	case EmptyFolder:
+		return util.T("Empty folders")
+	case LinuxHiddenFile:
+		return util.T("Linux/MacOS hidden files (starting with .)")
+	case OfficeTempFile:
+		return util.T("Office temporary files")
+	case NodeModulesDir:
+		return util.T("Node.js node_modules folders")
+	case PyCacheDir:
+		return util.T("Python __pycache__ folders")
+	case BuildTargetDir:
+		return util.T("Cargo/Maven target folders")
+	case CacheDir:
+		return util.T(".cache folders")
+	default:
+		return util.T("Unknown")
+	}
+}
+
+// Name returns the identifier of a DirtyFileType in the JSON results, which isn't translated
+func (d DirtyFileType) Name() string {
+	switch d {
+	case EmptyFile:
+		return "empty-file"
 	case SmallFile:
-		return "Files smaller than 1KB"
+		return "small-file"
 	case MacHiddenFile:
-		return "macOS .DS_Store files"
+		return "ds-store"
 	case WindowsHiddenFile:
-		return "Windows Thumbs.db files"
+		return "thumbs-db"
 	case EmptyFolder:
-		return "Empty folders"
+		return "empty-folder"
 	case LinuxHiddenFile:
-		return "Linux/MacOS hidden files (starting with .)"
+		return "hidden-file"
 	case OfficeTempFile:
-		return "Office temporary files"
+		return "office-temp"
+	case NodeModulesDir:
+		return "node-modules"
+	case PyCacheDir:
+		return "pycache"
+	case BuildTargetDir:
+		return "build-target"
+	case CacheDir:
+		return "cache"
 	default:
-		return "Unknown"
+		return "unknown"
 	}
 }
 
@@ -444,8 +1461,8 @@ func isDirtyFile(path string, info os.FileInfo) bool {
 		return true
 	}
 
-	// Check for small file (< 1KB)
-	if info.Size() < 1024 {
+	// Check for small file (< smallFileThreshold)
+	if info.Size() < smallFileThreshold {
 		return true
 	}
 
@@ -523,23 +1540,104 @@ func isOfficeTempFile(fileName string) bool {
 	return false
 }
 
+// safeHiddenFiles contains hidden file names that are meaningful and never treated as dirty
+var safeHiddenFiles = []string{
+	".gitignore", ".gitattributes", ".gitmodules", ".gitkeep", ".keep",
+	".env", ".envrc", ".editorconfig", ".dockerignore", ".npmrc", ".nvmrc", ".htaccess",
+	".bashrc", ".bash_profile", ".bash_logout", ".profile", ".zshrc", ".zprofile", ".vimrc", ".inputrc",
+}
+
+// safeHiddenDirs contains hidden directories whose contents are never treated as dirty
+var safeHiddenDirs = []string{".git", ".hg", ".svn", ".ssh", ".gnupg", ".config"}
+
+// isSafeFile checks if a file or directory is on the built-in or user-defined safe-list
+func isSafeFile(path string, safePatterns []*regexp.Regexp) bool {
+	fileName := filepath.Base(path)
+
+	// Check the built-in safe-list of hidden files, including variants such as .env.local
+	for _, safeName := range safeHiddenFiles {
+		if fileName == safeName || strings.HasPrefix(fileName, safeName+".") {
+			return true
+		}
+	}
+
+	// Check the built-in safe-list of hidden directories
+	for _, safeDir := range safeHiddenDirs {
+		if fileName == safeDir {
+			return true
+		}
+	}
+
+	// Check the user-defined safe-list against both the full path and the file name
+	for _, pattern := range safePatterns {
+		if pattern.MatchString(path) || pattern.MatchString(fileName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// buildArtifactType returns the build artifact type of a directory, if it is a well-known regenerable directory
+func buildArtifactType(path string) (DirtyFileType, bool) {
+	switch filepath.Base(path) {
+	case "node_modules":
+		return NodeModulesDir, true
+	case "__pycache__":
+		return PyCacheDir, true
+	case ".cache":
+		return CacheDir, true
+	case "target":
+		// Only treat target as a build artifact when it belongs to a Cargo or Maven project
+		parentDir := filepath.Dir(path)
+		for _, marker := range []string{"Cargo.toml", "pom.xml"} {
+			if _, err := os.Stat(filepath.Join(parentDir, marker)); err == nil {
+				return BuildTargetDir, true
+			}
+		}
+	}
+	return 0, false
+}
+
 // findDirtyFiles finds all dirty files in the specified folders
-func findDirtyFiles(folderPaths []string) (map[DirtyFileType][]string, error) {
+func findDirtyFiles(folderPaths []string, safePatterns []*regexp.Regexp) (map[DirtyFileType][]string, error) {
 	dirtyFiles := make(map[DirtyFileType][]string)
 
 	for _, folderPath := range folderPaths {
 		err := filepath.Walk(folderPath, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				// Skip files that can't be accessed
+				util.RecordSkipped(path, err)
 				return nil
 			}
 
+			// Skip safe-listed files, and the whole tree of safe-listed directories
+			if isSafeFile(path, safePatterns) {
+				if info.IsDir() && path != folderPath {
+					return filepath.SkipDir
+				}
+				if !info.IsDir() {
+					return nil
+				}
+			}
+
 			// Check if the file/directory matches any dirty criteria
 			if info.IsDir() {
+				// Build artifact folders are reported as a whole, don't look inside them
+				if dt, ok := buildArtifactType(path); ok && path != folderPath {
+					dirtyFiles[dt] = append(dirtyFiles[dt], path)
+					return filepath.SkipDir
+				}
+
+				// Skip VCS and package-manager internals
+				if isDefaultExcluded(path, folderPath, info) {
+					return filepath.SkipDir
+				}
+
 				if isEmptyFolder(path) {
 					dirtyFiles[EmptyFolder] = append(dirtyFiles[EmptyFolder], path)
 				}
-			} else {
+			} else if !isWorkspaceFile(path, info) {
 				fileName := filepath.Base(path)
 
 				// Check for empty files
@@ -547,8 +1645,8 @@ func findDirtyFiles(folderPaths []string) (map[DirtyFileType][]string, error) {
 					dirtyFiles[EmptyFile] = append(dirtyFiles[EmptyFile], path)
 				}
 
-				// Check for small files (< 1KB)
-				if info.Size() > 0 && info.Size() < 1024 {
+				// Check for small files (< smallFileThreshold)
+				if info.Size() > 0 && info.Size() < smallFileThreshold {
 					dirtyFiles[SmallFile] = append(dirtyFiles[SmallFile], path)
 				}
 
@@ -585,9 +1683,16 @@ func findDirtyFiles(folderPaths []string) (map[DirtyFileType][]string, error) {
 }
 
 // handleDirtyFiles handles the removal of dirty files based on user selection
-func handleDirtyFiles(folderPaths []string, listOnly bool, deleteToDir string) error {
+func handleDirtyFiles(folderPaths []string, listOnly bool, print0 bool, deleteToDir string, confirmEachType bool, safePatterns []*regexp.Regexp, recycleBin bool, emitScript string, flatten bool) error {
 	// Define all possible dirty file types
-	allDirtyTypes := []DirtyFileType{EmptyFile, SmallFile, MacHiddenFile, WindowsHiddenFile, EmptyFolder, LinuxHiddenFile, OfficeTempFile}
+	var allDirtyTypes []DirtyFileType
+	for _, dt := range []DirtyFileType{EmptyFile, SmallFile, MacHiddenFile, WindowsHiddenFile, EmptyFolder, LinuxHiddenFile, OfficeTempFile, NodeModulesDir, PyCacheDir, BuildTargetDir, CacheDir} {
+		// Skip the small file rule when it's disabled
+		if dt == SmallFile && smallFileThreshold <= 0 {
+			continue
+		}
+		allDirtyTypes = append(allDirtyTypes, dt)
+	}
 
 	// Prepare options for user selection
 	options := make([]string, len(allDirtyTypes))
@@ -621,7 +1726,7 @@ func handleDirtyFiles(folderPaths []string, listOnly bool, deleteToDir string) e
 	}
 
 	// Find all dirty files
-	dirtyFiles, err := findDirtyFiles(folderPaths)
+	dirtyFiles, err := findDirtyFiles(folderPaths, safePatterns)
 	if err != nil {
 		return fmt.Errorf("error finding dirty files: %v", err)
 	}
@@ -648,12 +1753,12 @@ func handleDirtyFiles(folderPaths []string, listOnly bool, deleteToDir string) e
 				}
 
 				// Add "All" option if there are multiple files
-				allOption := fmt.Sprintf("All %d files", len(files))
+				allOption := fmt.Sprintf(util.T("All %d files"), len(files))
 				fileOptions = append(fileOptions, allOption)
 
 				// Ask user which files to delete from this category
 				selectedFileOptions, err := util.SelectMultiple(
-					fmt.Sprintf("Select files to delete from %s (use space to select multiple, enter to confirm):", dt.String()),
+					fmt.Sprintf(util.T("Select files to delete from %s (use space to select multiple, enter to confirm):"), dt.String()),
 					fileOptions,
 				)
 				if err != nil {
@@ -681,14 +1786,30 @@ func handleDirtyFiles(folderPaths []string, listOnly bool, deleteToDir string) e
 
 	// Display results
 	totalFiles := 0
+	var totalSize, totalDiskSize int64
 	for dt, files := range filteredDirtyFiles {
 		// Remove empty entries after user selection
 		if len(files) > 0 {
-			util.PrintProcess("\n%s (%d):\n", dt.String(), len(files))
+			// Calculate the total size of this category
+			var categorySize, categoryDiskSize int64
+			for _, file := range files {
+				size, diskSize, _ := util.GetPathSize(file)
+				categorySize += size
+				categoryDiskSize += diskSize
+			}
+
+			util.PrintProcess("\n%s (%d, %s, %s on disk):\n", dt.String(), len(files), util.FormatSize(categorySize), util.FormatSize(categoryDiskSize))
 			for _, file := range files {
-				util.PrintProcess("  %s\n", file)
+				if print0 {
+					util.PrintPath0(file)
+				} else {
+					util.PrintProcess("  %s\n", file)
+				}
+				util.EmitJSON("dirty", map[string]any{"type": dt.Name(), "path": file})
 			}
 			totalFiles += len(files)
+			totalSize += categorySize
+			totalDiskSize += categoryDiskSize
 		} else {
 			// If user deselected all files in a category, remove it from the map
 			delete(filteredDirtyFiles, dt)
@@ -700,7 +1821,7 @@ func handleDirtyFiles(folderPaths []string, listOnly bool, deleteToDir string) e
 		return nil
 	}
 
-	util.PrintProcess("\nTotal dirty files found: %d\n", totalFiles)
+	util.PrintProcess("\nTotal dirty files found: %d (%s, %s on disk)\n", totalFiles, util.FormatSize(totalSize), util.FormatSize(totalDiskSize))
 
 	// If list only, exit here
 	if listOnly {
@@ -708,49 +1829,108 @@ func handleDirtyFiles(folderPaths []string, listOnly bool, deleteToDir string) e
 		return nil
 	}
 
-	// Ask for confirmation before deletion
-	confirmed, err := util.Confirm("Do you want to proceed with deletion? (y/N)", false)
-	if err != nil {
-		return fmt.Errorf("error getting confirmation: %v", err)
+	if confirmEachType {
+		// Ask for confirmation for each dirty file type separately
+		for _, dt := range allDirtyTypes {
+			files, exists := filteredDirtyFiles[dt]
+			if !exists {
+				continue
+			}
+
+			confirmed, err := util.Confirm(fmt.Sprintf(util.T("Delete %d files from %s? (y/N)"), len(files), dt.String()), false)
+			if err != nil {
+				return fmt.Errorf("error getting confirmation for %s: %v", dt.String(), err)
+			}
+
+			if !confirmed {
+				util.PrintProcess("Skipping %s\n", dt.String())
+				delete(filteredDirtyFiles, dt)
+			}
+		}
+
+		if len(filteredDirtyFiles) == 0 {
+			util.PrintSuccess("Operation cancelled by user.\n")
+			return nil
+		}
+	} else if emitScript == "" {
+		// Ask for confirmation before deletion, a script is reviewed before it's run instead
+		confirmed, err := util.Confirm("Do you want to proceed with deletion? (y/N)", false)
+		if err != nil {
+			return fmt.Errorf("error getting confirmation: %v", err)
+		}
+
+		if !confirmed {
+			util.PrintSuccess("Operation cancelled by user.\n")
+			return nil
+		}
 	}
 
-	if !confirmed {
-		util.PrintSuccess("Operation cancelled by user.\n")
+	if recycleBin {
+		// Send the files to the Recycle Bin instead of the delete directory
+		filesDeleted := 0
+		for _, files := range filteredDirtyFiles {
+			for _, file := range files {
+				if err := util.MoveToRecycleBin(file); err != nil {
+					util.PrintError("Error moving %s to the Recycle Bin: %v\n", file, err)
+					continue
+				}
+
+				util.PrintProcess("Moved %s to the Recycle Bin\n", file)
+				util.EmitJSON("recycled", map[string]any{"path": file})
+				filesDeleted++
+			}
+		}
+
+		util.PrintSuccess("Successfully moved %d dirty files to the Recycle Bin\n", filesDeleted)
 		return nil
 	}
 
-	// Create the destination directory if it doesn't exist
-	if err := os.MkdirAll(deleteToDir, 0755); err != nil {
+	// Write the moves to a script instead of performing them
+	var script *util.ScriptWriter
+	if emitScript != "" {
+		script, err = util.NewScriptWriter(emitScript, "clean dirty")
+		if err != nil {
+			return fmt.Errorf("error creating script %s: %v", emitScript, err)
+		}
+	} else if err := os.MkdirAll(deleteToDir, 0755); err != nil {
+		// Create the destination directory if it doesn't exist
 		return fmt.Errorf("error creating delete directory %s: %v", deleteToDir, err)
 	}
 
+	// The files moved are recorded, so undo can put them back
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+	journal := newJournal(db, "clean dirty")
+
+	// Destinations already used by the script, which doesn't move anything yet
+	scripted := make(map[string]bool)
+
 	// Process deletions
 	filesDeleted := 0
 	for _, files := range filteredDirtyFiles {
 		for _, file := range files {
-			// Create destination path preserving directory structure
-			relPath, err := filepath.Rel(folderPaths[0], file)
-			if err != nil {
-				// If we can't get relative path, just use the filename
-				relPath = filepath.Base(file)
-			}
-			destPath := filepath.Join(deleteToDir, relPath)
-
-			// For directories, we need to make sure the destination path is unique
-			if info, err := os.Stat(file); err == nil && info.IsDir() {
-				// For directories, append a suffix to avoid conflicts
-				counter := 1
-				originalDestPath := destPath
-				for {
-					if _, err := os.Stat(destPath); os.IsNotExist(err) {
-						break
-					}
-					ext := filepath.Ext(originalDestPath)
-					name := strings.TrimSuffix(originalDestPath, ext)
-					destPath = fmt.Sprintf("%s_%d%s", name, counter, ext)
-					counter++
+			// Create destination path preserving directory structure from the parent of the folder the file is in,
+			// so files of several folders keep apart, or directly in the delete directory when flattening
+			relPath := filepath.Base(file)
+			if !flatten {
+				if rel, err := getRelativePathFromParent(file, folderPaths); err == nil {
+					relPath = rel
 				}
 			}
+			// Append a suffix when the destination is taken, by an earlier run or another folder
+			destPath := util.UniqueDestPath(filepath.Join(deleteToDir, relPath), scripted)
+
+			if script != nil {
+				script.Move(file, destPath)
+				scripted[destPath] = true
+				util.PrintProcess("Scripted moving %s to %s\n", file, destPath)
+				util.EmitJSON("scripted", map[string]any{"path": file, "to": destPath})
+				filesDeleted++
+				continue
+			}
 
 			// Create destination directory if needed
 			destDir := filepath.Dir(destPath)
@@ -765,11 +1945,24 @@ func handleDirtyFiles(folderPaths []string, listOnly bool, deleteToDir string) e
 				continue
 			}
 
+			if err := util.RecordQuarantine(deleteToDir, destPath, file); err != nil {
+				util.PrintWarning("Warning: Could not record %s in the manifest: %v\n", destPath, err)
+			}
+			journal.recordMove(file, destPath, deleteToDir, nil)
 			util.PrintProcess("Moved %s to %s\n", file, destPath)
+			util.EmitJSON("moved", map[string]any{"path": file, "to": destPath})
 			filesDeleted++
 		}
 	}
 
+	if script != nil {
+		if err := script.Close(); err != nil {
+			return fmt.Errorf("error writing script %s: %v", emitScript, err)
+		}
+		util.PrintSuccess("Wrote %d moves to %s, review and run it to move the dirty files to %s\n", filesDeleted, emitScript, deleteToDir)
+		return nil
+	}
+
 	util.PrintSuccess("Successfully moved %d dirty files to %s\n", filesDeleted, deleteToDir)
 	return nil
 }
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// completeTags completes a tag flag with the tags recorded in the database
+func completeTags(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	db, err := data.Connect()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer db.Close()
+
+	tags, err := db.GetTags()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completions []string
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, toComplete) {
+			completions = append(completions, tag)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeCatalogedDirs completes a path with the directories recorded in the database, one level at a
+// time. Relative paths and paths the database doesn't know fall back to the shell's file completion.
+func completeCatalogedDirs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if strings.HasPrefix(toComplete, "@") {
+		return completePathAliases(toComplete)
+	}
+	if toComplete != "" && !filepath.IsAbs(toComplete) {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+
+	db, err := data.Connect()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	defer db.Close()
+
+	// Without a path, start with the directory containing everything that was synced
+	dir := ""
+	if toComplete != "" {
+		dir = toComplete
+		if !strings.HasSuffix(dir, string(filepath.Separator)) {
+			dir = filepath.Dir(dir)
+		}
+	}
+
+	dirs, err := db.GetCatalogedDirs(dir)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+
+	var completions []string
+	for _, catalogedDir := range dirs {
+		// Directories end with a separator, so the next completion continues inside them
+		catalogedDir = strings.TrimSuffix(catalogedDir, string(filepath.Separator)) + string(filepath.Separator)
+		if strings.HasPrefix(catalogedDir, toComplete) {
+			completions = append(completions, catalogedDir)
+		}
+	}
+	if len(completions) == 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return completions, cobra.ShellCompDirectiveNoSpace
+}
+
+// completePathAliases completes the @name path aliases defined in the config
+func completePathAliases(toComplete string) ([]string, cobra.ShellCompDirective) {
+	config, err := util.LoadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completions []string
+	for name := range config.Aliases {
+		alias := "@" + name + string(filepath.Separator)
+		if strings.HasPrefix(alias, toComplete) {
+			completions = append(completions, alias)
+		}
+	}
+	sort.Strings(completions)
+	return completions, cobra.ShellCompDirectiveNoSpace
+}
+
+// completeProfiles completes the --profile flag with the profiles defined in the config
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	config, err := util.LoadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completions []string
+	for name := range config.Profiles {
+		if strings.HasPrefix(name, toComplete) {
+			completions = append(completions, name)
+		}
+	}
+	sort.Strings(completions)
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeColumns completes the comma-separated --columns flag of a table with its column names
+func completeColumns(columns []string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		// Only the name after the last comma is being completed
+		prefix, current := "", toComplete
+		if i := strings.LastIndex(toComplete, ","); i >= 0 {
+			prefix, current = toComplete[:i+1], toComplete[i+1:]
+		}
+
+		var completions []string
+		for _, column := range columns {
+			if strings.HasPrefix(column, current) {
+				completions = append(completions, prefix+column)
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	}
+}
@@ -0,0 +1,376 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// cpCmd represents the cp command
+var cpCmd = &cobra.Command{
+	Use:   "cp <src> <dst>",
+	Short: "Copy a file or folder, verifying every copy",
+	Long: `Copy a file or folder like cp, verifying every copy and recording it in the catalog. When the destination is an existing folder, the source is copied into it.
+
+Every file is copied while its Blake3 and MD5 values are calculated, the copy is read back and compared with them and with the values recorded for the source, and flushed to disk, before it appears under its name. The copy keeps the modification time, permissions, owner when allowed, and extended attributes of the source. A file whose contents don't match stops the copy.
+
+An interrupted copy is resumed by running the same command again: a file copied halfway continues from where it stopped, and the files already copied with the same size and modification time are skipped.`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		src, err := util.CanonicalPath(args[0])
+		if err != nil {
+			util.PrintError("Error getting absolute path for %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+		dst, err := util.CanonicalPath(args[1])
+		if err != nil {
+			util.PrintError("Error getting absolute path for %s: %v\n", args[1], err)
+			os.Exit(1)
+		}
+		if info, err := os.Stat(dst); err == nil && info.IsDir() {
+			dst = filepath.Join(dst, filepath.Base(src))
+		}
+		exitUnlessAllowed(dst)
+
+		if err := copyVerified(src, dst); err != nil {
+			util.PrintError("Error copying %s to %s: %v\n", src, dst, err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cpCmd)
+}
+
+// copyVerified copies a file or folder, verifying every file, and records the copies
+func copyVerified(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if isSubPath(dst, src) {
+		return fmt.Errorf("can't copy %s into itself", src)
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	if !info.IsDir() {
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("%s is not a regular file or folder", src)
+		}
+		if alreadyCopied(dst, info) {
+			util.PrintSuccess("%s is already copied to %s.\n", src, dst)
+			return nil
+		}
+		if _, err := os.Lstat(dst); err == nil {
+			return fmt.Errorf("%s exists", dst)
+		}
+		if err := copyFileRecorded(db, src, dst, info); err != nil {
+			return err
+		}
+		util.PrintSuccess("Copied %s to %s (%s), verified.\n", src, dst, util.FormatSize(info.Size()))
+		return nil
+	}
+
+	files, size, err := copyTreeVerified(db, src, dst, false)
+	if err != nil {
+		return err
+	}
+	util.PrintSuccess("Copied %d files (%s) to %s, verified.\n", files, util.FormatSize(size), dst)
+	return nil
+}
+
+// copyFileRecorded copies a file with copyFileVerified and records the copy
+func copyFileRecorded(db *data.DB, src, dst string, info os.FileInfo) error {
+	// A record left at the path of a file deleted since would be taken for the copy's
+	if _, err := dropRecordsUnder(db, dst); err != nil {
+		return err
+	}
+	record, err := copyFileVerified(db, src, dst, info)
+	if err != nil {
+		return err
+	}
+
+	// The copy is a file of its own, owned by whoever made it
+	copied := *record
+	copied.Owner = ""
+	if err := recordFileAt(db, &copied, dst); err != nil {
+		return err
+	}
+	util.EmitJSON("copied", map[string]any{"path": src, "to": dst, "size": info.Size(), "md5": record.MD5, "blake3": record.Blake3})
+	return nil
+}
+
+// copyTreeVerified copies the files of a folder with copyFileVerified, recreating its folders and symlinks,
+// and returns the number and size of the files copied. With remove, the source files are deleted once
+// copied and their records follow them, and the emptied folders are removed.
+func copyTreeVerified(db *data.DB, src, dst string, remove bool) (int, int64, error) {
+	var files int
+	var size int64
+	var dirs []string
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, relPath)
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return fmt.Errorf("error creating directory %s: %v", target, err)
+			}
+			dirs = append(dirs, path)
+		case info.Mode()&os.ModeSymlink != 0:
+			if _, err := os.Lstat(target); err == nil && !remove {
+				return nil
+			}
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return fmt.Errorf("error creating symlink %s: %v", target, err)
+			}
+			if remove {
+				if err := os.Remove(path); err != nil {
+					return err
+				}
+				if _, err := moveRecords(db, path, target); err != nil {
+					return err
+				}
+			}
+		case info.Mode().IsRegular():
+			if remove {
+				if err := moveFileVerified(db, path, target, info); err != nil {
+					return err
+				}
+			} else if alreadyCopied(target, info) {
+				return nil
+			} else if _, err := os.Lstat(target); err == nil {
+				return fmt.Errorf("%s exists", target)
+			} else if err := copyFileRecorded(db, path, target, info); err != nil {
+				return err
+			}
+			files++
+			size += info.Size()
+		default:
+			util.PrintWarning("Warning: Skipping %s, it is not a regular file\n", path)
+		}
+		return nil
+	})
+	if err != nil {
+		return files, size, err
+	}
+
+	// Folders get their modification times once their files are in, deepest first, and are removed
+	// once emptied when moving
+	for i := len(dirs) - 1; i >= 0; i-- {
+		relPath, _ := filepath.Rel(src, dirs[i])
+		if info, err := os.Stat(dirs[i]); err == nil {
+			os.Chtimes(filepath.Join(dst, relPath), info.ModTime(), info.ModTime())
+		}
+		if remove {
+			os.Remove(dirs[i])
+		}
+	}
+	return files, size, nil
+}
+
+// copyFileVerified copies a file while hashing it, verifies the copy against the contents read, the copy read
+// back and the values recorded for the file, flushes it to disk and gives it the metadata of the file. The copy
+// only appears under its name once verified, a copy interrupted before is resumed. It returns the record of
+// the file, hashed first when it wasn't synced or changed since.
+func copyFileVerified(db *data.DB, src, dst string, info os.FileInfo) (*data.FileInfo, error) {
+	record, err := upToDateRecord(db, src, info)
+	if err != nil {
+		return nil, err
+	}
+
+	partialPath := dst + ".partial"
+	var blake3Hash, md5Hash string
+	for {
+		var resumed int64
+		err := util.Retry(src, func() error {
+			// A retry resumes from the bytes written so far
+			var err error
+			blake3Hash, md5Hash, resumed, err = resumeCopyHashed(src, partialPath, info.Size())
+			return err
+		})
+		if err != nil {
+			// The partial copy is kept for the next attempt to resume
+			return nil, fmt.Errorf("error copying %s: %v", src, err)
+		}
+		if blake3Hash == record.Blake3 && md5Hash == record.MD5 {
+			break
+		}
+		os.Remove(partialPath)
+
+		// The copy resumed may be of another version of the file, it is copied again from the start
+		if resumed > 0 {
+			util.PrintWarning("Warning: The copy of %s resumed from %s doesn't match, copying it again\n", src, util.FormatSize(resumed))
+			continue
+		}
+		return nil, fmt.Errorf("%s doesn't match the values recorded for it, it changed or is corrupted", src)
+	}
+	if copied, copiedMD5, err := util.FileBlake3MD5(partialPath); err != nil || copied != blake3Hash || copiedMD5 != md5Hash {
+		os.Remove(partialPath)
+		return nil, fmt.Errorf("the copy of %s doesn't match it when read back", src)
+	}
+
+	copyMetadata(src, partialPath, info)
+	if err := os.Chtimes(partialPath, info.ModTime(), info.ModTime()); err != nil {
+		os.Remove(partialPath)
+		return nil, fmt.Errorf("error setting modification time of %s: %v", dst, err)
+	}
+	if err := os.Rename(partialPath, dst); err != nil {
+		os.Remove(partialPath)
+		return nil, fmt.Errorf("error renaming %s: %v", partialPath, err)
+	}
+	syncDir(filepath.Dir(dst))
+	return record, nil
+}
+
+// resumeCopyHashed copies src to partialPath, keeping the bytes already there when they're fewer than size,
+// and returns the Blake3 and MD5 values of the whole copy and the number of bytes kept
+func resumeCopyHashed(src, partialPath string, size int64) (string, string, int64, error) {
+	if err := os.MkdirAll(filepath.Dir(partialPath), 0755); err != nil {
+		return "", "", 0, fmt.Errorf("error creating directory %s: %w", filepath.Dir(partialPath), err)
+	}
+
+	// Open source file
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("error opening source file: %w", err)
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(partialPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("error creating destination file: %w", err)
+	}
+	defer dstFile.Close()
+
+	// The bytes already copied are hashed again instead of copied
+	hasher := util.NewHashingWriter()
+	kept, err := io.Copy(hasher, dstFile)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("error reading the partial copy: %w", err)
+	}
+	if kept > size {
+		if err := dstFile.Truncate(0); err != nil {
+			return "", "", 0, fmt.Errorf("error truncating the partial copy: %w", err)
+		}
+		if _, err := dstFile.Seek(0, io.SeekStart); err != nil {
+			return "", "", 0, err
+		}
+		hasher, kept = util.NewHashingWriter(), 0
+	}
+	if kept > 0 {
+		util.PrintProcess("Resuming the copy of %s after %s\n", src, util.FormatSize(kept))
+		if _, err := srcFile.Seek(kept, io.SeekStart); err != nil {
+			return "", "", kept, fmt.Errorf("error seeking source file: %w", err)
+		}
+	}
+
+	copied, err := io.Copy(io.MultiWriter(dstFile, hasher), srcFile)
+	util.CountBytesCopied(copied)
+	util.CountFileHashed(kept + copied)
+	if err != nil {
+		return "", "", kept, fmt.Errorf("error copying file contents: %w", err)
+	}
+
+	// Sync to ensure data is written to disk
+	if err := dstFile.Sync(); err != nil {
+		return "", "", kept, fmt.Errorf("error syncing destination file: %w", err)
+	}
+
+	blake3Hash, md5Hash := hasher.Sums()
+	return blake3Hash, md5Hash, kept, nil
+}
+
+// copyMetadata gives a copy the owner, extended attributes, permissions and ACL of its source. The owner is
+// only kept when allowed, like cp -p does, the others are best effort.
+func copyMetadata(src, dst string, info os.FileInfo) {
+	// The owner goes first, changing it clears the setuid and setgid bits
+	if uid, gid, ok := util.FileOwnerIDs(info); ok {
+		util.SetFileOwner(dst, uid, gid)
+	}
+
+	// Extended attributes go before the permission bits, which may make the file read-only
+	if err := util.CopyXattrs(src, dst); err != nil {
+		util.PrintWarning("Warning: Could not copy the extended attributes of %s: %v\n", src, err)
+	}
+
+	if err := os.Chmod(dst, info.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+		util.PrintWarning("Warning: Could not set the permissions of %s: %v\n", dst, err)
+	}
+	if acl, err := util.FileACL(src); err == nil && acl != "" {
+		if err := util.SetFileACL(dst, acl); err != nil {
+			util.PrintWarning("Warning: Could not set the ACL of %s: %v\n", dst, err)
+		}
+	}
+}
+
+// alreadyCopied reports whether a file with the size and modification time of a source file is at dst, as
+// copies only appear under their name once verified
+func alreadyCopied(dst string, info os.FileInfo) bool {
+	dstInfo, err := os.Lstat(dst)
+	return err == nil && dstInfo.Mode().IsRegular() && dstInfo.Size() == info.Size() && dstInfo.ModTime().Equal(info.ModTime())
+}
+
+// recordFileAt records a file at the path it was copied or moved to, from the record of its source
+func recordFileAt(db *data.DB, record *data.FileInfo, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("error getting file info for %s: %v", path, err)
+	}
+
+	record.ID = 0
+	record.Path = path
+	record.Name = filepath.Base(path)
+	record.DiskSize = util.GetDiskUsage(info)
+	record.CTime = util.GetCreationTime(info)
+	record.SetVolume()
+	record.SetPermissions(info)
+	if err := db.UpsertFileInfo(record); err != nil {
+		return fmt.Errorf("error upserting file info for %s: %v", path, err)
+	}
+	return nil
+}
+
+// isSubPath reports whether path is dir or below it
+func isSubPath(path, dir string) bool {
+	relPath, err := filepath.Rel(dir, path)
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// syncDir flushes the entries of a directory to disk, so a file renamed into it survives a crash. This is
+// best effort, directories can't be flushed on every platform.
+func syncDir(dir string) {
+	if f, err := os.Open(dir); err == nil {
+		f.Sync()
+		f.Close()
+	}
+}
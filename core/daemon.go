@@ -0,0 +1,147 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Keep directories watched in the background",
+	Long:  `Commands installing and removing a daemon running watch in the background, as a systemd service on Linux, a launchd job on macOS and a service on Windows, so the records of directories stay up to date without a terminal left open or hand-written units. Periodic runs of other commands are installed with schedule.`,
+}
+
+// daemonInstallCmd represents the daemon install command
+var daemonInstallCmd = &cobra.Command{
+	Use:   "install [flags] <dirs>",
+	Short: "Watch directories in the background",
+	Long: `Install a daemon running watch on the directories, started now and again at every login, or at boot with --system, and restarted when it fails. On Linux a systemd service named fsakd-<name> is written, enabled and started, and its output goes to the journal (journalctl --user -u fsakd-<name>). On macOS a launchd job is written and loaded, with its output in the logs directory of the workspace. On Windows a service named fsakd-<name> is registered and started, running as LocalSystem with its messages in the Application event log; it needs an administrator, and is always installed for the whole system.
+
+The daemon is installed for the current user (--user, the default) unless --system is given, which installs it for the whole system and needs root. It uses the workspace, profile and language of the install. Installing a daemon of the same name again replaces its directories and flags.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("name")
+		system, _ := cmd.Flags().GetBool("system")
+
+		if err := installDaemon(cmd, name, args, system); err != nil {
+			util.PrintError("Error installing daemon: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// daemonRemoveCmd represents the daemon remove command
+var daemonRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Stop and remove a daemon",
+	Long:  `Stop a daemon installed by daemon install and remove it, deleting its systemd service, launchd job or Windows service. The pending changes are recorded before it stops.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("name")
+		system, _ := cmd.Flags().GetBool("system")
+
+		paths, err := util.RemoveDaemon(name, system)
+		if err != nil {
+			util.PrintError("Error removing daemon: %v\n", err)
+			os.Exit(1)
+		}
+		for _, path := range paths {
+			util.PrintProcess("Removed %s\n", path)
+		}
+		util.PrintSuccess("Daemon %s removed.\n", name)
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{daemonInstallCmd, daemonRemoveCmd} {
+		cmd.Flags().String("name", "watch", "Name of the daemon, to run several with different directories")
+		cmd.Flags().Bool("user", false, "Install for the current user, the default")
+		cmd.Flags().Bool("system", false, "Install for the whole system instead of the current user (needs root)")
+		cmd.MarkFlagsMutuallyExclusive("user", "system")
+	}
+
+	// The flags of watch, passed on to the daemon
+	daemonInstallCmd.Flags().StringP("tag", "T", "", "Tag of the files recorded, files already recorded keep theirs")
+	daemonInstallCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	daemonInstallCmd.Flags().StringP("blacklist", "B", "", "Blacklist file containing paths to exclude (supports regex)")
+	daemonInstallCmd.Flags().IntP("batch", "b", 100, "Maximum number of records written to the database at once")
+	daemonInstallCmd.Flags().Duration("debounce", 2*time.Second, "Time without events for a file before it's recorded, such as 500ms or 10s")
+
+	daemonCmd.AddCommand(daemonInstallCmd)
+	daemonCmd.AddCommand(daemonRemoveCmd)
+	rootCmd.AddCommand(daemonCmd)
+}
+
+// installDaemon checks the directories and flags of the watch command and registers it with the service manager
+func installDaemon(cmd *cobra.Command, name string, dirs []string, system bool) error {
+	if err := util.ValidateScheduleName(name); err != nil {
+		return err
+	}
+
+	// The daemon doesn't run in the current directory, so it gets absolute paths
+	dirs, err := absolutePaths(dirs)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+	}
+
+	args := []string{watchCmd.Name()}
+	if tag, _ := cmd.Flags().GetString("tag"); tag != "" {
+		args = append(args, "--tag", tag)
+	}
+	if blacklistFile, _ := cmd.Flags().GetString("blacklist"); blacklistFile != "" {
+		if _, err := util.ReadBlacklist(blacklistFile); err != nil {
+			return fmt.Errorf("error reading blacklist: %v", err)
+		}
+		absPath, err := filepath.Abs(blacklistFile)
+		if err != nil {
+			return err
+		}
+		args = append(args, "--blacklist", absPath)
+	}
+	if batchSize, _ := cmd.Flags().GetInt("batch"); batchSize < 1 {
+		return fmt.Errorf("--batch must be at least 1")
+	} else if cmd.Flags().Changed("batch") {
+		args = append(args, "--batch", fmt.Sprint(batchSize))
+	}
+	if debounce, _ := cmd.Flags().GetDuration("debounce"); debounce <= 0 {
+		return fmt.Errorf("--debounce must be positive")
+	} else if cmd.Flags().Changed("debounce") {
+		args = append(args, "--debounce", debounce.String())
+	}
+	args = append(args, "--")
+	args = append(args, dirs...)
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error getting the working directory: %v", err)
+	}
+
+	paths, err := util.InstallDaemon(util.Daemon{
+		Name:   name,
+		Args:   args,
+		Env:    util.ScheduleEnv(),
+		Dir:    dir,
+		System: system,
+	})
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		util.PrintProcess("Wrote %s\n", path)
+	}
+	util.PrintSuccess("Daemon %s installed, watching %s.\n", name, strings.Join(dirs, ", "))
+	return nil
+}
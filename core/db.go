@@ -0,0 +1,311 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// dbCmd represents the db command
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the database",
+	Long:  `Commands for managing the records of the database.`,
+}
+
+// dbImportSumsCmd represents the db import-sums command
+var dbImportSumsCmd = &cobra.Command{
+	Use:   "import-sums <manifest>",
+	Short: "Import checksums from md5sum, sha256sum or b3sum manifests",
+	Long: `Import the checksums of an existing manifest written by md5sum, sha256sum or b3sum, in the GNU or the BSD-style (--tag) format. Records missing an MD5, SHA256 or Blake3 value get the one of the manifest, and records are created for the listed files that aren't in the database yet, so previously generated checksums become queryable without hashing the files again.
+
+The paths of the manifest are relative to --base, by default the directory of the manifest. Files modified after the manifest was written are skipped, and values that differ from the ones already recorded are reported and not imported.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		base, _ := cmd.Flags().GetString("base")
+		algorithm, _ := cmd.Flags().GetString("algo")
+		tag, _ := cmd.Flags().GetString("tag")
+
+		if algorithm == "" {
+			algorithm = util.SumAlgorithmFromName(filepath.Base(args[0]))
+		} else if algorithm != util.SumMD5 && algorithm != util.SumSHA256 && algorithm != util.SumBlake3 {
+			util.PrintError("Error: invalid --algo %s, expected %s, %s or %s\n", algorithm, util.SumMD5, util.SumSHA256, util.SumBlake3)
+			os.Exit(1)
+		}
+
+		// Manifests list the files relative to the directory they were written in
+		if base == "" {
+			base = filepath.Dir(args[0])
+		}
+		base, err := filepath.Abs(base)
+		if err != nil {
+			util.PrintError("Error getting absolute path for %s: %v\n", base, err)
+			os.Exit(1)
+		}
+
+		if err := importSums(args[0], base, algorithm, tag); err != nil {
+			util.PrintError("Error importing checksums: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// dbPruneCmd represents the db prune command
+var dbPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete the records of a tag or a volume in bulk",
+	Long: `Delete the records synced with a tag, or of a volume, with their history and extended attributes, so the catalog of disks that are gone or of data that no longer matters can be expired without writing SQL. With --older-than only the records that weren't synced for that long are deleted, such as 1y, 6m, 2w or 30d. The files themselves are never touched.
+
+Without --tag and --volume, the rules of the [retention] section of the configuration are applied, such as "tag:old-usb = 1y" or "volume:BACKUP-2019 = 6m".`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tag, _ := cmd.Flags().GetString("tag")
+		volume, _ := cmd.Flags().GetString("volume")
+		olderThan, _ := cmd.Flags().GetString("older-than")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		var filters []data.PruneFilter
+		if tag != "" || volume != "" {
+			filter := data.PruneFilter{Tag: tag, Volume: volume}
+			if olderThan != "" {
+				maxAge, err := util.ParseAge(olderThan)
+				if err != nil {
+					util.PrintError("Error: invalid --older-than: %v\n", err)
+					os.Exit(1)
+				}
+				filter.SyncedBefore = time.Now().Add(-maxAge)
+			}
+			filters = append(filters, filter)
+		} else {
+			config, err := util.LoadConfig()
+			if err != nil {
+				util.PrintError("Error loading config: %v\n", err)
+				os.Exit(1)
+			}
+			if olderThan != "" || len(config.Retention) == 0 {
+				util.PrintError("Error: --tag or --volume is required when no [retention] rules are configured\n")
+				os.Exit(1)
+			}
+			for _, rule := range config.Retention {
+				filters = append(filters, data.PruneFilter{Tag: rule.Tag, Volume: rule.Volume, SyncedBefore: time.Now().Add(-rule.MaxAge)})
+			}
+		}
+
+		if err := pruneRecords(filters, dryRun); err != nil {
+			util.PrintError("Error pruning records: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	dbImportSumsCmd.Flags().String("base", "", "Directory the paths of the manifest are relative to (default: the directory of the manifest)")
+	dbImportSumsCmd.Flags().String("algo", "", "Algorithm of the manifest: md5, sha256 or blake3 (default: guessed from the manifest name)")
+	dbImportSumsCmd.Flags().StringP("tag", "T", "", "Tag for the records created by the import")
+	dbImportSumsCmd.RegisterFlagCompletionFunc("base", completeCatalogedDirs)
+	dbImportSumsCmd.RegisterFlagCompletionFunc("algo", cobra.FixedCompletions([]string{util.SumMD5, util.SumSHA256, util.SumBlake3}, cobra.ShellCompDirectiveNoFileComp))
+	dbImportSumsCmd.RegisterFlagCompletionFunc("tag", completeTags)
+
+	dbPruneCmd.Flags().StringP("tag", "T", "", "Delete the records synced with this tag")
+	dbPruneCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	dbPruneCmd.Flags().String("volume", "", "Delete the records of this volume, by ID or label")
+	dbPruneCmd.Flags().String("older-than", "", "Only delete the records that weren't synced for this long, such as 1y, 6m, 2w or 30d")
+	dbPruneCmd.Flags().Bool("dry-run", false, "Only print how many records would be deleted")
+
+	dbCmd.AddCommand(dbImportSumsCmd)
+	dbCmd.AddCommand(dbPruneCmd)
+	rootCmd.AddCommand(dbCmd)
+}
+
+// sumField returns the field of a record holding the hash of an algorithm
+func sumField(record *data.FileInfo, algorithm string) *string {
+	switch algorithm {
+	case util.SumMD5:
+		return &record.MD5
+	case util.SumSHA256:
+		return &record.SHA256
+	}
+	return &record.Blake3
+}
+
+// importSums records the checksums of a manifest, whose paths are relative to base. Lines naming their
+// algorithm override the given one.
+func importSums(manifest, base, algorithm, tag string) error {
+	entries, malformed, err := util.ReadSumFile(manifest)
+	if err != nil {
+		return fmt.Errorf("error reading manifest %s: %v", manifest, err)
+	}
+
+	// The checksums of files modified after the manifest was written are outdated
+	manifestInfo, err := os.Stat(manifest)
+	if err != nil {
+		return fmt.Errorf("error getting file info for %s: %v", manifest, err)
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var created, enriched, known, conflicting, missing, outdated int
+	for _, entry := range entries {
+		entryAlgorithm := entry.Algorithm
+		if entryAlgorithm == "" {
+			entryAlgorithm = algorithm
+		}
+		if entryAlgorithm == "" {
+			return fmt.Errorf("can't tell the algorithm of %s from its name, use --algo", manifest)
+		}
+		if len(entry.Hash) != util.SumHashLength(entryAlgorithm) {
+			malformed++
+			continue
+		}
+
+		path := filepath.FromSlash(entry.Name)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(base, path)
+		}
+
+		record, err := db.GetFileInfoByPath(path)
+		if err == nil {
+			if record.MTime.After(manifestInfo.ModTime()) {
+				outdated++
+				continue
+			}
+
+			field := sumField(record, entryAlgorithm)
+			switch *field {
+			case "":
+				*field = entry.Hash
+				if err := db.UpsertFileInfo(record); err != nil {
+					return fmt.Errorf("error upserting file info for %s: %v", path, err)
+				}
+				enriched++
+			case entry.Hash:
+				known++
+			default:
+				util.PrintWarning("Warning: the %s of %s in the manifest differs from the recorded one, keeping the recorded one\n", entryAlgorithm, path)
+				conflicting++
+			}
+			continue
+		}
+		if err != gorm.ErrRecordNotFound {
+			return fmt.Errorf("error getting file info for %s: %v", path, err)
+		}
+
+		// Records are only created for files that exist, their size and times come from the file
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			if err == nil {
+				err = fmt.Errorf("not a regular file")
+			}
+			util.RecordSkipped(path, err)
+			missing++
+			continue
+		}
+
+		if info.ModTime().After(manifestInfo.ModTime()) {
+			outdated++
+			continue
+		}
+
+		record = &data.FileInfo{
+			Key:      util.PathKey(path),
+			Name:     filepath.Base(path),
+			Path:     path,
+			Status:   0, // File exists
+			Size:     info.Size(),
+			DiskSize: util.GetDiskUsage(info),
+			Tag:      tag,
+			MTime:    info.ModTime(),
+			CTime:    util.GetCreationTime(info),
+		}
+		*sumField(record, entryAlgorithm) = entry.Hash
+		record.SetVolume()
+		record.SetPermissions(info)
+		if err := db.UpsertFileInfo(record); err != nil {
+			return fmt.Errorf("error upserting file info for %s: %v", path, err)
+		}
+		created++
+	}
+
+	util.PrintSuccess("Imported %s: %d records created, %d enriched, %d already known.\n", manifest, created, enriched, known)
+	if conflicting > 0 {
+		util.PrintWarning("%d checksums differ from the recorded ones, the files may have changed since the manifest was written.\n", conflicting)
+	}
+	if outdated > 0 {
+		util.PrintWarning("%d files were modified after the manifest was written and were skipped.\n", outdated)
+	}
+	if missing > 0 {
+		util.PrintWarning("%d listed files don't exist under %s.\n", missing, base)
+	}
+	if malformed > 0 {
+		util.PrintWarning("%d lines of the manifest couldn't be read.\n", malformed)
+	}
+	return nil
+}
+
+// describePruneFilter describes the records selected by a prune filter
+func describePruneFilter(filter data.PruneFilter) string {
+	var parts []string
+	if filter.Tag != "" {
+		parts = append(parts, fmt.Sprintf(util.T("tag %s"), filter.Tag))
+	}
+	if filter.Volume != "" {
+		parts = append(parts, fmt.Sprintf(util.T("volume %s"), filter.Volume))
+	}
+	if !filter.SyncedBefore.IsZero() {
+		parts = append(parts, fmt.Sprintf(util.T("last synced before %s"), filter.SyncedBefore.Format("2006-01-02 15:04")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// pruneRecords deletes the records selected by each filter, or only counts them on a dry run
+func pruneRecords(filters []data.PruneFilter, dryRun bool) error {
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var total int64
+	for _, filter := range filters {
+		files, size, err := db.CountPrunable(filter)
+		if err != nil {
+			return fmt.Errorf("error counting records of %s: %v", describePruneFilter(filter), err)
+		}
+		if files == 0 {
+			util.PrintProcess("No records of %s to delete\n", describePruneFilter(filter))
+			continue
+		}
+		if dryRun {
+			util.PrintProcess("%d records (%s) of %s would be deleted\n", files, util.FormatSize(size), describePruneFilter(filter))
+			total += files
+			continue
+		}
+
+		pruned, err := db.PruneFileInfos(filter)
+		if err != nil {
+			return fmt.Errorf("error deleting records of %s: %v", describePruneFilter(filter), err)
+		}
+		util.PrintProcess("Deleted %d records (%s) of %s\n", pruned, util.FormatSize(size), describePruneFilter(filter))
+		total += pruned
+	}
+
+	if dryRun {
+		util.PrintSuccess("Dry run: %d records would be deleted.\n", total)
+	} else {
+		util.PrintSuccess("Pruned %d records.\n", total)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// dedupeCmd represents the dedupe command for sharing storage between duplicate files
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe [folder paths...]",
+	Short: "Share storage between duplicate files without removing them",
+	Long: `Find duplicate files in specified folder paths using MD5 and Blake3 values and let the filesystem share their storage. With --block, the kernel dedup ioctl (FIDEDUPERANGE) shares extents between identical files on Btrfs/XFS. The kernel verifies the contents are identical, so both paths keep working.
+
+With --symlink, one canonical copy of each content is kept, the one in the earliest folder given, and the other copies are replaced with symlinks to it, which works on any filesystem and keeps every path browseable.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		block, _ := cmd.Flags().GetBool("block")
+		symlink, _ := cmd.Flags().GetBool("symlink")
+		maxMemoryValue, _ := cmd.Flags().GetString("max-memory")
+		scope, _ := cmd.Flags().GetString("scope")
+
+		maxMemory, err := parseMaxMemory(maxMemoryValue)
+		if err != nil {
+			util.PrintError("Error: %v\n", err)
+			os.Exit(1)
+		}
+		exitOnInvalidScope(scope)
+
+		if !block && !symlink {
+			util.PrintError("Error: a dedup mode is required (--block or --symlink)\n")
+			os.Exit(1)
+		}
+		if block && !util.BlockDedupeSupported() {
+			util.PrintError("Error: --block is only supported on Linux\n")
+			os.Exit(1)
+		}
+
+		exitUnlessAllowed(args...)
+
+		if symlink {
+			err = handleSymlinkDedupe(args, maxMemory, scope)
+		} else {
+			err = handleBlockDedupe(args, maxMemory, scope)
+		}
+		if err != nil {
+			util.PrintError("Error during dedupe operation: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	dedupeCmd.Flags().Bool("block", false, "Share extents between identical files with the kernel dedup ioctl (Btrfs/XFS, Linux only)")
+	dedupeCmd.Flags().Bool("symlink", false, "Replace duplicates with symlinks to one canonical copy, preferring copies in the earliest folders given")
+	dedupeCmd.MarkFlagsMutuallyExclusive("block", "symlink")
+	dedupeCmd.Flags().String("max-memory", "", "Memory limit (e.g. 512M, 2G), duplicate groups are moved to a temporary database when it's approached")
+	addScopeFlag(dedupeCmd)
+	rootCmd.AddCommand(dedupeCmd)
+}
+
+// handleBlockDedupe shares extents between the files of each duplicate group
+func handleBlockDedupe(folderPaths []string, maxMemory int64, scope string) error {
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	duplicateGroups, err := findDuplicateGroups(db, folderPaths, nil, false, maxMemory, nil)
+	if err != nil {
+		return err
+	}
+	duplicateGroups = scopeDuplicateGroups(duplicateGroups, folderPaths, scope)
+
+	if len(duplicateGroups) == 0 {
+		util.PrintSuccess("No duplicate files found.\n")
+		return nil
+	}
+
+	util.PrintProcess("Found %d groups of duplicate files.\n", len(duplicateGroups))
+
+	var totalDeduped int64
+	filesDeduped := 0
+	for i, group := range duplicateGroups {
+		// Sort by path so the same file is always used as the source
+		sort.Slice(group, func(j, k int) bool {
+			return group[j].Path < group[k].Path
+		})
+
+		source := group[0]
+		util.PrintProcess("Duplicate group %d/%d (%d files), source: %s\n", i+1, len(duplicateGroups), len(group), source.Path)
+
+		for _, fileInfo := range group[1:] {
+			// Skip files that already share all extents with the source
+			if shared, err := util.SharesAllExtents(source.Path, fileInfo.Path); err == nil && shared {
+				util.PrintProcess("Already sharing extents: %s\n", fileInfo.Path)
+				continue
+			}
+
+			deduped, err := util.DedupeFileRange(source.Path, fileInfo.Path)
+			if err != nil {
+				util.PrintWarning("Warning: Could not dedupe %s: %v\n", fileInfo.Path, err)
+				continue
+			}
+
+			util.PrintProcess("Deduped %s (%s)\n", fileInfo.Path, util.FormatSize(deduped))
+			totalDeduped += deduped
+			filesDeduped++
+		}
+	}
+
+	util.PrintSuccess("Successfully deduped %d files, %s of extents shared.\n", filesDeduped, util.FormatSize(totalDeduped))
+	return nil
+}
+
+// folderRank returns the index of the first folder containing path, so copies in earlier folders are preferred
+func folderRank(path string, folderPaths []string) int {
+	for i, folderPath := range folderPaths {
+		relPath, err := filepath.Rel(folderPath, path)
+		if err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			return i
+		}
+	}
+	return len(folderPaths)
+}
+
+// replaceWithSymlink replaces a file with a symlink to target, the file is only gone once the link is in place
+func replaceWithSymlink(path, target string) error {
+	tmpPath := path + ".fsak-link"
+	if err := os.Symlink(target, tmpPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// handleSymlinkDedupe keeps one canonical copy of each duplicate group, the one in the earliest folder, and
+// replaces the other copies with symlinks to it
+func handleSymlinkDedupe(folderPaths []string, maxMemory int64, scope string) error {
+	// Symlinks point to absolute paths, so they keep working from anywhere
+	for i, folderPath := range folderPaths {
+		absPath, err := filepath.Abs(folderPath)
+		if err != nil {
+			return fmt.Errorf("error getting absolute path for %s: %v", folderPath, err)
+		}
+		folderPaths[i] = absPath
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	duplicateGroups, err := findDuplicateGroups(db, folderPaths, nil, false, maxMemory, nil)
+	if err != nil {
+		return err
+	}
+	duplicateGroups = scopeDuplicateGroups(duplicateGroups, folderPaths, scope)
+
+	var totalFreed int64
+	filesLinked := 0
+	for i, group := range duplicateGroups {
+		// Copies replaced by an earlier run are found through the canonical copy they point to
+		group = slices.DeleteFunc(group, func(fileInfo *data.FileInfo) bool {
+			info, err := os.Lstat(fileInfo.Path)
+			return err != nil || !info.Mode().IsRegular()
+		})
+		if len(group) < 2 {
+			continue
+		}
+
+		sort.Slice(group, func(j, k int) bool {
+			rankJ, rankK := folderRank(group[j].Path, folderPaths), folderRank(group[k].Path, folderPaths)
+			if rankJ != rankK {
+				return rankJ < rankK
+			}
+			return group[j].Path < group[k].Path
+		})
+
+		canonical := group[0]
+		util.PrintProcess("Duplicate group %d/%d (%d files), canonical: %s\n", i+1, len(duplicateGroups), len(group), canonical.Path)
+
+		// Recorded hashes may be stale, only files still identical to the canonical copy are replaced
+		var records []*data.FileInfo
+		for _, fileInfo := range group {
+			info, err := os.Lstat(fileInfo.Path)
+			if err != nil {
+				return fmt.Errorf("error getting file info for %s: %v", fileInfo.Path, err)
+			}
+			record, err := upToDateRecord(db, fileInfo.Path, info)
+			if err != nil {
+				return err
+			}
+			records = append(records, record)
+		}
+
+		for _, record := range records[1:] {
+			if record.MD5 != records[0].MD5 || record.Blake3 != records[0].Blake3 {
+				util.PrintWarning("Warning: %s changed since it was hashed, skipping\n", record.Path)
+				continue
+			}
+
+			if err := replaceWithSymlink(record.Path, canonical.Path); err != nil {
+				util.PrintWarning("Warning: Could not replace %s with a symlink: %v\n", record.Path, err)
+				continue
+			}
+			util.PrintProcess("Linked %s -> %s\n", record.Path, canonical.Path)
+
+			// The path is a link now, the content is recorded once with the canonical copy
+			if err := db.DeleteFileInfo(record.Key); err != nil {
+				util.PrintWarning("Warning: Could not delete record for file %s from database: %v\n", record.Path, err)
+			}
+			totalFreed += record.GetDiskSize()
+			filesLinked++
+		}
+	}
+
+	if filesLinked == 0 {
+		util.PrintSuccess("No duplicate files found.\n")
+		return nil
+	}
+	util.PrintSuccess("Replaced %d files with symlinks, %s freed.\n", filesLinked, util.FormatSize(totalFreed))
+	return nil
+}
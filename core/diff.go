@@ -0,0 +1,167 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <dir1> <dir2>",
+	Short: "Compare two directory trees",
+	Long: `Compare the files of two directory trees by their path relative to the trees, and list the files only in the first tree (removed), only in the second tree (added), and at the same path with different content (changed). Files are compared by size, and files of the same size by their MD5 and Blake3 values, using the values recorded by sync info for files that didn't change since.
+
+With --names-only, files are only compared by path and size, nothing is hashed, for quick checks of trees on slow network shares. The command exits with status 1 when the trees differ.`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		namesOnly, _ := cmd.Flags().GetBool("names-only")
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		var dirs [2]string
+		for i, arg := range args {
+			dir, err := filepath.Abs(arg)
+			if err != nil {
+				util.PrintError("Error getting absolute path for %s: %v\n", arg, err)
+				os.Exit(1)
+			}
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				util.PrintError("Error: %s is not a directory\n", dir)
+				os.Exit(1)
+			}
+			dirs[i] = dir
+		}
+
+		differences, err := diffTrees(dirs[0], dirs[1], namesOnly, columns)
+		if err != nil {
+			util.PrintError("Error comparing directories: %v\n", err)
+			os.Exit(1)
+		}
+		// Differing trees fail, like diff, so scripts can check them
+		if differences > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// diffColumns are the columns of the diff table
+var diffColumns = []string{"change", "size", "path"}
+
+func init() {
+	diffCmd.Flags().Bool("names-only", false, "Only compare the paths and sizes of the files, without hashing them")
+	diffCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (change, size, path)")
+	diffCmd.RegisterFlagCompletionFunc("columns", completeColumns(diffColumns))
+	rootCmd.AddCommand(diffCmd)
+}
+
+// listTreeFiles returns the regular files of a directory tree by their path relative to it
+func listTreeFiles(dir string) (map[string]os.FileInfo, error) {
+	files := make(map[string]os.FileInfo)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			util.RecordSkipped(path, err)
+			return nil
+		}
+		// Skip VCS and package-manager internals, and the versions kept by merges
+		if isDefaultExcluded(path, dir, info) || (info.IsDir() && info.Name() == util.VersionsDirName) {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() || isWorkspaceFile(path, info) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return fmt.Errorf("error calculating relative path for %s: %v", path, err)
+		}
+		files[relPath] = info
+		return nil
+	})
+	return files, err
+}
+
+// diffTrees prints the files that differ between two trees and returns how many do
+func diffTrees(dir1, dir2 string, namesOnly bool, columns []string) (int, error) {
+	table := util.NewTable(diffColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return 0, err
+	}
+
+	util.PrintProcess("Listing the files of %s\n", dir1)
+	files1, err := listTreeFiles(dir1)
+	if err != nil {
+		return 0, fmt.Errorf("error walking %s: %v", dir1, err)
+	}
+	util.PrintProcess("Listing the files of %s\n", dir2)
+	files2, err := listTreeFiles(dir2)
+	if err != nil {
+		return 0, fmt.Errorf("error walking %s: %v", dir2, err)
+	}
+
+	var db *data.DB
+	if !namesOnly {
+		// Connect to database
+		if db, err = data.Connect(); err != nil {
+			return 0, fmt.Errorf("error connecting to database: %v", err)
+		}
+		defer db.Close()
+	}
+
+	relPaths := make([]string, 0, len(files1)+len(files2))
+	for relPath := range files1 {
+		relPaths = append(relPaths, relPath)
+	}
+	for relPath := range files2 {
+		if _, ok := files1[relPath]; !ok {
+			relPaths = append(relPaths, relPath)
+		}
+	}
+	sort.Strings(relPaths)
+
+	var removed, added, changed int
+	for _, relPath := range relPaths {
+		info1, in1 := files1[relPath]
+		info2, in2 := files2[relPath]
+
+		switch {
+		case !in2:
+			table.AddRow(util.T("removed"), util.FormatSize(info1.Size()), relPath)
+			removed++
+		case !in1:
+			table.AddRow(util.T("added"), util.FormatSize(info2.Size()), relPath)
+			added++
+		case info1.Size() != info2.Size():
+			table.AddRow(util.T("changed"), util.FormatSize(info1.Size())+" → "+util.FormatSize(info2.Size()), relPath)
+			changed++
+		case !namesOnly:
+			record1, err := upToDateRecord(db, filepath.Join(dir1, relPath), info1)
+			if err != nil {
+				return 0, err
+			}
+			record2, err := upToDateRecord(db, filepath.Join(dir2, relPath), info2)
+			if err != nil {
+				return 0, err
+			}
+			if record1.MD5 != record2.MD5 || record1.Blake3 != record2.Blake3 {
+				table.AddRow(util.T("changed"), util.FormatSize(info1.Size()), relPath)
+				changed++
+			}
+		}
+	}
+
+	differences := removed + added + changed
+	if differences == 0 {
+		util.PrintSuccess("No differences found.\n")
+		return 0, nil
+	}
+
+	table.Print()
+	util.PrintWarning("%d files only in %s, %d files only in %s, %d files changed.\n", removed, dir1, added, dir2, changed)
+	return differences, nil
+}
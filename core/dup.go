@@ -0,0 +1,362 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// dupCmd represents the dup command
+var dupCmd = &cobra.Command{
+	Use:   "dup",
+	Short: "Inspect duplicate files recorded in the database",
+	Long:  `Commands for inspecting duplicate files using the MD5 and Blake3 values recorded by sync info. These commands never walk directories, prompt or move files.`,
+}
+
+// dupListCmd represents the dup list command
+var dupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List duplicate groups from the database",
+	Long:  `Print the duplicate groups recorded in the database with their paths, sizes and reclaimable bytes. This is read-only: no directories are walked, nothing is asked and no files are moved.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tag, _ := cmd.Flags().GetString("tag")
+		pathPrefix, _ := cmd.Flags().GetString("path")
+		minSize, _ := cmd.Flags().GetInt64("min-size")
+		ext, _ := cmd.Flags().GetString("ext")
+		mimeType, _ := cmd.Flags().GetString("mime")
+		print0, _ := cmd.Flags().GetBool("print0")
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		if pathPrefix != "" {
+			absPath, err := filepath.Abs(pathPrefix)
+			if err != nil {
+				util.PrintError("Error getting absolute path for %s: %v\n", pathPrefix, err)
+				os.Exit(1)
+			}
+			pathPrefix = absPath
+		}
+
+		filter := data.DuplicateFilter{
+			Tag:        tag,
+			PathPrefix: pathPrefix,
+			MinSize:    minSize,
+			Ext:        strings.TrimPrefix(strings.ToLower(ext), "."),
+			Mime:       mimeType,
+		}
+		err := listDuplicateGroups(filter, print0, columns)
+		if err != nil {
+			util.PrintError("Error listing duplicate files: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// dupConflictsCmd represents the dup conflicts command
+var dupConflictsCmd = &cobra.Command{
+	Use:               "conflicts <dirs...>",
+	Short:             "Report files with the same name but different content",
+	Long:              `Report the files under the specified directories that share their name, or with --by path their path relative to the directory, but have different MD5 and Blake3 values. These are probable versioning conflicts, which deduplication by content ignores. The values recorded by sync info are used, nothing is read from disk.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		by, _ := cmd.Flags().GetString("by")
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		if by != conflictsByName && by != conflictsByPath {
+			util.PrintError("Error: invalid --by %s, expected %s or %s\n", by, conflictsByName, conflictsByPath)
+			os.Exit(1)
+		}
+
+		dirs := make([]string, len(args))
+		for i, dir := range args {
+			absPath, err := filepath.Abs(dir)
+			if err != nil {
+				util.PrintError("Error getting absolute path for %s: %v\n", dir, err)
+				os.Exit(1)
+			}
+			dirs[i] = absPath
+		}
+
+		err := listConflicts(dirs, by, columns)
+		if err != nil {
+			util.PrintError("Error listing conflicts: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// dupOverlapCmd represents the dup overlap command
+var dupOverlapCmd = &cobra.Command{
+	Use:               "overlap <set1> <set2>",
+	Short:             "Report how much of two datasets have the same content",
+	Long:              `Report how much of the files of two datasets, tags or directories, have their contents in the other one, by the MD5 and Blake3 values recorded by sync info, such as how many bytes of an old laptop backup are already on the NAS archive. A dataset is tag:<tag> for the records synced with a tag, or a path for the records under it. Both directions are reported, files without full hashes are counted apart.`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		filters := make([]data.DuplicateFilter, len(args))
+		for i, arg := range args {
+			if tag, ok := strings.CutPrefix(arg, "tag:"); ok {
+				filters[i].Tag = tag
+				continue
+			}
+			absPath, err := util.CanonicalPath(arg)
+			if err != nil {
+				util.PrintError("Error getting absolute path for %s: %v\n", arg, err)
+				os.Exit(1)
+			}
+			filters[i].PathPrefix = absPath
+		}
+
+		err := reportOverlap(args, filters, columns)
+		if err != nil {
+			util.PrintError("Error comparing %s and %s: %v\n", args[0], args[1], err)
+			os.Exit(1)
+		}
+	},
+}
+
+// What files are compared by in dup conflicts
+const (
+	conflictsByName = "name" // Files with the same name anywhere in the directories
+	conflictsByPath = "path" // Files at the same path relative to their directory
+)
+
+// dupListColumns are the columns of the dup list table
+var dupListColumns = []string{"group", "size", "reclaimable", "modified", "tag", "path"}
+
+// dupOverlapColumns are the columns of the dup overlap table
+var dupOverlapColumns = []string{"set", "files", "size", "shared", "shared-size", "percent", "unhashed"}
+
+// dupConflictsColumns are the columns of the dup conflicts table
+var dupConflictsColumns = []string{"group", "size", "modified", "blake3", "path"}
+
+func init() {
+	dupListCmd.Flags().StringP("tag", "T", "", "Only consider files synced with this tag")
+	dupListCmd.Flags().StringP("path", "p", "", "Only consider files under this path")
+	dupListCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	dupListCmd.RegisterFlagCompletionFunc("path", completeCatalogedDirs)
+	dupListCmd.Flags().Int64("min-size", 0, "Only consider files of at least this many bytes")
+	dupListCmd.Flags().String("ext", "", "Only consider files with this extension, such as jpg")
+	dupListCmd.Flags().String("mime", "", "Only consider files of this MIME type, or of all the types starting with it when it ends with a slash, such as image/")
+	dupListCmd.Flags().Bool("print0", false, "Print the duplicate paths to stdout separated by NUL bytes for xargs -0, messages go to stderr")
+	dupListCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (group, size, reclaimable, modified, tag, path)")
+	dupListCmd.RegisterFlagCompletionFunc("columns", completeColumns(dupListColumns))
+	dupCmd.AddCommand(dupListCmd)
+
+	dupConflictsCmd.Flags().String("by", conflictsByName, "Compare files by name or by path relative to their directory")
+	dupConflictsCmd.RegisterFlagCompletionFunc("by", cobra.FixedCompletions([]string{conflictsByName, conflictsByPath}, cobra.ShellCompDirectiveNoFileComp))
+	dupConflictsCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (group, size, modified, blake3, path)")
+	dupConflictsCmd.RegisterFlagCompletionFunc("columns", completeColumns(dupConflictsColumns))
+	dupCmd.AddCommand(dupConflictsCmd)
+
+	dupOverlapCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (set, files, size, shared, shared-size, percent, unhashed)")
+	dupOverlapCmd.RegisterFlagCompletionFunc("columns", completeColumns(dupOverlapColumns))
+	dupCmd.AddCommand(dupOverlapCmd)
+	rootCmd.AddCommand(dupCmd)
+}
+
+// listDuplicateGroups prints the duplicate groups recorded in the database
+func listDuplicateGroups(filter data.DuplicateFilter, print0 bool, columns []string) error {
+	table := util.NewTable(dupListColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var records []*data.FileInfo
+	if err := db.GetDuplicateFileInfos(filter, &records); err != nil {
+		return fmt.Errorf("error getting duplicate files: %v", err)
+	}
+
+	// Records of the same group are adjacent
+	var duplicateGroups [][]*data.FileInfo
+	for i, record := range records {
+		if i == 0 || record.Blake3 != records[i-1].Blake3 || record.MD5 != records[i-1].MD5 {
+			duplicateGroups = append(duplicateGroups, nil)
+		}
+		duplicateGroups[len(duplicateGroups)-1] = append(duplicateGroups[len(duplicateGroups)-1], record)
+	}
+
+	if len(duplicateGroups) == 0 {
+		util.PrintSuccess("No duplicate files found.\n")
+		return nil
+	}
+
+	// Show the groups with the most reclaimable space first
+	reclaimableOf := func(group []*data.FileInfo) int64 {
+		return group[0].Size * int64(len(group)-1)
+	}
+	sort.SliceStable(duplicateGroups, func(i, j int) bool {
+		return reclaimableOf(duplicateGroups[i]) > reclaimableOf(duplicateGroups[j])
+	})
+
+	var totalFiles int
+	var totalReclaimable int64
+	for i, group := range duplicateGroups {
+		reclaimable := reclaimableOf(group)
+		for j, record := range group {
+			if print0 {
+				util.PrintPath0(record.Path)
+				continue
+			}
+
+			// The reclaimable space belongs to the group, show it once
+			reclaimableCell := ""
+			if j == 0 {
+				reclaimableCell = util.FormatSize(reclaimable)
+			}
+			table.AddRow(fmt.Sprint(i+1), util.FormatSize(record.Size), reclaimableCell, record.MTime.Format("2006-01-02 15:04"), record.Tag, record.Path)
+		}
+
+		totalFiles += len(group)
+		totalReclaimable += reclaimable
+	}
+
+	if !print0 {
+		table.Print()
+	}
+	util.PrintSuccess("Found %d duplicate groups (%d files), %s reclaimable.\n", len(duplicateGroups), totalFiles, util.FormatSize(totalReclaimable))
+	return nil
+}
+
+// listConflicts prints the files under dirs that share their name or relative path but differ in content
+func listConflicts(dirs []string, by string, columns []string) error {
+	table := util.NewTable(dupConflictsColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	// Group the records of all directories by name or relative path
+	groups := make(map[string][]*data.FileInfo)
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		var records []*data.FileInfo
+		if err := db.GetHashedFileInfos(data.DuplicateFilter{PathPrefix: dir}, &records); err != nil {
+			return fmt.Errorf("error getting files under %s: %v", dir, err)
+		}
+
+		for _, record := range records {
+			// Nested directories must not list a file twice
+			if seen[record.Path] {
+				continue
+			}
+			seen[record.Path] = true
+
+			key := filepath.Base(record.Path)
+			if by == conflictsByPath {
+				if key, err = filepath.Rel(dir, record.Path); err != nil {
+					continue
+				}
+			}
+			groups[key] = append(groups[key], record)
+		}
+	}
+
+	// Only groups with different content are conflicts
+	var keys []string
+	for key, group := range groups {
+		for _, record := range group[1:] {
+			if record.Blake3 != group[0].Blake3 || record.MD5 != group[0].MD5 {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	if len(keys) == 0 {
+		util.PrintSuccess("No conflicts found.\n")
+		return nil
+	}
+
+	var totalFiles int
+	for i, key := range keys {
+		// Show the newest version of each group first
+		group := groups[key]
+		sort.SliceStable(group, func(a, b int) bool {
+			return group[a].MTime.After(group[b].MTime)
+		})
+
+		for _, record := range group {
+			table.AddRow(fmt.Sprint(i+1), util.FormatSize(record.Size), record.MTime.Format("2006-01-02 15:04"), record.Blake3[:12], record.Path)
+		}
+		totalFiles += len(group)
+	}
+
+	table.Print()
+	util.PrintWarning("Found %d conflicts (%d files) with the same %s and different content.\n", len(keys), totalFiles, by)
+	return nil
+}
+
+// reportOverlap prints how much of each of two datasets, named by names, has its contents in the other one
+func reportOverlap(names []string, filters []data.DuplicateFilter, columns []string) error {
+	table := util.NewTable(dupOverlapColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var overlaps [2]data.Overlap
+	for i := range overlaps {
+		if overlaps[i], err = db.GetOverlap(filters[i], filters[1-i]); err != nil {
+			return err
+		}
+		if overlaps[i].Files == 0 {
+			return fmt.Errorf("no records of %s", names[i])
+		}
+	}
+
+	for i, overlap := range overlaps {
+		percent := percentOf(overlap.SharedSize, overlap.Size)
+		table.AddRow(names[i], fmt.Sprint(overlap.Files), util.FormatSize(overlap.Size), fmt.Sprint(overlap.Shared),
+			util.FormatSize(overlap.SharedSize), fmt.Sprintf("%.1f%%", percent), fmt.Sprint(overlap.Unhashed))
+		util.EmitJSON("overlap", map[string]any{
+			"set":           names[i],
+			"other":         names[1-i],
+			"files":         overlap.Files,
+			"size":          overlap.Size,
+			"shared":        overlap.Shared,
+			"shared_size":   overlap.SharedSize,
+			"percent":       percent,
+			"unhashed":      overlap.Unhashed,
+			"unhashed_size": overlap.UnhashedSize,
+		})
+	}
+	table.Print()
+
+	for i, overlap := range overlaps {
+		util.PrintSuccess("%s vs %s: %.1f%% of the bytes already present\n", names[i], names[1-i], percentOf(overlap.SharedSize, overlap.Size))
+	}
+	if overlaps[0].Unhashed > 0 || overlaps[1].Unhashed > 0 {
+		util.PrintWarning("Files without full hashes aren't compared, sync them without --quick first\n")
+	}
+	return nil
+}
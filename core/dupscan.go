@@ -0,0 +1,138 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"gorm.io/gorm"
+)
+
+// duplicateScanFolders identifies the folders a run of clean dup scanned, or the results it imported, so its
+// groups are compared with the ones of the last run over the same folders
+func duplicateScanFolders(folderPaths []string, importResults string, scope string) string {
+	var folders []string
+	for _, folder := range folderPaths {
+		if canonical, err := util.CanonicalPath(folder); err == nil {
+			folder = canonical
+		}
+		folders = append(folders, folder)
+	}
+	slices.Sort(folders)
+	if importResults != "" {
+		if absPath, err := filepath.Abs(importResults); err == nil {
+			importResults = absPath
+		}
+		folders = append(folders, "import:"+importResults)
+	}
+	if scope != scopeAll {
+		folders = append(folders, "scope:"+scope)
+	}
+	return strings.Join(folders, "\n")
+}
+
+// duplicateContent identifies the contents of a file of a duplicate group, by its Blake3 value or its quick
+// hash when it was only quick hashed
+func duplicateContent(fileInfo *data.FileInfo) string {
+	if fileInfo.Blake3 != "" {
+		return fileInfo.Blake3
+	}
+	return fileInfo.QuickHash
+}
+
+// duplicateScanKey identifies a file of a duplicate group across scans, by its path and contents
+func duplicateScanKey(path, content string) string {
+	return path + "\x00" + content
+}
+
+// recordDuplicateScan records the groups found by a run, for the --diff-last of later runs. A failure only
+// loses the comparison, it doesn't stop the cleanup.
+func recordDuplicateScan(db *data.DB, folders string, groups [][]*data.FileInfo) {
+	scan := &data.DuplicateScan{Folders: folders, Groups: int64(len(groups))}
+	var files []*data.DuplicateScanFile
+	for i, group := range groups {
+		for _, fileInfo := range group {
+			files = append(files, &data.DuplicateScanFile{Group: i + 1, Path: fileInfo.Path, Content: duplicateContent(fileInfo)})
+		}
+	}
+	scan.Files = int64(len(files))
+	if err := db.SaveDuplicateScan(scan, files); err != nil {
+		util.PrintWarning("Warning: Could not record the duplicate groups for --diff-last: %v\n", err)
+	}
+}
+
+// diffDuplicateScan lists the groups with a file that wasn't in a group of the last run over the same
+// folders, or whose contents changed since, without recording or changing anything. Groups that only lost
+// files aren't new.
+func diffDuplicateScan(db *data.DB, folders string, groups [][]*data.FileInfo) error {
+	last, err := db.GetLastDuplicateScan(folders)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return fmt.Errorf("error getting the last scan: %v", err)
+	}
+
+	// Files of the groups of the last scan, by path and contents
+	known := make(map[string]bool)
+	if last == nil {
+		util.PrintProcess("No earlier run of clean dup over these folders, every group is new\n")
+	} else {
+		var files []*data.DuplicateScanFile
+		if err := db.GetDuplicateScanFiles(last.ID, &files); err != nil {
+			return fmt.Errorf("error getting the groups of the last scan: %v", err)
+		}
+		for _, file := range files {
+			known[duplicateScanKey(file.Path, file.Content)] = true
+		}
+		util.PrintProcess("Comparing with the %d groups found on %s\n", last.Groups, last.ScannedAt.Format("2006-01-02 15:04"))
+	}
+
+	if len(groups) == 0 {
+		util.PrintSuccess("No duplicate files found.\n")
+		return nil
+	}
+
+	table := util.NewTable("group", "size", "reclaimable", "change", "path")
+	newGroups, newFiles := 0, 0
+	var totalReclaimable int64
+	for _, group := range groups {
+		isNew := slices.ContainsFunc(group, func(fileInfo *data.FileInfo) bool {
+			return !known[duplicateScanKey(fileInfo.Path, duplicateContent(fileInfo))]
+		})
+		if !isNew {
+			continue
+		}
+		newGroups++
+
+		sortedGroup := slices.Clone(group)
+		slices.SortFunc(sortedGroup, func(a, b *data.FileInfo) int {
+			return strings.Compare(a.Path, b.Path)
+		})
+		reclaimable := sortedGroup[0].Size * int64(len(sortedGroup)-1)
+		totalReclaimable += reclaimable
+		for j, fileInfo := range sortedGroup {
+			// The reclaimable space belongs to the group, show it once
+			reclaimableCell := ""
+			if j == 0 {
+				reclaimableCell = util.FormatSize(reclaimable)
+			}
+			change := ""
+			fileIsNew := !known[duplicateScanKey(fileInfo.Path, duplicateContent(fileInfo))]
+			if fileIsNew {
+				change = util.T("new")
+				newFiles++
+			}
+			table.AddRow(fmt.Sprint(newGroups), util.FormatSize(fileInfo.Size), reclaimableCell, change, fileInfo.Path)
+			util.EmitJSON("duplicate", map[string]any{"group": newGroups, "path": fileInfo.Path, "size": fileInfo.Size, "new": fileIsNew})
+		}
+	}
+
+	if newGroups == 0 {
+		util.PrintSuccess("No new duplicate groups since the last run, %d groups found.\n", len(groups))
+		return nil
+	}
+	table.Print()
+	util.PrintSuccess("%d of %d duplicate groups are new (%d new files), %s reclaimable.\n", newGroups, len(groups), newFiles, util.FormatSize(totalReclaimable))
+	return nil
+}
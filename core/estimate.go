@@ -0,0 +1,46 @@
+package core
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// addYesFlag adds the --yes flag, which skips the confirmation of the estimate of a long operation
+func addYesFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolP("yes", "y", false, "Don't ask to proceed after printing how many files and bytes will be processed")
+}
+
+// sizeOfFiles returns the total size of listed files, the ones that can't be read are left out
+func sizeOfFiles(paths []string) int64 {
+	var size int64
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// confirmEstimate prints how many files and bytes an operation is about to process and asks whether to
+// proceed, since a few files can be terabytes. It doesn't ask when --yes is given or nobody can answer.
+func confirmEstimate(cmd *cobra.Command, files int, size int64) bool {
+	util.PrintProcess("About to process %d files (%s)\n", files, util.FormatSize(size))
+
+	yes, _ := cmd.Flags().GetBool("yes")
+	if yes || files == 0 || !util.CanPrompt() {
+		return true
+	}
+
+	confirmed, err := util.Confirm(fmt.Sprintf(util.T("Process %d files (%s)? (Y/n)"), files, util.FormatSize(size)), true)
+	if err != nil {
+		util.PrintError("Error getting confirmation: %v\n", err)
+		return false
+	}
+	if !confirmed {
+		util.PrintSuccess("Operation cancelled by user.\n")
+	}
+	return confirmed
+}
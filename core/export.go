@@ -0,0 +1,179 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export <dir>",
+	Short: "Write the files of a directory as a file list for rsync or rclone",
+	Long: `Write the files of a directory, relative to it, as a list for the --files-from option of rsync or the --files-from-raw option of rclone. With --missing-from, only the files whose MD5 and Blake3 values aren't in the target directory are listed, as merge dir would copy them, so the transfer can be made by rsync or rclone.
+
+The list is written to stdout unless --output is given, messages go to stderr.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		targetDir, _ := cmd.Flags().GetString("missing-from")
+		output, _ := cmd.Flags().GetString("output")
+		tag, _ := cmd.Flags().GetString("tag")
+
+		if format != exportFormatRsync && format != exportFormatRsync0 && format != exportFormatRclone {
+			util.PrintError("Error: invalid --format %s, expected %s, %s or %s\n", format, exportFormatRsync, exportFormatRsync0, exportFormatRclone)
+			os.Exit(1)
+		}
+
+		sourceDir, err := filepath.Abs(args[0])
+		if err != nil {
+			util.PrintError("Error getting absolute path for %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+		if targetDir != "" {
+			if targetDir, err = filepath.Abs(targetDir); err != nil {
+				util.PrintError("Error getting absolute path for %s: %v\n", targetDir, err)
+				os.Exit(1)
+			}
+		}
+
+		if err := exportFileList(sourceDir, targetDir, tag, format, output); err != nil {
+			util.PrintError("Error exporting file list: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// Formats of the exported file lists
+const (
+	exportFormatRsync  = "rsync"  // One path per line, for rsync --files-from
+	exportFormatRsync0 = "rsync0" // NUL-separated paths, for rsync --from0 --files-from
+	exportFormatRclone = "rclone" // One path per line with / separators, for rclone --files-from-raw
+)
+
+func init() {
+	exportCmd.Flags().String("format", exportFormatRsync, "Format of the list: rsync, rsync0 (NUL-separated, for rsync --from0) or rclone")
+	exportCmd.Flags().String("missing-from", "", "Only list the files whose content isn't in this target directory")
+	exportCmd.Flags().StringP("output", "o", "", "Write the list to this file instead of stdout")
+	exportCmd.Flags().StringP("tag", "T", "", "Only list the files recorded with this tag")
+	exportCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	exportCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{exportFormatRsync, exportFormatRsync0, exportFormatRclone}, cobra.ShellCompDirectiveNoFileComp))
+	exportCmd.RegisterFlagCompletionFunc("missing-from", completeCatalogedDirs)
+	rootCmd.AddCommand(exportCmd)
+}
+
+// exportFileList writes the files of sourceDir, or only those missing from targetDir when it's given, as a
+// file list in a format to output, stdout when it's empty. With a tag, only the files recorded with it are listed.
+func exportFileList(sourceDir, targetDir, tag, format, output string) error {
+	var db *data.DB
+	if targetDir != "" || tag != "" {
+		// Connect to database
+		var err error
+		db, err = data.Connect()
+		if err != nil {
+			return fmt.Errorf("error connecting to database: %v", err)
+		}
+		defer db.Close()
+	}
+
+	var paths []string
+	if targetDir != "" {
+		var err error
+		if paths, err = findFilesToCopy(db, sourceDir, targetDir); err != nil {
+			return err
+		}
+	} else {
+		err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				util.RecordSkipped(path, err)
+				return nil
+			}
+			// Skip VCS and package-manager internals, and the versions kept by merges
+			if isDefaultExcluded(path, sourceDir, info) || (info.IsDir() && info.Name() == util.VersionsDirName) {
+				return filepath.SkipDir
+			}
+			if info.Mode().IsRegular() && !isWorkspaceFile(path, info) {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error walking %s: %v", sourceDir, err)
+		}
+	}
+
+	// The records of the tag select the files, the ones that are gone since they were synced aren't listed
+	if tag != "" {
+		var records []*data.FileInfo
+		if err := db.GetFileInfos(data.DuplicateFilter{Tag: tag, PathPrefix: sourceDir}, &records); err != nil {
+			return fmt.Errorf("error getting files tagged %s: %v", tag, err)
+		}
+		tagged := make(map[string]bool, len(records))
+		for _, record := range records {
+			tagged[record.Path] = true
+		}
+		selected := paths[:0]
+		for _, path := range paths {
+			if tagged[path] {
+				selected = append(selected, path)
+			}
+		}
+		paths = selected
+	}
+	sort.Strings(paths)
+
+	var writer io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("error creating %s: %v", output, err)
+		}
+		defer file.Close()
+		writer = file
+	}
+	buffered := bufio.NewWriter(writer)
+
+	written, unlisted := 0, 0
+	for _, path := range paths {
+		relPath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return fmt.Errorf("error calculating relative path for %s: %v", path, err)
+		}
+
+		// Only NUL-separated lists can carry names containing line breaks
+		separator := "\n"
+		if format == exportFormatRsync0 {
+			separator = "\x00"
+		} else if strings.ContainsAny(relPath, "\r\n") {
+			util.PrintWarning("Warning: %q contains a line break, use --format %s to list it\n", relPath, exportFormatRsync0)
+			unlisted++
+			continue
+		}
+		if format == exportFormatRclone {
+			relPath = filepath.ToSlash(relPath)
+		}
+
+		if _, err := buffered.WriteString(relPath + separator); err != nil {
+			return fmt.Errorf("error writing file list: %v", err)
+		}
+		written++
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("error writing file list: %v", err)
+	}
+
+	util.PrintSuccess("Listed %d files of %s.\n", written, sourceDir)
+	if unlisted > 0 {
+		util.PrintWarning("%d files with line breaks in their names were left out.\n", unlisted)
+	}
+	return nil
+}
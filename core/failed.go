@@ -0,0 +1,68 @@
+package core
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// failedCmd represents the sync failed command
+var failedCmd = &cobra.Command{
+	Use:               "failed [dirs...]",
+	Short:             "List the files sync info couldn't hash",
+	Long:              `List the files the last scans of sync info couldn't hash, with why: permission denied, read error, too new (the file changed while it was hashed), excluded by the blacklist or locked by another program. sync info --retry-failed syncs exactly these files again.`,
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		if err := listFailedFiles(args, columns); err != nil {
+			util.PrintError("Error listing failed files: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// failedColumns are the columns of the failed files table
+var failedColumns = []string{"status", "path", "size", "reason"}
+
+func init() {
+	failedCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (status, path, size, reason)")
+	failedCmd.RegisterFlagCompletionFunc("columns", completeColumns(failedColumns))
+	syncCmd.AddCommand(failedCmd)
+}
+
+// listFailedFiles prints the files that couldn't be hashed, under any of dirs when they're given
+func listFailedFiles(dirs []string, columns []string) error {
+	table := util.NewTable(failedColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	absDirs, err := absolutePaths(dirs)
+	if err != nil {
+		return err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var records []*data.FileInfo
+	if err := db.GetFailedFileInfos(absDirs, &records); err != nil {
+		return fmt.Errorf("error getting failed files: %v", err)
+	}
+
+	for _, record := range records {
+		table.AddRow(util.T(data.StatusNames[record.Status]), record.Path, util.FormatSize(record.Size), record.Reason)
+	}
+	table.Print()
+
+	util.PrintSuccess("%d files couldn't be hashed.\n", len(records))
+	return nil
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// addFilesFromFlag adds the --files-from and --files-from0 flags, which list files to process instead of
+// walking directories
+func addFilesFromFlag(cmd *cobra.Command) {
+	cmd.Flags().String("files-from", "", "Read the files to process from this file, one per line, or from stdin with -")
+	cmd.Flags().String("files-from0", "", "Read the files to process from this file separated by NUL bytes (find -print0), or from stdin with -")
+	cmd.MarkFlagsMutuallyExclusive("files-from", "files-from0")
+}
+
+// orFilesFrom validates the arguments with args unless a file list is given, then any arguments are accepted
+func orFilesFrom(args cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, positional []string) error {
+		if cmd.Flags().Changed("files-from") || cmd.Flags().Changed("files-from0") {
+			return nil
+		}
+		return args(cmd, positional)
+	}
+}
+
+// readFilesFrom returns the absolute paths of the files listed by --files-from or --files-from0, nil when
+// neither is given
+func readFilesFrom(cmd *cobra.Command) ([]string, error) {
+	name, _ := cmd.Flags().GetString("files-from")
+	nul := false
+	if name == "" {
+		name, _ = cmd.Flags().GetString("files-from0")
+		nul = true
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	// Stdin carries the list, so prompts read the terminal
+	if name == "-" {
+		util.PromptsFromTerminal()
+	}
+
+	paths, err := util.ReadFileList(name, nul)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file list %s: %v", name, err)
+	}
+
+	for i, path := range paths {
+		if paths[i], err = filepath.Abs(path); err != nil {
+			return nil, fmt.Errorf("error getting absolute path for %s: %v", path, err)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// findCmd represents the find command
+var findCmd = &cobra.Command{
+	Use:   "find",
+	Short: "Find files recorded in the database",
+	Long: `Print the paths of the files recorded in the database matching all the given filters: a glob pattern on the file name, a size range, a hash value, a tag, a directory and a modification date range. Nothing is read from disk, so the files of drives that aren't attached are found too.
+
+Sizes are given like 512M or 1.5G, dates as YYYY-MM-DD. --hash matches the MD5, Blake3 or SHA-256 value. The paths are printed one per line, or separated by NUL bytes with --print0.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("name")
+		minSizeValue, _ := cmd.Flags().GetString("min-size")
+		maxSizeValue, _ := cmd.Flags().GetString("max-size")
+		hash, _ := cmd.Flags().GetString("hash")
+		tag, _ := cmd.Flags().GetString("tag")
+		pathPrefix, _ := cmd.Flags().GetString("path")
+		after, _ := cmd.Flags().GetString("modified-after")
+		before, _ := cmd.Flags().GetString("modified-before")
+		print0, _ := cmd.Flags().GetBool("print0")
+
+		filter := data.FindFilter{
+			DuplicateFilter: data.DuplicateFilter{Tag: tag},
+			Name:            name,
+			Hash:            strings.ToLower(hash),
+		}
+
+		if name != "" {
+			if _, err := filepath.Match(name, ""); err != nil {
+				util.PrintError("Error: invalid --name pattern %s: %v\n", name, err)
+				os.Exit(1)
+			}
+		}
+		for _, size := range []struct {
+			flag  string
+			value string
+			dest  *int64
+		}{{"min-size", minSizeValue, &filter.MinSize}, {"max-size", maxSizeValue, &filter.MaxSize}} {
+			if size.value == "" {
+				continue
+			}
+			n, err := util.ParseSize(size.value)
+			if err != nil {
+				util.PrintError("Error: invalid --%s: %v\n", size.flag, err)
+				os.Exit(1)
+			}
+			*size.dest = n
+		}
+		for _, date := range []struct {
+			flag  string
+			value string
+			dest  *time.Time
+		}{{"modified-after", after, &filter.ModifiedAfter}, {"modified-before", before, &filter.ModifiedBefore}} {
+			if date.value == "" {
+				continue
+			}
+			t, err := time.ParseInLocation("2006-01-02", date.value, time.Local)
+			if err != nil {
+				util.PrintError("Error: invalid --%s date %s, expected YYYY-MM-DD\n", date.flag, date.value)
+				os.Exit(1)
+			}
+			*date.dest = t
+		}
+
+		if pathPrefix != "" {
+			absPath, err := util.CanonicalPath(pathPrefix)
+			if err != nil {
+				util.PrintError("Error getting absolute path for %s: %v\n", pathPrefix, err)
+				os.Exit(1)
+			}
+			filter.PathPrefix = absPath
+		}
+
+		err := findFiles(filter, print0)
+		if err != nil {
+			util.PrintError("Error finding files: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	findCmd.Flags().String("name", "", "Only files whose name matches this glob pattern, such as '*.iso'")
+	findCmd.Flags().String("min-size", "", "Only files at least this large, such as 1G")
+	findCmd.Flags().String("max-size", "", "Only files at most this large, such as 100M")
+	findCmd.Flags().String("hash", "", "Only files with this MD5, Blake3 or SHA-256 value")
+	findCmd.Flags().StringP("tag", "T", "", "Only files synced with this tag")
+	findCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	findCmd.Flags().String("path", "", "Only files at or under this path")
+	findCmd.Flags().String("modified-after", "", "Only files modified on or after this date (YYYY-MM-DD)")
+	findCmd.Flags().String("modified-before", "", "Only files modified before this date (YYYY-MM-DD)")
+	findCmd.Flags().Bool("print0", false, "Print the paths separated by NUL bytes for xargs -0")
+	rootCmd.AddCommand(findCmd)
+}
+
+// findFiles prints the paths of the records matching the filter
+func findFiles(filter data.FindFilter, print0 bool) error {
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var records []*data.FileInfo
+	if err := db.FindFileInfos(filter, &records); err != nil {
+		return err
+	}
+
+	var totalSize int64
+	for _, record := range records {
+		totalSize += record.Size
+		switch {
+		case util.JSONEnabled():
+			util.EmitJSON("found", map[string]any{"path": record.Path, "size": record.Size, "modified": record.MTime, "tag": record.Tag, "md5": record.MD5, "blake3": record.Blake3})
+		case print0:
+			util.PrintPath0(record.Path)
+		default:
+			fmt.Println(record.Path)
+		}
+	}
+
+	util.PrintSuccess("Found %d files (%s).\n", len(records), util.FormatSize(totalSize))
+	return nil
+}
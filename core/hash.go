@@ -1,7 +1,7 @@
 package core
 
 import (
-	"fmt"
+	"os"
 
 	"github.com/baowuhe/go-fsak/util"
 	"github.com/spf13/cobra"
@@ -12,21 +12,57 @@ var hashCmd = &cobra.Command{
 	Use:   "hash [file]",
 	Short: "Calculate MD5 and Blake3 hash values of a file",
 	Long:  `Calculate MD5 and Blake3 hash values of a file with a single read operation`,
-	Args:  cobra.ExactArgs(1),
+	Args:  orFilesFrom(cobra.ExactArgs(1)),
 	Run: func(cmd *cobra.Command, args []string) {
-		filePath := args[0]
+		quick, _ := cmd.Flags().GetBool("quick")
 
-		blake3Val, md5Val, err := util.FileBlake3MD5(filePath)
+		listedFiles, err := readFilesFrom(cmd)
 		if err != nil {
-			fmt.Printf("[×] Error calculating hashes: %v\n", err)
-			return
+			util.PrintError("Error: %v\n", err)
+			os.Exit(1)
 		}
 
-		fmt.Printf("[√] MD5:    %s\n", md5Val)
-		fmt.Printf("[√] Blake3: %s\n", blake3Val)
+		filePaths := append(args, listedFiles...)
+		for _, filePath := range filePaths {
+			// Name the file when several are hashed
+			if len(filePaths) > 1 {
+				util.PrintProcess("%s\n", filePath)
+			}
+			hashFile(filePath, quick)
+		}
 	},
 }
 
 func init() {
+	hashCmd.Flags().BoolP("quick", "q", false, "Calculate a quick hash from the size and the first/last 1MB of the file")
+	addFilesFromFlag(hashCmd)
 	rootCmd.AddCommand(hashCmd)
 }
+
+// hashFile prints the hash values of a file
+func hashFile(filePath string, quick bool) {
+	if quick {
+		quickVal, err := util.FileQuickHash(filePath)
+		if err != nil {
+			util.PrintError("Error calculating quick hash: %v\n", err)
+			util.EmitJSON("error", map[string]any{"path": filePath, "error": err.Error()})
+			return
+		}
+
+		util.EmitJSON("hash", map[string]any{"path": filePath, "quick": quickVal})
+		util.PrintSuccess("Quick:  %s (size + first/last 1MB, matches are probabilistic)\n", quickVal)
+		return
+	}
+
+	blake3Val, md5Val, err := util.FileBlake3MD5(filePath)
+	if err != nil {
+		util.PrintError("Error calculating hashes: %v\n", err)
+		util.EmitJSON("error", map[string]any{"path": filePath, "error": err.Error()})
+		return
+	}
+
+	util.EmitJSON("hash", map[string]any{"path": filePath, "md5": md5Val, "blake3": blake3Val})
+
+	util.PrintSuccess("MD5:    %s\n", md5Val)
+	util.PrintSuccess("Blake3: %s\n", blake3Val)
+}
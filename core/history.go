@@ -0,0 +1,133 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:               "history <file>",
+	Short:             "Show the recorded versions of a file",
+	Long:              `Show the previous versions of a file recorded by sync info, with their hashes, sizes and modification times, to see when a file changed or to investigate bitrot.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		since, _ := cmd.Flags().GetString("since")
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		var sinceTime time.Time
+		if since != "" {
+			var err error
+			sinceTime, err = time.ParseInLocation("2006-01-02", since, time.Local)
+			if err != nil {
+				util.PrintError("Error: invalid --since date %s, expected YYYY-MM-DD\n", since)
+				os.Exit(1)
+			}
+		}
+
+		err := showFileHistory(args[0], sinceTime, columns)
+		if err != nil {
+			util.PrintError("Error showing file history: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// historyColumns are the columns of the history table
+var historyColumns = []string{"replaced", "modified", "size", "blake3"}
+
+func init() {
+	historyCmd.Flags().String("since", "", "Only show changes since this date (YYYY-MM-DD)")
+	historyCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (replaced, modified, size, blake3)")
+	historyCmd.RegisterFlagCompletionFunc("columns", completeColumns(historyColumns))
+	rootCmd.AddCommand(historyCmd)
+}
+
+// showFileHistory prints the recorded versions of a file
+func showFileHistory(filePath string, since time.Time, columns []string) error {
+	table := util.NewTable(historyColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	absPath, err := util.CanonicalPath(filePath)
+	if err != nil {
+		return fmt.Errorf("error getting absolute path for %s: %v", filePath, err)
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	current, err := db.GetFileInfoByPath(absPath)
+	if err == gorm.ErrRecordNotFound {
+		return fmt.Errorf("%s is not in the database, run sync info first", absPath)
+	}
+	if err != nil {
+		return fmt.Errorf("error getting file info for %s: %v", absPath, err)
+	}
+
+	var versions []*data.FileInfoHistory
+	if err := db.GetFileHistory(current, &versions); err != nil {
+		return fmt.Errorf("error getting history of %s: %v", absPath, err)
+	}
+
+	changes := 0
+	for _, version := range versions {
+		if version.ReplacedAt.Before(since) {
+			continue
+		}
+
+		table.AddRow(version.ReplacedAt.Format("2006-01-02 15:04"), version.MTime.Format("2006-01-02 15:04"), util.FormatSize(version.Size), historyHash(version.Blake3, version.QuickHash))
+		changes++
+	}
+	table.AddRow(util.T("current"), current.MTime.Format("2006-01-02 15:04"), util.FormatSize(current.Size), historyHash(current.Blake3, current.QuickHash))
+	table.Print()
+
+	// The file keeps its history when it's moved
+	previousPaths := make(map[string]bool)
+	for _, version := range versions {
+		if version.Path != absPath && !previousPaths[version.Path] {
+			previousPaths[version.Path] = true
+			util.PrintProcess("Previously at %s\n", version.Path)
+		}
+	}
+
+	var copies []*data.FileInfo
+	if err := db.GetFileInfosByContent(current, &copies); err != nil {
+		return fmt.Errorf("error getting copies of %s: %v", absPath, err)
+	}
+	for _, fileCopy := range copies {
+		if fileCopy.Path != absPath {
+			util.PrintProcess("Same contents at %s\n", fileCopy.Path)
+		}
+	}
+
+	if since.IsZero() {
+		util.PrintSuccess("%s changed %d times.\n", absPath, changes)
+	} else {
+		util.PrintSuccess("%s changed %d times since %s.\n", absPath, changes, since.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// historyHash formats the hash of a version, falling back to the quick hash
+func historyHash(blake3Hash, quickHash string) string {
+	if blake3Hash != "" {
+		return blake3Hash
+	}
+	if quickHash != "" {
+		return quickHash + " (quick)"
+	}
+	return "unknown"
+}
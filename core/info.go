@@ -1,11 +1,16 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/baowuhe/go-fsak/data"
 	"github.com/baowuhe/go-fsak/util"
@@ -15,21 +20,66 @@ import (
 
 // infoCmd represents the info command
 var infoCmd = &cobra.Command{
-	Use:   "info [flags] <dirs>",
-	Short: "Get file information and sync to database",
-	Long:  `Traverse one or more directories and their subdirectories, read file information, calculate MD5 and Blake3 values, and synchronize to SQLite database.`,
-	Args:  cobra.MinimumNArgs(1),
+	Use:               "info [flags] <dirs>",
+	Short:             "Get file information and sync to database",
+	Long:              `Traverse one or more directories and their subdirectories, read file information, calculate MD5 and Blake3 values, and synchronize to SQLite database.`,
+	Args:              orFilesFrom(orRetryFailed(cobra.MinimumNArgs(1))),
+	ValidArgsFunction: completeCatalogedDirs,
 	Run: func(cmd *cobra.Command, args []string) {
 		threads, _ := cmd.Flags().GetInt("threads")
 		tag, _ := cmd.Flags().GetString("tag")
 		force, _ := cmd.Flags().GetBool("force")
+		quick, _ := cmd.Flags().GetBool("quick")
 		blacklistFile, _ := cmd.Flags().GetString("blacklist")
 		batchSize, _ := cmd.Flags().GetInt("batch")
+		finderTags, _ := cmd.Flags().GetBool("finder-tags")
+		xattrs, _ := cmd.Flags().GetBool("xattrs")
+		retryLocked, _ := cmd.Flags().GetBool("retry-locked-at-end")
+		label, _ := cmd.Flags().GetString("label")
+		retryFailed, _ := cmd.Flags().GetBool("retry-failed")
+		hugeSizeValue, _ := cmd.Flags().GetString("huge-size")
+		hugeThreads, _ := cmd.Flags().GetInt("huge-threads")
+		fast, _ := cmd.Flags().GetBool("fast")
+
+		if finderTags && !util.FinderTagsSupported() {
+			util.PrintError("Error: --finder-tags is only supported on macOS\n")
+			os.Exit(1)
+		}
+
+		hugeSize, err := util.ParseSize(hugeSizeValue)
+		if err != nil {
+			util.PrintError("Error: invalid --huge-size: %v\n", err)
+			os.Exit(1)
+		}
+		if threads < 1 || hugeThreads < 1 {
+			util.PrintError("Error: --threads and --huge-threads must be at least 1\n")
+			os.Exit(1)
+		}
+
+		listedFiles, err := readFilesFrom(cmd)
+		if err != nil {
+			util.PrintError("Error: %v\n", err)
+			os.Exit(1)
+		}
 
 		dirs := args
 
+		// Only the files the previous scans couldn't hash are synced, the directories given restrict them
+		if retryFailed {
+			failed, err := failedFiles(dirs)
+			if err != nil {
+				util.PrintError("Error getting the files that couldn't be hashed: %v\n", err)
+				os.Exit(1)
+			}
+			listedFiles = append(listedFiles, failed...)
+			dirs = nil
+		}
+
 		// Show what directories will be processed
 		util.PrintProcess("Starting to process directories: %v\n", dirs)
+		if listedFiles != nil {
+			util.PrintProcess("Listed files to process: %d\n", len(listedFiles))
+		}
 
 		// Load blacklist patterns
 		util.PrintProcess("Loading blacklist patterns from: %s\n", blacklistFile)
@@ -41,7 +91,7 @@ var infoCmd = &cobra.Command{
 		util.PrintProcess("Loaded %d blacklist patterns\n", len(blacklistPatterns))
 
 		// Process directories
-		processDirectories(dirs, threads, tag, force, blacklistPatterns, batchSize)
+		processDirectories(cmd, dirs, listedFiles, threads, hugeThreads, hugeSize, tag, label, force, quick, blacklistPatterns, batchSize, finderTags, xattrs, retryLocked, fast)
 	},
 }
 
@@ -49,19 +99,137 @@ func init() {
 	syncCmd.AddCommand(infoCmd)
 
 	infoCmd.Flags().IntP("threads", "t", 1, "Number of threads for calculation")
+	infoCmd.Flags().String("huge-size", "1G", "Files of at least this size are hashed by the --huge-threads workers, such as 512M or 4G")
+	infoCmd.Flags().Int("huge-threads", 1, "Number of threads for the files of at least --huge-size")
 	infoCmd.Flags().StringP("tag", "T", "", "Tag for this batch of sync data")
+	infoCmd.RegisterFlagCompletionFunc("tag", completeTags)
 	infoCmd.Flags().BoolP("force", "F", false, "Force overwrite existing data")
+	infoCmd.Flags().BoolP("quick", "q", false, "Only calculate a quick hash from the size and the first/last 1MB of each file (matches are probabilistic)")
 	infoCmd.Flags().StringP("blacklist", "B", "", "Blacklist file containing paths to exclude (supports regex)")
 	infoCmd.Flags().IntP("batch", "b", 10, "Number of records to batch update to SQLite database")
+	infoCmd.Flags().Bool("finder-tags", false, "Read macOS Finder tags into the database (macOS only)")
+	infoCmd.Flags().Bool("xattrs", false, "Record the extended attributes of files (user.*, security labels, macOS quarantine flags), so restore can reapply them")
+	infoCmd.Flags().Bool("retry-locked-at-end", false, "Try the files that were in use by other programs once more after all the others")
+	infoCmd.Flags().String("label", "", "Label of the scan session, shown by sync sessions")
+	infoCmd.Flags().Bool("fast", false, "Skip the files of directories whose modification time and number of entries didn't change since the last scan")
+	infoCmd.MarkFlagsMutuallyExclusive("fast", "force")
+	infoCmd.Flags().Bool("retry-failed", false, "Only sync the files previous scans couldn't hash, under the directories given if any")
+	addFilesFromFlag(infoCmd)
+	addYesFlag(infoCmd)
+}
+
+// orRetryFailed validates the arguments with args unless --retry-failed is given, then any arguments are accepted
+func orRetryFailed(args cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, positional []string) error {
+		if retryFailed, _ := cmd.Flags().GetBool("retry-failed"); retryFailed {
+			return nil
+		}
+		return args(cmd, positional)
+	}
+}
+
+// absolutePaths returns the canonical paths of paths
+func absolutePaths(paths []string) ([]string, error) {
+	absPaths := make([]string, 0, len(paths))
+	for _, path := range paths {
+		absPath, err := util.CanonicalPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("error getting absolute path for %s: %v", path, err)
+		}
+		absPaths = append(absPaths, absPath)
+	}
+	return absPaths, nil
+}
+
+// failedFiles returns the paths of the files previous scans couldn't hash, under any of dirs when they're given
+func failedFiles(dirs []string) ([]string, error) {
+	absDirs, err := absolutePaths(dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var records []*data.FileInfo
+	if err := db.GetFailedFileInfos(absDirs, &records); err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(records))
+	for i, record := range records {
+		paths[i] = record.Path
+	}
+	util.PrintProcess("Files previous scans couldn't hash: %d\n", len(paths))
+	return paths, nil
+}
+
+// unboundedQueue returns a channel receiving what is sent to in, in order, queuing it without limit so sending
+// to in never blocks. The channel is closed once in is closed and everything was received.
+func unboundedQueue(in <-chan string) <-chan string {
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		var pending []string
+		for in != nil || len(pending) > 0 {
+			// Only send when something is pending, a nil channel is never ready
+			var send chan string
+			var next string
+			if len(pending) > 0 {
+				send, next = out, pending[0]
+			}
+
+			select {
+			case path, ok := <-in:
+				if !ok {
+					in = nil
+					continue
+				}
+				pending = append(pending, path)
+			case send <- next:
+				pending = pending[1:]
+			}
+		}
+	}()
+	return out
 }
 
-func countFiles(dirs []string, blacklistPatterns []*regexp.Regexp) (int, error) {
+// scanDir returns the fingerprint of a directory, or nil when its entries can't be read
+func scanDir(path string, info os.FileInfo) *data.DirScan {
+	absPath, err := util.CanonicalPath(path)
+	if err != nil {
+		return nil
+	}
+	dir, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil
+	}
+	return &data.DirScan{Path: absPath, MTime: info.ModTime(), Children: len(names)}
+}
+
+// countFiles returns the number and the total size of the files in dirs that aren't excluded
+func countFiles(dirs []string, blacklistPatterns []*regexp.Regexp) (int, int64, error) {
 	totalFiles := 0
+	var totalSize int64
 
 	for _, dir := range dirs {
 		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
-				return err
+				// Skip unreadable files or directories, they're reported while processing
+				return nil
+			}
+
+			// Skip VCS and package-manager internals
+			if isDefaultExcluded(path, dir, info) {
+				return filepath.SkipDir
 			}
 
 			// Skip directories
@@ -69,6 +237,11 @@ func countFiles(dirs []string, blacklistPatterns []*regexp.Regexp) (int, error)
 				return nil
 			}
 
+			// Skip the database and its journal files
+			if isWorkspaceFile(path, info) {
+				return nil
+			}
+
 			// Check if the file matches any blacklist pattern
 			shouldSkip := false
 			for _, pattern := range blacklistPatterns {
@@ -83,28 +256,44 @@ func countFiles(dirs []string, blacklistPatterns []*regexp.Regexp) (int, error)
 			}
 
 			totalFiles++
+			totalSize += info.Size()
 
 			return nil
 		})
 
 		if err != nil {
-			return 0, err
+			return 0, 0, err
 		}
 	}
 
-	return totalFiles, nil
+	return totalFiles, totalSize, nil
 }
 
-func processDirectories(dirs []string, threads int, tag string, force bool, blacklistPatterns []*regexp.Regexp, batchSize int) {
+// processDirectories syncs the files in dirs and the listedFiles, which are processed without walking. Files of
+// at least hugeSize are queued for hugeThreads workers of their own, so a huge file doesn't hold up the small
+// ones and the small ones don't compete with it for the disk. It returns false when the sync was cancelled.
+func processDirectories(cmd *cobra.Command, dirs []string, listedFiles []string, threads int, hugeThreads int, hugeSize int64, tag string, label string, force bool, quick bool, blacklistPatterns []*regexp.Regexp, batchSize int, finderTags bool, xattrs bool, retryLocked bool, fast bool) bool {
+	// Only the blacklist applies to listed files
+	listedFiles = slices.DeleteFunc(listedFiles, func(path string) bool {
+		return slices.ContainsFunc(blacklistPatterns, func(pattern *regexp.Regexp) bool {
+			return pattern.MatchString(path)
+		})
+	})
+
 	// Count total files first
 	util.PrintProcess("Counting files in specified directories (this may take a moment)...\n")
-	totalFiles, err := countFiles(dirs, blacklistPatterns)
+	totalFiles, totalSize, err := countFiles(dirs, blacklistPatterns)
 	if err != nil {
 		util.PrintError("Error counting files: %v\n", err)
 		os.Exit(1)
 	}
+	totalFiles += len(listedFiles)
+	totalSize += sizeOfFiles(listedFiles)
 
 	util.PrintProcess("Total files to process: %d\n", totalFiles)
+	if !confirmEstimate(cmd, totalFiles, totalSize) {
+		return false
+	}
 
 	// Create a single database connection for all workers
 	util.PrintProcess("Connecting to database...\n")
@@ -113,24 +302,47 @@ func processDirectories(dirs []string, threads int, tag string, force bool, blac
 		util.PrintError("Error connecting to database: %v\n", err)
 		os.Exit(1)
 	}
-	defer func() {
-		sqlDB, _ := db.DB.DB()
-		if sqlDB != nil {
-			sqlDB.Close()
+	defer db.Close()
+
+	// Stamp the records written with a new scan session
+	sessionDirs := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if absDir, err := util.CanonicalPath(dir); err == nil {
+			dir = absDir
 		}
-	}()
+		sessionDirs = append(sessionDirs, dir)
+	}
+	session := &data.Session{Label: label, Dirs: strings.Join(sessionDirs, "\n"), Tag: tag}
+	if err := db.StartSession(session); err != nil {
+		util.PrintError("Error starting scan session: %v\n", err)
+		os.Exit(1)
+	}
+	util.PrintProcess("Scan session %d started\n", session.ID)
 
-	// Counter to track progress
-	var counter struct {
-		sync.Mutex
-		count int
+	// Fingerprints of the directories at the last scan, the ones of this scan are recorded once it's done
+	var lastScans map[string]*data.DirScan
+	if fast {
+		if lastScans, err = db.GetDirScans(); err != nil {
+			util.PrintError("Error getting the directories scanned before: %v\n", err)
+			os.Exit(1)
+		}
 	}
+	var dirScans []*data.DirScan
+	unchangedDirs := make(map[string]bool)
+	// Directories with files that couldn't be hashed get no fingerprint, so --fast tries them again
+	var failedDirs sync.Map
 
-	// Mutex to synchronize database operations
-	var dbMutex sync.Mutex
+	// Track progress by bytes
+	progress := util.NewProgress(totalFiles, totalSize)
 
-	// Channel to send file paths to be processed
+	// Files found, the ones not written are the skipped files of the session
+	var found atomic.Int64
+
+	// Channels to send file paths to be processed. Huge files have their own, which never blocks the walk so
+	// the small files keep flowing while the huge ones wait for their workers.
 	fileCh := make(chan string, threads*2)
+	hugeCh := make(chan string)
+	hugeQueue := unboundedQueue(hugeCh)
 	// Channel to collect processed file info for batching
 	resultCh := make(chan *data.FileInfo, threads*2)
 
@@ -138,54 +350,72 @@ func processDirectories(dirs []string, threads int, tag string, force bool, blac
 	var wg sync.WaitGroup
 
 	// Start worker goroutines for processing files (without database operations)
-	util.PrintProcess("Starting %d worker threads to process files...\n", threads)
-	for i := 0; i < threads; i++ {
+	util.PrintProcess("Starting %d worker threads to process files and %d for files of at least %s...\n", threads, hugeThreads, util.FormatSize(hugeSize))
+	for i := 0; i < threads+hugeThreads; i++ {
+		var queue <-chan string = fileCh
+		if i >= threads {
+			queue = hugeQueue
+		}
+
 		wg.Add(1)
-		go func(threadId int) {
+		go func(threadId int, queue <-chan string) {
 			defer wg.Done()
 
 			util.PrintProcess("Worker %d started and ready to process files\n", threadId)
-			for path := range fileCh {
-				fileInfo, err := processFileInfoOnly(path, tag, force, db)
+			for path := range queue {
+				fileInfo, err := processFileInfoOnly(path, tag, force, quick, finderTags, xattrs, db)
 				if err != nil {
+					if absPath, err := util.CanonicalPath(path); err == nil {
+						failedDirs.Store(filepath.Dir(absPath), true)
+					}
+				}
+				if util.IsLockedError(err) {
+					// Files held open by other programs don't stop the sync
+					util.PrintWarning("Skipping %s, it's in use by another program\n", path)
+					util.RecordSkipped(path, err)
+					recordFailure(db, path, tag, failureStatus(err), err.Error())
+					util.EmitJSON("locked", map[string]any{"path": path, "error": err.Error()})
+				} else if err != nil {
 					util.PrintError("Error processing file %s in worker %d: %v\n", path, threadId, err)
+					util.RecordSkipped(path, err)
+					recordFailure(db, path, tag, failureStatus(err), err.Error())
+					util.EmitJSON("error", map[string]any{"path": path, "error": err.Error()})
 				} else if fileInfo != nil {
 					resultCh <- fileInfo
+					continue
+				}
+
+				// Skipped and failed files count as done
+				if info, err := os.Stat(path); err == nil {
+					progress.Add(info.Size())
 				}
 			}
 			util.PrintProcess("Worker %d finished processing files\n", threadId)
-		}(i) // Pass thread ID to identify each worker
+		}(i, queue) // Pass thread ID to identify each worker
 	}
 
 	// Start a goroutine to handle batching and database updates
+	batchDone := make(chan struct{})
 	go func() {
+		defer close(batchDone)
 		batch := make([]*data.FileInfo, 0, batchSize)
 		for fileInfo := range resultCh {
 			batch = append(batch, fileInfo)
 
 			// If batch is full, save to database
 			if len(batch) >= batchSize {
-				dbMutex.Lock()
 				for _, info := range batch {
 					if err := db.UpsertFileInfo(info); err != nil {
 						util.PrintError("Error upserting file info: %v\n", err)
+					} else {
+						emitSynced(info)
 					}
 				}
-				dbMutex.Unlock()
 
-				// Update counter for all files in the batch
-				counter.Lock()
+				// Update progress for all files in the batch
 				for _, info := range batch {
-					counter.count++
-					currentCount := counter.count
-					// Calculate percentage
-					percentage := 0.0
-					if totalFiles > 0 {
-						percentage = float64(currentCount) / float64(totalFiles) * 100
-					}
-					util.PrintProcess("[ %d / %d (%.2f%%)]: %s\n", currentCount, totalFiles, percentage, info.Path)
+					progress.Report(info.Size, info.Path)
 				}
-				counter.Unlock()
 
 				batch = batch[:0] // Reset batch
 			}
@@ -193,41 +423,67 @@ func processDirectories(dirs []string, threads int, tag string, force bool, blac
 
 		// Save remaining items in the batch
 		if len(batch) > 0 {
-			dbMutex.Lock()
 			for _, info := range batch {
 				if err := db.UpsertFileInfo(info); err != nil {
 					util.PrintError("Error upserting file info: %v\n", err)
+				} else {
+					emitSynced(info)
 				}
 			}
-			dbMutex.Unlock()
 
-			// Update counter for all files in the final batch
-			counter.Lock()
+			// Update progress for all files in the final batch
 			for _, info := range batch {
-				counter.count++
-				currentCount := counter.count
-				// Calculate percentage
-				percentage := 0.0
-				if totalFiles > 0 {
-					percentage = float64(currentCount) / float64(totalFiles) * 100
-				}
-				util.PrintProcess("[ %d / %d (%.2f%%)]: %s\n", currentCount, totalFiles, percentage, info.Path)
+				progress.Report(info.Size, info.Path)
 			}
-			counter.Unlock()
 		}
 	}()
 
+	// queueFile sends a file to the workers of its size
+	queueFile := func(path string, size int64) {
+		found.Add(1)
+		if size >= hugeSize {
+			hugeCh <- path
+		} else {
+			fileCh <- path
+		}
+	}
+
 	// Walk through directories and send files to the channel
 	util.PrintProcess("Walking through directories to collect files for processing...\n")
 	for i, dir := range dirs {
 		util.PrintProcess("Scanning directory %d/%d: %s\n", i+1, len(dirs), dir)
 		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
-				return err
+				// Skip unreadable files or directories
+				util.RecordSkipped(path, err)
+				return nil
 			}
 
-			// Skip directories
+			// Skip VCS and package-manager internals
+			if isDefaultExcluded(path, dir, info) {
+				return filepath.SkipDir
+			}
+
+			// Skip directories, after taking their fingerprint
 			if info.IsDir() {
+				if scan := scanDir(path, info); scan != nil {
+					dirScans = append(dirScans, scan)
+					if last := lastScans[scan.Path]; last != nil && data.SameMTime(last.MTime, scan.MTime) && last.Children == scan.Children {
+						unchangedDirs[path] = true
+					}
+				}
+				return nil
+			}
+
+			// Skip the database and its journal files
+			if isWorkspaceFile(path, info) {
+				return nil
+			}
+
+			// The files of unchanged directories are skipped by --fast, their subdirectories are still walked
+			if unchangedDirs[filepath.Dir(path)] {
+				found.Add(1)
+				progress.Add(info.Size())
 				return nil
 			}
 
@@ -241,11 +497,16 @@ func processDirectories(dirs []string, threads int, tag string, force bool, blac
 			}
 
 			if shouldSkip {
+				// Only recorded once, excluded files can be many
+				absPath, _ := util.CanonicalPath(path)
+				if record, err := db.GetFileInfoByPath(absPath); err != nil || record.Status != data.StatusExcluded {
+					recordFailure(db, path, tag, data.StatusExcluded, util.T("matched the blacklist"))
+				}
 				return nil
 			}
 
 			// Send file path to be processed
-			fileCh <- path
+			queueFile(path, info.Size())
 
 			return nil
 		})
@@ -257,55 +518,153 @@ func processDirectories(dirs []string, threads int, tag string, force bool, blac
 		}
 	}
 
-	// Close the file channel to signal workers to stop
-	util.PrintProcess("All files collected, closing processing channel...\n")
+	for _, path := range listedFiles {
+		// Files that can't be stat'ed are reported by the workers
+		var size int64
+		if info, err := os.Stat(path); err == nil {
+			size = info.Size()
+		}
+		queueFile(path, size)
+	}
+
+	// Close the file channels to signal workers to stop
+	util.PrintProcess("All files collected, closing processing channels...\n")
 	close(fileCh)
+	close(hugeCh)
 
 	// Wait for all workers to finish
 	util.PrintProcess("Waiting for all workers to complete processing...\n")
 	wg.Wait()
 
-	// Close the result channel after all workers finish
+	// Close the result channel after all workers finish and wait for the last batch to be saved
 	close(resultCh)
+	<-batchDone
+
+	// The programs holding files open may have closed them by now
+	if retryLocked {
+		retryLockedFiles(db, tag, force, quick, finderTags, xattrs)
+	}
+
+	dirScans = slices.DeleteFunc(dirScans, func(scan *data.DirScan) bool {
+		_, failed := failedDirs.Load(scan.Path)
+		return failed
+	})
+	if err := db.SaveDirScans(dirScans); err != nil {
+		util.PrintError("Error recording the directories scanned: %v\n", err)
+	}
+
+	if err := db.FinishSession(found.Load(), util.GetRunStats().BytesHashed); err != nil {
+		util.PrintError("Error finishing scan session %d: %v\n", session.ID, err)
+	} else {
+		util.PrintProcess("Scan session %d: %d files added, %d updated, %d skipped\n", session.ID, session.FilesAdded, session.FilesUpdated, session.FilesSkipped)
+		util.EmitJSON("session", map[string]any{"id": session.ID, "found": found.Load(), "added": session.FilesAdded, "updated": session.FilesUpdated, "skipped": session.FilesSkipped})
+	}
 
 	util.PrintSuccess("Sync operation completed.")
+	return true
+}
+
+// emitSynced writes the record of a synced file as a JSON result
+func emitSynced(fileInfo *data.FileInfo) {
+	util.EmitJSON("synced", map[string]any{
+		"path":   fileInfo.Path,
+		"size":   fileInfo.Size,
+		"mtime":  fileInfo.MTime,
+		"md5":    fileInfo.MD5,
+		"blake3": fileInfo.Blake3,
+		"quick":  fileInfo.QuickHash,
+		"tag":    fileInfo.Tag,
+	})
+}
+
+// retryLockedFiles syncs the files skipped because they were in use once more, the ones still in use
+// stay skipped
+func retryLockedFiles(db *data.DB, tag string, force bool, quick bool, finderTags bool, xattrs bool) {
+	locked := util.TakeLockedFiles()
+	if len(locked) == 0 {
+		return
+	}
+
+	util.PrintProcess("Retrying %d files that were in use\n", len(locked))
+	for _, path := range locked {
+		fileInfo, err := processFileInfoOnly(path, tag, force, quick, finderTags, xattrs, db)
+		if err != nil {
+			util.PrintWarning("Skipping %s again: %v\n", path, err)
+			util.RecordSkipped(path, err)
+			recordFailure(db, path, tag, failureStatus(err), err.Error())
+			continue
+		}
+		if fileInfo == nil {
+			continue
+		}
+		if err := db.UpsertFileInfo(fileInfo); err != nil {
+			util.PrintError("Error upserting file info: %v\n", err)
+			continue
+		}
+		emitSynced(fileInfo)
+		util.PrintProcess("Synced %s\n", path)
+	}
 }
 
 // processFileInfoOnly processes a file and returns its FileInfo struct without saving to database
-func processFileInfoOnly(filePath string, tag string, force bool, db *data.DB) (*data.FileInfo, error) {
+func processFileInfoOnly(filePath string, tag string, force bool, quick bool, finderTags bool, xattrs bool, db *data.DB) (*data.FileInfo, error) {
 	// Get file info
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("error getting file info for %s: %v", filePath, err)
+		return nil, fmt.Errorf("error getting file info for %s: %w", filePath, err)
 	}
 
-	// Calculate absolute path for database lookup
-	absPath, err := filepath.Abs(filePath)
+	// Calculate canonical path for database lookup
+	absPath, err := util.CanonicalPath(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("error getting absolute path for %s: %v", filePath, err)
 	}
 
 	// Check if file already exists in database
-	if !force {
-		_, err := db.GetFileInfoByPath(absPath)
-		if err == nil {
-			// File exists in database and force is false, skip
-			util.PrintWarning("Skipping existing file: %s\n", filePath)
-			return nil, nil // Return nil to indicate file should be skipped
-		} else if err != gorm.ErrRecordNotFound {
+	var existing *data.FileInfo
+	if !force || quick {
+		existing, err = db.GetFileInfoByPath(absPath)
+		if err == gorm.ErrRecordNotFound {
+			// The file doesn't exist in DB, so continue processing
+			existing = nil
+		} else if err != nil {
 			// If there's an error other than "record not found", return the error
 			return nil, fmt.Errorf("error checking if file exists in database: %v", err)
 		}
-		// If err is gorm.ErrRecordNotFound, the file doesn't exist in DB, so continue processing
 	}
 
-	// Calculate file key (Blake3 of absolute path)
-	key := util.CalculateBlake3String(absPath)
+	// File exists in database with the hashes of this mode and force is false, skip
+	if existing != nil && !force && existing.Status == data.StatusOK && ((quick && existing.QuickHash != "") || (!quick && existing.HasFullHashes())) {
+		util.PrintWarning("Skipping existing file: %s\n", filePath)
+		return nil, nil // Return nil to indicate file should be skipped
+	}
 
-	// Calculate MD5 and Blake3 with single file read
-	blake3Hash, md5Hash, err := util.FileBlake3MD5(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("error calculating hashes for %s: %v", filePath, err)
+	// Calculate file key (Blake3 of the canonical path)
+	key := util.PathKey(absPath)
+
+	var blake3Hash, md5Hash, quickHash string
+	if quick {
+		// Only sample the head and the tail of the file
+		quickHash, err = util.FileQuickHash(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("error calculating quick hash for %s: %w", filePath, err)
+		}
+
+		// Keep the full hashes of a file that hasn't changed since they were calculated
+		if existing != nil && existing.Size == fileInfo.Size() && data.SameMTime(existing.MTime, fileInfo.ModTime()) {
+			md5Hash, blake3Hash = existing.MD5, existing.Blake3
+		}
+	} else {
+		// Calculate MD5 and Blake3 with single file read
+		blake3Hash, md5Hash, err = util.FileBlake3MD5(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("error calculating hashes for %s: %w", filePath, err)
+		}
+	}
+
+	// A file still being written can't be recorded with its hashes
+	if after, err := os.Stat(filePath); err == nil && (after.Size() != fileInfo.Size() || !after.ModTime().Equal(fileInfo.ModTime())) {
+		return nil, fmt.Errorf("error hashing %s: %w", filePath, errChangedWhileHashed)
 	}
 
 	// Get actual creation time
@@ -313,17 +672,118 @@ func processFileInfoOnly(filePath string, tag string, force bool, db *data.DB) (
 
 	// Create database record
 	dbRecord := &data.FileInfo{
-		Key:    key,
-		Name:   filepath.Base(filePath),
-		Path:   absPath,
-		Status: 0, // File exists
-		MD5:    md5Hash,
-		Blake3: blake3Hash,
-		Size:   fileInfo.Size(),
-		Tag:    tag,
-		MTime:  fileInfo.ModTime(),
-		CTime:  ctime,
+		Key:       key,
+		Name:      filepath.Base(filePath),
+		Path:      absPath,
+		Status:    0, // File exists
+		MD5:       md5Hash,
+		Blake3:    blake3Hash,
+		QuickHash: quickHash,
+		Size:      fileInfo.Size(),
+		DiskSize:  util.GetDiskUsage(fileInfo),
+		Tag:       tag,
+		MTime:     fileInfo.ModTime(),
+		CTime:     ctime,
 	}
 
+	// Read macOS Finder tags if requested
+	if finderTags {
+		tags, err := util.ReadFinderTags(filePath)
+		if err != nil {
+			util.PrintWarning("Warning: Could not read Finder tags for %s: %v\n", filePath, err)
+		} else {
+			dbRecord.FinderTags = strings.Join(tags, ",")
+		}
+	}
+
+	// Read extended attributes if requested
+	if xattrs {
+		attrs, err := util.ReadXattrs(filePath)
+		if err != nil {
+			util.PrintWarning("Warning: Could not read extended attributes of %s: %v\n", filePath, err)
+		} else {
+			dbRecord.Xattrs = attrs
+		}
+	}
+
+	// Record the volume so the entry stays valid when it's mounted elsewhere
+	dbRecord.SetVolume()
+	dbRecord.SetPermissions(fileInfo)
+
 	return dbRecord, nil
 }
+
+// errChangedWhileHashed is the error of a file that changed while it was hashed, such as a download in progress
+var errChangedWhileHashed = errors.New("the file changed while it was hashed")
+
+// failureStatus classifies the error a file couldn't be hashed with
+func failureStatus(err error) int {
+	switch {
+	case util.IsLockedError(err):
+		return data.StatusLocked
+	case errors.Is(err, fs.ErrPermission):
+		return data.StatusPermission
+	case errors.Is(err, errChangedWhileHashed):
+		return data.StatusTooNew
+	default:
+		return data.StatusReadError
+	}
+}
+
+// recordFailure records why a file couldn't be hashed in its record, so sync info --retry-failed can try it
+// again. Files that are gone aren't recorded.
+func recordFailure(db *data.DB, path string, tag string, status int, reason string) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return
+	}
+	absPath, err := util.CanonicalPath(path)
+	if err != nil {
+		return
+	}
+
+	record := &data.FileInfo{
+		Key:      util.PathKey(absPath),
+		Name:     filepath.Base(absPath),
+		Path:     absPath,
+		Status:   status,
+		Reason:   reason,
+		Size:     info.Size(),
+		DiskSize: util.GetDiskUsage(info),
+		Tag:      tag,
+		MTime:    info.ModTime(),
+		CTime:    util.GetCreationTime(info),
+	}
+	record.SetVolume()
+	record.SetPermissions(info)
+	if err := db.MarkFailed(record); err != nil {
+		util.PrintWarning("Warning: Could not record why %s was skipped: %v\n", path, err)
+	}
+}
+
+// upToDateRecord returns the record of a file with its hashes, hashing and recording the file again when
+// it changed since it was synced
+func upToDateRecord(db *data.DB, path string, info os.FileInfo) (*data.FileInfo, error) {
+	record, err := db.GetFileInfoByPath(path)
+	if err == nil && record.HasFullHashes() && record.Size == info.Size() && data.SameMTime(record.MTime, info.ModTime()) {
+		return record, nil
+	}
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("error getting file info for %s: %v", path, err)
+	}
+
+	// Keep the tag the file was synced with
+	tag := ""
+	if err == nil {
+		tag = record.Tag
+	}
+
+	record, err = processFileInfoOnly(path, tag, true, false, false, false, db)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.UpsertFileInfo(record); err != nil {
+		return nil, fmt.Errorf("error upserting file info for %s: %v", path, err)
+	}
+	return record, nil
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// intakeCmd represents the intake command
+var intakeCmd = &cobra.Command{
+	Use:   "intake <mountpoint>",
+	Short: "Inventory a newly attached drive against the catalog",
+	Long: `Sync every file of a newly attached drive with a tag, then report how much of its contents is already recorded elsewhere in the catalog, by MD5 and Blake3 values, and how much is only on the drive. When nothing is only on the drive, it can be wiped without losing anything the catalog knows of.
+
+Files already synced from the drive keep their records and are tagged too. With --list-new, the files only on the drive are listed, so they can be copied before wiping it.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tag, _ := cmd.Flags().GetString("tag")
+		threads, _ := cmd.Flags().GetInt("threads")
+		listNew, _ := cmd.Flags().GetBool("list-new")
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		if threads < 1 {
+			util.PrintError("Error: --threads must be at least 1\n")
+			os.Exit(1)
+		}
+
+		mountpoint, err := util.CanonicalPath(args[0])
+		if err != nil {
+			util.PrintError("Error getting absolute path for %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+		if info, err := os.Stat(mountpoint); err != nil || !info.IsDir() {
+			util.PrintError("Error: %s is not a directory\n", mountpoint)
+			os.Exit(1)
+		}
+
+		// Synced like sync info with its defaults, huge files of 1G or more get a worker of their own
+		if !processDirectories(cmd, []string{mountpoint}, nil, threads, 1, 1<<30, tag, "intake", false, false, nil, 10, false, false, false, false) {
+			return
+		}
+
+		if err := reportIntake(mountpoint, tag, listNew, columns); err != nil {
+			util.PrintError("Error reporting the intake of %s: %v\n", mountpoint, err)
+			os.Exit(1)
+		}
+	},
+}
+
+// intakeColumns are the columns of the list of files only on the drive
+var intakeColumns = []string{"size", "modified", "path"}
+
+func init() {
+	intakeCmd.Flags().StringP("tag", "T", "", "Tag given to every file of the drive")
+	intakeCmd.MarkFlagRequired("tag")
+	intakeCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	intakeCmd.Flags().IntP("threads", "t", 1, "Number of threads for calculation")
+	intakeCmd.Flags().Bool("list-new", false, "List the files whose contents are only on the drive")
+	intakeCmd.Flags().StringSlice("columns", nil, "Columns of --list-new to show, in order (size, modified, path)")
+	intakeCmd.RegisterFlagCompletionFunc("columns", completeColumns(intakeColumns))
+	addYesFlag(intakeCmd)
+	rootCmd.AddCommand(intakeCmd)
+}
+
+// reportIntake tags the records of a drive and reports how much of its contents is recorded elsewhere
+func reportIntake(mountpoint string, tag string, listNew bool, columns []string) error {
+	table := util.NewTable(intakeColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	// Records that were up to date weren't written by the sync and still have their old tag
+	if _, err := db.SetTagByPath(mountpoint, tag); err != nil {
+		return fmt.Errorf("error tagging the records of %s: %v", mountpoint, err)
+	}
+
+	var records, uncovered []*data.FileInfo
+	if err := db.GetFileInfos(data.DuplicateFilter{PathPrefix: mountpoint}, &records); err != nil {
+		return fmt.Errorf("error getting the records of %s: %v", mountpoint, err)
+	}
+	if err := db.GetUncoveredFileInfos(mountpoint, &uncovered); err != nil {
+		return fmt.Errorf("error comparing %s with the catalog: %v", mountpoint, err)
+	}
+	isNew := make(map[string]bool, len(uncovered))
+	for _, record := range uncovered {
+		isNew[record.Path] = true
+	}
+
+	// Records of files removed from the drive since an earlier sync are left out
+	var total, covered, newFiles, unhashed, gone intakeCount
+	for _, record := range records {
+		if _, err := os.Lstat(record.Path); os.IsNotExist(err) {
+			gone.add(record)
+			continue
+		}
+		total.add(record)
+		switch {
+		case record.Blake3 == "" || record.MD5 == "":
+			unhashed.add(record)
+		case isNew[record.Path]:
+			newFiles.add(record)
+			table.AddRow(util.FormatSize(record.Size), record.MTime.Format("2006-01-02 15:04"), record.Path)
+		default:
+			covered.add(record)
+		}
+	}
+	if listNew {
+		table.Print()
+	}
+
+	util.PrintProcess("Intake of %s, tagged %q: %d files (%s)\n", mountpoint, tag, total.files, util.FormatSize(total.size))
+	util.PrintProcess("Already in the catalog: %d files (%s, %.1f%% of the bytes)\n", covered.files, util.FormatSize(covered.size), percentOf(covered.size, total.size))
+	util.PrintProcess("Only on this drive: %d files (%s, %.1f%% of the bytes)\n", newFiles.files, util.FormatSize(newFiles.size), percentOf(newFiles.size, total.size))
+	if unhashed.files > 0 {
+		util.PrintWarning("Not hashed: %d files (%s), see sync failed\n", unhashed.files, util.FormatSize(unhashed.size))
+	}
+	if gone.files > 0 {
+		util.PrintWarning("%d records are of files no longer on the drive, run clean info to remove them\n", gone.files)
+	}
+
+	if newFiles.files == 0 && unhashed.files == 0 {
+		util.PrintSuccess("Everything on %s is already in the catalog, the drive can be wiped.\n", mountpoint)
+	} else {
+		util.PrintWarning("Keep %s until the files only on it are copied elsewhere.\n", mountpoint)
+	}
+	return nil
+}
+
+// intakeCount is a number of files and their size
+type intakeCount struct {
+	files int
+	size  int64
+}
+
+// add counts the file of a record
+func (c *intakeCount) add(record *data.FileInfo) {
+	c.files++
+	c.size += record.Size
+}
+
+// percentOf returns part as a percentage of total, 0 when total is 0
+func percentOf(part int64, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"path/filepath"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+)
+
+// journal records the files moved or copied by a command as one operation of the journal, so undo can
+// reverse it. The operation is created with its first file, runs that change nothing leave no operation.
+type journal struct {
+	db        *data.DB
+	command   string
+	operation *data.Operation
+}
+
+// newJournal starts the journal of a run of command
+func newJournal(db *data.DB, command string) *journal {
+	return &journal{db: db, command: command}
+}
+
+// recordMove records the move of a file or folder to a deleted folder, with the record of the file that was
+// deleted, if any
+func (j *journal) recordMove(src, dst, deletedDir string, record *data.FileInfo) {
+	entry := &data.OperationEntry{Action: data.ActionMove, Src: src, Dst: dst, DeletedDir: deletedDir}
+	if record != nil {
+		entry.Size, entry.MTime = record.Size, record.MTime
+		entry.MD5, entry.Blake3, entry.Tag = record.MD5, record.Blake3, record.Tag
+	}
+	j.add(entry)
+}
+
+// recordCopy records the copy of a file to a path where nothing was, with the values of the copy
+func (j *journal) recordCopy(src, dst string, record *data.FileInfo) {
+	j.add(&data.OperationEntry{Action: data.ActionCopy, Src: src, Dst: dst, Size: record.Size, MTime: record.MTime, MD5: record.MD5, Blake3: record.Blake3})
+}
+
+// add records an entry, the journal never stops the command
+func (j *journal) add(entry *data.OperationEntry) {
+	for _, path := range []*string{&entry.Src, &entry.Dst, &entry.DeletedDir} {
+		if *path != "" {
+			if absPath, err := filepath.Abs(*path); err == nil {
+				*path = absPath
+			}
+		}
+	}
+
+	if j.operation == nil {
+		operation := &data.Operation{Command: j.command}
+		if err := j.db.StartOperation(operation); err != nil {
+			util.PrintWarning("Warning: Could not record the operation in the journal, it can't be undone: %v\n", err)
+			return
+		}
+		j.operation = operation
+		util.PrintProcess("Recording the changes as operation %d, undo %d reverses them\n", operation.ID, operation.ID)
+	}
+	if err := j.db.AddOperationEntry(j.operation, entry); err != nil {
+		util.PrintWarning("Warning: Could not record %s in the journal: %v\n", entry.Src, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -28,11 +29,24 @@ var dirCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		sourceDir, _ := cmd.Flags().GetString("from")
 		targetDir, _ := cmd.Flags().GetString("to")
+		delta, _ := cmd.Flags().GetBool("delta")
+		check, _ := cmd.Flags().GetBool("check")
+		layout, _ := cmd.Flags().GetString("layout")
+		update, _ := cmd.Flags().GetBool("update")
+		keepVersions, _ := cmd.Flags().GetInt("keep-versions")
 
 		if sourceDir == "" || targetDir == "" {
 			util.PrintError("Both source (-f) and target (-t) directories must be specified\n")
 			os.Exit(1)
 		}
+		if layout != mergeLayoutDated && layout != mergeLayoutMirror {
+			util.PrintError("Error: invalid --layout %s, expected %s or %s\n", layout, mergeLayoutDated, mergeLayoutMirror)
+			os.Exit(1)
+		}
+		if keepVersions < 0 {
+			util.PrintError("Error: invalid --keep-versions %d, expected 0 or more\n", keepVersions)
+			os.Exit(1)
+		}
 
 		// Convert to absolute paths
 		var err error
@@ -57,8 +71,34 @@ var dirCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if check {
+			util.PrintProcess("Checking whether the files of %s are in %s\n", sourceDir, targetDir)
+			missing, err := checkMerge(sourceDir, targetDir)
+			if err != nil {
+				util.PrintError("Error during merge check: %v\n", err)
+				os.Exit(1)
+			}
+			// An incomplete target fails, so scripts can tell whether a backup is complete
+			if missing > 0 {
+				os.Exit(1)
+			}
+			return
+		}
+
+		exitUnlessAllowed(targetDir)
+
+		// Every file of the source is a candidate until its hashes are compared with the target
+		files, size, err := countFiles([]string{sourceDir}, nil)
+		if err != nil {
+			util.PrintError("Error counting files: %v\n", err)
+			os.Exit(1)
+		}
+		if !confirmEstimate(cmd, files, size) {
+			return
+		}
+
 		util.PrintProcess("Starting merge operation from %s to %s\n", sourceDir, targetDir)
-		err = performMerge(sourceDir, targetDir)
+		err = performMerge(sourceDir, targetDir, delta, layout, update, keepVersions)
 		if err != nil {
 			util.PrintError("Error during merge: %v\n", err)
 			os.Exit(1)
@@ -67,11 +107,29 @@ var dirCmd = &cobra.Command{
 	},
 }
 
+// Layouts of the copied files in the target directory
+const (
+	mergeLayoutDated  = "dated"  // Below a FSAK_<YYMMdd> directory
+	mergeLayoutMirror = "mirror" // At their relative path in the source, renamed on collisions
+)
+
 // Initialize the commands
 func init() {
 	// Add flags to dirCmd
 	dirCmd.Flags().StringP("from", "f", "", "Source directory to merge from (required)")
 	dirCmd.Flags().StringP("to", "t", "", "Target directory to merge to (required)")
+	dirCmd.RegisterFlagCompletionFunc("from", completeCatalogedDirs)
+	dirCmd.RegisterFlagCompletionFunc("to", completeCatalogedDirs)
+	dirCmd.Flags().Bool("delta", false, "Update older files at the same path in target from newer ones with different content, transferring only changed blocks and keeping the older version in .fsak-versions")
+	dirCmd.Flags().Bool("check", false, "Only report the source files missing from the target, without copying anything")
+	addYesFlag(dirCmd)
+	dirCmd.Flags().Bool("update", false, "For files at the same path in both trees with different content, keep the newer one in target and the older one in .fsak-versions")
+	dirCmd.Flags().Int("keep-versions", 0, "Keep only the newest N previous versions of each file replaced by --update or --delta in .fsak-versions (0 keeps all)")
+	dirCmd.MarkFlagsMutuallyExclusive("check", "delta")
+	dirCmd.MarkFlagsMutuallyExclusive("check", "update")
+	dirCmd.MarkFlagsMutuallyExclusive("check", "keep-versions")
+	dirCmd.Flags().String("layout", mergeLayoutDated, "Where copied files go: dated (a FSAK_<YYMMdd> folder) or mirror (their original relative path, renamed on collisions)")
+	dirCmd.RegisterFlagCompletionFunc("layout", cobra.FixedCompletions([]string{mergeLayoutDated, mergeLayoutMirror}, cobra.ShellCompDirectiveNoFileComp))
 
 	// Mark required flags
 	_ = dirCmd.MarkFlagRequired("from")
@@ -85,57 +143,36 @@ func init() {
 }
 
 // performMerge executes the merge operation between source and target directories
-func performMerge(sourceDir, targetDir string) error {
+func performMerge(sourceDir, targetDir string, delta bool, layout string, update bool, keepVersions int) error {
 	// Connect to database
 	db, err := data.Connect()
 	if err != nil {
 		return fmt.Errorf("error connecting to database: %v", err)
 	}
-	defer func() {
-		sqlDB, _ := db.DB.DB()
-		if sqlDB != nil {
-			sqlDB.Close()
-		}
-	}()
-
-	// Create FSAK_<YYMMdd> directory in target
-	dateStr := time.Now().Format("060102") // YYMMdd format
-	backupDir := filepath.Join(targetDir, fmt.Sprintf("FSAK_%s", dateStr))
-	if err := os.MkdirAll(backupDir, 0755); err != nil {
-		return fmt.Errorf("error creating backup directory: %v", err)
-	}
-	util.PrintProcess("Created backup directory: %s\n", backupDir)
-
-	// Get all files in source and target directories and their MD5/Blake3 values
-	sourceFiles, err := getFilesWithHashes(db, sourceDir)
-	if err != nil {
-		return fmt.Errorf("error getting source files: %v", err)
+	defer db.Close()
+
+	// The mirror layout places files directly in the target
+	backupDir := targetDir
+	if layout == mergeLayoutDated {
+		// Create FSAK_<YYMMdd> directory in target
+		dateStr := time.Now().Format("060102") // YYMMdd format
+		backupDir = filepath.Join(targetDir, fmt.Sprintf("FSAK_%s", dateStr))
+		if err := os.MkdirAll(backupDir, 0755); err != nil {
+			return fmt.Errorf("error creating backup directory: %v", err)
+		}
+		util.PrintProcess("Created backup directory: %s\n", backupDir)
 	}
-	util.PrintProcess("Found %d files in source directory\n", len(sourceFiles))
 
-	targetFiles, err := getFilesWithHashes(db, targetDir)
+	filesToCopy, err := findFilesToCopy(db, sourceDir, targetDir)
 	if err != nil {
-		return fmt.Errorf("error getting target files: %v", err)
-	}
-	util.PrintProcess("Found %d files in target directory\n", len(targetFiles))
-
-	// Find files from source that don't exist in target based on MD5 and Blake3
-	var filesToCopy []string
-	for srcPath, srcHashes := range sourceFiles {
-		found := false
-		for _, targetHashes := range targetFiles {
-			if srcHashes.MD5 == targetHashes.MD5 && srcHashes.Blake3 == targetHashes.Blake3 {
-				found = true
-				break
-			}
-		}
-		if !found {
-			filesToCopy = append(filesToCopy, srcPath)
-		}
+		return err
 	}
 
 	util.PrintProcess("Found %d files to copy\n", len(filesToCopy))
 
+	// The copies are recorded, so undo can delete them
+	journal := newJournal(db, "merge dir")
+
 	// Copy files that don't exist in target
 	for _, srcPath := range filesToCopy {
 		// Calculate relative path from source directory
@@ -147,16 +184,61 @@ func performMerge(sourceDir, targetDir string) error {
 		// Construct destination path in backup directory
 		dstPath := filepath.Join(backupDir, relPath)
 
-		// Create directories for destination path if they don't exist
-		dstDir := filepath.Dir(dstPath)
-		if err := os.MkdirAll(dstDir, 0755); err != nil {
-			return fmt.Errorf("error creating directory %s: %v", dstDir, err)
+		// A different version at the same path in target is replaced by the newer one
+		existingPath := filepath.Join(targetDir, relPath)
+		existingInfo, err := os.Stat(existingPath)
+		replacing := (update || delta) && err == nil && existingInfo.Mode().IsRegular()
+		if replacing {
+			replaced, versionPath, err := keepOlderVersion(srcPath, existingPath, targetDir, relPath, existingInfo, delta, keepVersions)
+			if err != nil {
+				return err
+			}
+			if !replaced {
+				continue
+			}
+			// The older version kept aside is journaled before the copy or the delta transfer, so undo deletes
+			// the newer version and then puts the older one back
+			record, _ := db.GetFileInfoByPath(existingPath)
+			journal.recordMove(existingPath, versionPath, "", record)
+			dstPath = existingPath
 		}
 
-		// Copy file
-		util.PrintProcess("Copying %s to %s\n", srcPath, dstPath)
-		if err := copyFile(srcPath, dstPath); err != nil {
-			return fmt.Errorf("error copying %s to %s: %v", srcPath, dstPath, err)
+		// Copies are hashed while they're written, delta transfers are hashed afterwards
+		var blake3Hash, md5Hash string
+		// Only the copies to free paths and the files replacing an older version are undone
+		created := false
+
+		// An older version at the same path in target is updated with a delta transfer instead
+		if delta && replacing {
+			util.PrintProcess("Updating %s from %s\n", dstPath, srcPath)
+			transferred, err := util.DeltaCopy(srcPath, dstPath)
+			if err != nil {
+				return fmt.Errorf("error updating %s from %s: %v", dstPath, srcPath, err)
+			}
+			util.PrintProcess("Transferred %s of changed data\n", util.FormatSize(transferred))
+			created = true
+		} else {
+			// Create directories for destination path if they don't exist
+			dstDir := filepath.Dir(dstPath)
+			if err := os.MkdirAll(dstDir, 0755); err != nil {
+				return fmt.Errorf("error creating directory %s: %v", dstDir, err)
+			}
+
+			// A different file at the same path in the mirror is kept, the copy gets a free name
+			if layout == mergeLayoutMirror {
+				if freePath := freeCopyPath(dstPath); freePath != dstPath {
+					util.PrintWarning("Warning: %s exists with different content, placing the copy at %s\n", dstPath, freePath)
+					dstPath = freePath
+				}
+			}
+
+			// Copy file
+			_, statErr := os.Lstat(dstPath)
+			created = os.IsNotExist(statErr)
+			util.PrintProcess("Copying %s to %s\n", srcPath, dstPath)
+			if blake3Hash, md5Hash, err = copyFileHashed(srcPath, dstPath); err != nil {
+				return fmt.Errorf("error copying %s to %s: %v", srcPath, dstPath, err)
+			}
 		}
 
 		// Calculate and store file info in database
@@ -165,18 +247,24 @@ func performMerge(sourceDir, targetDir string) error {
 			return fmt.Errorf("error getting file info for %s: %v", srcPath, err)
 		}
 
-		absDstPath, err := filepath.Abs(dstPath)
+		absDstPath, err := util.CanonicalPath(dstPath)
 		if err != nil {
 			return fmt.Errorf("error getting absolute path for %s: %v", dstPath, err)
 		}
 
-		// Calculate path key (Blake3 of absolute path)
-		key := util.CalculateBlake3String(absDstPath)
+		// Calculate path key (Blake3 of the canonical path)
+		key := util.PathKey(absDstPath)
 
-		// Calculate MD5 and Blake3 for the copied file with single file read
-		blake3Hash, md5Hash, err := util.FileBlake3MD5(dstPath)
-		if err != nil {
-			return fmt.Errorf("error calculating hashes for %s: %v", dstPath, err)
+		// Calculate MD5 and Blake3 for the updated file with single file read
+		if blake3Hash == "" {
+			if blake3Hash, md5Hash, err = util.FileBlake3MD5(dstPath); err != nil {
+				return fmt.Errorf("error calculating hashes for %s: %v", dstPath, err)
+			}
+		}
+
+		// The copy is verified against the hashes the source was selected by
+		if source, err := db.GetFileInfoByPath(srcPath); err == nil && source.HasFullHashes() && (source.Blake3 != blake3Hash || source.MD5 != md5Hash) {
+			util.PrintWarning("Warning: %s changed since it was hashed, the copy has its current contents\n", srcPath)
 		}
 
 		// Get creation time
@@ -184,27 +272,175 @@ func performMerge(sourceDir, targetDir string) error {
 
 		// Create database record for copied file
 		dbRecord := &data.FileInfo{
-			Key:    key,
-			Name:   filepath.Base(dstPath),
-			Path:   absDstPath,
-			Status: 0, // File exists
-			MD5:    md5Hash,
-			Blake3: blake3Hash,
-			Size:   fileInfo.Size(),
-			Tag:    "", // No specific tag for copied files
-			MTime:  fileInfo.ModTime(),
-			CTime:  ctime,
+			Key:      key,
+			Name:     filepath.Base(dstPath),
+			Path:     absDstPath,
+			Status:   0, // File exists
+			MD5:      md5Hash,
+			Blake3:   blake3Hash,
+			Size:     fileInfo.Size(),
+			DiskSize: util.GetDiskUsage(fileInfo),
+			Tag:      "", // No specific tag for copied files
+			MTime:    fileInfo.ModTime(),
+			CTime:    ctime,
 		}
+		dbRecord.SetVolume()
 
 		// Insert or update record in database
 		if err := db.UpsertFileInfo(dbRecord); err != nil {
 			return fmt.Errorf("error upserting file info for %s: %v", dstPath, err)
 		}
+		if created {
+			journal.recordCopy(srcPath, absDstPath, dbRecord)
+		}
+		util.EmitJSON("copied", map[string]any{"path": srcPath, "to": absDstPath, "size": dbRecord.Size, "md5": md5Hash, "blake3": blake3Hash})
 	}
 
 	return nil
 }
 
+// keepOlderVersion compares a source file with the different file at its path in target and keeps the
+// older one in the versions directory of target. It reports whether the source is newer, so target has to
+// be replaced, which is left to the caller, and the path target was kept at in the versions directory.
+// With delta the replaced file is copied instead of moved, with its modification time, as the delta transfer
+// reads it. Only the newest keep versions are kept, unless keep is 0.
+func keepOlderVersion(srcPath, existingPath, targetDir, relPath string, existingInfo os.FileInfo, delta bool, keep int) (bool, string, error) {
+	srcInfo, err := os.Stat(srcPath)
+	if err != nil {
+		return false, "", fmt.Errorf("error getting file info for %s: %v", srcPath, err)
+	}
+
+	versionPath, err := util.NewVersionPath(targetDir, relPath)
+	if err != nil {
+		return false, "", err
+	}
+
+	if !srcInfo.ModTime().After(existingInfo.ModTime()) {
+		// Target has the newer version, keep the source one next to it
+		if err := copyFile(srcPath, versionPath); err != nil {
+			return false, "", fmt.Errorf("error copying %s to %s: %v", srcPath, versionPath, err)
+		}
+		util.PrintProcess("Kept newer %s, the older %s is kept at %s\n", existingPath, srcPath, versionPath)
+		return false, "", pruneVersions(targetDir, relPath, keep)
+	}
+
+	if delta {
+		// Keep the modification time, which a restore gives back
+		if err = copyFile(existingPath, versionPath); err == nil {
+			os.Chtimes(versionPath, existingInfo.ModTime(), existingInfo.ModTime())
+		}
+	} else {
+		err = os.Rename(existingPath, versionPath)
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("error keeping %s at %s: %v", existingPath, versionPath, err)
+	}
+	util.PrintProcess("Replacing older %s, it's kept at %s\n", existingPath, versionPath)
+	return true, versionPath, pruneVersions(targetDir, relPath, keep)
+}
+
+// pruneVersions removes the versions of the file at relPath in targetDir beyond the newest keep ones
+func pruneVersions(targetDir, relPath string, keep int) error {
+	removed, err := util.PruneVersions(targetDir, relPath, keep)
+	for _, path := range removed {
+		util.PrintProcess("Removed old version %s\n", path)
+	}
+	return err
+}
+
+// findFilesToCopy returns the files of the source directory whose MD5 and Blake3 values don't exist in
+// the target directory
+func findFilesToCopy(db *data.DB, sourceDir, targetDir string) ([]string, error) {
+	// Get all files in source and target directories and their MD5/Blake3 values
+	sourceFiles, err := getFilesWithHashes(db, sourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("error getting source files: %v", err)
+	}
+	util.PrintProcess("Found %d files in source directory\n", len(sourceFiles))
+
+	targetFiles, err := getFilesWithHashes(db, targetDir)
+	if err != nil {
+		return nil, fmt.Errorf("error getting target files: %v", err)
+	}
+	util.PrintProcess("Found %d files in target directory\n", len(targetFiles))
+
+	// Find files from source that don't exist in target based on MD5 and Blake3
+	var filesToCopy []string
+	for srcPath, srcHashes := range sourceFiles {
+		found := false
+		for _, targetHashes := range targetFiles {
+			if srcHashes.MD5 == targetHashes.MD5 && srcHashes.Blake3 == targetHashes.Blake3 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			filesToCopy = append(filesToCopy, srcPath)
+		}
+	}
+
+	return filesToCopy, nil
+}
+
+// freeCopyPath returns path if nothing exists there, otherwise the first free name of the form "name (1).ext"
+func freeCopyPath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for counter := 1; ; counter++ {
+		// Other errors are reported by the copy
+		if _, err := os.Lstat(path); err != nil {
+			return path
+		}
+		path = fmt.Sprintf("%s (%d)%s", base, counter, ext)
+	}
+}
+
+// maxMissingSamples is the number of missing files listed by merge dir --check
+const maxMissingSamples = 10
+
+// checkMerge reports the files of the source directory that are missing from the target directory,
+// without creating the backup directory or copying anything, and returns their number
+func checkMerge(sourceDir, targetDir string) (int, error) {
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return 0, fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	missingFiles, err := findFilesToCopy(db, sourceDir, targetDir)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(missingFiles) == 0 {
+		util.PrintSuccess("All files of %s are in %s.\n", sourceDir, targetDir)
+		return 0, nil
+	}
+
+	var missingSize int64
+	for _, path := range missingFiles {
+		if info, err := os.Stat(path); err == nil {
+			missingSize += info.Size()
+		}
+	}
+
+	util.PrintWarning("%d files (%s) of %s are missing from %s\n", len(missingFiles), util.FormatSize(missingSize), sourceDir, targetDir)
+	sort.Strings(missingFiles)
+	// Only a sample is printed, the JSON results have them all
+	for _, path := range missingFiles {
+		util.EmitJSON("missing", map[string]any{"path": path, "target": targetDir})
+	}
+	for _, path := range missingFiles[:min(len(missingFiles), maxMissingSamples)] {
+		util.PrintProcess("  %s\n", path)
+	}
+	if len(missingFiles) > maxMissingSamples {
+		util.PrintProcess("  ... and %d more\n", len(missingFiles)-maxMissingSamples)
+	}
+
+	return len(missingFiles), nil
+}
+
 // FileHashes stores MD5 and Blake3 values for a file
 type FileHashes struct {
 	MD5    string
@@ -214,25 +450,32 @@ type FileHashes struct {
 // getFilesWithHashes traverses the directory and calculates MD5 and Blake3 for each file
 // It first checks the database for existing values before calculating
 func getFilesWithHashes(db *data.DB, dir string) (map[string]*FileHashes, error) {
-	// First, count total files for progress tracking
+	// First, count total files and bytes for progress tracking
 	totalFiles := 0
+	var totalSize int64
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			// Skip unreadable files or directories
 			return nil
 		}
 
+		// Skip VCS and package-manager internals, and the versions kept by merges
+		if isDefaultExcluded(path, dir, info) || (info.IsDir() && info.Name() == util.VersionsDirName) {
+			return filepath.SkipDir
+		}
+
 		// Skip directories
 		if info.IsDir() {
 			return nil
 		}
 
-		// Check if it's the database file itself to avoid processing it
-		if strings.HasSuffix(path, "fsak.db") {
+		// Skip the database and its journal files
+		if isWorkspaceFile(path, info) {
 			return nil
 		}
 
 		totalFiles++
+		totalSize += info.Size()
 		return nil
 	})
 
@@ -246,27 +489,32 @@ func getFilesWithHashes(db *data.DB, dir string) (map[string]*FileHashes, error)
 
 	// Now process files and track progress
 	files := make(map[string]*FileHashes)
-	processedFiles := 0
+	progress := util.NewProgress(totalFiles, totalSize)
 
 	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			// Skip unreadable files or directories
+			util.RecordSkipped(path, err)
 			return nil
 		}
 
+		// Skip VCS and package-manager internals, and the versions kept by merges
+		if isDefaultExcluded(path, dir, info) || (info.IsDir() && info.Name() == util.VersionsDirName) {
+			return filepath.SkipDir
+		}
+
 		// Skip directories
 		if info.IsDir() {
 			return nil
 		}
 
-		// Check if it's the database file itself to avoid processing it
-		if strings.HasSuffix(path, "fsak.db") {
+		// Skip the database and its journal files
+		if isWorkspaceFile(path, info) {
 			return nil
 		}
 
-		processedFiles++
 		// Get absolute path
-		absPath, err := filepath.Abs(path)
+		absPath, err := util.CanonicalPath(path)
 		if err != nil {
 			return fmt.Errorf("error getting absolute path for %s: %v", path, err)
 		}
@@ -281,30 +529,38 @@ func getFilesWithHashes(db *data.DB, dir string) (map[string]*FileHashes, error)
 			}
 
 			// Show progress
-			percentage := float64(processedFiles) / float64(totalFiles) * 100
-			util.PrintProcess("[ %d / %d (%.2f%%)]: %s\n", processedFiles, totalFiles, percentage, absPath)
+			progress.Report(info.Size(), absPath)
 		} else {
 			// Not in database or missing hash values, calculate them with single file read
 			blake3Hash, md5Hash, err := util.FileBlake3MD5(path)
+			if util.IsLockedError(err) {
+				// Files held open by other programs don't stop the merge, they're reported as skipped
+				util.PrintWarning("Skipping %s, it's in use by another program\n", path)
+				util.RecordSkipped(path, err)
+				return nil
+			}
 			if err != nil {
 				return fmt.Errorf("error calculating hashes for %s: %v", path, err)
 			}
 
 			// Store in database for future use
-			key := util.CalculateBlake3String(absPath)
+			key := util.PathKey(absPath)
 
 			dbRecord := &data.FileInfo{
-				Key:    key,
-				Name:   filepath.Base(path),
-				Path:   absPath,
-				Status: 0, // File exists
-				MD5:    md5Hash,
-				Blake3: blake3Hash,
-				Size:   info.Size(),
-				Tag:    "",
-				MTime:  info.ModTime(),
-				CTime:  util.GetCreationTime(info),
+				Key:      key,
+				Name:     filepath.Base(path),
+				Path:     absPath,
+				Status:   0, // File exists
+				MD5:      md5Hash,
+				Blake3:   blake3Hash,
+				Size:     info.Size(),
+				DiskSize: util.GetDiskUsage(info),
+				Tag:      "",
+				MTime:    info.ModTime(),
+				CTime:    util.GetCreationTime(info),
 			}
+			dbRecord.SetVolume()
+			dbRecord.SetPermissions(info)
 
 			if err := db.UpsertFileInfo(dbRecord); err != nil {
 				return fmt.Errorf("error upserting file info for %s: %v", path, err)
@@ -316,8 +572,7 @@ func getFilesWithHashes(db *data.DB, dir string) (map[string]*FileHashes, error)
 			}
 
 			// Show progress
-			percentage := float64(processedFiles) / float64(totalFiles) * 100
-			util.PrintProcess("[ %d / %d (%.2f%%)]: %s\n", processedFiles, totalFiles, percentage, absPath)
+			progress.Report(info.Size(), absPath)
 		}
 
 		return nil
@@ -326,32 +581,69 @@ func getFilesWithHashes(db *data.DB, dir string) (map[string]*FileHashes, error)
 	return files, err
 }
 
-// copyFile copies a file from src to dst
+// copyFile copies a file from src to dst, retrying on transient errors
 func copyFile(src, dst string) error {
+	return util.Retry(src, func() error {
+		return copyFileOnce(src, dst, nil)
+	})
+}
+
+// copyFileHashed copies a file from src to dst like copyFile, and returns the Blake3 and MD5 values of the
+// contents written, calculated while they're copied
+func copyFileHashed(src, dst string) (string, string, error) {
+	var hasher *util.HashingWriter
+	err := util.Retry(src, func() error {
+		// A retry copies the file from the start
+		hasher = util.NewHashingWriter()
+		return copyFileOnce(src, dst, hasher)
+	})
+	if err != nil {
+		return "", "", err
+	}
+	blake3Hash, md5Hash := hasher.Sums()
+	return blake3Hash, md5Hash, nil
+}
+
+// copyFileOnce copies a file from src to dst in a single attempt, writing the contents to tee as well when
+// it isn't nil
+func copyFileOnce(src, dst string, tee io.Writer) error {
 	// Open source file
 	srcFile, err := os.Open(src)
 	if err != nil {
-		return fmt.Errorf("error opening source file: %v", err)
+		return fmt.Errorf("error opening source file: %w", err)
 	}
 	defer srcFile.Close()
 
 	// Create destination file
 	dstFile, err := os.Create(dst)
 	if err != nil {
-		return fmt.Errorf("error creating destination file: %v", err)
+		return fmt.Errorf("error creating destination file: %w", err)
 	}
 	defer dstFile.Close()
 
 	// Copy contents
-	_, err = io.Copy(dstFile, srcFile)
+	var writer io.Writer = dstFile
+	if tee != nil {
+		writer = io.MultiWriter(dstFile, tee)
+	}
+	copied, err := io.Copy(writer, srcFile)
 	if err != nil {
-		return fmt.Errorf("error copying file contents: %v", err)
+		return fmt.Errorf("error copying file contents: %w", err)
+	}
+	util.CountBytesCopied(copied)
+	if tee != nil {
+		util.CountFileHashed(copied)
 	}
 
 	// Sync to ensure data is written to disk
 	err = dstFile.Sync()
 	if err != nil {
-		return fmt.Errorf("error syncing destination file: %v", err)
+		return fmt.Errorf("error syncing destination file: %w", err)
+	}
+
+	// Extended attributes are best effort, the target filesystem may not support them
+	if err := util.CopyXattrs(src, dst); err != nil {
+		util.PrintWarning("Warning: Could not copy the extended attributes of %s: %v\n", src, err)
 	}
 
 	return nil
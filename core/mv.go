@@ -0,0 +1,167 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// mvCmd represents the mv command
+var mvCmd = &cobra.Command{
+	Use:   "mv <src> <dst>",
+	Short: "Move a file or folder, verifying copies to another drive",
+	Long: `Move a file or folder like mv, so that its records follow it in the catalog. When the destination is an existing folder, the source is moved into it.
+
+Within a filesystem the source is renamed. To another filesystem, every file is copied while its Blake3 and MD5 values are calculated, the copy is read back and compared with them and with the values recorded for the source, and flushed to disk, before the source is deleted. The copy keeps the modification time, permissions and extended attributes of the source. A file whose contents don't match is left in place.`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		src, err := util.CanonicalPath(args[0])
+		if err != nil {
+			util.PrintError("Error getting absolute path for %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+		dst, err := util.CanonicalPath(args[1])
+		if err != nil {
+			util.PrintError("Error getting absolute path for %s: %v\n", args[1], err)
+			os.Exit(1)
+		}
+		if info, err := os.Stat(dst); err == nil && info.IsDir() {
+			dst = filepath.Join(dst, filepath.Base(src))
+		}
+		exitUnlessAllowed(src, dst)
+
+		if err := moveVerified(src, dst); err != nil {
+			util.PrintError("Error moving %s to %s: %v\n", src, dst, err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mvCmd)
+}
+
+// moveVerified moves a file or folder, renaming it when possible and otherwise copying and verifying every
+// file before deleting it, and moves the records of the files with them
+func moveVerified(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%s exists", dst)
+	}
+	if isSubPath(dst, src) {
+		return fmt.Errorf("can't move %s into itself", src)
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	// Nothing is at the destination, records there are of files deleted since
+	if _, err := dropRecordsUnder(db, dst); err != nil {
+		return err
+	}
+
+	if os.Rename(src, dst) == nil {
+		moved, err := moveRecords(db, src, dst)
+		if err != nil {
+			return err
+		}
+		util.EmitJSON("moved", map[string]any{"path": src, "to": dst})
+		util.PrintSuccess("Renamed %s to %s, %d records follow it.\n", src, dst, moved)
+		return nil
+	}
+
+	if !info.IsDir() {
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("%s is not a regular file or folder", src)
+		}
+		if err := moveFileVerified(db, src, dst, info); err != nil {
+			return err
+		}
+		util.PrintSuccess("Moved %s to %s (%s), verified.\n", src, dst, util.FormatSize(info.Size()))
+		return nil
+	}
+
+	files, size, err := copyTreeVerified(db, src, dst, true)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(src); err == nil {
+		util.PrintWarning("Warning: %s still holds files that weren't moved\n", src)
+	}
+
+	util.PrintSuccess("Moved %d files (%s) to %s, verified.\n", files, util.FormatSize(size), dst)
+	return nil
+}
+
+// moveFileVerified copies a file to another filesystem with copyFileVerified and deletes it, its record follows
+// the copy
+func moveFileVerified(db *data.DB, src, dst string, info os.FileInfo) error {
+	record, err := copyFileVerified(db, src, dst, info)
+	if err != nil {
+		return fmt.Errorf("%v, %s was left in place", err, src)
+	}
+
+	moved := *record
+	moved.MovedFrom = src
+	if err := os.Remove(src); err != nil {
+		// Both files exist, the copy gets a record of its own
+		moved.MovedFrom = ""
+		err = fmt.Errorf("%s was copied to %s but couldn't be deleted: %v", src, dst, err)
+		if recordErr := recordFileAt(db, &moved, dst); recordErr != nil {
+			return recordErr
+		}
+		return err
+	}
+	if err := recordFileAt(db, &moved, dst); err != nil {
+		return err
+	}
+	util.EmitJSON("moved", map[string]any{"path": src, "to": dst, "size": info.Size(), "md5": record.MD5, "blake3": record.Blake3})
+	return nil
+}
+
+// moveRecords moves the records at or under src to the paths under dst the files were renamed to, and
+// returns their number
+func moveRecords(db *data.DB, src, dst string) (int, error) {
+	var records []*data.FileInfo
+	if err := db.GetFileInfos(data.DuplicateFilter{PathPrefix: src}, &records); err != nil {
+		return 0, fmt.Errorf("error getting the records of %s: %v", src, err)
+	}
+	for _, record := range records {
+		relPath, err := filepath.Rel(src, record.Path)
+		if err != nil {
+			return 0, err
+		}
+		moved := *record
+		moved.MovedFrom = record.Path
+		if err := recordFileAt(db, &moved, filepath.Join(dst, relPath)); err != nil {
+			return 0, err
+		}
+	}
+	return len(records), nil
+}
+
+// dropRecordsUnder deletes the records at or under a path and returns their number
+func dropRecordsUnder(db *data.DB, path string) (int, error) {
+	var records []*data.FileInfo
+	if err := db.GetFileInfos(data.DuplicateFilter{PathPrefix: path}, &records); err != nil {
+		return 0, fmt.Errorf("error getting the records of %s: %v", path, err)
+	}
+	for _, record := range records {
+		if err := db.DeleteFileInfo(record.Key); err != nil {
+			return 0, fmt.Errorf("error deleting the record of %s: %v", record.Path, err)
+		}
+	}
+	return len(records), nil
+}
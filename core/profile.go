@@ -0,0 +1,85 @@
+package core
+
+import (
+	_ "expvar" // Registers /debug/vars with runtime memory stats
+	"fmt"
+	"net"
+	"net/http"
+	_ "net/http/pprof" // Registers the /debug/pprof endpoints
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// Profiling options, hidden because they're only meant for diagnosing performance problems
+var (
+	pprofAddr      string
+	cpuProfilePath string
+	memProfilePath string
+	cpuProfileFile *os.File
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&pprofAddr, "pprof", "", "Serve pprof endpoints and runtime metrics on this address (e.g. localhost:6060)")
+	rootCmd.PersistentFlags().StringVar(&cpuProfilePath, "cpu-profile", "", "Write a CPU profile to this file")
+	rootCmd.PersistentFlags().StringVar(&memProfilePath, "mem-profile", "", "Write a heap profile to this file when the command finishes")
+	rootCmd.PersistentFlags().MarkHidden("pprof")
+	rootCmd.PersistentFlags().MarkHidden("cpu-profile")
+	rootCmd.PersistentFlags().MarkHidden("mem-profile")
+}
+
+// startProfiling starts the pprof server and the CPU profile if requested
+func startProfiling(cmd *cobra.Command, args []string) error {
+	if pprofAddr != "" {
+		listener, err := net.Listen("tcp", pprofAddr)
+		if err != nil {
+			return fmt.Errorf("error starting pprof server: %v", err)
+		}
+
+		util.PrintProcess("Serving pprof on http://%s/debug/pprof/ and runtime metrics on http://%s/debug/vars\n", listener.Addr(), listener.Addr())
+		go http.Serve(listener, nil)
+	}
+
+	if cpuProfilePath != "" {
+		f, err := os.Create(cpuProfilePath)
+		if err != nil {
+			return fmt.Errorf("error creating CPU profile: %v", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return fmt.Errorf("error starting CPU profile: %v", err)
+		}
+		cpuProfileFile = f
+	}
+
+	return nil
+}
+
+// stopProfiling stops the CPU profile and writes the heap profile if requested
+func stopProfiling(cmd *cobra.Command, args []string) error {
+	if cpuProfileFile != nil {
+		pprof.StopCPUProfile()
+		cpuProfileFile.Close()
+		util.PrintProcess("CPU profile written to %s\n", cpuProfilePath)
+	}
+
+	if memProfilePath != "" {
+		f, err := os.Create(memProfilePath)
+		if err != nil {
+			return fmt.Errorf("error creating heap profile: %v", err)
+		}
+		defer f.Close()
+
+		// Get up-to-date statistics of live objects
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			return fmt.Errorf("error writing heap profile: %v", err)
+		}
+		util.PrintProcess("Heap profile written to %s\n", memProfilePath)
+	}
+
+	return nil
+}
@@ -0,0 +1,277 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// quarantineCmd represents the quarantine command
+var quarantineCmd = &cobra.Command{
+	Use:   "quarantine",
+	Short: "Inspect the deleted folders",
+	Long:  `Commands for the deleted folders clean dup, clean dirty and clean build move files to.`,
+}
+
+// quarantineStatsCmd represents the quarantine stats command
+var quarantineStatsCmd = &cobra.Command{
+	Use:   "stats [deleted dirs...]",
+	Short: "Summarize the files in the deleted folders by source, age and size",
+	Long: `Summarize the files sitting in deleted folders by the folder they were moved from and by how long ago they were moved, with their number and size, so it can be seen at a glance whether a folder is safe to purge. Without arguments, the deleted folder of the workspace and the ones the journal recorded moves to are summarized.
+
+The original path and the time of every move are read from the MANIFEST.tsv of the folder. Files missing from it are counted with an unknown source, by their modification time. --depth sets how many directory levels of the original paths the sources are grouped by.`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		depth, _ := cmd.Flags().GetInt("depth")
+
+		if depth < 1 {
+			util.PrintError("Error: --depth must be at least 1\n")
+			os.Exit(1)
+		}
+
+		dirs, err := absolutePaths(args)
+		if err != nil {
+			util.PrintError("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := quarantineStats(dirs, depth); err != nil {
+			util.PrintError("Error summarizing the deleted folders: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	quarantineStatsCmd.Flags().Int("depth", 3, "Number of directory levels of the original paths the sources are grouped by")
+	quarantineCmd.AddCommand(quarantineStatsCmd)
+	rootCmd.AddCommand(quarantineCmd)
+}
+
+// quarantineAges are the age brackets of quarantine stats, by the time since the files were moved
+var quarantineAges = []struct {
+	name   string
+	maxAge time.Duration
+}{
+	{"less than a week", 7 * 24 * time.Hour},
+	{"1 week to 1 month", 30 * 24 * time.Hour},
+	{"1 to 6 months", 182 * 24 * time.Hour},
+	{"6 months to 1 year", 365 * 24 * time.Hour},
+	{"more than a year", 1<<63 - 1},
+}
+
+// quarantineGroup totals the files of a deleted folder, a source or an age bracket
+type quarantineGroup struct {
+	name   string
+	files  int
+	size   int64
+	oldest time.Time
+}
+
+// add counts files moved at a time in the group
+func (g *quarantineGroup) add(files int, size int64, movedAt time.Time) {
+	g.files += files
+	g.size += size
+	if g.oldest.IsZero() || movedAt.Before(g.oldest) {
+		g.oldest = movedAt
+	}
+}
+
+// quarantineStats prints the files in deleted folders by folder, source and age, of the deleted folder of the
+// workspace and the ones of the journal when no folder is given
+func quarantineStats(dirs []string, depth int) error {
+	if len(dirs) == 0 {
+		var err error
+		if dirs, err = knownDeletedDirs(); err != nil {
+			return err
+		}
+	}
+
+	byDir := make(map[string]*quarantineGroup)
+	bySource := make(map[string]*quarantineGroup)
+	byAge := make([]quarantineGroup, len(quarantineAges))
+	for i, age := range quarantineAges {
+		byAge[i].name = util.T(age.name)
+	}
+	var total quarantineGroup
+
+	now := time.Now()
+	count := func(dir, source string, files int, size int64, movedAt time.Time) {
+		if byDir[dir] == nil {
+			byDir[dir] = &quarantineGroup{name: dir}
+		}
+		byDir[dir].add(files, size, movedAt)
+		if bySource[source] == nil {
+			bySource[source] = &quarantineGroup{name: source}
+		}
+		bySource[source].add(files, size, movedAt)
+		for i, age := range quarantineAges {
+			if now.Sub(movedAt) < age.maxAge {
+				byAge[i].add(files, size, movedAt)
+				break
+			}
+		}
+		total.add(files, size, movedAt)
+	}
+
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			util.PrintWarning("Skipping %s, it's not a folder\n", dir)
+			continue
+		}
+		entries, err := util.ReadQuarantineManifest(filepath.Join(dir, util.QuarantineManifestName))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		// Files moved there, the ones put back or purged since are gone
+		listed := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			listed[filepath.Clean(entry.Path)] = true
+			files, size := countTree(filepath.Join(dir, entry.Path))
+			if files > 0 {
+				count(dir, quarantineSource(entry.Origin, depth), files, size, entry.MovedAt)
+			}
+		}
+
+		// Files the manifest doesn't know
+		unknown := util.T("unknown")
+		err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				util.RecordSkipped(path, err)
+				return nil
+			}
+			relPath, _ := filepath.Rel(dir, path)
+			if listed[relPath] {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() || relPath == util.QuarantineManifestName || relPath == util.QuarantineManifestName+".partial" {
+				return nil
+			}
+			count(dir, unknown, 1, info.Size(), info.ModTime())
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error walking %s: %v", dir, err)
+		}
+	}
+
+	if total.files == 0 {
+		util.PrintSuccess("No files in the deleted folders.\n")
+		return nil
+	}
+
+	util.PrintProcess("By deleted folder:")
+	printQuarantineGroups("folder", sortedQuarantineGroups(byDir))
+	util.PrintProcess("By source:")
+	printQuarantineGroups("source", sortedQuarantineGroups(bySource))
+	util.PrintProcess("By time since moved:")
+	var ages []*quarantineGroup
+	for i := range byAge {
+		if byAge[i].files > 0 {
+			ages = append(ages, &byAge[i])
+		}
+	}
+	printQuarantineGroups("moved", ages)
+
+	util.PrintSuccess("%d files (%s) in %d deleted folders, the oldest moved %s.\n", total.files, util.FormatSize(total.size), len(byDir), total.oldest.Format("2006-01-02"))
+	return nil
+}
+
+// knownDeletedDirs returns the deleted folder of the workspace and the ones the journal recorded moves to
+func knownDeletedDirs() ([]string, error) {
+	var dirs []string
+	workspaceDeleted, err := deletedDirArg(nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(workspaceDeleted); err == nil {
+		dirs = append(dirs, workspaceDeleted)
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	journalDirs, err := db.GetDeletedDirs()
+	if err != nil {
+		return nil, fmt.Errorf("error getting the deleted folders of the journal: %v", err)
+	}
+	for _, dir := range journalDirs {
+		if _, err := os.Stat(dir); err == nil && !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
+
+// quarantineSource returns the directory an original path is grouped by, its first depth directories
+func quarantineSource(origin string, depth int) string {
+	dir := filepath.Dir(origin)
+	volume := filepath.VolumeName(dir)
+	parts := strings.Split(strings.Trim(dir[len(volume):], string(filepath.Separator)), string(filepath.Separator))
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return volume + string(filepath.Separator) + filepath.Join(parts...)
+}
+
+// countTree returns the number and total size of the files at or under a path, none when it's gone
+func countTree(path string) (int, int64) {
+	files := 0
+	var size int64
+	filepath.Walk(path, func(walkPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			if !os.IsNotExist(err) {
+				util.RecordSkipped(walkPath, err)
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			files++
+			size += info.Size()
+		}
+		return nil
+	})
+	return files, size
+}
+
+// sortedQuarantineGroups returns the groups, the largest first
+func sortedQuarantineGroups(groups map[string]*quarantineGroup) []*quarantineGroup {
+	sorted := make([]*quarantineGroup, 0, len(groups))
+	for _, group := range groups {
+		sorted = append(sorted, group)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].size != sorted[j].size {
+			return sorted[i].size > sorted[j].size
+		}
+		return sorted[i].name < sorted[j].name
+	})
+	return sorted
+}
+
+// printQuarantineGroups prints a table of groups named in the column, with their files, size and oldest move,
+// and writes them as JSON results
+func printQuarantineGroups(column string, groups []*quarantineGroup) {
+	table := util.NewTable(column, "files", "size", "oldest")
+	for _, group := range groups {
+		table.AddRow(group.name, fmt.Sprint(group.files), util.FormatSize(group.size), group.oldest.Format("2006-01-02"))
+		util.EmitJSON("quarantined", map[string]any{"by": column, "name": group.name, "files": group.files, "size": group.size, "oldest": group.oldest})
+	}
+	table.Print()
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// readOnlyMode refuses the commands changing files and deleting records, set by --read-only, the
+// read-only setting of the config or FSAK_READ_ONLY
+var readOnlyMode bool
+
+// mutatingCommands are the commands changing files or deleting records, with the flags that make them only
+// report what they would do
+var mutatingCommands map[*cobra.Command][]string
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&readOnlyMode, "read-only", false, "Refuse any command changing files or deleting database records, for safe exploratory runs")
+
+	mutatingCommands = map[*cobra.Command][]string{
+		cleanInfoCmd:       nil,
+		cleanDupCmd:        {"emit-script", "diff-last"},
+		cleanDirtyCmd:      {"list", "emit-script"},
+		cleanBuildCmd:      {"list"},
+		dedupeCmd:          nil,
+		dirCmd:             {"check"},
+		backupCmd:          nil,
+		restoreCmd:         nil,
+		versionsRestoreCmd: nil,
+		scheduleInstallCmd: nil,
+		scheduleRemoveCmd:  nil,
+		daemonInstallCmd:   nil,
+		daemonRemoveCmd:    nil,
+		storeAddCmd:        nil,
+		storeCheckoutCmd:   nil,
+		dbPruneCmd:         {"dry-run"},
+		sessionRollbackCmd: {"dry-run"},
+		touchsyncCmd:       {"dry-run"},
+		undoCmd:            {"dry-run"},
+		undoImportCmd:      nil,
+		mvCmd:              nil,
+		cpCmd:              nil,
+		snapshotDeleteCmd:  nil,
+		watchCmd:           nil,
+	}
+}
+
+// exitUnlessAllowed exits when a path a command would change is outside the allowed-paths of the config
+func exitUnlessAllowed(paths ...string) {
+	for _, path := range paths {
+		if err := util.CheckAllowedPath(path); err != nil {
+			util.PrintError("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// applyReadOnly enables the read-only mode when requested and refuses the command if it would change files
+func applyReadOnly(cmd *cobra.Command) error {
+	config, err := util.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if readOnlyMode || config.ReadOnly || os.Getenv(util.ReadOnlyEnv) != "" {
+		util.SetReadOnly(true)
+	}
+	if !util.ReadOnly() {
+		return nil
+	}
+
+	reportFlags, ok := mutatingCommands[cmd]
+	if !ok {
+		return nil
+	}
+	for _, name := range reportFlags {
+		if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed && flag.Value.String() != "false" && flag.Value.String() != "" {
+			return nil
+		}
+	}
+
+	if len(reportFlags) == 0 {
+		return fmt.Errorf("%s changes files or deletes records, which read-only mode refuses", cmd.CommandPath())
+	}
+	return fmt.Errorf("%s changes files or deletes records, which read-only mode refuses, use --%s to only report what it would do", cmd.CommandPath(), strings.Join(reportFlags, " or --"))
+}
@@ -0,0 +1,274 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore <dest>",
+	Short: "Restore files from a backup snapshot",
+	Long:  `Restore the files of a snapshot created by backup, or only a subtree of it with --path, to the destination directory, keeping their path relative to the snapshot. Each restored file is verified against the MD5 and Blake3 values recorded when the snapshot was created.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		backupDir, _ := cmd.Flags().GetString("from")
+		snapshot, _ := cmd.Flags().GetString("snapshot")
+		subPath, _ := cmd.Flags().GetString("path")
+
+		backupDir, err := filepath.Abs(backupDir)
+		if err != nil {
+			util.PrintError("Error getting absolute path for backup directory: %v\n", err)
+			os.Exit(1)
+		}
+		destDir, err := filepath.Abs(args[0])
+		if err != nil {
+			util.PrintError("Error getting absolute path for destination: %v\n", err)
+			os.Exit(1)
+		}
+
+		// The subtree is relative to the snapshot and must stay inside it
+		subPath = filepath.Clean(subPath)
+		if filepath.IsAbs(subPath) || subPath == ".." || strings.HasPrefix(subPath, ".."+string(filepath.Separator)) {
+			util.PrintError("Error: --path %s must be relative to the snapshot\n", subPath)
+			os.Exit(1)
+		}
+
+		exitUnlessAllowed(destDir)
+
+		failed, err := restoreSnapshot(backupDir, snapshot, subPath, destDir)
+		if err != nil {
+			util.PrintError("Error during restore: %v\n", err)
+			os.Exit(1)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	restoreCmd.Flags().StringP("from", "f", "", "Backup directory containing the snapshots (required)")
+	restoreCmd.Flags().StringP("snapshot", "s", "", "Restore the last snapshot taken on or before this date (YYYY-MM-DD), or the snapshot with this name (default: the latest)")
+	restoreCmd.Flags().StringP("path", "p", "", "Only restore this file or directory, relative to the snapshot")
+	restoreCmd.RegisterFlagCompletionFunc("from", completeCatalogedDirs)
+	restoreCmd.RegisterFlagCompletionFunc("snapshot", completeSnapshots)
+	_ = restoreCmd.MarkFlagRequired("from")
+	rootCmd.AddCommand(restoreCmd)
+}
+
+// completeSnapshots completes the --snapshot flag with the snapshots of the --from directory
+func completeSnapshots(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	backupDir, _ := cmd.Flags().GetString("from")
+	if backupDir == "" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	snapshots, _ := listSnapshots(backupDir)
+	return snapshots, cobra.ShellCompDirectiveNoFileComp
+}
+
+// findSnapshot returns the snapshot of backupDir selected by at, either a snapshot name or a date selecting
+// the last snapshot taken on or before it, the latest one when at is empty
+func findSnapshot(backupDir string, at string) (string, error) {
+	snapshots, err := listSnapshots(backupDir)
+	if err != nil {
+		return "", err
+	}
+	if len(snapshots) == 0 {
+		return "", fmt.Errorf("no snapshots in %s", backupDir)
+	}
+	if at == "" {
+		return snapshots[len(snapshots)-1], nil
+	}
+
+	for _, snapshot := range snapshots {
+		if snapshot == at {
+			return snapshot, nil
+		}
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", at, time.Local)
+	if err != nil {
+		return "", fmt.Errorf("invalid snapshot %s, expected a date (YYYY-MM-DD) or a snapshot name", at)
+	}
+
+	// Snapshot names sort by time, so the last one before the next day is taken
+	end := day.AddDate(0, 0, 1).Format(snapshotTimeFormat)
+	selected := ""
+	for _, snapshot := range snapshots {
+		if snapshot < end {
+			selected = snapshot
+		}
+	}
+	if selected == "" {
+		return "", fmt.Errorf("no snapshot in %s was taken on or before %s, the first one is %s", backupDir, at, snapshots[0])
+	}
+	return selected, nil
+}
+
+// restoreSnapshot copies the subtree at subPath of a snapshot to destDir and verifies the copies against
+// the recorded hashes, it returns the number of files that failed the verification
+func restoreSnapshot(backupDir, at, subPath, destDir string) (int, error) {
+	snapshot, err := findSnapshot(backupDir, at)
+	if err != nil {
+		return 0, err
+	}
+	snapshotDir := filepath.Join(backupDir, snapshot)
+
+	rootPath := filepath.Join(snapshotDir, subPath)
+	if _, err := os.Lstat(rootPath); err != nil {
+		return 0, fmt.Errorf("%s is not in snapshot %s", subPath, snapshot)
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return 0, fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	if subPath == "." {
+		util.PrintProcess("Restoring snapshot %s to %s\n", snapshot, destDir)
+	} else {
+		util.PrintProcess("Restoring %s from snapshot %s to %s\n", subPath, snapshot, destDir)
+	}
+
+	var restored, verified, unverified, failed, skipped int
+	var restoredSize int64
+	err = filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			util.RecordSkipped(path, err)
+			return nil
+		}
+
+		relPath, err := filepath.Rel(snapshotDir, path)
+		if err != nil {
+			return fmt.Errorf("error calculating relative path for %s: %v", path, err)
+		}
+		dstPath := filepath.Join(destDir, relPath)
+
+		if info.IsDir() {
+			if err := os.MkdirAll(dstPath, 0755); err != nil {
+				return fmt.Errorf("error creating directory %s: %v", dstPath, err)
+			}
+			return nil
+		}
+
+		// Files already in the destination are kept
+		if _, err := os.Lstat(dstPath); err == nil {
+			util.PrintWarning("Skipping existing file: %s\n", dstPath)
+			skipped++
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+			return fmt.Errorf("error creating directory %s: %v", filepath.Dir(dstPath), err)
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				util.RecordSkipped(path, err)
+				return nil
+			}
+			if err := os.Symlink(target, dstPath); err != nil {
+				return fmt.Errorf("error creating symlink %s: %v", dstPath, err)
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		util.PrintProcess("Restoring %s\n", dstPath)
+		if err := copyFile(path, dstPath); err != nil {
+			return fmt.Errorf("error copying %s to %s: %v", path, dstPath, err)
+		}
+		if err := os.Chtimes(dstPath, info.ModTime(), info.ModTime()); err != nil {
+			return fmt.Errorf("error setting modification time of %s: %v", dstPath, err)
+		}
+		restored++
+		restoredSize += info.Size()
+
+		// Verify the restored file against the hashes recorded by backup
+		record, err := db.GetFileInfoByPath(path)
+		if err == gorm.ErrRecordNotFound || (err == nil && !record.HasFullHashes()) {
+			util.PrintWarning("Warning: no hashes are recorded for %s, it can't be verified\n", path)
+			if err == nil {
+				restoreMetadata(db, dstPath, record)
+			}
+			unverified++
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error getting file info for %s: %v", path, err)
+		}
+
+		blake3Hash, md5Hash, err := util.FileBlake3MD5(dstPath)
+		if err != nil {
+			return fmt.Errorf("error calculating hashes for %s: %v", dstPath, err)
+		}
+		if blake3Hash != record.Blake3 || md5Hash != record.MD5 {
+			util.PrintError("Hash mismatch: %s differs from the content recorded for %s\n", dstPath, path)
+			failed++
+			return nil
+		}
+		restoreMetadata(db, dstPath, record)
+		verified++
+		return nil
+	})
+	if err != nil {
+		return failed, err
+	}
+
+	util.PrintSuccess("Restored %d files (%s) from snapshot %s to %s, %d verified.\n", restored, util.FormatSize(restoredSize), snapshot, destDir, verified)
+	if skipped > 0 {
+		util.PrintWarning("%d existing files were skipped.\n", skipped)
+	}
+	if unverified > 0 {
+		util.PrintWarning("%d files couldn't be verified.\n", unverified)
+	}
+	if failed > 0 {
+		util.PrintError("%d files don't match their recorded hashes.\n", failed)
+	}
+	return failed, nil
+}
+
+// restoreMetadata reapplies the owner, extended attributes, permission bits and ACL recorded for a file when
+// it was backed up, once it's verified since they can make it unreadable. Only root can usually give files to
+// other users or set security labels, failures are warnings.
+func restoreMetadata(db *data.DB, path string, record *data.FileInfo) {
+	// The owner goes first, changing it clears the setuid and setgid bits
+	if record.UID != nil && record.GID != nil {
+		if err := util.SetFileOwner(path, *record.UID, *record.GID); err != nil {
+			util.PrintWarning("Warning: Could not restore the owner of %s: %v\n", path, err)
+		}
+	}
+
+	// Extended attributes go before the permission bits, which may make the file read-only
+	xattrs, err := db.GetXattrs(record.Key)
+	if err == nil && len(xattrs) > 0 {
+		err = util.WriteXattrs(path, xattrs)
+	}
+	if err != nil {
+		util.PrintWarning("Warning: Could not restore the extended attributes of %s: %v\n", path, err)
+	}
+
+	if record.Mode != nil {
+		if err := os.Chmod(path, os.FileMode(*record.Mode)); err != nil {
+			util.PrintWarning("Warning: Could not restore the permissions of %s: %v\n", path, err)
+		}
+	}
+	if record.ACL != "" {
+		if err := util.SetFileACL(path, record.ACL); err != nil {
+			util.PrintWarning("Warning: Could not restore the ACL of %s: %v\n", path, err)
+		}
+	}
+}
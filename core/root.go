@@ -1,31 +1,395 @@
 package core
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/baowuhe/go-fsak/util"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var rootCmd = &cobra.Command{
-	Use:               "fsak",
-	Short:             "File System Swiss Army Knife",
-	Long:              `A command-line tool for enhanced file management operations.`,
-	CompletionOptions: cobra.CompletionOptions{DisableDefaultCmd: true},
+	Use:   "fsak",
+	Short: "File System Swiss Army Knife",
+	Long:  `A command-line tool for enhanced file management operations.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// The arguments are valid at this point, don't show the usage for later errors
+		cmd.SilenceUsage = true
+		runStartedAt = time.Now()
+		if err := applyEnvFlags(cmd); err != nil {
+			return err
+		}
+		if err := applyProfile(cmd); err != nil {
+			return err
+		}
+		if err := applyConfigDefaults(cmd); err != nil {
+			return err
+		}
+		if err := applyReadOnly(cmd); err != nil {
+			return err
+		}
+		if noColor {
+			util.DisableColors()
+		}
+		if jsonResults {
+			util.EnableJSON()
+		}
+		if err := printWorkspaceDir(); err != nil {
+			return err
+		}
+		if err := expandPathAliases(cmd, args); err != nil {
+			return err
+		}
+		util.SetRetryPolicy(retryAttempts, retryDelay)
+		return startProfiling(cmd, args)
+	},
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		if err := reportSkippedFiles(); err != nil {
+			return err
+		}
+		if err := finishRun(cmd, args); err != nil {
+			return err
+		}
+		return stopProfiling(cmd, args)
+	},
 }
 
 // Execute executes the root command.
 func Execute() error {
+	util.SetLocale(util.DetectLocale())
+	localizeCommand(rootCmd)
 	return rootCmd.Execute()
 }
 
+// localizeCommand translates the help texts of a command, its flags and its subcommands
+func localizeCommand(cmd *cobra.Command) {
+	cmd.Short = util.T(cmd.Short)
+	cmd.Long = util.T(cmd.Long)
+
+	translateUsage := func(flag *pflag.Flag) {
+		flag.Usage = util.T(flag.Usage)
+	}
+	cmd.LocalFlags().VisitAll(translateUsage)
+	cmd.PersistentFlags().VisitAll(translateUsage)
+
+	for _, sub := range cmd.Commands() {
+		localizeCommand(sub)
+	}
+}
+
+// noDefaultExcludes disables the built-in exclusion of VCS and package-manager internals
+var noDefaultExcludes bool
+
+// includeWorkspace lets walks into the workspace and the database, which hold the state of fsak
+var includeWorkspace bool
+
+// oneFileSystem keeps walks on the filesystem of the directory they start from, like du -x
+var oneFileSystem bool
+
+// profileName selects a profile of the config, FSAK_PROFILE is used when it's empty
+var profileName string
+
+// noColor prints the messages without colors, as NO_COLOR does
+var noColor bool
+
+// jsonResults writes the results of the commands as JSON lines to stdout
+var jsonResults bool
+
+// errorsReportPath is the file the skipped paths are written to
+var errorsReportPath string
+
+// Retry policy for reads and copies failing with transient I/O errors
+var (
+	retryAttempts int
+	retryDelay    time.Duration
+)
+
 func init() {
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Use the workspace, database and option defaults of this config profile (default $FSAK_PROFILE)")
+	rootCmd.RegisterFlagCompletionFunc("profile", completeProfiles)
+	rootCmd.PersistentFlags().BoolVar(&jsonResults, "json", false, "Write the results to stdout as JSON lines for jq and other tools, messages go to stderr")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Print messages without colors (colors are also off when NO_COLOR is set or the output isn't a terminal)")
+	rootCmd.PersistentFlags().BoolVarP(&oneFileSystem, "one-file-system", "x", false, "Don't descend into directories on other filesystems (mount points) while walking, like du -x")
+	rootCmd.PersistentFlags().BoolVar(&noDefaultExcludes, "no-default-excludes", false, "Don't exclude VCS and package-manager internals (.git, .hg, .svn, node_modules, ...) from scans")
+	rootCmd.PersistentFlags().BoolVar(&includeWorkspace, "include-workspace", false, "Don't exclude the workspace, the database and its backups from scans")
+	rootCmd.PersistentFlags().StringVar(&errorsReportPath, "errors-to", "", "Write every skipped or unreadable path with the reason to this file")
+	rootCmd.PersistentFlags().IntVar(&retryAttempts, "retries", 2, "Number of times a read or copy failing with a transient I/O error is retried")
+	rootCmd.PersistentFlags().DurationVar(&retryDelay, "retry-delay", 500*time.Millisecond, "Delay before the first retry, doubled for every further retry")
 	rootCmd.AddCommand(versionCmd)
 }
 
+// envFlagsSkipped are the flags with environment variables of their own, read where they're used
+var envFlagsSkipped = []string{"help", "profile", "read-only"}
+
+// commandLineOnlyFlags are the flags overriding safety checks, which only count when given on the command
+// line: they're never read from the environment, and the config and profiles can't set them
+var commandLineOnlyFlags = []string{"i-know-what-i-am-doing"}
+
+// applyEnvFlags sets the flags of the command not given on the command line from the FSAK_<FLAG>
+// environment variables, such as FSAK_THREADS for --threads or FSAK_DELETED_SAVE_DIR for --deleted-save-dir
+func applyEnvFlags(cmd *cobra.Command) error {
+	var err error
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || slices.Contains(envFlagsSkipped, flag.Name) || slices.Contains(commandLineOnlyFlags, flag.Name) {
+			return
+		}
+		name := "FSAK_" + strings.ToUpper(strings.ReplaceAll(flag.Name, "-", "_"))
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			return
+		}
+		if setErr := cmd.Flags().Set(flag.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid %s value of %s: %v", name, flag.Name, setErr)
+		}
+	})
+	return err
+}
+
+// applyConfigDefaults uses the settings of the [defaults.<command>] section of the config for the command,
+// such as [defaults.sync info], then the ones of [defaults], as the defaults of the command's flags. Flags
+// given on the command line, by environment variables or by the profile win.
+func applyConfigDefaults(cmd *cobra.Command) error {
+	config, err := util.LoadConfig()
+	if err != nil {
+		return err
+	}
+	for command, settings := range config.Defaults {
+		for _, key := range commandLineOnlyFlags {
+			if _, ok := settings[key]; ok {
+				return fmt.Errorf("%s can't be set in [%s] of the config, it must be given on the command line", key, strings.TrimSuffix("defaults."+command, "."))
+			}
+		}
+	}
+
+	commandPath := strings.TrimSpace(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()))
+	for _, command := range []string{commandPath, ""} {
+		section := "defaults"
+		if command != "" {
+			section += "." + command
+		}
+		// Settings for flags of other commands are ignored
+		for key, value := range config.Defaults[command] {
+			flag := cmd.Flags().Lookup(key)
+			if flag == nil || flag.Changed || key == "profile" {
+				continue
+			}
+			if err := cmd.Flags().Set(key, value); err != nil {
+				return fmt.Errorf("invalid %s setting in [%s] of the config: %v", key, section, err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyProfile selects the profile named by --profile or FSAK_PROFILE and uses its settings as the
+// defaults of the command's flags, flags given on the command line win
+func applyProfile(cmd *cobra.Command) error {
+	name := profileName
+	if name == "" {
+		name = os.Getenv("FSAK_PROFILE")
+	}
+	if name == "" {
+		return nil
+	}
+
+	profile, err := util.SetProfile(name)
+	if err != nil {
+		return err
+	}
+	for _, key := range commandLineOnlyFlags {
+		if _, ok := profile.Flags[key]; ok {
+			return fmt.Errorf("%s can't be set in profile %s, it must be given on the command line", key, name)
+		}
+	}
+
+	// Settings for flags of other commands are ignored
+	for key, value := range profile.Flags {
+		flag := cmd.Flags().Lookup(key)
+		if flag == nil || flag.Changed || key == "profile" {
+			continue
+		}
+		if err := cmd.Flags().Set(key, value); err != nil {
+			return fmt.Errorf("invalid %s setting in profile %s: %v", key, name, err)
+		}
+	}
+	return nil
+}
+
+// printWorkspaceDir prints the workspace directory and the selected profile
+func printWorkspaceDir() error {
+	// The shell already printed it
+	if os.Getenv(ShellEnv) != "" {
+		return nil
+	}
+
+	wsDir, err := util.GetWorkspaceDir()
+	if err != nil {
+		return fmt.Errorf("error getting workspace directory: %v", err)
+	}
+
+	// Get current user to show more user-friendly path
+	currentUser, err := os.UserHomeDir()
+	if err == nil {
+		// Replace home directory path with ~ for brevity, but only if the workspace directory is under the home directory
+		relPath, err2 := filepath.Rel(currentUser, wsDir)
+		if err2 == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			wsDir = filepath.Join("~", relPath)
+		}
+	}
+
+	if profile := util.GetProfile(); profile != nil {
+		util.PrintProcess("Workspace directory: %s (profile %s)\n", wsDir, profile.Name)
+	} else {
+		util.PrintProcess("Workspace directory: %s\n", wsDir)
+	}
+	return nil
+}
+
+// isDefaultExcluded checks if a directory found while walking root is excluded by default, or by
+// --one-file-system when it's on another filesystem than root. The workspace is excluded even as the root.
+func isDefaultExcluded(path, root string, info os.FileInfo) bool {
+	if !info.IsDir() {
+		return false
+	}
+	if !includeWorkspace && util.IsWorkspacePath(path, info) {
+		if _, reported := workspaceDirs.LoadOrStore(path, true); !reported {
+			util.PrintProcess("Not scanning %s, it holds the state of fsak\n", path)
+		}
+		return true
+	}
+	if path == root {
+		return false
+	}
+	if oneFileSystem && !onRootDevice(root, info) {
+		// Scans walk their directories more than once
+		if _, reported := otherFilesystems.LoadOrStore(path, true); !reported {
+			util.PrintProcess("Not crossing into %s, it's on another filesystem\n", path)
+		}
+		return true
+	}
+	return !noDefaultExcludes && util.IsDefaultExcludedDir(info.Name())
+}
+
+// isWorkspaceFile checks if a file found while walking is the database or one of its journal files, which
+// are never scanned unless --include-workspace is given
+func isWorkspaceFile(path string, info os.FileInfo) bool {
+	return !includeWorkspace && !info.IsDir() && util.IsWorkspacePath(path, info)
+}
+
+// workspaceDirs are the directories of the workspace left out so far
+var workspaceDirs sync.Map
+
+// rootDevices caches the filesystem of the roots being walked, by path
+var rootDevices sync.Map
+
+// otherFilesystems are the directories left out by --one-file-system so far
+var otherFilesystems sync.Map
+
+// onRootDevice reports whether a directory is on the filesystem of root, or whether that can't be told
+func onRootDevice(root string, info os.FileInfo) bool {
+	device, ok := util.DeviceID(info)
+	if !ok {
+		return true
+	}
+
+	rootDevice, ok := rootDevices.Load(root)
+	if !ok {
+		rootInfo, err := os.Stat(root)
+		if err != nil {
+			return true
+		}
+		if rootDevice, ok = util.DeviceID(rootInfo); !ok {
+			return true
+		}
+		rootDevices.Store(root, rootDevice)
+	}
+	return device == rootDevice.(uint64)
+}
+
+// reportSkippedFiles prints how many paths were skipped and writes the error report if requested
+func reportSkippedFiles() error {
+	skipped := util.GetSkippedFiles()
+	if len(skipped) > 0 {
+		locked, transient := 0, 0
+		for _, file := range skipped {
+			switch {
+			case file.Locked:
+				locked++
+			case file.Transient:
+				transient++
+			}
+		}
+		util.PrintWarning("Skipped %d files or directories that could not be read (%d in use by other programs, %d transient I/O errors that persisted through retries, %d permanent errors)\n", len(skipped), locked, transient, len(skipped)-locked-transient)
+	}
+
+	if errorsReportPath != "" {
+		if err := util.WriteSkippedReport(errorsReportPath); err != nil {
+			return fmt.Errorf("error writing error report: %v", err)
+		}
+		util.PrintProcess("Error report written to %s\n", errorsReportPath)
+	} else if len(skipped) > 0 {
+		util.PrintProcess("Use --errors-to <file> to write the skipped paths and reasons to a file\n")
+	}
+
+	return nil
+}
+
+// expandPathAliases replaces the @name path aliases in the arguments and string flags of a command
+func expandPathAliases(cmd *cobra.Command, args []string) error {
+	// Completion requests carry the partial paths being completed
+	if strings.HasPrefix(cmd.Name(), cobra.ShellCompRequestCmd) {
+		return nil
+	}
+
+	for i, arg := range args {
+		expanded, err := util.ExpandPathAlias(arg)
+		if err != nil {
+			return err
+		}
+		args[i] = expanded
+	}
+
+	var err error
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if err != nil || flag.Value.Type() != "string" {
+			return
+		}
+
+		var expanded string
+		if expanded, err = util.ExpandPathAlias(flag.Value.String()); err == nil && expanded != flag.Value.String() {
+			err = flag.Value.Set(expanded)
+		}
+	})
+	return err
+}
+
+// parseMaxMemory parses the value of a --max-memory flag, an empty value means no limit
+func parseMaxMemory(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	maxMemory, err := util.ParseSize(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --max-memory: %v", err)
+	}
+	return maxMemory, nil
+}
+
+// fsakVersion is the version of fsak, recorded with the statistics of every run
+const fsakVersion = "0.1.0"
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number",
 	Long:  `Print the version number of fsak.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		util.PrintSuccess("fsak v0.1.0")
+		util.PrintSuccess("fsak v%s\n", fsakVersion)
 	},
 }
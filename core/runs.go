@@ -0,0 +1,161 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// runsCmd represents the runs command
+var runsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "List the statistics of previous runs",
+	Long:  `List the summaries recorded at the end of the commands that did some work, newest first: elapsed time, files and bytes hashed, bytes copied, database writes, errors and skipped paths, with the version and machine they ran on, so performance can be compared across versions and machines.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		command, _ := cmd.Flags().GetString("command")
+		limit, _ := cmd.Flags().GetInt("limit")
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		if err := listRuns(command, limit, columns); err != nil {
+			util.PrintError("Error listing runs: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// runsColumns are the columns of the runs table
+var runsColumns = []string{"started", "command", "elapsed", "files", "hashed", "copied", "writes", "errors", "skipped", "version", "host"}
+
+// runStartedAt is when the current command started, for the summary of its run
+var runStartedAt time.Time
+
+func init() {
+	runsCmd.Flags().String("command", "", "Only list the runs of this command, such as \"sync info\"")
+	runsCmd.Flags().Int("limit", 20, "Number of runs to list")
+	runsCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (started, command, elapsed, files, hashed, copied, writes, errors, skipped, version, host)")
+	runsCmd.RegisterFlagCompletionFunc("columns", completeColumns(runsColumns))
+	rootCmd.AddCommand(runsCmd)
+}
+
+// finishRun prints the statistics of the current command, appends them to the runs log of the workspace
+// and records them in the runs table, unless the command did no work
+func finishRun(cmd *cobra.Command, args []string) error {
+	stats := util.GetRunStats()
+	if stats.Idle() {
+		return nil
+	}
+	elapsed := time.Since(runStartedAt).Round(time.Millisecond)
+
+	util.PrintProcess("Finished in %s: %d files hashed (%s), %s copied, %d database writes, %d errors, %d skipped\n",
+		elapsed, stats.FilesHashed, util.FormatSize(stats.BytesHashed), util.FormatSize(stats.BytesCopied), stats.DBWrites, stats.Errors, stats.Skipped)
+	util.EmitJSON("run", map[string]any{
+		"command":      cmd.CommandPath(),
+		"elapsed_ms":   elapsed.Milliseconds(),
+		"files_hashed": stats.FilesHashed,
+		"bytes_hashed": stats.BytesHashed,
+		"bytes_copied": stats.BytesCopied,
+		"db_writes":    stats.DBWrites,
+		"errors":       stats.Errors,
+		"skipped":      stats.Skipped,
+	})
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	run := &data.Run{
+		Command:     cmd.CommandPath(),
+		Args:        strings.Join(args, " "),
+		Version:     fsakVersion,
+		Host:        host,
+		Platform:    runtime.GOOS + "/" + runtime.GOARCH,
+		StartedAt:   runStartedAt,
+		Elapsed:     elapsed,
+		FilesHashed: stats.FilesHashed,
+		BytesHashed: stats.BytesHashed,
+		BytesCopied: stats.BytesCopied,
+		DBWrites:    stats.DBWrites,
+		Errors:      stats.Errors,
+		Skipped:     stats.Skipped,
+	}
+
+	if err := logRun(run); err != nil {
+		util.PrintWarning("Warning: Could not write the runs log: %v\n", err)
+	}
+
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+	if err := db.AddRun(run); err != nil {
+		return fmt.Errorf("error recording run: %v", err)
+	}
+	return nil
+}
+
+// logRun appends the statistics of a run as a line of key=value pairs to logs/runs.log in the workspace
+func logRun(run *data.Run) error {
+	wsDir, err := util.GetWorkspaceDir()
+	if err != nil {
+		return err
+	}
+	logDir := filepath.Join(wsDir, "logs")
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(logDir, "runs.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "started=%s command=%q args=%q version=%s host=%s platform=%s elapsed=%s files_hashed=%d bytes_hashed=%d bytes_copied=%d db_writes=%d errors=%d skipped=%d\n",
+		run.StartedAt.Format(time.RFC3339), run.Command, run.Args, run.Version, run.Host, run.Platform, run.Elapsed,
+		run.FilesHashed, run.BytesHashed, run.BytesCopied, run.DBWrites, run.Errors, run.Skipped)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// listRuns prints the latest recorded runs
+func listRuns(command string, limit int, columns []string) error {
+	table := util.NewTable(runsColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	if command != "" && !strings.HasPrefix(command, rootCmd.Name()+" ") {
+		command = rootCmd.Name() + " " + command
+	}
+	var runs []*data.Run
+	if err := db.GetRuns(command, limit, &runs); err != nil {
+		return fmt.Errorf("error getting runs: %v", err)
+	}
+
+	for _, run := range runs {
+		table.AddRow(run.StartedAt.Format("2006-01-02 15:04"), run.Command, run.Elapsed.String(), fmt.Sprint(run.FilesHashed),
+			util.FormatSize(run.BytesHashed), util.FormatSize(run.BytesCopied), fmt.Sprint(run.DBWrites), fmt.Sprint(run.Errors),
+			fmt.Sprint(run.Skipped), run.Version, run.Host)
+	}
+	table.Print()
+
+	util.PrintSuccess("%d runs listed.\n", len(runs))
+	return nil
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// scheduleCmd represents the schedule command
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Run fsak commands periodically",
+	Long:  `Commands installing and removing scheduled runs of fsak commands, as systemd units on Linux, launchd jobs on macOS and scheduled tasks on Windows, so the catalog is kept up to date without hand-written units.`,
+}
+
+// scheduleInstallCmd represents the schedule install command
+var scheduleInstallCmd = &cobra.Command{
+	Use:   "install <name> -- <command> [args...]",
+	Short: "Run an fsak command periodically",
+	Long: `Install a schedule running an fsak command at the interval given by --every, such as "schedule install photos --every 6h -- sync info ~/Pictures". On Linux a systemd service and timer named fsak-<name> are written and the timer is enabled, on macOS a launchd job is written and loaded, and on Windows a task is registered in the fsak folder of Task Scheduler, with the output of the runs in the logs directory of the workspace.
+
+Schedules are installed for the current user unless --system is given, which installs them for the whole system and needs root (SYSTEM tasks and an administrator on Windows). The command runs in the current directory, with the workspace, profile and language of the install.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		every, _ := cmd.Flags().GetDuration("every")
+		system, _ := cmd.Flags().GetBool("system")
+
+		if err := installSchedule(args[0], args[1:], every, system); err != nil {
+			util.PrintError("Error installing schedule: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// scheduleRemoveCmd represents the schedule remove command
+var scheduleRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a schedule",
+	Long:  `Stop and remove a schedule installed by schedule install, deleting its systemd units, launchd job or scheduled task.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		system, _ := cmd.Flags().GetBool("system")
+
+		paths, err := util.RemoveSchedule(args[0], system)
+		if err != nil {
+			util.PrintError("Error removing schedule: %v\n", err)
+			os.Exit(1)
+		}
+		for _, path := range paths {
+			util.PrintProcess("Removed %s\n", path)
+		}
+		util.PrintSuccess("Schedule %s removed.\n", args[0])
+	},
+}
+
+func init() {
+	scheduleInstallCmd.Flags().Duration("every", 24*time.Hour, "Interval between runs, such as 30m, 6h or 168h")
+	scheduleInstallCmd.Flags().Bool("system", false, "Install for the whole system instead of the current user (needs root)")
+	scheduleRemoveCmd.Flags().Bool("system", false, "Remove a schedule installed for the whole system")
+
+	scheduleCmd.AddCommand(scheduleInstallCmd)
+	scheduleCmd.AddCommand(scheduleRemoveCmd)
+	rootCmd.AddCommand(scheduleCmd)
+}
+
+// installSchedule checks the scheduled command and registers it with the service manager
+func installSchedule(name string, args []string, every time.Duration, system bool) error {
+	if err := util.ValidateScheduleName(name); err != nil {
+		return err
+	}
+	if every < time.Minute {
+		return fmt.Errorf("--every must be at least 1m, got %s", every)
+	}
+
+	// Catch typos now rather than at the first run
+	target, _, err := rootCmd.Find(args)
+	if err != nil || target == rootCmd || !target.Runnable() {
+		return fmt.Errorf("%q is not an fsak command", args[0])
+	}
+	if target.Parent() == scheduleCmd || target == shellCmd {
+		return fmt.Errorf("%s can't be scheduled", target.CommandPath())
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error getting the working directory: %v", err)
+	}
+
+	paths, err := util.InstallSchedule(util.Schedule{
+		Name:   name,
+		Every:  every,
+		Args:   args,
+		Env:    util.ScheduleEnv(),
+		Dir:    dir,
+		System: system,
+	})
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		util.PrintProcess("Wrote %s\n", path)
+	}
+	util.PrintSuccess("Schedule %s installed, running %s every %s.\n", name, target.CommandPath(), every)
+	return nil
+}
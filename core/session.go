@@ -0,0 +1,250 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// sessionsCmd represents the sync sessions command
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List the scan sessions of sync info",
+	Long:  `List the runs of sync info, newest first, with their label, tag and directories, when they started and ended, and how many files each added, updated and skipped, to see how each scan changed the catalog.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		limit, _ := cmd.Flags().GetInt("limit")
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		if err := listSessions(limit, columns); err != nil {
+			util.PrintError("Error listing scan sessions: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// sessionShowCmd represents the sync show command
+var sessionShowCmd = &cobra.Command{
+	Use:   "show <session>",
+	Short: "List the records written by a scan session",
+	Long:  `List the records last written by a scan session of sync info, and whether the session added them, found them changed or only refreshed them. Records written again by a later command are listed under that command.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		if err := showSession(parseSessionID(args[0]), columns); err != nil {
+			util.PrintError("Error showing scan session: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// sessionRollbackCmd represents the sync rollback command
+var sessionRollbackCmd = &cobra.Command{
+	Use:   "rollback <session>",
+	Short: "Undo the changes a scan session made to the database",
+	Long:  `Undo what a scan session of sync info did to the database: the records it added are deleted, even when later scans refreshed them, and the previous versions of the files it found changed are restored, at the paths they had when the session followed a moved file. Changed records written again by a later command aren't restored, and the files themselves are never touched.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		if err := rollbackSession(parseSessionID(args[0]), dryRun); err != nil {
+			util.PrintError("Error rolling back scan session: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// sessionsColumns are the columns of the sessions table
+var sessionsColumns = []string{"id", "started", "elapsed", "label", "tag", "dirs", "added", "updated", "skipped", "hashed", "state"}
+
+// sessionShowColumns are the columns of the table of a session's records
+var sessionShowColumns = []string{"change", "path", "size"}
+
+func init() {
+	sessionsCmd.Flags().Int("limit", 20, "Number of sessions to list")
+	sessionsCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (id, started, elapsed, label, tag, dirs, added, updated, skipped, hashed, state)")
+	sessionsCmd.RegisterFlagCompletionFunc("columns", completeColumns(sessionsColumns))
+	sessionShowCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (change, path, size)")
+	sessionShowCmd.RegisterFlagCompletionFunc("columns", completeColumns(sessionShowColumns))
+	sessionRollbackCmd.Flags().Bool("dry-run", false, "Only report what would be undone")
+	syncCmd.AddCommand(sessionsCmd)
+	syncCmd.AddCommand(sessionShowCmd)
+	syncCmd.AddCommand(sessionRollbackCmd)
+}
+
+// parseSessionID parses the ID of a scan session, exiting when it's not a number
+func parseSessionID(arg string) int64 {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id <= 0 {
+		util.PrintError("Error: invalid session %s, expected the ID listed by sync sessions\n", arg)
+		os.Exit(1)
+	}
+	return id
+}
+
+// sessionState describes whether a session finished, was interrupted or was rolled back
+func sessionState(session *data.Session) string {
+	switch {
+	case session.RolledBackAt != nil:
+		return "rolled back"
+	case session.EndedAt == nil:
+		return "interrupted"
+	default:
+		return "done"
+	}
+}
+
+// sessionRecords returns the records last written by a scan session, with whether the session added them,
+// found them changed or only refreshed them
+func sessionRecords(db *data.DB, id int64) ([]*data.FileInfo, []string, error) {
+	var records []*data.FileInfo
+	if err := db.GetSessionFileInfos(id, &records); err != nil {
+		return nil, nil, fmt.Errorf("error getting records of scan session %d: %v", id, err)
+	}
+	var replaced []*data.FileInfoHistory
+	if err := db.GetSessionReplaced(id, &replaced); err != nil {
+		return nil, nil, fmt.Errorf("error getting replaced versions of scan session %d: %v", id, err)
+	}
+	changed := make(map[string]bool, len(replaced))
+	for _, version := range replaced {
+		changed[version.FileKey] = true
+	}
+
+	changes := make([]string, len(records))
+	for i, record := range records {
+		switch {
+		case changed[record.Key]:
+			changes[i] = "changed"
+		case record.AddedBy == id:
+			changes[i] = "added"
+		default:
+			changes[i] = "refreshed"
+		}
+	}
+	return records, changes, nil
+}
+
+// listSessions prints the latest scan sessions
+func listSessions(limit int, columns []string) error {
+	table := util.NewTable(sessionsColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var sessions []*data.Session
+	if err := db.GetSessions(limit, &sessions); err != nil {
+		return fmt.Errorf("error getting scan sessions: %v", err)
+	}
+
+	for _, session := range sessions {
+		elapsed := ""
+		if session.EndedAt != nil {
+			elapsed = session.EndedAt.Sub(session.StartedAt).Round(time.Second).String()
+		}
+		table.AddRow(fmt.Sprint(session.ID), session.StartedAt.Format("2006-01-02 15:04"), elapsed, session.Label, session.Tag,
+			strings.ReplaceAll(session.Dirs, "\n", ", "), fmt.Sprint(session.FilesAdded), fmt.Sprint(session.FilesUpdated),
+			fmt.Sprint(session.FilesSkipped), util.FormatSize(session.BytesHashed), util.T(sessionState(session)))
+	}
+	table.Print()
+
+	util.PrintSuccess("%d scan sessions listed.\n", len(sessions))
+	return nil
+}
+
+// showSession prints the records last written by a scan session
+func showSession(id int64, columns []string) error {
+	table := util.NewTable(sessionShowColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	session, err := db.GetSession(id)
+	if err == gorm.ErrRecordNotFound {
+		return fmt.Errorf("no scan session %d", id)
+	}
+	if err != nil {
+		return fmt.Errorf("error getting scan session %d: %v", id, err)
+	}
+
+	records, changes, err := sessionRecords(db, id)
+	if err != nil {
+		return err
+	}
+	for i, record := range records {
+		table.AddRow(util.T(changes[i]), record.Path, util.FormatSize(record.Size))
+	}
+	table.Print()
+
+	util.PrintSuccess("Session %d (%s): %d files added, %d updated, %d skipped, %d records still written by it.\n",
+		session.ID, util.T(sessionState(session)), session.FilesAdded, session.FilesUpdated, session.FilesSkipped, len(records))
+	return nil
+}
+
+// rollbackSession undoes the changes of a scan session to the database
+func rollbackSession(id int64, dryRun bool) error {
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	session, err := db.GetSession(id)
+	if err == gorm.ErrRecordNotFound {
+		return fmt.Errorf("no scan session %d", id)
+	}
+	if err != nil {
+		return fmt.Errorf("error getting scan session %d: %v", id, err)
+	}
+	if session.RolledBackAt != nil {
+		return fmt.Errorf("scan session %d was already rolled back on %s", id, session.RolledBackAt.Format("2006-01-02 15:04"))
+	}
+
+	if dryRun {
+		_, changes, err := sessionRecords(db, id)
+		if err != nil {
+			return err
+		}
+		var restored int
+		for _, change := range changes {
+			if change == "changed" {
+				restored++
+			}
+		}
+		deleted, err := db.CountSessionAdded(id)
+		if err != nil {
+			return fmt.Errorf("error counting records added by scan session %d: %v", id, err)
+		}
+		util.PrintSuccess("Dry run: %d records would be restored and %d deleted.\n", restored, deleted)
+		return nil
+	}
+
+	restored, deleted, err := db.RollbackSession(id)
+	if err != nil {
+		return err
+	}
+	util.PrintSuccess("Rolled back scan session %d: %d records restored, %d deleted.\n", id, restored, deleted)
+	return nil
+}
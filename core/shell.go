@@ -0,0 +1,329 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/kballard/go-shellquote"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+// ShellEnv is set in the environment of the commands run by the shell
+const ShellEnv = "FSAK_SHELL"
+
+// shellBuiltins are the commands handled by the shell itself, every other line runs an fsak command
+var shellBuiltins = []string{"cd", "exit", "help", "quit", "tag"}
+
+// shellCmd represents the shell command
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Start an interactive shell for exploratory cleanup sessions",
+	Long:  `Start an interactive shell where every fsak command can be run without the fsak prefix, for example catalog search, dup list, history or clean dup. Lines can be recalled with the up and down arrows and commands, flags and paths are completed with Tab. The shell also has the builtins cd, tag, help and exit.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := runShell()
+		if err != nil {
+			util.PrintError("Error running shell: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(shellCmd)
+}
+
+// runShell reads and runs commands until exit or Ctrl-D
+func runShell() error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return errors.New("the shell needs an interactive terminal")
+	}
+
+	// Commands run in a child process, so a failing command can't end the session
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("error getting fsak executable: %v", err)
+	}
+
+	screen := struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}
+	terminal := term.NewTerminal(screen, "fsak> ")
+	terminal.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		return completeShellLine(terminal, line, pos)
+	}
+
+	util.PrintProcess("Type help for the shell builtins, any other line runs an fsak command. Exit with exit or Ctrl-D.")
+	for {
+		line, err := readShellLine(fd, terminal)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading input: %v", err)
+		}
+
+		args, err := shellquote.Split(line)
+		if err != nil {
+			util.PrintError("Error: %v\n", err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		switch args[0] {
+		case "exit", "quit":
+			return nil
+		case "help":
+			printShellHelp()
+		case "cd":
+			changeShellDir(args[1:])
+		case "tag":
+			if len(args) < 3 {
+				util.PrintError("Usage: tag <tag> <paths...>")
+				continue
+			}
+			if err := tagPaths(args[1], args[2:]); err != nil {
+				util.PrintError("Error tagging files: %v\n", err)
+			}
+		case "shell":
+			util.PrintWarning("Warning: already in the fsak shell")
+		default:
+			runShellCommand(executable, args)
+		}
+	}
+}
+
+// readShellLine reads a line with the terminal in raw mode, which is restored for the command output
+func readShellLine(fd int, terminal *term.Terminal) (string, error) {
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(fd, oldState)
+
+	if width, height, err := term.GetSize(fd); err == nil && width > 0 {
+		terminal.SetSize(width, height)
+	}
+	return terminal.ReadLine()
+}
+
+// runShellCommand runs an fsak command in a child process attached to the terminal
+func runShellCommand(executable string, args []string) {
+	command := exec.Command(executable, args...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	command.Env = append(os.Environ(), ShellEnv+"=1")
+	if profile := util.GetProfile(); profile != nil {
+		command.Env = append(command.Env, "FSAK_PROFILE="+profile.Name)
+	}
+	if util.ReadOnly() {
+		command.Env = append(command.Env, util.ReadOnlyEnv+"=1")
+	}
+
+	// Ctrl-C stops the command, not the shell
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	err := command.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		// Commands print their own errors, only report failures to start them
+		util.PrintError("Error running %s: %v\n", args[0], err)
+	}
+}
+
+// printShellHelp prints the shell builtins
+func printShellHelp() {
+	util.PrintProcess("Shell builtins:")
+	util.PrintProcess("  cd <dir>              Change the directory relative paths are resolved against")
+	util.PrintProcess("  tag <tag> <paths...>  Set the tag of the cataloged files at or under the paths")
+	util.PrintProcess("  help                  Show this help")
+	util.PrintProcess("  exit, quit            Leave the shell (or press Ctrl-D)")
+	util.PrintProcess("Any other line runs an fsak command, for example:")
+	util.PrintProcess("  catalog search <volume> <pattern>, dup list -p <dir>, history <file>, clean dup <dirs>")
+	util.PrintProcess("Use <command> --help for the options of a command.")
+}
+
+// changeShellDir changes the working directory of the shell and the commands it runs
+func changeShellDir(args []string) {
+	dir := ""
+	if len(args) > 0 {
+		dir = args[0]
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			util.PrintError("Error getting home directory: %v\n", err)
+			return
+		}
+		dir = home
+	}
+
+	dir, err := util.ExpandPathAlias(dir)
+	if err != nil {
+		util.PrintError("Error: %v\n", err)
+		return
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		util.PrintError("Error changing directory: %v\n", err)
+		return
+	}
+	if wd, err := os.Getwd(); err == nil {
+		util.PrintProcess("%s\n", wd)
+	}
+}
+
+// tagPaths sets the tag of the records at or under the specified paths
+func tagPaths(tag string, paths []string) error {
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	for _, path := range paths {
+		path, err := util.ExpandPathAlias(path)
+		if err != nil {
+			return err
+		}
+
+		absPath, err := util.CanonicalPath(path)
+		if err != nil {
+			return fmt.Errorf("error getting absolute path for %s: %v", path, err)
+		}
+
+		tagged, err := db.SetTagByPath(absPath, tag)
+		if err != nil {
+			return fmt.Errorf("error tagging %s: %v", absPath, err)
+		}
+		if tagged == 0 {
+			util.PrintWarning("Warning: no cataloged files under %s, run sync info first\n", absPath)
+			continue
+		}
+		util.PrintSuccess("Tagged %d files under %s with %s.\n", tagged, absPath, tag)
+	}
+	return nil
+}
+
+// completeShellLine completes the word before the cursor with a command, flag or path name.
+// When several names match, the common prefix is inserted and the names are listed.
+func completeShellLine(terminal *term.Terminal, line string, pos int) (string, int, bool) {
+	words := strings.Fields(line[:pos])
+	current := ""
+	if len(words) > 0 && !strings.HasSuffix(line[:pos], " ") {
+		current = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	var names []string
+	if len(words) == 0 {
+		names = append(names, shellBuiltins...)
+		names = append(names, commandNames(rootCmd)...)
+	} else if cmd, rest, err := rootCmd.Find(words); err == nil && strings.HasPrefix(current, "-") {
+		names = flagNames(cmd)
+	} else if err == nil && len(rest) == 0 && cmd.HasSubCommands() {
+		names = commandNames(cmd)
+	} else {
+		names = pathNames(current)
+	}
+
+	var candidates []string
+	for _, name := range names {
+		if strings.HasPrefix(name, current) {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", 0, false
+	}
+	sort.Strings(candidates)
+
+	completion := candidates[0]
+	for _, candidate := range candidates[1:] {
+		for !strings.HasPrefix(candidate, completion) {
+			completion = completion[:len(completion)-1]
+		}
+	}
+	if len(candidates) == 1 && !strings.HasSuffix(completion, string(filepath.Separator)) {
+		completion += " "
+	}
+	if len(candidates) > 1 && completion == current {
+		terminal.Write([]byte(strings.Join(candidates, "  ") + "\n"))
+	}
+
+	start := pos - len(current)
+	return line[:start] + completion + line[pos:], start + len(completion), true
+}
+
+// commandNames returns the names of the visible subcommands of a command
+func commandNames(cmd *cobra.Command) []string {
+	var names []string
+	for _, sub := range cmd.Commands() {
+		if sub.IsAvailableCommand() && sub.Name() != "shell" {
+			names = append(names, sub.Name())
+		}
+	}
+	return names
+}
+
+// flagNames returns the visible flags of a command, including the inherited global options
+func flagNames(cmd *cobra.Command) []string {
+	var names []string
+	addFlag := func(flag *pflag.Flag) {
+		if !flag.Hidden {
+			names = append(names, "--"+flag.Name)
+		}
+	}
+	cmd.LocalFlags().VisitAll(addFlag)
+	cmd.InheritedFlags().VisitAll(addFlag)
+	return names
+}
+
+// pathNames returns the paths in the directory of a partial path, directories end with a separator
+func pathNames(partial string) []string {
+	dir, base := filepath.Split(partial)
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	}
+
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		// Hidden files are only completed when asked for
+		if strings.HasPrefix(entry.Name(), ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		name := dir + entry.Name()
+		if entry.IsDir() {
+			name += string(filepath.Separator)
+		}
+		names = append(names, name)
+	}
+	return names
+}
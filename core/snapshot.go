@@ -0,0 +1,381 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Record and compare the state of directory trees over time",
+	Long:  `Commands for recording the paths, sizes and hashes of the files of a directory tree at a point in time, and comparing two such snapshots later to see which files were added, removed, changed or moved. Snapshots are stored in the database, the files themselves aren't copied.`,
+}
+
+// snapshotCreateCmd represents the snapshot create command
+var snapshotCreateCmd = &cobra.Command{
+	Use:               "create <dir>",
+	Short:             "Record the state of a directory tree",
+	Long:              `Record the path, size, modification time, MD5 and Blake3 values of every file of a directory tree as a snapshot. The values recorded by sync info are used for the files that didn't change since, the others are hashed and recorded in the catalog too. The same directories as diff are skipped, such as .git and node_modules.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		label, _ := cmd.Flags().GetString("label")
+
+		dir, err := util.CanonicalPath(args[0])
+		if err != nil {
+			util.PrintError("Error getting absolute path for %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			util.PrintError("Error: %s is not a directory\n", dir)
+			os.Exit(1)
+		}
+
+		if err := createTreeSnapshot(cmd, dir, label); err != nil {
+			util.PrintError("Error creating snapshot of %s: %v\n", dir, err)
+			os.Exit(1)
+		}
+	},
+}
+
+// snapshotListCmd represents the snapshot list command
+var snapshotListCmd = &cobra.Command{
+	Use:               "list [dir]",
+	Short:             "List the snapshots",
+	Long:              `List the snapshots, newest first, with their ID, label, directory and number of files, optionally only the ones of the trees at or under a directory.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		dir := ""
+		if len(args) == 1 {
+			var err error
+			if dir, err = util.CanonicalPath(args[0]); err != nil {
+				util.PrintError("Error getting absolute path for %s: %v\n", args[0], err)
+				os.Exit(1)
+			}
+		}
+
+		if err := listTreeSnapshots(dir, columns); err != nil {
+			util.PrintError("Error listing snapshots: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// snapshotDiffCmd represents the snapshot diff command
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff <a> <b>",
+	Short: "Compare two snapshots",
+	Long: `Compare two snapshots by the path of their files relative to their directory, and list the files only in the second one (added), only in the first one (removed), at the same path with different content (changed), and removed from one path and added at another with the same MD5 and Blake3 values (moved). The snapshots are given by the ID listed by snapshot list, and may be of different trees, such as a folder and its backup.
+
+The command exits with status 1 when the snapshots differ.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		differences, err := diffTreeSnapshots(parseSnapshotID(args[0]), parseSnapshotID(args[1]), columns)
+		if err != nil {
+			util.PrintError("Error comparing snapshots: %v\n", err)
+			os.Exit(1)
+		}
+		// Differing snapshots fail, like diff, so scripts can check them
+		if differences > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// snapshotDeleteCmd represents the snapshot delete command
+var snapshotDeleteCmd = &cobra.Command{
+	Use:   "delete <snapshot>",
+	Short: "Delete a snapshot",
+	Long:  `Delete a snapshot and the entries of its files from the database. The files themselves are never touched.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := deleteTreeSnapshot(parseSnapshotID(args[0])); err != nil {
+			util.PrintError("Error deleting snapshot: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// snapshotListColumns are the columns of the snapshot list table
+var snapshotListColumns = []string{"id", "created", "label", "files", "size", "dir"}
+
+// snapshotDiffColumns are the columns of the snapshot diff table
+var snapshotDiffColumns = []string{"change", "size", "path"}
+
+func init() {
+	snapshotCreateCmd.Flags().String("label", "", "Label describing the snapshot, shown by snapshot list")
+	addYesFlag(snapshotCreateCmd)
+	snapshotListCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (id, created, label, files, size, dir)")
+	snapshotListCmd.RegisterFlagCompletionFunc("columns", completeColumns(snapshotListColumns))
+	snapshotDiffCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (change, size, path)")
+	snapshotDiffCmd.RegisterFlagCompletionFunc("columns", completeColumns(snapshotDiffColumns))
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+	snapshotCmd.AddCommand(snapshotDeleteCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
+
+// parseSnapshotID parses the ID of a snapshot, exiting when it's not a number
+func parseSnapshotID(arg string) int64 {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id <= 0 {
+		util.PrintError("Error: invalid snapshot %s, expected the ID listed by snapshot list\n", arg)
+		os.Exit(1)
+	}
+	return id
+}
+
+// createTreeSnapshot records the files of a directory tree as a snapshot
+func createTreeSnapshot(cmd *cobra.Command, dir string, label string) error {
+	util.PrintProcess("Listing the files of %s\n", dir)
+	files, err := listTreeFiles(dir)
+	if err != nil {
+		return fmt.Errorf("error walking %s: %v", dir, err)
+	}
+
+	relPaths := make([]string, 0, len(files))
+	var totalSize int64
+	for relPath, info := range files {
+		relPaths = append(relPaths, relPath)
+		totalSize += info.Size()
+	}
+	sort.Strings(relPaths)
+	if !confirmEstimate(cmd, len(relPaths), totalSize) {
+		return nil
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	progress := util.NewProgress(len(relPaths), totalSize)
+	entries := make([]*data.SnapshotEntry, 0, len(relPaths))
+	for _, relPath := range relPaths {
+		path := filepath.Join(dir, relPath)
+		info := files[relPath]
+		record, err := upToDateRecord(db, path, info)
+		if util.IsLockedError(err) {
+			util.PrintWarning("Skipping %s, it's in use by another program\n", path)
+		}
+		if err != nil {
+			util.RecordSkipped(path, err)
+			progress.Add(info.Size())
+			continue
+		}
+		progress.Report(info.Size(), path)
+
+		entries = append(entries, &data.SnapshotEntry{
+			Path:   filepath.ToSlash(relPath),
+			Size:   record.Size,
+			MTime:  record.MTime,
+			MD5:    record.MD5,
+			Blake3: record.Blake3,
+		})
+	}
+
+	snapshot := &data.Snapshot{Label: label, Dir: dir, Files: int64(len(entries))}
+	for _, entry := range entries {
+		snapshot.Size += entry.Size
+	}
+	if err := db.CreateSnapshot(snapshot, entries); err != nil {
+		return fmt.Errorf("error recording the snapshot: %v", err)
+	}
+
+	util.EmitJSON("snapshot", map[string]any{"id": snapshot.ID, "dir": dir, "files": snapshot.Files, "size": snapshot.Size})
+	util.PrintSuccess("Created snapshot %d of %s: %d files (%s).\n", snapshot.ID, dir, snapshot.Files, util.FormatSize(snapshot.Size))
+	return nil
+}
+
+// listTreeSnapshots prints the snapshots of the trees at or under dir, or all of them when dir is empty
+func listTreeSnapshots(dir string, columns []string) error {
+	table := util.NewTable(snapshotListColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var snapshots []*data.Snapshot
+	if err := db.GetSnapshots(dir, &snapshots); err != nil {
+		return fmt.Errorf("error getting snapshots: %v", err)
+	}
+	for _, snapshot := range snapshots {
+		table.AddRow(fmt.Sprint(snapshot.ID), snapshot.CreatedAt.Format("2006-01-02 15:04"), snapshot.Label,
+			fmt.Sprint(snapshot.Files), util.FormatSize(snapshot.Size), snapshot.Dir)
+	}
+	table.Print()
+
+	util.PrintSuccess("%d snapshots listed.\n", len(snapshots))
+	return nil
+}
+
+// Kinds of differences between two snapshots
+const (
+	snapshotAdded   = "added"
+	snapshotRemoved = "removed"
+	snapshotChanged = "changed"
+	snapshotMoved   = "moved"
+)
+
+// snapshotChange is a difference between two snapshots
+type snapshotChange struct {
+	Kind string
+	Path string              // Path in the second snapshot, or in the first one for removed files
+	From string              // Path in the first snapshot of a moved file
+	Old  *data.SnapshotEntry // Entry in the first snapshot, nil for added files
+	New  *data.SnapshotEntry // Entry in the second snapshot, nil for removed files
+}
+
+// compareTreeSnapshots returns the differences between the entries of two snapshots, ordered by path. A file
+// removed from one path and added at another with the same contents is moved, files with the same
+// contents are paired in path order.
+func compareTreeSnapshots(old, new []*data.SnapshotEntry) []snapshotChange {
+	oldByPath := make(map[string]*data.SnapshotEntry, len(old))
+	for _, entry := range old {
+		oldByPath[entry.Path] = entry
+	}
+	newByPath := make(map[string]*data.SnapshotEntry, len(new))
+	for _, entry := range new {
+		newByPath[entry.Path] = entry
+	}
+
+	var changes []snapshotChange
+	removedByContent := make(map[string][]*data.SnapshotEntry)
+	for _, entry := range old {
+		if _, ok := newByPath[entry.Path]; !ok {
+			removedByContent[entry.Blake3+entry.MD5] = append(removedByContent[entry.Blake3+entry.MD5], entry)
+		}
+	}
+
+	moved := make(map[string]bool)
+	for _, entry := range new {
+		previous, ok := oldByPath[entry.Path]
+		switch {
+		case !ok:
+			key := entry.Blake3 + entry.MD5
+			if candidates := removedByContent[key]; len(candidates) > 0 && entry.Blake3 != "" {
+				removedByContent[key] = candidates[1:]
+				moved[candidates[0].Path] = true
+				changes = append(changes, snapshotChange{Kind: snapshotMoved, Path: entry.Path, From: candidates[0].Path, Old: candidates[0], New: entry})
+				continue
+			}
+			changes = append(changes, snapshotChange{Kind: snapshotAdded, Path: entry.Path, New: entry})
+		case previous.Size != entry.Size || previous.Blake3 != entry.Blake3 || previous.MD5 != entry.MD5:
+			changes = append(changes, snapshotChange{Kind: snapshotChanged, Path: entry.Path, Old: previous, New: entry})
+		}
+	}
+	for _, entry := range old {
+		if _, ok := newByPath[entry.Path]; !ok && !moved[entry.Path] {
+			changes = append(changes, snapshotChange{Kind: snapshotRemoved, Path: entry.Path, Old: entry})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// diffTreeSnapshots prints the differences between two snapshots and returns how many there are
+func diffTreeSnapshots(oldID, newID int64, columns []string) (int, error) {
+	table := util.NewTable(snapshotDiffColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return 0, err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return 0, fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var snapshots [2]*data.Snapshot
+	var entries [2][]*data.SnapshotEntry
+	for i, id := range []int64{oldID, newID} {
+		snapshot, err := db.GetSnapshot(id)
+		if err == gorm.ErrRecordNotFound {
+			return 0, fmt.Errorf("snapshot %d doesn't exist, see snapshot list", id)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("error getting snapshot %d: %v", id, err)
+		}
+		if err := db.GetSnapshotEntries(id, &entries[i]); err != nil {
+			return 0, fmt.Errorf("error getting the files of snapshot %d: %v", id, err)
+		}
+		snapshots[i] = snapshot
+	}
+
+	changes := compareTreeSnapshots(entries[0], entries[1])
+	counts := make(map[string]int)
+	for _, change := range changes {
+		counts[change.Kind]++
+		switch change.Kind {
+		case snapshotAdded:
+			table.AddRow(util.T(change.Kind), util.FormatSize(change.New.Size), change.Path)
+		case snapshotRemoved:
+			table.AddRow(util.T(change.Kind), util.FormatSize(change.Old.Size), change.Path)
+		case snapshotChanged:
+			table.AddRow(util.T(change.Kind), util.FormatSize(change.Old.Size)+" → "+util.FormatSize(change.New.Size), change.Path)
+		case snapshotMoved:
+			table.AddRow(util.T(change.Kind), util.FormatSize(change.New.Size), change.From+" → "+change.Path)
+		}
+		util.EmitJSON("change", map[string]any{"change": change.Kind, "path": change.Path, "from": change.From})
+	}
+
+	if len(changes) == 0 {
+		util.PrintSuccess("No differences between snapshot %d of %s and snapshot %d of %s.\n", oldID, snapshots[0].Dir, newID, snapshots[1].Dir)
+		return 0, nil
+	}
+
+	table.Print()
+	util.PrintWarning("%d files added, %d removed, %d changed, %d moved.\n", counts[snapshotAdded], counts[snapshotRemoved], counts[snapshotChanged], counts[snapshotMoved])
+	return len(changes), nil
+}
+
+// deleteTreeSnapshot deletes a snapshot from the database
+func deleteTreeSnapshot(id int64) error {
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	snapshot, err := db.GetSnapshot(id)
+	if err == gorm.ErrRecordNotFound {
+		return fmt.Errorf("snapshot %d doesn't exist, see snapshot list", id)
+	}
+	if err != nil {
+		return fmt.Errorf("error getting snapshot %d: %v", id, err)
+	}
+	if err := db.DeleteSnapshot(id); err != nil {
+		return err
+	}
+
+	util.PrintSuccess("Deleted snapshot %d of %s.\n", id, snapshot.Dir)
+	return nil
+}
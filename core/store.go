@@ -0,0 +1,346 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// storeCmd represents the store command
+var storeCmd = &cobra.Command{
+	Use:   "store",
+	Short: "Archive files in a deduplicated content-addressable store",
+	Long:  `Commands for the content-addressable store of the workspace, an archive for cold data where every content is kept once in the objects directory, named after its Blake3 value, and the paths the files had are recorded in the database so their trees can be materialized again.`,
+}
+
+// storeAddCmd represents the store add command
+var storeAddCmd = &cobra.Command{
+	Use:   "add <paths...>",
+	Short: "Move files into the store",
+	Long: `Move files and the files of directories into the store. Each file is moved to objects/ab/cdef... in the workspace, named after its Blake3 value, unless an object with the same contents is already stored, and its path is recorded as the logical path of the content. The stored objects are read-only.
+
+The files are removed from their directories once stored, use store checkout to materialize them again.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		exitUnlessAllowed(args...)
+
+		if err := addToStore(args); err != nil {
+			util.PrintError("Error adding files to the store: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// storeCheckoutCmd represents the store checkout command
+var storeCheckoutCmd = &cobra.Command{
+	Use:   "checkout <logical path> <dest>",
+	Short: "Materialize a stored tree",
+	Long: `Materialize the files stored at or under a logical path in a destination directory, keeping their paths relative to it. Files are hard links to the objects by default, which takes no space but makes them read-only, and copies when the destination is on another filesystem. Use --copy for files that can be edited, or --symlink for links to the objects.
+
+Files already in the destination are kept.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		copyFiles, _ := cmd.Flags().GetBool("copy")
+		symlink, _ := cmd.Flags().GetBool("symlink")
+
+		logicalPath, err := filepath.Abs(args[0])
+		if err != nil {
+			util.PrintError("Error getting absolute path for %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+		destDir, err := filepath.Abs(args[1])
+		if err != nil {
+			util.PrintError("Error getting absolute path for destination: %v\n", err)
+			os.Exit(1)
+		}
+
+		exitUnlessAllowed(destDir)
+
+		if err := checkoutFromStore(logicalPath, destDir, copyFiles, symlink); err != nil {
+			util.PrintError("Error materializing stored files: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// storeListCmd represents the store list command
+var storeListCmd = &cobra.Command{
+	Use:   "list [logical path]",
+	Short: "List the stored files",
+	Long:  `List the files stored at or under a logical path, all of them by default, and summarize the space the store saves by keeping every content once.`,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		logicalPath := ""
+		if len(args) > 0 {
+			absPath, err := filepath.Abs(args[0])
+			if err != nil {
+				util.PrintError("Error getting absolute path for %s: %v\n", args[0], err)
+				os.Exit(1)
+			}
+			logicalPath = absPath
+		}
+
+		if err := listStore(logicalPath, columns); err != nil {
+			util.PrintError("Error listing stored files: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// storeColumns are the columns of the store list table
+var storeColumns = []string{"path", "size", "modified", "blake3"}
+
+func init() {
+	storeCheckoutCmd.Flags().Bool("copy", false, "Copy the files instead of hard linking them, so they can be edited")
+	storeCheckoutCmd.Flags().Bool("symlink", false, "Create symlinks to the objects instead of hard links")
+	storeCheckoutCmd.MarkFlagsMutuallyExclusive("copy", "symlink")
+	storeListCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (path, size, modified, blake3)")
+	storeListCmd.RegisterFlagCompletionFunc("columns", completeColumns(storeColumns))
+
+	storeCmd.AddCommand(storeAddCmd)
+	storeCmd.AddCommand(storeCheckoutCmd)
+	storeCmd.AddCommand(storeListCmd)
+	rootCmd.AddCommand(storeCmd)
+}
+
+// storeObject makes sure the object of a file's contents exists, linking or copying the file to it, and
+// reports whether a new object was created. Copies are verified against the Blake3 value.
+func storeObject(path, objectPath, blake3 string) (bool, error) {
+	if _, err := os.Stat(objectPath); err == nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0755); err != nil {
+		return false, fmt.Errorf("error creating directory %s: %v", filepath.Dir(objectPath), err)
+	}
+
+	// The object only appears under its name once complete
+	partialPath := objectPath + ".partial"
+	os.Remove(partialPath)
+	if os.Link(path, partialPath) != nil {
+		if err := copyFile(path, partialPath); err != nil {
+			os.Remove(partialPath)
+			return false, fmt.Errorf("error copying %s to the store: %v", path, err)
+		}
+		copied, _, err := util.FileBlake3MD5(partialPath)
+		if err != nil || copied != blake3 {
+			os.Remove(partialPath)
+			return false, fmt.Errorf("the copy of %s in the store doesn't match its Blake3 value", path)
+		}
+	}
+	if err := os.Chmod(partialPath, 0444); err != nil {
+		os.Remove(partialPath)
+		return false, fmt.Errorf("error making %s read-only: %v", partialPath, err)
+	}
+	if err := os.Rename(partialPath, objectPath); err != nil {
+		os.Remove(partialPath)
+		return false, fmt.Errorf("error moving %s into the store: %v", path, err)
+	}
+	return true, nil
+}
+
+// addToStore moves the files at the paths into the store and records their logical paths
+func addToStore(paths []string) error {
+	storeDir, err := util.GetStoreDir()
+	if err != nil {
+		return fmt.Errorf("error getting store directory: %v", err)
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var stored, objects int
+	var storedSize, dedupedSize int64
+	for _, root := range paths {
+		root, err := filepath.Abs(root)
+		if err != nil {
+			return fmt.Errorf("error getting absolute path for %s: %v", root, err)
+		}
+
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				util.RecordSkipped(path, err)
+				return nil
+			}
+
+			// Skip VCS and package-manager internals, and the store itself
+			if info.IsDir() {
+				if isDefaultExcluded(path, root, info) || info.Name() == util.VersionsDirName || path == storeDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.Mode().IsRegular() || isWorkspaceFile(path, info) {
+				return nil
+			}
+
+			record, err := upToDateRecord(db, path, info)
+			if err != nil {
+				return err
+			}
+
+			created, err := storeObject(path, util.StoreObjectPath(storeDir, record.Blake3), record.Blake3)
+			if err != nil {
+				return err
+			}
+			entry := &data.StoreEntry{
+				Path:     path,
+				Blake3:   record.Blake3,
+				MD5:      record.MD5,
+				Size:     info.Size(),
+				Mode:     uint32(info.Mode().Perm()),
+				MTime:    info.ModTime(),
+				StoredAt: time.Now(),
+			}
+			if err := db.UpsertStoreEntry(entry); err != nil {
+				return fmt.Errorf("error recording stored file %s: %v", path, err)
+			}
+
+			// The contents are safe in the store, the file only lives on as a logical path
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("error removing stored file %s: %v", path, err)
+			}
+			if err := db.DeleteFileInfo(record.Key); err != nil {
+				util.PrintWarning("Warning: Could not delete record for file %s from database: %v\n", path, err)
+			}
+
+			if created {
+				util.PrintProcess("Stored %s\n", path)
+				objects++
+			} else {
+				util.PrintProcess("Stored %s, its contents were already in the store\n", path)
+				dedupedSize += info.Size()
+			}
+			stored++
+			storedSize += info.Size()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	util.PrintSuccess("Stored %d files (%s) in %d new objects, %s already in the store.\n", stored, util.FormatSize(storedSize), objects, util.FormatSize(dedupedSize))
+	return nil
+}
+
+// checkoutFromStore materializes the files stored at or under logicalPath in destDir, as hard links to the
+// objects falling back to copies, as copies or as symlinks
+func checkoutFromStore(logicalPath, destDir string, copyFiles, symlink bool) error {
+	storeDir, err := util.GetStoreDir()
+	if err != nil {
+		return fmt.Errorf("error getting store directory: %v", err)
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var entries []*data.StoreEntry
+	if err := db.GetStoreEntries(logicalPath, &entries); err != nil {
+		return fmt.Errorf("error getting stored files: %v", err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no files are stored at or under %s", logicalPath)
+	}
+
+	// A single stored file is materialized in the destination directory
+	baseDir := logicalPath
+	if len(entries) == 1 && entries[0].Path == logicalPath {
+		baseDir = filepath.Dir(logicalPath)
+	}
+
+	var linked, copied, skipped int
+	for _, entry := range entries {
+		relPath, err := filepath.Rel(baseDir, entry.Path)
+		if err != nil {
+			return fmt.Errorf("error calculating relative path for %s: %v", entry.Path, err)
+		}
+		dstPath := filepath.Join(destDir, relPath)
+		objectPath := util.StoreObjectPath(storeDir, entry.Blake3)
+
+		// Files already in the destination are kept
+		if _, err := os.Lstat(dstPath); err == nil {
+			util.PrintWarning("Skipping existing file: %s\n", dstPath)
+			skipped++
+			continue
+		}
+		if _, err := os.Stat(objectPath); err != nil {
+			return fmt.Errorf("the object of %s is missing from the store: %v", entry.Path, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+			return fmt.Errorf("error creating directory %s: %v", filepath.Dir(dstPath), err)
+		}
+
+		switch {
+		case symlink:
+			if err := os.Symlink(objectPath, dstPath); err != nil {
+				return fmt.Errorf("error creating symlink %s: %v", dstPath, err)
+			}
+			linked++
+		case !copyFiles && os.Link(objectPath, dstPath) == nil:
+			linked++
+		default:
+			// A failing link (another filesystem) falls back to a copy
+			if err := copyFile(objectPath, dstPath); err != nil {
+				return fmt.Errorf("error copying %s to %s: %v", objectPath, dstPath, err)
+			}
+			if err := os.Chmod(dstPath, os.FileMode(entry.Mode)); err != nil {
+				return fmt.Errorf("error setting permissions of %s: %v", dstPath, err)
+			}
+			if err := os.Chtimes(dstPath, entry.MTime, entry.MTime); err != nil {
+				return fmt.Errorf("error setting modification time of %s: %v", dstPath, err)
+			}
+			copied++
+		}
+		util.PrintProcess("Materialized %s\n", dstPath)
+	}
+
+	util.PrintSuccess("Materialized %d files in %s (%d linked, %d copied), %d skipped.\n", linked+copied, destDir, linked, copied, skipped)
+	return nil
+}
+
+// listStore prints the files stored at or under logicalPath and a summary of the store
+func listStore(logicalPath string, columns []string) error {
+	table := util.NewTable(storeColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var entries []*data.StoreEntry
+	if err := db.GetStoreEntries(logicalPath, &entries); err != nil {
+		return fmt.Errorf("error getting stored files: %v", err)
+	}
+	for _, entry := range entries {
+		table.AddRow(entry.Path, util.FormatSize(entry.Size), entry.MTime.Format("2006-01-02 15:04"), entry.Blake3)
+	}
+	table.Print()
+
+	summary, err := db.GetStoreSummary()
+	if err != nil {
+		return fmt.Errorf("error summarizing the store: %v", err)
+	}
+	util.PrintSuccess("%d files are stored in %d objects (%s), %s saved by deduplication.\n", summary.Entries, summary.Objects, util.FormatSize(summary.ObjectSize), util.FormatSize(summary.LogicalSize-summary.ObjectSize))
+	return nil
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// touchsyncCmd represents the touchsync command
+var touchsyncCmd = &cobra.Command{
+	Use:   "touchsync <paths...>",
+	Short: "Restore modification times from the catalog",
+	Long: `Hash the files under the paths given and restore the modification time recorded for their contents, when it's earlier than the one they have now. This repairs the dates a copy tool or a backup program clobbered, such as photos that all got the date they were copied on, while the contents are unchanged.
+
+The earliest time recorded for the same contents is used, from the records of every path holding them and their previous versions, so files copied to a new location or synced again after their dates were clobbered are repaired too. Files whose contents aren't in the catalog are left alone.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		if !dryRun {
+			exitUnlessAllowed(args...)
+		}
+
+		if err := touchsync(cmd, args, dryRun); err != nil {
+			util.PrintError("Error restoring modification times: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// mtimeTolerance is the difference between modification times that is ignored, as FAT rounds them to 2 seconds
+const mtimeTolerance = 2 * time.Second
+
+func init() {
+	touchsyncCmd.Flags().Bool("dry-run", false, "Only report the modification times that would be restored")
+	addYesFlag(touchsyncCmd)
+	rootCmd.AddCommand(touchsyncCmd)
+}
+
+// touchsync restores the modification times recorded for the contents of the files under paths
+func touchsync(cmd *cobra.Command, paths []string, dryRun bool) error {
+	var files []string
+	var totalSize int64
+	for _, path := range paths {
+		found, err := getAllFilesInFolder(path)
+		if err != nil {
+			return fmt.Errorf("error getting files from %s: %v", path, err)
+		}
+		for _, file := range found {
+			if info, err := os.Lstat(file); err == nil && info.Mode().IsRegular() {
+				files = append(files, file)
+				totalSize += info.Size()
+			}
+		}
+	}
+	if !confirmEstimate(cmd, len(files), totalSize) {
+		return nil
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	progress := util.NewProgress(len(files), totalSize)
+	var restored, unknown int
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			util.RecordSkipped(file, err)
+			continue
+		}
+
+		blake3Hash, md5Hash, err := util.FileBlake3MD5(file)
+		if err != nil {
+			if util.IsLockedError(err) {
+				util.PrintWarning("Skipping %s, it's in use by another program\n", file)
+			}
+			util.RecordSkipped(file, err)
+			progress.Add(info.Size())
+			continue
+		}
+		progress.Report(info.Size(), file)
+
+		earliest, found, err := db.GetEarliestMTime(blake3Hash, md5Hash, info.Size())
+		if err != nil {
+			return fmt.Errorf("error getting the recorded modification times of %s: %v", file, err)
+		}
+		if !found {
+			unknown++
+			continue
+		}
+		if info.ModTime().Sub(earliest) < mtimeTolerance {
+			continue
+		}
+
+		if dryRun {
+			util.PrintProcess("Would restore %s from %s to %s\n", file, info.ModTime().Format("2006-01-02 15:04:05"), earliest.Format("2006-01-02 15:04:05"))
+			restored++
+			continue
+		}
+
+		// The access time is left unchanged
+		if err := os.Chtimes(file, time.Time{}, earliest); err != nil {
+			util.PrintError("Error restoring the modification time of %s: %v\n", file, err)
+			continue
+		}
+		if absPath, err := util.CanonicalPath(file); err == nil {
+			if err := db.SetMTimeByPath(absPath, blake3Hash, md5Hash, earliest); err != nil {
+				util.PrintWarning("Warning: Could not update the record of %s: %v\n", file, err)
+			}
+		}
+		util.PrintProcess("Restored %s from %s to %s\n", file, info.ModTime().Format("2006-01-02 15:04:05"), earliest.Format("2006-01-02 15:04:05"))
+		restored++
+	}
+
+	if dryRun {
+		util.PrintSuccess("Dry run: %d modification times would be restored, %d files aren't in the catalog.\n", restored, unknown)
+	} else {
+		util.PrintSuccess("Restored %d modification times, %d files aren't in the catalog.\n", restored, unknown)
+	}
+	return nil
+}
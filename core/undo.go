@@ -0,0 +1,670 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// undoCmd represents the undo command
+var undoCmd = &cobra.Command{
+	Use:   "undo [operation id | deleted dir]",
+	Short: "Reverse an operation, or put the files of a deleted folder back",
+	Long: `Reverse the last operation of the journal, or the one given by the ID listed by undo list. Every run of clean dup, clean dirty, clean build and merge dir that moved or copied files is recorded as an operation. The files it moved to a deleted folder are moved back to their original paths, with the records clean dup deleted, and the files merge dir copied are deleted unless they changed since. Files whose original path is taken again are left where they are, and the operation can be undone again once that's resolved.
+
+Given a deleted folder instead, every file moved to it is moved back to its original path, as recorded in its MANIFEST.tsv, and the manifest keeps the files that weren't put back, so undo can be run again. Without arguments and without operations in the journal, the deleted folder of the workspace is used.
+
+When the deleted folder was copied to another machine, --map rewrites the original paths, such as --map /Users/me=/home/me. A manifest lost on the way can be exported with undo export on the first machine and imported with undo import on the second one.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		mappings, _ := cmd.Flags().GetStringSlice("map")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		pathMap, err := parsePathMappings(mappings)
+		if err != nil {
+			util.PrintError("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// An operation ID, unless a folder has that name
+		if len(args) == 0 || isOperationID(args[0]) {
+			var id int64
+			if len(args) == 1 {
+				id, _ = strconv.ParseInt(args[0], 10, 64)
+			}
+			if err := undoOperation(id, pathMap, dryRun); err != nil {
+				util.PrintError("Error undoing the operation: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		deletedDir, err := deletedDirArg(args)
+		if err != nil {
+			util.PrintError("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := undoQuarantine(deletedDir, pathMap, dryRun); err != nil {
+			util.PrintError("Error putting files back: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// undoListCmd represents the undo list command
+var undoListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the operations of the journal",
+	Long:  `List the operations recorded in the journal, newest first, with their ID, the command, the number and size of the files moved or copied, and when they were undone.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		if err := listOperations(columns); err != nil {
+			util.PrintError("Error listing operations: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// undoExportCmd represents the undo export command
+var undoExportCmd = &cobra.Command{
+	Use:   "export <file> [deleted dir]",
+	Short: "Export the manifest of a deleted folder for another machine",
+	Long:  `Write the manifest of a deleted folder, the one of the workspace unless another is given, to a file with the size and the Blake3 value of every file still in the folder, so undo import can find the files on another machine even after the folder was reorganized there.`,
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		deletedDir, err := deletedDirArg(args[1:])
+		if err != nil {
+			util.PrintError("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := exportQuarantine(deletedDir, args[0]); err != nil {
+			util.PrintError("Error exporting the manifest of %s: %v\n", deletedDir, err)
+			os.Exit(1)
+		}
+	},
+}
+
+// undoImportCmd represents the undo import command
+var undoImportCmd = &cobra.Command{
+	Use:   "import <file> [deleted dir]",
+	Short: "Import a manifest exported on another machine",
+	Long:  `Add the entries of a manifest written by undo export to the manifest of a deleted folder, the one of the workspace unless another is given, so undo can put its files back. Files are found at the path they had in the folder, or by their size and Blake3 value when they were moved within it. With --map the original paths are rewritten for this machine.`,
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		mappings, _ := cmd.Flags().GetStringSlice("map")
+
+		deletedDir, err := deletedDirArg(args[1:])
+		if err != nil {
+			util.PrintError("Error: %v\n", err)
+			os.Exit(1)
+		}
+		pathMap, err := parsePathMappings(mappings)
+		if err != nil {
+			util.PrintError("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := importQuarantine(args[0], deletedDir, pathMap); err != nil {
+			util.PrintError("Error importing %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+	},
+}
+
+// operationListColumns are the columns of the undo list table
+var operationListColumns = []string{"id", "started", "command", "files", "size", "undone"}
+
+func init() {
+	undoCmd.Flags().StringSlice("map", nil, "Rewrite the original paths starting with a directory, as <old>=<new>, can be repeated")
+	undoCmd.Flags().Bool("dry-run", false, "Only report the files that would be put back")
+	undoImportCmd.Flags().StringSlice("map", nil, "Rewrite the original paths starting with a directory, as <old>=<new>, can be repeated")
+	undoListCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (id, started, command, files, size, undone)")
+	undoListCmd.RegisterFlagCompletionFunc("columns", completeColumns(operationListColumns))
+	undoCmd.AddCommand(undoListCmd)
+	undoCmd.AddCommand(undoExportCmd)
+	undoCmd.AddCommand(undoImportCmd)
+	rootCmd.AddCommand(undoCmd)
+}
+
+// deletedDirArg returns the absolute deleted directory given, or the one of the workspace
+func deletedDirArg(args []string) (string, error) {
+	if len(args) > 0 {
+		return filepath.Abs(args[0])
+	}
+	workspaceDir, err := util.GetWorkspaceDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting workspace directory: %v", err)
+	}
+	return filepath.Join(workspaceDir, "deleted"), nil
+}
+
+// pathMapping rewrites the paths under a directory to another one
+type pathMapping struct {
+	from string
+	to   string
+}
+
+// parsePathMappings parses the <old>=<new> values of --map, the longest directory first
+func parsePathMappings(values []string) ([]pathMapping, error) {
+	var mappings []pathMapping
+	for _, value := range values {
+		from, to, ok := strings.Cut(value, "=")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid --map %s, expected <old>=<new>", value)
+		}
+		mappings = append(mappings, pathMapping{from: filepath.Clean(from), to: filepath.Clean(to)})
+	}
+	sort.Slice(mappings, func(i, j int) bool {
+		return len(mappings[i].from) > len(mappings[j].from)
+	})
+	return mappings, nil
+}
+
+// mapPath rewrites a path with the first mapping of a directory holding it
+func mapPath(path string, mappings []pathMapping) string {
+	for _, mapping := range mappings {
+		if path == mapping.from {
+			return mapping.to
+		}
+		if rest, ok := strings.CutPrefix(path, mapping.from+string(filepath.Separator)); ok {
+			return filepath.Join(mapping.to, rest)
+		}
+	}
+	return path
+}
+
+// undoQuarantine moves the files of a deleted directory back to their original paths, and keeps the entries
+// of the ones left in its manifest
+func undoQuarantine(deletedDir string, mappings []pathMapping, dryRun bool) error {
+	manifestPath := filepath.Join(deletedDir, util.QuarantineManifestName)
+	entries, err := util.ReadQuarantineManifest(manifestPath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no %s in %s, import an exported one with undo import", util.QuarantineManifestName, deletedDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	var left []util.QuarantineEntry
+	restored := 0
+	for _, entry := range entries {
+		src := filepath.Join(deletedDir, entry.Path)
+		dst := mapPath(entry.Origin, mappings)
+
+		if _, err := os.Lstat(src); err != nil {
+			util.PrintWarning("Skipping %s, it's no longer in %s\n", entry.Path, deletedDir)
+			left = append(left, entry)
+			continue
+		}
+		if _, err := os.Lstat(dst); err == nil {
+			util.PrintWarning("Skipping %s, %s exists\n", entry.Path, dst)
+			left = append(left, entry)
+			continue
+		}
+		if err := util.CheckAllowedPath(dst); err != nil {
+			util.PrintWarning("Skipping %s: %v\n", entry.Path, err)
+			left = append(left, entry)
+			continue
+		}
+
+		if dryRun {
+			util.PrintProcess("Would put %s back to %s\n", src, dst)
+			left = append(left, entry)
+			restored++
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return fmt.Errorf("error creating directory %s: %v", filepath.Dir(dst), err)
+		}
+		if err := moveBack(src, dst); err != nil {
+			util.PrintError("Error moving %s to %s: %v\n", src, dst, err)
+			left = append(left, entry)
+			continue
+		}
+		util.PrintProcess("Put %s back to %s\n", src, dst)
+		util.EmitJSON("restored", map[string]any{"path": src, "to": dst})
+		restored++
+	}
+
+	if dryRun {
+		util.PrintSuccess("Dry run, %d of %d files could be put back.\n", restored, len(entries))
+		return nil
+	}
+
+	if restored > 0 {
+		if err := util.WriteQuarantineManifest(manifestPath, nil, left); err != nil {
+			return fmt.Errorf("error updating %s: %v", manifestPath, err)
+		}
+	}
+	util.PrintSuccess("Put %d files back, %d left in %s. Run sync info on their folders to record them again.\n", restored, len(left), deletedDir)
+	return nil
+}
+
+// moveBack moves a file or a directory, copying a file with its modification time when the destination is on
+// another filesystem
+func moveBack(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	info, statErr := os.Lstat(src)
+	if statErr != nil || !info.Mode().IsRegular() {
+		return err
+	}
+	if err := copyFile(src, dst); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	if err := os.Chtimes(dst, time.Now(), info.ModTime()); err != nil {
+		util.PrintWarning("Warning: Could not set the modification time of %s: %v\n", dst, err)
+	}
+	return os.Remove(src)
+}
+
+// isOperationID reports whether an argument of undo is the ID of an operation rather than a deleted folder
+func isOperationID(arg string) bool {
+	if _, err := strconv.ParseInt(arg, 10, 64); err != nil {
+		return false
+	}
+	info, err := os.Stat(arg)
+	return err != nil || !info.IsDir()
+}
+
+// listOperations prints the operations of the journal
+func listOperations(columns []string) error {
+	table := util.NewTable(operationListColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var operations []*data.Operation
+	if err := db.GetOperations(&operations); err != nil {
+		return fmt.Errorf("error getting operations: %v", err)
+	}
+	for _, operation := range operations {
+		undone := ""
+		if operation.UndoneAt != nil {
+			undone = operation.UndoneAt.Format("2006-01-02 15:04")
+		}
+		table.AddRow(fmt.Sprint(operation.ID), operation.StartedAt.Format("2006-01-02 15:04"), operation.Command,
+			fmt.Sprint(operation.Files), util.FormatSize(operation.Size), undone)
+	}
+	table.Print()
+
+	util.PrintSuccess("%d operations listed.\n", len(operations))
+	return nil
+}
+
+// undoOperation reverses an operation of the journal, the last one not undone yet when id is 0: the files it
+// moved are moved back with their records, the copies it made are deleted
+func undoOperation(id int64, mappings []pathMapping, dryRun bool) error {
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	var operation *data.Operation
+	if id == 0 {
+		operation, err = db.GetLastOperation()
+		if err == gorm.ErrRecordNotFound {
+			// Files moved before the journal are only in the manifest
+			deletedDir, err := deletedDirArg(nil)
+			if err != nil {
+				return err
+			}
+			util.PrintProcess("No operation to undo in the journal, putting back the files of %s\n", deletedDir)
+			return undoQuarantine(deletedDir, mappings, dryRun)
+		}
+	} else {
+		operation, err = db.GetOperation(id)
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("no operation %d in the journal, see undo list", id)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("error getting the operation: %v", err)
+	}
+	if operation.UndoneAt != nil {
+		return fmt.Errorf("operation %d was undone on %s", operation.ID, operation.UndoneAt.Format("2006-01-02 15:04"))
+	}
+
+	var entries []*data.OperationEntry
+	if err := db.GetOperationEntries(operation.ID, &entries); err != nil {
+		return fmt.Errorf("error getting the files of operation %d: %v", operation.ID, err)
+	}
+	util.PrintProcess("Undoing operation %d, %s of %s: %d files\n", operation.ID, operation.Command, operation.StartedAt.Format("2006-01-02 15:04"), len(entries))
+
+	// Paths put back by deleted folder, dropped from their manifest
+	putBack := make(map[string]map[string]bool)
+	undone, skipped := 0, 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		var changed bool
+		var err error
+		switch entry.Action {
+		case data.ActionMove:
+			changed, err = undoMove(db, entry, mapPath(entry.Src, mappings), dryRun)
+		case data.ActionCopy:
+			changed, err = undoCopy(db, entry, dryRun)
+		default:
+			err = fmt.Errorf("unknown action %s", entry.Action)
+		}
+		if err != nil {
+			util.PrintWarning("Skipping %s: %v\n", entry.Dst, err)
+			skipped++
+			continue
+		}
+		if !changed {
+			continue
+		}
+		undone++
+		if entry.DeletedDir != "" && !dryRun {
+			if relPath, err := filepath.Rel(entry.DeletedDir, entry.Dst); err == nil {
+				if putBack[entry.DeletedDir] == nil {
+					putBack[entry.DeletedDir] = make(map[string]bool)
+				}
+				putBack[entry.DeletedDir][relPath] = true
+			}
+		}
+	}
+
+	if dryRun {
+		util.PrintSuccess("Dry run, %d of %d files of operation %d could be undone.\n", undone, len(entries), operation.ID)
+		return nil
+	}
+
+	for deletedDir, relPaths := range putBack {
+		if err := dropQuarantineEntries(deletedDir, relPaths); err != nil {
+			util.PrintWarning("Warning: Could not update the manifest of %s: %v\n", deletedDir, err)
+		}
+	}
+	if skipped > 0 {
+		util.PrintSuccess("Undid %d files of operation %d, %d skipped. Run undo %d again once they're resolved.\n", undone, operation.ID, skipped, operation.ID)
+		return nil
+	}
+	if err := db.MarkOperationUndone(operation.ID); err != nil {
+		return fmt.Errorf("error marking operation %d as undone: %v", operation.ID, err)
+	}
+	util.PrintSuccess("Undid operation %d, %d files.\n", operation.ID, undone)
+	return nil
+}
+
+// undoMove moves a file back from where an operation moved it, and restores its record. It reports whether
+// anything was done, the file may have been put back before.
+func undoMove(db *data.DB, entry *data.OperationEntry, origin string, dryRun bool) (bool, error) {
+	if _, err := os.Lstat(entry.Dst); err != nil {
+		if _, err := os.Lstat(origin); err == nil {
+			return false, nil
+		}
+		return false, fmt.Errorf("it's no longer there")
+	}
+	if _, err := os.Lstat(origin); err == nil {
+		return false, fmt.Errorf("%s exists", origin)
+	}
+	if err := util.CheckAllowedPath(origin); err != nil {
+		return false, err
+	}
+
+	if dryRun {
+		util.PrintProcess("Would put %s back to %s\n", entry.Dst, origin)
+		return true, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(origin), 0755); err != nil {
+		return false, fmt.Errorf("error creating directory %s: %v", filepath.Dir(origin), err)
+	}
+	if err := moveBack(entry.Dst, origin); err != nil {
+		return false, fmt.Errorf("error moving it to %s: %v", origin, err)
+	}
+	util.PrintProcess("Put %s back to %s\n", entry.Dst, origin)
+	util.EmitJSON("restored", map[string]any{"path": entry.Dst, "to": origin, "operation": entry.OperationID})
+
+	restoreRecord(db, entry, origin)
+	return true, nil
+}
+
+// restoreRecord records a file put back with the values of the record deleted when it was moved, unless the
+// file changed since
+func restoreRecord(db *data.DB, entry *data.OperationEntry, path string) {
+	if entry.Blake3 == "" {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() != entry.Size || !data.SameMTime(entry.MTime, info.ModTime()) {
+		util.PrintWarning("Warning: %s changed since it was moved, run sync info to record it\n", path)
+		return
+	}
+	absPath, err := util.CanonicalPath(path)
+	if err != nil {
+		return
+	}
+
+	record := &data.FileInfo{
+		Key:      util.PathKey(absPath),
+		Name:     filepath.Base(absPath),
+		Path:     absPath,
+		MD5:      entry.MD5,
+		Blake3:   entry.Blake3,
+		Size:     info.Size(),
+		DiskSize: util.GetDiskUsage(info),
+		Tag:      entry.Tag,
+		MTime:    info.ModTime(),
+		CTime:    util.GetCreationTime(info),
+	}
+	record.SetVolume()
+	record.SetPermissions(info)
+	if err := db.UpsertFileInfo(record); err != nil {
+		util.PrintWarning("Warning: Could not restore the record of %s: %v\n", path, err)
+	}
+}
+
+// undoCopy deletes a copy made by an operation with its record, unless it changed since. It reports whether
+// anything was done, the copy may have been deleted before.
+func undoCopy(db *data.DB, entry *data.OperationEntry, dryRun bool) (bool, error) {
+	info, err := os.Lstat(entry.Dst)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !info.Mode().IsRegular() || info.Size() != entry.Size {
+		return false, fmt.Errorf("it changed since it was copied")
+	}
+	if blake3Val, _, err := util.FileBlake3MD5(entry.Dst); err != nil {
+		return false, fmt.Errorf("error calculating hashes: %v", err)
+	} else if blake3Val != entry.Blake3 {
+		return false, fmt.Errorf("it changed since it was copied")
+	}
+	if err := util.CheckAllowedPath(entry.Dst); err != nil {
+		return false, err
+	}
+
+	if dryRun {
+		util.PrintProcess("Would delete the copy %s of %s\n", entry.Dst, entry.Src)
+		return true, nil
+	}
+	if err := os.Remove(entry.Dst); err != nil {
+		return false, err
+	}
+	if _, err := dropRecordsUnder(db, entry.Dst); err != nil {
+		util.PrintWarning("Warning: %v\n", err)
+	}
+	util.PrintProcess("Deleted the copy %s of %s\n", entry.Dst, entry.Src)
+	util.EmitJSON("removed", map[string]any{"path": entry.Dst, "operation": entry.OperationID})
+	return true, nil
+}
+
+// dropQuarantineEntries removes the entries of files put back from the manifest of a deleted folder
+func dropQuarantineEntries(deletedDir string, relPaths map[string]bool) error {
+	manifestPath := filepath.Join(deletedDir, util.QuarantineManifestName)
+	entries, err := util.ReadQuarantineManifest(manifestPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	left := slices.DeleteFunc(entries, func(entry util.QuarantineEntry) bool {
+		return relPaths[entry.Path]
+	})
+	return util.WriteQuarantineManifest(manifestPath, nil, left)
+}
+
+// exportQuarantine writes the manifest of a deleted directory to a file, with the size and Blake3 value of
+// every file still there
+func exportQuarantine(deletedDir, path string) error {
+	entries, err := util.ReadQuarantineManifest(filepath.Join(deletedDir, util.QuarantineManifestName))
+	if err != nil {
+		return err
+	}
+
+	exported := make([]util.QuarantineEntry, 0, len(entries))
+	for _, entry := range entries {
+		file := filepath.Join(deletedDir, entry.Path)
+		info, err := os.Stat(file)
+		if err != nil || !info.Mode().IsRegular() {
+			// Folders such as the node_modules of clean build are exported without a value
+			if err == nil {
+				exported = append(exported, entry)
+			} else {
+				util.PrintWarning("Skipping %s, it's no longer in %s\n", entry.Path, deletedDir)
+			}
+			continue
+		}
+
+		blake3Val, _, err := util.FileBlake3MD5(file)
+		if err != nil {
+			return fmt.Errorf("error calculating hashes for %s: %v", file, err)
+		}
+		entry.Size = info.Size()
+		entry.Blake3 = blake3Val
+		exported = append(exported, entry)
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	header := []string{
+		fmt.Sprintf("fsak quarantine manifest of %s on %s, exported %s", deletedDir, host, time.Now().Format(time.RFC3339)),
+		"time, path in the deleted folder, original path, size, blake3",
+	}
+	if err := util.WriteQuarantineManifest(path, header, exported); err != nil {
+		return err
+	}
+	util.PrintSuccess("Exported %d entries to %s\n", len(exported), path)
+	return nil
+}
+
+// importQuarantine adds the entries of an exported manifest to the manifest of a deleted directory, finding
+// the files moved within the directory by their size and Blake3 value
+func importQuarantine(path, deletedDir string, mappings []pathMapping) error {
+	imported, err := util.ReadQuarantineManifest(path)
+	if err != nil {
+		return err
+	}
+	manifestPath := filepath.Join(deletedDir, util.QuarantineManifestName)
+	entries, err := util.ReadQuarantineManifest(manifestPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	known := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		known[entry.Path] = true
+	}
+
+	// Files of the folder by size, hashed when an entry is looked up by its value
+	var bySize map[int64][]string
+	hashes := make(map[string]string)
+	blake3Of := func(file string) string {
+		if _, ok := hashes[file]; !ok {
+			hashes[file], _, _ = util.FileBlake3MD5(file)
+		}
+		return hashes[file]
+	}
+
+	added, missing := 0, 0
+	for _, entry := range imported {
+		entry.Origin = mapPath(entry.Origin, mappings)
+		file := filepath.Join(deletedDir, entry.Path)
+
+		info, err := os.Stat(file)
+		found := err == nil && (entry.Blake3 == "" || !info.Mode().IsRegular() || (info.Size() == entry.Size && blake3Of(file) == entry.Blake3))
+		if !found && entry.Blake3 != "" {
+			if bySize == nil {
+				if bySize, err = filesBySize(deletedDir); err != nil {
+					return err
+				}
+			}
+			for _, candidate := range bySize[entry.Size] {
+				relPath, err := filepath.Rel(deletedDir, candidate)
+				if err == nil && !known[relPath] && blake3Of(candidate) == entry.Blake3 {
+					entry.Path = relPath
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			util.PrintWarning("Skipping %s, it isn't in %s\n", entry.Path, deletedDir)
+			missing++
+			continue
+		}
+		if known[entry.Path] {
+			continue
+		}
+
+		known[entry.Path] = true
+		entry.Size, entry.Blake3 = -1, ""
+		entries = append(entries, entry)
+		added++
+	}
+
+	if added > 0 {
+		if err := util.WriteQuarantineManifest(manifestPath, nil, entries); err != nil {
+			return fmt.Errorf("error updating %s: %v", manifestPath, err)
+		}
+	}
+	util.PrintSuccess("Imported %d entries into %s, %d files weren't found.\n", added, manifestPath, missing)
+	return nil
+}
+
+// filesBySize returns the regular files under a directory by size, except its manifest
+func filesBySize(dir string) (map[int64][]string, error) {
+	files := make(map[int64][]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			util.RecordSkipped(path, err)
+			return nil
+		}
+		if info.Mode().IsRegular() && path != filepath.Join(dir, util.QuarantineManifestName) {
+			files[info.Size()] = append(files[info.Size()], path)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify [paths...]",
+	Short: "Check recorded files against their recorded hashes",
+	Long: `Hash the recorded files again and compare them with the MD5 and Blake3 values recorded by sync info, to find silent corruption such as bit rot on an aging disk. Only files whose size and modification time didn't change since they were synced are compared, the others are reported as modified.
+
+Select the files with --tag, such as every file tagged critical, with paths, or both. Without either, every record with full hashes is verified. Disks of a NAS are read faster with --threads, and --json reports the result of every file. The command exits with status 1 when a file doesn't match.`,
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tag, _ := cmd.Flags().GetString("tag")
+		threads, _ := cmd.Flags().GetInt("threads")
+
+		if threads < 1 {
+			util.PrintError("Error: --threads must be at least 1\n")
+			os.Exit(1)
+		}
+
+		paths := make([]string, len(args))
+		for i, path := range args {
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				util.PrintError("Error getting absolute path for %s: %v\n", path, err)
+				os.Exit(1)
+			}
+			paths[i] = absPath
+		}
+
+		corrupted, err := verifyRecords(cmd, tag, paths, threads)
+		if err != nil {
+			util.PrintError("Error verifying files: %v\n", err)
+			os.Exit(1)
+		}
+		if corrupted > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	verifyCmd.Flags().StringP("tag", "T", "", "Only verify the files synced with this tag")
+	verifyCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	verifyCmd.Flags().IntP("threads", "t", 1, "Number of files verified in parallel")
+	addYesFlag(verifyCmd)
+	rootCmd.AddCommand(verifyCmd)
+}
+
+// verifyRecords hashes the recorded files of a tag and under paths again and compares them with their
+// recorded hashes with threads workers, it returns the number of files that don't match
+func verifyRecords(cmd *cobra.Command, tag string, paths []string, threads int) (int, error) {
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return 0, fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+	var records []*data.FileInfo
+	seen := make(map[string]bool)
+	var totalSize int64
+	for _, path := range paths {
+		var found []*data.FileInfo
+		if err := db.GetHashedFileInfos(data.DuplicateFilter{Tag: tag, PathPrefix: path}, &found); err != nil {
+			return 0, fmt.Errorf("error getting recorded files: %v", err)
+		}
+		for _, record := range found {
+			if !seen[record.Path] {
+				seen[record.Path] = true
+				records = append(records, record)
+				totalSize += record.Size
+			}
+		}
+	}
+
+	if len(records) == 0 {
+		util.PrintSuccess("No recorded files with full hashes to verify.\n")
+		return 0, nil
+	}
+	if !confirmEstimate(cmd, len(records), totalSize) {
+		return 0, nil
+	}
+
+	progress := util.NewProgress(len(records), totalSize)
+	var matched, corrupted, modified, missing atomic.Int64
+	counts := map[verifyResult]*atomic.Int64{
+		verifyMatched:   &matched,
+		verifyCorrupted: &corrupted,
+		verifyModified:  &modified,
+		verifyMissing:   &missing,
+	}
+
+	// Large disks are verified faster with one worker per drive of the array
+	recordCh := make(chan *data.FileInfo, threads*2)
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for record := range recordCh {
+				result := verifyRecord(record, progress)
+				if count, ok := counts[result]; ok {
+					count.Add(1)
+				}
+				util.EmitJSON("verified", map[string]any{"path": record.Path, "result": string(result)})
+			}
+		}()
+	}
+	for _, record := range records {
+		recordCh <- record
+	}
+	close(recordCh)
+	wg.Wait()
+
+	util.PrintSuccess("Verified %d files: %d match, %d corrupted, %d modified since synced, %d missing.\n", len(records), matched.Load(), corrupted.Load(), modified.Load(), missing.Load())
+	return int(corrupted.Load()), nil
+}
+
+// Results of verifying a recorded file
+type verifyResult string
+
+const (
+	verifyMatched   verifyResult = "match"     // The file has its recorded hashes
+	verifyCorrupted verifyResult = "corrupted" // The contents changed without their size or modification time
+	verifyModified  verifyResult = "modified"  // The file changed since it was synced, it can't be compared
+	verifyMissing   verifyResult = "missing"   // The file no longer exists
+	verifySkipped   verifyResult = "skipped"   // The file couldn't be read
+)
+
+// verifyRecord hashes a recorded file again and compares it with its recorded hashes
+func verifyRecord(record *data.FileInfo, progress *util.Progress) verifyResult {
+	info, err := os.Stat(record.Path)
+	if os.IsNotExist(err) {
+		util.PrintWarning("Missing: %s\n", record.Path)
+		progress.Add(record.Size)
+		return verifyMissing
+	}
+	if err != nil {
+		util.RecordSkipped(record.Path, err)
+		progress.Add(record.Size)
+		return verifySkipped
+	}
+	if info.Size() != record.Size || !data.SameMTime(record.MTime, info.ModTime()) {
+		util.PrintWarning("Modified since it was synced: %s\n", record.Path)
+		progress.Add(record.Size)
+		return verifyModified
+	}
+
+	blake3Hash, md5Hash, err := util.FileBlake3MD5(record.Path)
+	if err != nil {
+		if util.IsLockedError(err) {
+			util.PrintWarning("Skipping %s, it's in use by another program\n", record.Path)
+		}
+		util.RecordSkipped(record.Path, err)
+		progress.Add(record.Size)
+		return verifySkipped
+	}
+	progress.Report(record.Size, record.Path)
+
+	if blake3Hash != record.Blake3 || md5Hash != record.MD5 {
+		util.PrintError("Corrupted: %s doesn't match the hashes recorded on %s\n", record.Path, record.SyncedAt.Format("2006-01-02"))
+		return verifyCorrupted
+	}
+	return verifyMatched
+}
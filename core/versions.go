@@ -0,0 +1,177 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/spf13/cobra"
+)
+
+// versionsCmd represents the versions command
+var versionsCmd = &cobra.Command{
+	Use:   "versions",
+	Short: "List and restore the kept versions of a file",
+	Long:  `Commands for the previous versions of files kept in .fsak-versions by merge dir --update and --keep-versions.`,
+}
+
+// versionsListCmd represents the versions list command
+var versionsListCmd = &cobra.Command{
+	Use:               "list <file>",
+	Short:             "List the kept versions of a file",
+	Long:              `List the previous versions of a file kept in the .fsak-versions directory of the tree it belongs to, newest first.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+
+		if err := listVersions(args[0], columns); err != nil {
+			util.PrintError("Error listing versions: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// versionsRestoreCmd represents the versions restore command
+var versionsRestoreCmd = &cobra.Command{
+	Use:               "restore <file>",
+	Short:             "Restore a kept version of a file",
+	Long:              `Restore a previous version of a file from the .fsak-versions directory of the tree it belongs to, the newest one unless --version selects another. The current file is kept as a new version first, so a restore can be undone.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		version, _ := cmd.Flags().GetString("version")
+
+		exitUnlessAllowed(args[0])
+
+		if err := restoreVersion(args[0], version); err != nil {
+			util.PrintError("Error restoring version: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// versionsColumns are the columns of the versions list table
+var versionsColumns = []string{"version", "modified", "size", "path"}
+
+func init() {
+	versionsListCmd.Flags().StringSlice("columns", nil, "Columns to show, in order (version, modified, size, path)")
+	versionsListCmd.RegisterFlagCompletionFunc("columns", completeColumns(versionsColumns))
+	versionsRestoreCmd.Flags().String("version", "", "Version to restore, as shown by versions list (default: the newest)")
+
+	versionsCmd.AddCommand(versionsListCmd)
+	versionsCmd.AddCommand(versionsRestoreCmd)
+	rootCmd.AddCommand(versionsCmd)
+}
+
+// findVersions returns the kept versions of a file, newest first, with the directory keeping them and
+// the path of the file relative to it
+func findVersions(filePath string) (string, string, []util.Version, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("error getting absolute path for %s: %v", filePath, err)
+	}
+
+	root, relPath, found := util.FindVersionsRoot(absPath)
+	if !found {
+		return "", "", nil, fmt.Errorf("no versions of %s are kept", absPath)
+	}
+
+	versions, err := util.ListVersions(root, relPath)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if len(versions) == 0 {
+		return "", "", nil, fmt.Errorf("no versions of %s are kept", absPath)
+	}
+	return root, relPath, versions, nil
+}
+
+// versionName returns the name of a version given to --version
+func versionName(version util.Version) string {
+	name := filepath.Base(version.Path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// listVersions prints the kept versions of a file
+func listVersions(filePath string, columns []string) error {
+	table := util.NewTable(versionsColumns...)
+	if err := table.SelectColumns(columns); err != nil {
+		return err
+	}
+
+	root, relPath, versions, err := findVersions(filePath)
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions {
+		info, err := os.Stat(version.Path)
+		if err != nil {
+			return fmt.Errorf("error getting file info for %s: %v", version.Path, err)
+		}
+		table.AddRow(versionName(version), info.ModTime().Format("2006-01-02 15:04"), util.FormatSize(info.Size()), version.Path)
+	}
+	table.Print()
+
+	util.PrintSuccess("%d versions of %s are kept in %s.\n", len(versions), relPath, filepath.Join(root, util.VersionsDirName))
+	return nil
+}
+
+// restoreVersion replaces a file with one of its kept versions, the newest one when name is empty, keeping
+// the current file as a new version
+func restoreVersion(filePath string, name string) error {
+	root, relPath, versions, err := findVersions(filePath)
+	if err != nil {
+		return err
+	}
+
+	restored := versions[0]
+	if name != "" {
+		found := false
+		for _, version := range versions {
+			if versionName(version) == name {
+				restored, found = version, true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("no version %s of %s is kept, see versions list", name, relPath)
+		}
+	}
+
+	restoredInfo, err := os.Stat(restored.Path)
+	if err != nil {
+		return fmt.Errorf("error getting file info for %s: %v", restored.Path, err)
+	}
+
+	targetPath := filepath.Join(root, relPath)
+	if _, err := os.Stat(targetPath); err == nil {
+		versionPath, err := util.NewVersionPath(root, relPath)
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(targetPath, versionPath); err != nil {
+			return fmt.Errorf("error keeping %s at %s: %v", targetPath, versionPath, err)
+		}
+		util.PrintProcess("Keeping the current %s at %s\n", targetPath, versionPath)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("error getting file info for %s: %v", targetPath, err)
+	}
+
+	// The file may have been removed with its directory
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		return fmt.Errorf("error creating directory %s: %v", filepath.Dir(targetPath), err)
+	}
+	if err := copyFile(restored.Path, targetPath); err != nil {
+		return fmt.Errorf("error copying %s to %s: %v", restored.Path, targetPath, err)
+	}
+	if err := os.Chtimes(targetPath, restoredInfo.ModTime(), restoredInfo.ModTime()); err != nil {
+		return fmt.Errorf("error setting modification time of %s: %v", targetPath, err)
+	}
+
+	util.PrintSuccess("Restored %s from version %s.\n", targetPath, versionName(restored))
+	return nil
+}
@@ -0,0 +1,348 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/baowuhe/go-fsak/data"
+	"github.com/baowuhe/go-fsak/util"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch [flags] <dirs>",
+	Short: "Keep the records of directories up to date as files change",
+	Long: `Watch one or more directories and their subdirectories, and update the database as files are created, modified, renamed or deleted, instead of rescanning them with sync info. Run sync info on the directories first, changes made while they aren't watched are only found by a scan.
+
+A file is hashed once no event was received for it for --debounce, so a file being written is hashed once it's complete, and the records are written by batches. Renamed and moved files keep their records with their history, when they are at least 4 KiB and no other record has their contents on the volume. The records of deleted files are deleted. Stop watching with Ctrl-C, the pending changes are recorded first.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeCatalogedDirs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tag, _ := cmd.Flags().GetString("tag")
+		blacklistFile, _ := cmd.Flags().GetString("blacklist")
+		batchSize, _ := cmd.Flags().GetInt("batch")
+		debounce, _ := cmd.Flags().GetDuration("debounce")
+
+		if batchSize < 1 {
+			util.PrintError("Error: --batch must be at least 1\n")
+			os.Exit(1)
+		}
+		if debounce <= 0 {
+			util.PrintError("Error: --debounce must be positive\n")
+			os.Exit(1)
+		}
+
+		// Started by the service manager of Windows, the messages also go to the event log
+		service := util.RunningAsService()
+
+		dirs, err := absolutePaths(args)
+		if err != nil {
+			util.PrintError("Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, dir := range dirs {
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				util.PrintError("Error: %s is not a directory\n", dir)
+				os.Exit(1)
+			}
+		}
+
+		blacklistPatterns, err := util.ReadBlacklist(blacklistFile)
+		if err != nil {
+			util.PrintError("Error reading blacklist: %v\n", err)
+			os.Exit(1)
+		}
+
+		watch := func(stop <-chan struct{}) error {
+			return watchDirs(dirs, tag, blacklistPatterns, batchSize, debounce, stop)
+		}
+		if service {
+			err = util.RunService(watch)
+		} else {
+			err = watch(nil)
+		}
+		if err != nil {
+			util.PrintError("Error watching directories: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	watchCmd.Flags().StringP("tag", "T", "", "Tag of the files recorded, files already recorded keep theirs")
+	watchCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	watchCmd.Flags().StringP("blacklist", "B", "", "Blacklist file containing paths to exclude (supports regex)")
+	watchCmd.Flags().IntP("batch", "b", 100, "Maximum number of records written to the database at once")
+	watchCmd.Flags().Duration("debounce", 2*time.Second, "Time without events for a file before it's recorded, such as 500ms or 10s")
+	rootCmd.AddCommand(watchCmd)
+}
+
+// watcher keeps the records of watched directories up to date
+type watcher struct {
+	db                *data.DB
+	notify            *fsnotify.Watcher
+	roots             []string
+	watched           map[string]bool // Directories being watched
+	tag               string
+	blacklistPatterns []*regexp.Regexp
+	batchSize         int
+}
+
+// watchDirs watches directories until interrupted or stop is closed, recording the files changed once they're
+// settled
+func watchDirs(dirs []string, tag string, blacklistPatterns []*regexp.Regexp, batchSize int, debounce time.Duration, stop <-chan struct{}) error {
+	// Connect to database
+	db, err := data.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer notify.Close()
+
+	w := &watcher{
+		db:                db,
+		notify:            notify,
+		roots:             dirs,
+		watched:           make(map[string]bool),
+		tag:               tag,
+		blacklistPatterns: blacklistPatterns,
+		batchSize:         batchSize,
+	}
+	for _, dir := range dirs {
+		if _, err := w.watchTree(dir); err != nil {
+			return err
+		}
+	}
+	util.PrintProcess("Watching %d directories under %s, press Ctrl-C to stop\n", len(w.watched), strings.Join(dirs, ", "))
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupts)
+
+	// Paths changed with the time of their last event
+	pending := make(map[string]time.Time)
+	ticker := time.NewTicker(debounce / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-notify.Events:
+			if !ok {
+				return nil
+			}
+			pending[event.Name] = time.Now()
+		case err, ok := <-notify.Errors:
+			if !ok {
+				return nil
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				util.PrintWarning("Warning: Too many changes at once, some were missed, run sync info on %s\n", strings.Join(dirs, ", "))
+			} else {
+				util.PrintWarning("Warning: %v\n", err)
+			}
+		case <-ticker.C:
+			var settled []string
+			for path, last := range pending {
+				if time.Since(last) >= debounce {
+					settled = append(settled, path)
+					delete(pending, path)
+				}
+			}
+			for _, path := range w.record(settled) {
+				pending[path] = time.Now()
+			}
+		case <-interrupts:
+			w.finish(pending)
+			return nil
+		case <-stop:
+			w.finish(pending)
+			return nil
+		}
+	}
+}
+
+// finish records the pending changes before watching stops
+func (w *watcher) finish(pending map[string]time.Time) {
+	settled := make([]string, 0, len(pending))
+	for path := range pending {
+		settled = append(settled, path)
+	}
+	w.record(settled)
+	util.PrintSuccess("Stopped watching.")
+}
+
+// watchTree watches a directory and its subdirectories, and returns the files found in the directories not
+// watched before
+func (w *watcher) watchTree(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Skip unreadable files or directories
+			util.RecordSkipped(path, err)
+			return nil
+		}
+		if isDefaultExcluded(path, w.root(path), info) || w.blacklisted(path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			if info.Mode().IsRegular() && !isWorkspaceFile(path, info) {
+				files = append(files, path)
+			}
+			return nil
+		}
+		if w.watched[path] {
+			return nil
+		}
+		if err := w.notify.Add(path); err != nil {
+			return fmt.Errorf("error watching %s: %v", path, err)
+		}
+		w.watched[path] = true
+		return nil
+	})
+	return files, err
+}
+
+// root returns the watched directory a path is under
+func (w *watcher) root(path string) string {
+	for _, root := range w.roots {
+		if isSubPath(path, root) {
+			return root
+		}
+	}
+	return path
+}
+
+// blacklisted checks if a path matches a blacklist pattern
+func (w *watcher) blacklisted(path string) bool {
+	return slices.ContainsFunc(w.blacklistPatterns, func(pattern *regexp.Regexp) bool {
+		return pattern.MatchString(path)
+	})
+}
+
+// record updates the records of the paths changed, and returns the paths of the files still being written,
+// which are tried again later. The files are recorded before the records of the deleted paths are deleted,
+// so a renamed file takes over its record.
+func (w *watcher) record(paths []string) []string {
+	slices.Sort(paths)
+
+	var files, gone, busy []string
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		switch {
+		case os.IsNotExist(err):
+			gone = append(gone, path)
+		case err != nil:
+			util.PrintWarning("Warning: Could not read %s: %v\n", path, err)
+		case info.IsDir():
+			if w.watched[path] || isDefaultExcluded(path, w.root(path), info) || w.blacklisted(path) {
+				continue
+			}
+			// A directory created or moved in, its files have no events of their own
+			found, err := w.watchTree(path)
+			if err != nil {
+				util.PrintWarning("Warning: %v\n", err)
+			}
+			files = append(files, found...)
+		case info.Mode().IsRegular():
+			if !isWorkspaceFile(path, info) && !w.blacklisted(path) {
+				files = append(files, path)
+			}
+		}
+	}
+
+	batch := make([]*data.FileInfo, 0, w.batchSize)
+	for _, path := range files {
+		fileInfo, err := w.hash(path)
+		if errors.Is(err, errChangedWhileHashed) || util.IsLockedError(err) {
+			busy = append(busy, path)
+			continue
+		}
+		if err != nil {
+			util.PrintError("Error processing file %s: %v\n", path, err)
+			util.RecordSkipped(path, err)
+			recordFailure(w.db, path, w.tag, failureStatus(err), err.Error())
+			util.EmitJSON("error", map[string]any{"path": path, "error": err.Error()})
+			continue
+		}
+		if fileInfo == nil {
+			continue
+		}
+		batch = append(batch, fileInfo)
+		if len(batch) >= w.batchSize {
+			w.save(batch)
+			batch = batch[:0]
+		}
+	}
+	w.save(batch)
+
+	for _, path := range gone {
+		// Deleted or moved away directories are no longer watched
+		for dir := range w.watched {
+			if isSubPath(dir, path) {
+				delete(w.watched, dir)
+			}
+		}
+		dropped, err := dropRecordsUnder(w.db, path)
+		if err != nil {
+			util.PrintError("Error: %v\n", err)
+			continue
+		}
+		if dropped > 0 {
+			util.EmitJSON("removed", map[string]any{"path": path, "records": dropped})
+			util.PrintProcess("Removed %s (%d records)\n", path, dropped)
+		}
+	}
+	return busy
+}
+
+// hash returns the new record of a file, or nil when its record is up to date
+func (w *watcher) hash(path string) (*data.FileInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	tag := w.tag
+	record, err := w.db.GetFileInfoByPath(path)
+	if err == nil {
+		if record.Status == data.StatusOK && record.HasFullHashes() && record.Size == info.Size() && data.SameMTime(record.MTime, info.ModTime()) {
+			return nil, nil
+		}
+		if tag == "" {
+			tag = record.Tag
+		}
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("error getting file info for %s: %v", path, err)
+	}
+	return processFileInfoOnly(path, tag, true, false, false, false, w.db)
+}
+
+// save writes a batch of records to the database
+func (w *watcher) save(batch []*data.FileInfo) {
+	for _, fileInfo := range batch {
+		if err := w.db.UpsertFileInfo(fileInfo); err != nil {
+			util.PrintError("Error upserting file info: %v\n", err)
+			continue
+		}
+		emitSynced(fileInfo)
+		util.PrintProcess("Synced %s\n", fileInfo.Path)
+	}
+}
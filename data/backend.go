@@ -0,0 +1,399 @@
+package data
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/baowuhe/go-fsak/util"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// backend is the database the catalog is kept in, the SQLite file of the workspace by default, or a PostgreSQL
+// or MySQL server several machines share when FSAK_DB_DSN or the dsn setting of the config names one. It holds
+// what differs between them, the queries themselves are written once for all of them.
+type backend interface {
+	// open opens a connection pool of at most maxConns connections, readers only run queries
+	open(maxConns int, reader bool) (*gorm.DB, error)
+	// prepareSchema adapts the column types of the models to the database before they're migrated
+	prepareSchema(db *gorm.DB, models ...any) error
+	// schemaVersion returns the form of the paths of the records written before the forms were kept per owner,
+	// see migratePaths
+	schemaVersion(db *gorm.DB) (int, error)
+	// maintain checks and backs up the database, or converts its tables, once it's migrated
+	maintain(db *gorm.DB) error
+	// insertIgnore turns an INSERT statement into one skipping the rows a unique index already has
+	insertIgnore(insert string) string
+	// timePrecision returns the precision the times are kept with
+	timePrecision() time.Duration
+	// glob returns the condition matching a column against a glob pattern, with the argument of the pattern
+	glob(column, pattern string) (string, string)
+	// String describes the database for messages, without passwords
+	String() string
+}
+
+// selectBackend returns the database server named by util.GetDBDSN, or the SQLite file of the workspace
+func selectBackend() (backend, error) {
+	dsn, err := util.GetDBDSN()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case dsn == "":
+		dbPath, err := GetDBPath()
+		if err != nil {
+			return nil, err
+		}
+		return sqliteBackend{path: dbPath}, nil
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		return postgresBackend{dsn: dsn}, nil
+	case strings.HasPrefix(dsn, "mysql://"):
+		config, err := mysqldriver.ParseDSN(strings.TrimPrefix(dsn, "mysql://"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", util.DBDSNEnv, err)
+		}
+		return mysqlBackend{config: config}, nil
+	default:
+		return nil, fmt.Errorf("invalid %s %s, expected a postgres:// or mysql:// address", util.DBDSNEnv, redactDSN(dsn))
+	}
+}
+
+// gormConfig is the configuration of every connection pool
+func gormConfig() *gorm.Config {
+	return &gorm.Config{
+		Logger:      logger.Default.LogMode(logger.Silent), // Silent by default
+		PrepareStmt: true,                                  // Reuse prepared statements for the per-file lookups and upserts
+	}
+}
+
+// limitConns configures the connection pool of a database
+func limitConns(db *gorm.DB, maxConns int) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	sqlDB.SetMaxOpenConns(maxConns)
+	sqlDB.SetMaxIdleConns(maxConns)
+	sqlDB.SetConnMaxLifetime(0) // Connections can live indefinitely
+	return nil
+}
+
+// globToLike converts a glob pattern to a LIKE pattern using '\' as the escape character. Character classes
+// such as [abc] have no LIKE equivalent and are matched literally.
+func globToLike(pattern string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`, "*", "%", "?", "_").Replace(pattern)
+}
+
+// redactDSN returns a data source name without its password
+func redactDSN(dsn string) string {
+	if parsed, err := url.Parse(dsn); err == nil && parsed.User != nil {
+		if _, ok := parsed.User.Password(); ok {
+			parsed.User = url.UserPassword(parsed.User.Username(), "xxxxx")
+			return parsed.String()
+		}
+	}
+	return dsn
+}
+
+// sqliteBackend keeps the catalog in a SQLite file
+type sqliteBackend struct {
+	path string
+}
+
+func (b sqliteBackend) open(maxConns int, reader bool) (*gorm.DB, error) {
+	// WAL mode lets readers run concurrently with the writer
+	dsn := b.path + "?_busy_timeout=30000&_journal_mode=WAL&_sync=0&_cache_size=10000"
+	if reader {
+		dsn += "&_query_only=1"
+	}
+	db, err := gorm.Open(sqlite.Open(dsn), gormConfig())
+	if err != nil {
+		return nil, err
+	}
+	if err := limitConns(db, maxConns); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (sqliteBackend) prepareSchema(db *gorm.DB, models ...any) error {
+	return nil
+}
+
+func (sqliteBackend) schemaVersion(db *gorm.DB) (int, error) {
+	var version int
+	err := db.Raw("PRAGMA user_version").Scan(&version).Error
+	return version, err
+}
+
+func (b sqliteBackend) maintain(db *gorm.DB) error {
+	return autoBackup(db, b.path)
+}
+
+func (sqliteBackend) insertIgnore(insert string) string {
+	return strings.Replace(insert, "INSERT INTO", "INSERT OR IGNORE INTO", 1)
+}
+
+func (sqliteBackend) timePrecision() time.Duration {
+	return time.Nanosecond
+}
+
+func (sqliteBackend) glob(column, pattern string) (string, string) {
+	return column + " GLOB ?", pattern
+}
+
+func (b sqliteBackend) String() string {
+	return b.path
+}
+
+// retypeColumns changes the column types of the models retype returns a new one for. The schemas
+// are cached by the connection pool, so its migrations create the columns with the new types.
+func retypeColumns(db *gorm.DB, models []any, retype func(dataType string, field *schema.Field) string) error {
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		for _, field := range stmt.Schema.Fields {
+			if dataType := retype(strings.ToLower(string(field.DataType)), field); dataType != "" {
+				field.DataType = schema.DataType(dataType)
+			}
+		}
+	}
+	return nil
+}
+
+// isIndexed checks if a field is a primary key or has an index or a unique constraint
+func isIndexed(field *schema.Field) bool {
+	if field.PrimaryKey {
+		return true
+	}
+	for _, setting := range []string{"INDEX", "UNIQUE", "UNIQUEINDEX"} {
+		if _, ok := field.TagSettings[setting]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// postgresBackend keeps the catalog in a PostgreSQL database
+type postgresBackend struct {
+	dsn string
+}
+
+func (b postgresBackend) open(maxConns int, reader bool) (*gorm.DB, error) {
+	// Only the writer writes, the connections of readers aren't restricted
+	db, err := gorm.Open(postgres.Open(b.dsn), gormConfig())
+	if err != nil {
+		return nil, err
+	}
+	if err := limitConns(db, maxConns); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (postgresBackend) prepareSchema(db *gorm.DB, models ...any) error {
+	return retypeColumns(db, models, func(dataType string, field *schema.Field) string {
+		if dataType == "tinyint" {
+			return "smallint"
+		}
+		return ""
+	})
+}
+
+func (postgresBackend) schemaVersion(db *gorm.DB) (int, error) {
+	// The forms were always kept per owner on database servers
+	return pathsAsGiven, nil
+}
+
+func (postgresBackend) maintain(db *gorm.DB) error {
+	// Backups of database servers are up to their administrators
+	return nil
+}
+
+func (postgresBackend) insertIgnore(insert string) string {
+	return insert + " ON CONFLICT DO NOTHING"
+}
+
+func (postgresBackend) timePrecision() time.Duration {
+	return time.Microsecond
+}
+
+func (postgresBackend) glob(column, pattern string) (string, string) {
+	return column + ` LIKE ? ESCAPE '\'`, globToLike(pattern)
+}
+
+func (b postgresBackend) String() string {
+	return redactDSN(b.dsn)
+}
+
+// mysqlUniqueText is the type of the text columns of unique indexes on MySQL, which can't index text columns
+// whole. InnoDB indexes keys of at most 3072 bytes, 4 per character, next to the 64 characters of the keys of
+// files. Longer values, such as the paths of the store over 700 characters, are refused.
+const mysqlUniqueText = "varchar(700)"
+
+// mysqlPrefixLength is the length of the prefixes of the other indexed text columns on MySQL, such as paths,
+// which keep text of any length and are indexed by their first 768 characters, the most InnoDB can index
+const mysqlPrefixLength = 768
+
+// mysqlBackend keeps the catalog in a MySQL or MariaDB database
+type mysqlBackend struct {
+	config *mysqldriver.Config
+}
+
+func (b mysqlBackend) open(maxConns int, reader bool) (*gorm.DB, error) {
+	config := b.config.Clone()
+	config.ParseTime = true
+	if config.Params == nil {
+		config.Params = make(map[string]string)
+	}
+	if config.Params["charset"] == "" {
+		config.Params["charset"] = "utf8mb4"
+	}
+	// Identifiers such as "key" are quoted and '\' is the escape character of LIKE, as with the other databases.
+	// Values too long for their column are refused rather than truncated, a truncated path would be a missing
+	// file to clean info.
+	config.Params["sql_mode"] = "'STRICT_TRANS_TABLES,ANSI_QUOTES,NO_BACKSLASH_ESCAPES'"
+
+	// Times keep their microseconds, like on PostgreSQL
+	precision := 6
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: config.FormatDSN(), DefaultDatetimePrecision: &precision}), gormConfig())
+	if err != nil {
+		return nil, err
+	}
+	if err := limitConns(db, maxConns); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (mysqlBackend) prepareSchema(db *gorm.DB, models ...any) error {
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		for _, field := range stmt.Schema.Fields {
+			if strings.ToLower(string(field.DataType)) != "text" || !isIndexed(field) {
+				continue
+			}
+			if isUnique(field) {
+				field.DataType = mysqlUniqueText
+				continue
+			}
+			if err := prefixIndexes(db, model, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isUnique checks if a field is a primary key or has a unique index or constraint
+func isUnique(field *schema.Field) bool {
+	if field.PrimaryKey {
+		return true
+	}
+	_, unique := field.TagSettings["UNIQUE"]
+	_, uniqueIndex := field.TagSettings["UNIQUEINDEX"]
+	return unique || uniqueIndex
+}
+
+// prefixIndexes indexes a text column by its first mysqlPrefixLength characters. The indexes of the column
+// created when it was a varchar(700) are dropped, so it can be converted to text and indexed again by the
+// migration.
+func prefixIndexes(db *gorm.DB, model any, field *schema.Field) error {
+	// The indexes are parsed from the tag when they're migrated
+	segments := strings.Split(field.Tag.Get("gorm"), ";")
+	for i, segment := range segments {
+		name, _, _ := strings.Cut(segment, ":")
+		// The schemas are cached, a second connection finds them prefixed already
+		if strings.ToUpper(strings.TrimSpace(name)) == "INDEX" && !strings.Contains(segment, "length:") {
+			if !strings.Contains(segment, ":") {
+				segment += ":"
+			}
+			segments[i] = fmt.Sprintf("%s,length:%d", segment, mysqlPrefixLength)
+		}
+	}
+	gormTag := strings.Join(segments, ";")
+	field.Tag = reflect.StructTag(strings.Replace(string(field.Tag), `gorm:"`+field.Tag.Get("gorm")+`"`, `gorm:"`+gormTag+`"`, 1))
+
+	migrator := db.Migrator()
+	if !migrator.HasTable(model) {
+		return nil
+	}
+	columns, err := migrator.ColumnTypes(model)
+	if err != nil {
+		return err
+	}
+	for _, column := range columns {
+		if column.Name() != field.DBName || strings.ToLower(column.DatabaseTypeName()) != "varchar" {
+			continue
+		}
+		for _, index := range field.Schema.ParseIndexes() {
+			if len(index.Fields) == 1 && index.Fields[0].Field == field && migrator.HasIndex(model, index.Name) {
+				if err := migrator.DropIndex(model, index.Name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (mysqlBackend) schemaVersion(db *gorm.DB) (int, error) {
+	// The forms were always kept per owner on database servers
+	return pathsAsGiven, nil
+}
+
+// mysqlCollation is the collation of the tables on MySQL, which compares paths, tags and hashes byte by byte as
+// the other databases do, rather than ignoring their case
+const mysqlCollation = "utf8mb4_bin"
+
+func (mysqlBackend) maintain(db *gorm.DB) error {
+	// The tables are created with the default collation of the database, they're converted once
+	var tables []string
+	err := db.Raw(`SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE()
+		AND LEFT(table_name, 3) = 'tb_' AND table_collation <> ?`, mysqlCollation).Scan(&tables).Error
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if err := db.Exec(fmt.Sprintf(`ALTER TABLE "%s" CONVERT TO CHARACTER SET utf8mb4 COLLATE %s`, table, mysqlCollation)).Error; err != nil {
+			util.PrintWarning("Warning: Could not convert table %s to %s, its paths are compared ignoring case: %v\n", table, mysqlCollation, err)
+		}
+	}
+
+	// Backups of database servers are up to their administrators
+	return nil
+}
+
+func (mysqlBackend) insertIgnore(insert string) string {
+	return strings.Replace(insert, "INSERT INTO", "INSERT IGNORE INTO", 1)
+}
+
+func (mysqlBackend) timePrecision() time.Duration {
+	return time.Microsecond
+}
+
+func (mysqlBackend) glob(column, pattern string) (string, string) {
+	// The tables are converted to a binary collation, names are matched case-sensitively as with GLOB
+	return column + ` LIKE ? ESCAPE '\'`, globToLike(pattern)
+}
+
+func (b mysqlBackend) String() string {
+	config := b.config.Clone()
+	if config.Passwd != "" {
+		config.Passwd = "xxxxx"
+	}
+	return "mysql://" + config.FormatDSN()
+}
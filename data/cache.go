@@ -0,0 +1,122 @@
+package data
+
+import (
+	"container/list"
+	"sync"
+)
+
+// pathCacheSize is the number of path lookups kept in memory
+const pathCacheSize = 16384
+
+// pathCacheEntry is a cached lookup, Info is nil when the path has no record
+type pathCacheEntry struct {
+	Path string
+	Info *FileInfo
+}
+
+// pathCache is a small LRU cache of file info records keyed by path
+type pathCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	keys    map[string]*list.Element // Entries with a record, by the key of the record
+	order   *list.List               // Most recently used at the front
+	size    int
+}
+
+// newPathCache creates an LRU cache holding up to size lookups
+func newPathCache(size int) *pathCache {
+	return &pathCache{
+		entries: make(map[string]*list.Element, size),
+		keys:    make(map[string]*list.Element, size),
+		order:   list.New(),
+		size:    size,
+	}
+}
+
+// Get returns a copy of the cached record of a path, and whether the path was cached at all
+func (c *pathCache) Get(path string) (*FileInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[path]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+
+	entry := elem.Value.(*pathCacheEntry)
+	if entry.Info == nil {
+		return nil, true
+	}
+	// Callers modify the records they get, never hand out the cached one
+	info := *entry.Info
+	return &info, true
+}
+
+// Put caches the record of a path, a nil record caches that the path has no record
+func (c *pathCache) Put(path string, info *FileInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var stored *FileInfo
+	if info != nil {
+		copied := *info
+		stored = &copied
+	}
+
+	elem, ok := c.entries[path]
+	if ok {
+		c.unindex(elem)
+		elem.Value.(*pathCacheEntry).Info = stored
+		c.order.MoveToFront(elem)
+	} else {
+		elem = c.order.PushFront(&pathCacheEntry{Path: path, Info: stored})
+		c.entries[path] = elem
+	}
+	if stored != nil {
+		// A record cached at another path before it moved is stale
+		if previous, ok := c.keys[stored.Key]; ok && previous != elem {
+			c.remove(previous)
+		}
+		c.keys[stored.Key] = elem
+	}
+
+	if c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// unindex drops an entry from the key index
+func (c *pathCache) unindex(elem *list.Element) {
+	entry := elem.Value.(*pathCacheEntry)
+	if entry.Info != nil && c.keys[entry.Info.Key] == elem {
+		delete(c.keys, entry.Info.Key)
+	}
+}
+
+// remove drops an entry from the cache
+func (c *pathCache) remove(elem *list.Element) {
+	c.unindex(elem)
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*pathCacheEntry).Path)
+}
+
+// RemoveKey drops the cached record with the given key
+func (c *pathCache) RemoveKey(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.keys[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// Clear drops all cached records
+func (c *pathCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element, c.size)
+	c.keys = make(map[string]*list.Element, c.size)
+	c.order.Init()
+}
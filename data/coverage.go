@@ -0,0 +1,54 @@
+package data
+
+import (
+	"path/filepath"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// GetUncoveredFileInfos retrieves the hashed records at or under an absolute directory whose MD5 and Blake3
+// values aren't recorded outside it, such as the files only on a drive, ordered by path
+func (db *DB) GetUncoveredFileInfos(dir string, records *[]*FileInfo) error {
+	dir = strings.TrimSuffix(canonicalPath(dir), string(filepath.Separator))
+	return wherePathUnder(db.Where("blake3 <> '' AND md5 <> ''"), dir).
+		Where(`NOT EXISTS (SELECT 1 FROM tb_file_infos AS other WHERE other.blake3 = tb_file_infos.blake3
+			AND other.md5 = tb_file_infos.md5 AND NOT (other.path = ? OR other.path LIKE ? ESCAPE '\'))`,
+			dir, escapeLike(dir+string(filepath.Separator))+"%").
+		Order("path").
+		Find(records).Error
+}
+
+// Overlap tells how much of the records of a set have their contents recorded by the records of another set
+type Overlap struct {
+	Files        int64 // Records of the set
+	Size         int64
+	Shared       int64 // Records whose MD5 and Blake3 values are also recorded by a record of the other set
+	SharedSize   int64
+	Unhashed     int64 // Records without full hashes, which can't be compared
+	UnhashedSize int64
+}
+
+// GetOverlap returns how much of the records matching from have their MD5 and Blake3 values recorded by a
+// record matching in. A record matching both is in the other set itself.
+func (db *DB) GetOverlap(from DuplicateFilter, in DuplicateFilter) (Overlap, error) {
+	var overlap Overlap
+
+	// The columns of the filter in the subquery are the ones of the other records
+	others := in.apply(db.Table("tb_file_infos AS other").Select("1").
+		Where("other.blake3 = tb_file_infos.blake3 AND other.md5 = tb_file_infos.md5"))
+
+	sum := func(query *gorm.DB, files *int64, size *int64) error {
+		return from.apply(query.Model(&FileInfo{}).Select("count(*), coalesce(sum(size), 0)")).Row().Scan(files, size)
+	}
+	if err := sum(db.DB, &overlap.Files, &overlap.Size); err != nil {
+		return overlap, err
+	}
+	if err := sum(db.Where("blake3 <> '' AND md5 <> ''").Where("EXISTS (?)", others), &overlap.Shared, &overlap.SharedSize); err != nil {
+		return overlap, err
+	}
+	if err := sum(db.Where("blake3 = '' OR md5 = ''"), &overlap.Unhashed, &overlap.UnhashedSize); err != nil {
+		return overlap, err
+	}
+	return overlap, nil
+}
@@ -0,0 +1,61 @@
+package data
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DirScan is the fingerprint of a directory at its last scan by sync info. Adding, removing or renaming an
+// entry changes the modification time of a directory, so when it and the number of entries are the same,
+// sync info --fast skips the files of the directory.
+type DirScan struct {
+	Path      string    `gorm:"primaryKey;type:text"`
+	MTime     time.Time `gorm:"column:mtime"`
+	Children  int
+	SessionID int64 // Scan session that recorded the fingerprint
+	ScannedAt time.Time
+}
+
+// TableName specifies the table name for DirScan
+func (DirScan) TableName() string {
+	return "tb_dir_scans"
+}
+
+// GetDirScans retrieves the fingerprints of the directories scanned, by path
+func (db *DB) GetDirScans() (map[string]*DirScan, error) {
+	var records []*DirScan
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	scans := make(map[string]*DirScan, len(records))
+	for _, record := range records {
+		scans[record.Path] = record
+	}
+	return scans, nil
+}
+
+// SaveDirScans records the fingerprints of the directories a scan went through, stamped with the current scan
+// session, replacing the ones of earlier scans
+func (db *DB) SaveDirScans(scans []*DirScan) error {
+	now := time.Now()
+	for _, scan := range scans {
+		scan.ScannedAt = now
+		if db.session != nil {
+			scan.SessionID = db.session.ID
+		}
+	}
+	return db.write(func(tx *gorm.DB) error {
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "path"}},
+			DoUpdates: clause.AssignmentColumns([]string{"mtime", "children", "session_id", "scanned_at"}),
+		}).CreateInBatches(scans, 500).Error
+	})
+}
+
+// clearDirScans forgets the fingerprints of the directories, so the next sync info --fast looks at every file
+// again after records were deleted
+func clearDirScans(tx *gorm.DB) error {
+	return tx.Where("1 = 1").Delete(&DirScan{}).Error
+}
@@ -0,0 +1,85 @@
+package data
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// duplicateScansKept is the number of duplicate scans kept for each set of folders
+const duplicateScansKept = 5
+
+// DuplicateScan is the result of a run of clean dup, the duplicate groups it found in a set of folders, so a
+// later run can tell the groups that are new since
+type DuplicateScan struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	Folders   string    `gorm:"type:text;not null;index"` // Canonical folders scanned, sorted and separated by newlines
+	ScannedAt time.Time `gorm:"index"`
+	Groups    int64
+	Files     int64
+}
+
+// TableName specifies the table name for DuplicateScan
+func (DuplicateScan) TableName() string {
+	return "tb_duplicate_scans"
+}
+
+// DuplicateScanFile is a file of a duplicate group found by a scan
+type DuplicateScanFile struct {
+	ID      int64  `gorm:"primaryKey;autoIncrement"`
+	ScanID  int64  `gorm:"not null;index"`
+	Group   int    `gorm:"column:group_number"` // Number of the group in the scan, from 1
+	Path    string `gorm:"type:text;not null"`
+	Content string `gorm:"type:varchar(64)"` // Blake3 of the file, its quick hash for quick scans
+}
+
+// TableName specifies the table name for DuplicateScanFile
+func (DuplicateScanFile) TableName() string {
+	return "tb_duplicate_scan_files"
+}
+
+// SaveDuplicateScan records a scan with the files of its groups in one transaction, and deletes the oldest
+// scans of the same folders beyond the ones kept
+func (db *DB) SaveDuplicateScan(scan *DuplicateScan, files []*DuplicateScanFile) error {
+	scan.ScannedAt = time.Now()
+	return db.write(func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(scan).Error; err != nil {
+				return err
+			}
+			for _, file := range files {
+				file.ScanID = scan.ID
+			}
+			if len(files) > 0 {
+				if err := tx.CreateInBatches(files, 500).Error; err != nil {
+					return err
+				}
+			}
+
+			var ids []int64
+			err := tx.Model(&DuplicateScan{}).Where("folders = ?", scan.Folders).Order("scanned_at DESC, id DESC").Pluck("id", &ids).Error
+			if err != nil || len(ids) <= duplicateScansKept {
+				return err
+			}
+			expired := ids[duplicateScansKept:]
+			if err := tx.Where("scan_id IN (?)", expired).Delete(&DuplicateScanFile{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("id IN (?)", expired).Delete(&DuplicateScan{}).Error
+		})
+	})
+}
+
+// GetLastDuplicateScan retrieves the newest scan of a set of folders
+func (db *DB) GetLastDuplicateScan(folders string) (*DuplicateScan, error) {
+	var scan DuplicateScan
+	if err := db.Where("folders = ?", folders).Order("scanned_at DESC, id DESC").First(&scan).Error; err != nil {
+		return nil, err
+	}
+	return &scan, nil
+}
+
+// GetDuplicateScanFiles retrieves the files of the groups of a scan, by group
+func (db *DB) GetDuplicateScanFiles(id int64, files *[]*DuplicateScanFile) error {
+	return db.Where("scan_id = ?", id).Order("group_number, path").Find(files).Error
+}
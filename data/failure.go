@@ -0,0 +1,54 @@
+package data
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/baowuhe/go-fsak/util"
+	"gorm.io/gorm"
+)
+
+// MarkFailed records why a file couldn't be hashed. An existing record keeps its values and only gets the
+// status and reason, a file without a record gets one without hashes.
+func (db *DB) MarkFailed(fileInfo *FileInfo) error {
+	fileInfo.canonicalize()
+	err := db.write(func(tx *gorm.DB) error {
+		result := tx.Model(&FileInfo{}).Where(`"key" = ?`, fileInfo.Key).
+			Updates(map[string]any{"status": fileInfo.Status, "reason": fileInfo.Reason, "synced_at": time.Now()})
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+
+		fileInfo.Ext, fileInfo.Mime = util.FileType(fileInfo.Name)
+		fileInfo.SyncedAt = time.Now()
+		fileInfo.Owner = util.CurrentOwner()
+		if db.session != nil {
+			fileInfo.SessionID = db.session.ID
+			fileInfo.AddedBy = db.session.ID
+		}
+		return tx.Create(fileInfo).Error
+	})
+
+	// The cached record has the previous status
+	db.paths.RemoveKey(fileInfo.Key)
+	return err
+}
+
+// GetFailedFileInfos retrieves the records of the files the last scan couldn't hash, under any of dirs when
+// they're given
+func (db *DB) GetFailedFileInfos(dirs []string, records *[]*FileInfo) error {
+	query := db.Where("status <> ?", StatusOK)
+	if len(dirs) > 0 {
+		conditions := make([]string, len(dirs))
+		values := make([]any, 0, 2*len(dirs))
+		for i, dir := range dirs {
+			// Match whole path components, like the path prefix of a DuplicateFilter
+			dir = strings.TrimSuffix(canonicalPath(dir), string(filepath.Separator))
+			conditions[i] = `(path = ? OR path LIKE ? ESCAPE '\')`
+			values = append(values, dir, escapeLike(dir+string(filepath.Separator))+"%")
+		}
+		query = query.Where("("+strings.Join(conditions, " OR ")+")", values...)
+	}
+	return query.Order("path").Find(records).Error
+}
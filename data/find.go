@@ -0,0 +1,44 @@
+package data
+
+import (
+	"strings"
+	"time"
+
+	"github.com/baowuhe/go-fsak/util"
+)
+
+// FindFilter selects records by their recorded attributes for find, zero fields don't filter
+type FindFilter struct {
+	DuplicateFilter
+	Name           string    // Glob pattern matched against the file name, such as *.iso
+	MaxSize        int64     // Only records at most this large, when positive
+	Hash           string    // Only records whose MD5, Blake3 or SHA-256 value is this lowercase hex value
+	ModifiedAfter  time.Time // Only records modified at or after this time
+	ModifiedBefore time.Time // Only records modified before this time
+}
+
+// FindFileInfos retrieves the records matching the filter, ordered by path
+func (db *DB) FindFileInfos(filter FindFilter, records *[]*FileInfo) error {
+	query := filter.apply(db.Model(&FileInfo{}))
+	if filter.Name != "" {
+		// GLOB is case-sensitive, fold both sides where the file names are not
+		if util.CaseInsensitivePaths() {
+			query = query.Where(db.backend.glob("lower(name)", strings.ToLower(filter.Name)))
+		} else {
+			query = query.Where(db.backend.glob("name", filter.Name))
+		}
+	}
+	if filter.MaxSize > 0 {
+		query = query.Where("size <= ?", filter.MaxSize)
+	}
+	if filter.Hash != "" {
+		query = query.Where("(md5 = ? OR blake3 = ? OR sha256 = ?)", filter.Hash, filter.Hash, filter.Hash)
+	}
+	if !filter.ModifiedAfter.IsZero() {
+		query = query.Where("mtime >= ?", filter.ModifiedAfter)
+	}
+	if !filter.ModifiedBefore.IsZero() {
+		query = query.Where("mtime < ?", filter.ModifiedBefore)
+	}
+	return query.Order("path").Find(records).Error
+}
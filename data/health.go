@@ -0,0 +1,184 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/baowuhe/go-fsak/util"
+	"gorm.io/gorm"
+)
+
+// backupInterval is the minimum age of the latest automatic backup before a new one is made
+const backupInterval = 24 * time.Hour
+
+// backupsToKeep is the number of automatic backups kept
+const backupsToKeep = 3
+
+// errCorrupted is returned by the integrity check of a corrupted database
+var errCorrupted = errors.New("database integrity check failed")
+
+// isCorruptionError checks if an error means the database file is damaged. The error codes of SQLite are only
+// defined by cgo builds of its driver, the messages of SQLITE_CORRUPT and SQLITE_NOTADB are matched instead.
+func isCorruptionError(err error) bool {
+	if errors.Is(err, errCorrupted) {
+		return true
+	}
+	if err == nil {
+		return false
+	}
+
+	message := err.Error()
+	return strings.Contains(message, "database disk image is malformed") || strings.Contains(message, "file is not a database")
+}
+
+// checkIntegrity runs SQLite's quick integrity check, which reads the whole database
+func checkIntegrity(db *gorm.DB) error {
+	var results []string
+	if err := db.Raw("PRAGMA quick_check").Scan(&results).Error; err != nil {
+		return err
+	}
+
+	if len(results) != 1 || results[0] != "ok" {
+		return fmt.Errorf("%w: %s", errCorrupted, strings.Join(results, "; "))
+	}
+	return nil
+}
+
+// getBackupDir returns the directory of the automatic backups
+func getBackupDir(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), "backups")
+}
+
+// listBackups returns the automatic backups, newest first
+func listBackups(dbPath string) ([]string, error) {
+	backups, err := filepath.Glob(filepath.Join(getBackupDir(dbPath), "fsak-*.db"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The names contain a sortable timestamp
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups, nil
+}
+
+// autoBackup checks the integrity of the database and backs it up when the latest backup is too old.
+// The full check is only run together with the backup, so it doesn't slow down every command.
+func autoBackup(db *gorm.DB, dbPath string) error {
+	backups, err := listBackups(dbPath)
+	if err != nil {
+		return err
+	}
+	if len(backups) > 0 {
+		if info, err := os.Stat(backups[0]); err == nil && time.Since(info.ModTime()) < backupInterval {
+			return nil
+		}
+	}
+
+	// Never back up a damaged database over the good backups
+	if err := checkIntegrity(db); err != nil {
+		return err
+	}
+
+	backupDir := getBackupDir(dbPath)
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return err
+	}
+
+	backupPath := filepath.Join(backupDir, fmt.Sprintf("fsak-%s.db", time.Now().Format("20060102-150405")))
+	if err := db.Exec("VACUUM INTO ?", backupPath).Error; err != nil {
+		return err
+	}
+
+	// Remove the oldest backups
+	backups = append([]string{backupPath}, backups...)
+	for _, oldBackup := range backups[min(len(backups), backupsToKeep):] {
+		os.Remove(oldBackup)
+	}
+
+	return nil
+}
+
+// moveAsideCorrupted renames the database and its WAL files so a new database can be created
+func moveAsideCorrupted(dbPath string) (string, error) {
+	corruptPath := fmt.Sprintf("%s.corrupt-%s", dbPath, time.Now().Format("20060102-150405"))
+	if err := os.Rename(dbPath, corruptPath); err != nil {
+		return "", err
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if _, err := os.Stat(dbPath + suffix); err == nil {
+			os.Rename(dbPath+suffix, corruptPath+suffix)
+		}
+	}
+	return corruptPath, nil
+}
+
+// restoreBackup copies a backup to the database path
+func restoreBackup(backupPath, dbPath string) error {
+	src, err := os.Open(backupPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(dbPath)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+	return dst.Close()
+}
+
+// recoverDatabase asks the user how to recover from a corrupted database and applies the choice
+func recoverDatabase(dbPath string, cause error) error {
+	util.PrintError("The database %s is corrupted: %v\n", dbPath, cause)
+
+	backups, err := listBackups(dbPath)
+	if err != nil {
+		return err
+	}
+
+	rebuildOption := util.T("Rebuild an empty database (the corrupted file is kept next to it)")
+	abortOption := util.T("Abort")
+	var options []string
+	if len(backups) > 0 {
+		options = append(options, fmt.Sprintf(util.T("Restore the latest automatic backup (%s)"), filepath.Base(backups[0])))
+	}
+	options = append(options, rebuildOption, abortOption)
+
+	choice, err := util.SelectOne("How do you want to recover the database?", options)
+	if err != nil {
+		return fmt.Errorf("database is corrupted and recovery was not confirmed: %v", cause)
+	}
+	if choice == abortOption {
+		return fmt.Errorf("database is corrupted: %v", cause)
+	}
+
+	corruptPath, err := moveAsideCorrupted(dbPath)
+	if err != nil {
+		return fmt.Errorf("error moving corrupted database aside: %v", err)
+	}
+	util.PrintProcess("Corrupted database moved to %s\n", corruptPath)
+
+	if choice == rebuildOption {
+		util.PrintSuccess("An empty database will be created, run sync info to catalog your files again.\n")
+		return nil
+	}
+
+	// Restore the backup as the new database
+	if err := restoreBackup(backups[0], dbPath); err != nil {
+		return fmt.Errorf("error restoring backup %s: %v", backups[0], err)
+	}
+
+	util.PrintSuccess("Restored the database from %s, files synced since then need to be synced again.\n", backups[0])
+	return nil
+}
@@ -0,0 +1,123 @@
+package data
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Operation is a run of a command that moved or copied files, such as clean dup or merge dir, recorded in
+// the journal so undo can reverse it
+type Operation struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	Command   string    `gorm:"type:text;not null"`
+	StartedAt time.Time `gorm:"index"`
+	Files     int64
+	Size      int64
+	UndoneAt  *time.Time // When undo reversed the operation, nil until then
+}
+
+// TableName specifies the table name for Operation
+func (Operation) TableName() string {
+	return "tb_operations"
+}
+
+// Actions of the journal entries
+const (
+	ActionMove = "move" // The file was moved from Src to Dst
+	ActionCopy = "copy" // The file at Src was copied to Dst, which didn't exist
+)
+
+// OperationEntry is a file moved or copied by an operation. The record of a moved file that was deleted is
+// kept with it, so undo can restore it without hashing the file again.
+type OperationEntry struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement"`
+	OperationID int64     `gorm:"not null;index"`
+	Action      string    `gorm:"type:varchar(8);not null"` // One of the Action values
+	Src         string    `gorm:"type:text;not null"`
+	Dst         string    `gorm:"type:text;not null"`
+	DeletedDir  string    `gorm:"type:text"` // Deleted folder whose manifest lists the move, if any
+	Size        int64     `gorm:"type:bigint"`
+	MTime       time.Time `gorm:"column:mtime"`
+	MD5         string    `gorm:"type:varchar(32)"` // Hashes of the deleted record, empty when the record was kept
+	Blake3      string    `gorm:"type:varchar(64)"`
+	Tag         string    `gorm:"type:varchar(32)"`
+	CreatedAt   time.Time
+}
+
+// TableName specifies the table name for OperationEntry
+func (OperationEntry) TableName() string {
+	return "tb_operation_entries"
+}
+
+// StartOperation records a new operation of a command
+func (db *DB) StartOperation(operation *Operation) error {
+	operation.StartedAt = time.Now()
+	return db.write(func(tx *gorm.DB) error {
+		return tx.Create(operation).Error
+	})
+}
+
+// AddOperationEntry records a file moved or copied by an operation, and counts it in the operation
+func (db *DB) AddOperationEntry(operation *Operation, entry *OperationEntry) error {
+	entry.OperationID = operation.ID
+	entry.CreatedAt = time.Now()
+	err := db.write(func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(entry).Error; err != nil {
+				return err
+			}
+			return tx.Model(operation).Updates(map[string]any{
+				"files": gorm.Expr("files + 1"),
+				"size":  gorm.Expr("size + ?", entry.Size),
+			}).Error
+		})
+	})
+	if err == nil {
+		operation.Files++
+		operation.Size += entry.Size
+	}
+	return err
+}
+
+// GetOperations retrieves the operations of the journal, newest first
+func (db *DB) GetOperations(operations *[]*Operation) error {
+	return db.Order("started_at DESC, id DESC").Find(operations).Error
+}
+
+// GetOperation retrieves an operation by ID
+func (db *DB) GetOperation(id int64) (*Operation, error) {
+	var operation Operation
+	if err := db.First(&operation, id).Error; err != nil {
+		return nil, err
+	}
+	return &operation, nil
+}
+
+// GetLastOperation retrieves the newest operation that wasn't undone
+func (db *DB) GetLastOperation() (*Operation, error) {
+	var operation Operation
+	if err := db.Where("undone_at IS NULL").Order("started_at DESC, id DESC").First(&operation).Error; err != nil {
+		return nil, err
+	}
+	return &operation, nil
+}
+
+// GetOperationEntries retrieves the files of an operation in the order they were handled
+func (db *DB) GetOperationEntries(id int64, entries *[]*OperationEntry) error {
+	return db.Where("operation_id = ?", id).Order("id").Find(entries).Error
+}
+
+// MarkOperationUndone records that an operation was reversed
+func (db *DB) MarkOperationUndone(id int64) error {
+	return db.write(func(tx *gorm.DB) error {
+		return tx.Model(&Operation{}).Where("id = ?", id).Update("undone_at", time.Now()).Error
+	})
+}
+
+// GetDeletedDirs retrieves the deleted folders the operations of the journal moved files to
+func (db *DB) GetDeletedDirs() ([]string, error) {
+	var dirs []string
+	err := db.Model(&OperationEntry{}).Where("deleted_dir != ''").Distinct().Order("deleted_dir").Pluck("deleted_dir", &dirs).Error
+	return dirs, err
+}
@@ -0,0 +1,73 @@
+package data
+
+import (
+	"time"
+
+	"github.com/baowuhe/go-fsak/util"
+	"gorm.io/gorm"
+)
+
+// PruneFilter selects the records expired by db prune
+type PruneFilter struct {
+	Tag          string    // Only records with this tag
+	Volume       string    // Only records of this volume, matched by ID or label
+	SyncedBefore time.Time // Only records last written before this time, any when zero
+}
+
+// apply adds the filter conditions to a query
+func (f PruneFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.Tag != "" {
+		query = query.Where("tag = ?", f.Tag)
+	}
+	if f.Volume != "" {
+		query = query.Where("(volume_id = ? OR volume_label = ?)", f.Volume, f.Volume)
+	}
+	if !f.SyncedBefore.IsZero() {
+		query = query.Where("synced_at < ?", f.SyncedBefore)
+	}
+	return query
+}
+
+// CountPrunable returns how many records a prune would delete and the size of their files
+func (db *DB) CountPrunable(filter PruneFilter) (int64, int64, error) {
+	var files, size int64
+	err := filter.apply(db.Model(&FileInfo{}).Select("count(*), coalesce(sum(size), 0)")).Row().Scan(&files, &size)
+	return files, size, err
+}
+
+// PruneFileInfos deletes the records matching the filter with their history and extended attributes, and
+// returns how many records were deleted
+func (db *DB) PruneFileInfos(filter PruneFilter) (int64, error) {
+	if util.ReadOnly() {
+		return 0, util.ErrReadOnly
+	}
+
+	var pruned int64
+	err := db.write(func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			keys := filter.apply(tx.Model(&FileInfo{}).Select("key"))
+			if err := tx.Where("file_key IN (?)", keys).Delete(&FileInfoHistory{}).Error; err != nil {
+				return err
+			}
+			keys = filter.apply(tx.Model(&FileInfo{}).Select("key"))
+			if err := tx.Where("file_key IN (?)", keys).Delete(&Xattr{}).Error; err != nil {
+				return err
+			}
+			result := filter.apply(tx).Delete(&FileInfo{})
+			if result.Error != nil {
+				return result.Error
+			}
+			pruned = result.RowsAffected
+
+			// Directories whose records were deleted must be scanned again
+			if pruned > 0 {
+				return clearDirScans(tx)
+			}
+			return nil
+		})
+	})
+
+	// The cached records may be gone
+	db.paths.Clear()
+	return pruned, err
+}
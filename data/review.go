@@ -0,0 +1,46 @@
+package data
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReviewDecision is what was selected in a duplicate group reviewed by clean dup, so an interrupted review
+// can be resumed with the groups that weren't decided yet
+type ReviewDecision struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	GroupKey  string    `gorm:"type:varchar(64);uniqueIndex"` // Blake3 of the sorted paths of the group
+	Selected  string    `gorm:"type:text"`                    // Paths selected, separated by newlines, empty when all were kept
+	DecidedAt time.Time `gorm:"index"`
+}
+
+// TableName specifies the table name for ReviewDecision
+func (ReviewDecision) TableName() string {
+	return "tb_review_decisions"
+}
+
+// GetReviewDecisions retrieves the decisions of the duplicate groups reviewed before, by group key
+func (db *DB) GetReviewDecisions() (map[string]*ReviewDecision, error) {
+	var records []*ReviewDecision
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	decisions := make(map[string]*ReviewDecision, len(records))
+	for _, record := range records {
+		decisions[record.GroupKey] = record
+	}
+	return decisions, nil
+}
+
+// SaveReviewDecision records the decision of a duplicate group, replacing an earlier one
+func (db *DB) SaveReviewDecision(decision *ReviewDecision) error {
+	decision.DecidedAt = time.Now()
+	return db.write(func(tx *gorm.DB) error {
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "group_key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"selected", "decided_at"}),
+		}).Create(decision).Error
+	})
+}
@@ -0,0 +1,46 @@
+package data
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Run is the summary of a command that did some work, kept to compare performance across versions and machines
+type Run struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement"`
+	Command     string    `gorm:"type:text;not null;index"` // Command path, such as "fsak sync info"
+	Args        string    `gorm:"type:text"`
+	Version     string    `gorm:"type:varchar(32)"`
+	Host        string    `gorm:"type:varchar(255)"`
+	Platform    string    `gorm:"type:varchar(32)"` // GOOS/GOARCH
+	StartedAt   time.Time `gorm:"index"`
+	Elapsed     time.Duration
+	FilesHashed int64
+	BytesHashed int64
+	BytesCopied int64
+	DBWrites    int64 `gorm:"column:db_writes"`
+	Errors      int64
+	Skipped     int64
+}
+
+// TableName specifies the table name for Run
+func (Run) TableName() string {
+	return "tb_runs"
+}
+
+// AddRun records the summary of a command
+func (db *DB) AddRun(run *Run) error {
+	return db.write(func(tx *gorm.DB) error {
+		return tx.Create(run).Error
+	})
+}
+
+// GetRuns retrieves the latest runs, newest first, of a command when it's not empty
+func (db *DB) GetRuns(command string, limit int, runs *[]*Run) error {
+	query := db.Order("started_at DESC").Limit(limit)
+	if command != "" {
+		query = query.Where("command = ?", command)
+	}
+	return query.Find(runs).Error
+}
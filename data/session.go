@@ -0,0 +1,206 @@
+package data
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/baowuhe/go-fsak/util"
+	"gorm.io/gorm"
+)
+
+// Session is a run of sync info. The records it writes are stamped with its ID, so how it changed the
+// catalog can be reviewed and a bad scan rolled back.
+type Session struct {
+	ID           int64      `gorm:"primaryKey;autoIncrement"`
+	Label        string     `gorm:"type:text"`
+	Dirs         string     `gorm:"type:text"` // Directories scanned, separated by newlines
+	Tag          string     `gorm:"type:varchar(32)"`
+	StartedAt    time.Time  `gorm:"index"`
+	EndedAt      *time.Time // Nil while the scan runs, or when it was interrupted
+	FilesAdded   int64
+	FilesUpdated int64
+	FilesSkipped int64 // Files found that weren't written, because they were up to date or couldn't be read
+	BytesHashed  int64
+	RolledBackAt *time.Time
+}
+
+// TableName specifies the table name for Session
+func (Session) TableName() string {
+	return "tb_sessions"
+}
+
+// StartSession records a new scan session, the records written until FinishSession are stamped with its ID
+func (db *DB) StartSession(session *Session) error {
+	session.StartedAt = time.Now()
+	err := db.write(func(tx *gorm.DB) error {
+		return tx.Create(session).Error
+	})
+	if err == nil {
+		db.session = session
+	}
+	return err
+}
+
+// countSessionWrite counts a record written in the current scan session
+func (db *DB) countSessionWrite(added bool) {
+	if db.session == nil {
+		return
+	}
+	if added {
+		db.sessionAdded.Add(1)
+	} else {
+		db.sessionUpdated.Add(1)
+	}
+}
+
+// FinishSession records the end of the current scan session, files is the number of files it found
+func (db *DB) FinishSession(files int64, bytesHashed int64) error {
+	session := db.session
+	if session == nil {
+		return nil
+	}
+	db.session = nil
+
+	now := time.Now()
+	session.EndedAt = &now
+	session.FilesAdded = db.sessionAdded.Load()
+	session.FilesUpdated = db.sessionUpdated.Load()
+	session.FilesSkipped = max(files-session.FilesAdded-session.FilesUpdated, 0)
+	session.BytesHashed = bytesHashed
+	return db.write(func(tx *gorm.DB) error {
+		return tx.Save(session).Error
+	})
+}
+
+// GetSessions retrieves the latest scan sessions, newest first
+func (db *DB) GetSessions(limit int, sessions *[]*Session) error {
+	return db.Order("started_at DESC").Limit(limit).Find(sessions).Error
+}
+
+// GetSession retrieves a scan session by ID
+func (db *DB) GetSession(id int64) (*Session, error) {
+	var session Session
+	if err := db.First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetSessionFileInfos retrieves the records last written by a scan session
+func (db *DB) GetSessionFileInfos(id int64, records *[]*FileInfo) error {
+	return db.Where("session_id = ?", id).Order("path").Find(records).Error
+}
+
+// CountSessionAdded returns how many records a scan session added are left
+func (db *DB) CountSessionAdded(id int64) (int64, error) {
+	var count int64
+	err := db.Model(&FileInfo{}).Where("added_by = ?", id).Count(&count).Error
+	return count, err
+}
+
+// GetSessionReplaced retrieves the previous versions of the files a scan session found changed
+func (db *DB) GetSessionReplaced(id int64, versions *[]*FileInfoHistory) error {
+	return db.Where("replaced_by = ?", id).Find(versions).Error
+}
+
+// RollbackSession undoes what a scan session did to the records: the records it added are deleted, even when
+// later scans refreshed them, and the previous versions of the files it found changed are restored unless a
+// later command wrote them again. Records it only refreshed keep their values. It returns how many records
+// were restored and deleted.
+func (db *DB) RollbackSession(id int64) (int64, int64, error) {
+	if util.ReadOnly() {
+		return 0, 0, util.ErrReadOnly
+	}
+
+	var restored, deleted int64
+	err := db.write(func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			var records []*FileInfo
+			if err := tx.Where("session_id = ?", id).Find(&records).Error; err != nil {
+				return err
+			}
+
+			for _, record := range records {
+				var previous FileInfoHistory
+				err := tx.Where("file_key = ? AND replaced_by = ?", record.Key, id).Order("id DESC").First(&previous).Error
+				if err == nil {
+					if err := restoreVersion(tx, record, &previous); err != nil {
+						return err
+					}
+					restored++
+					continue
+				}
+				if err != gorm.ErrRecordNotFound {
+					return err
+				}
+
+				// Only refreshed, the values are the same as before the scan
+				if record.AddedBy != id {
+					if err := tx.Model(record).Update("session_id", 0).Error; err != nil {
+						return err
+					}
+				}
+			}
+
+			// Records the session added are deleted with their history
+			keys := tx.Model(&FileInfo{}).Select("key").Where("added_by = ?", id)
+			if err := tx.Where("file_key IN (?)", keys).Delete(&FileInfoHistory{}).Error; err != nil {
+				return err
+			}
+			keys = tx.Model(&FileInfo{}).Select("key").Where("added_by = ?", id)
+			if err := tx.Where("file_key IN (?)", keys).Delete(&Xattr{}).Error; err != nil {
+				return err
+			}
+			result := tx.Where("added_by = ?", id).Delete(&FileInfo{})
+			if result.Error != nil {
+				return result.Error
+			}
+			deleted = result.RowsAffected
+
+			// Directories whose records changed must be scanned again
+			if err := clearDirScans(tx); err != nil {
+				return err
+			}
+
+			return tx.Model(&Session{}).Where("id = ?", id).Update("rolled_back_at", time.Now()).Error
+		})
+	})
+
+	// The cached records may have changed
+	db.paths.Clear()
+	return restored, deleted, err
+}
+
+// restoreVersion puts the previous version of a record back, at the path it had when it was moved
+func restoreVersion(tx *gorm.DB, record *FileInfo, previous *FileInfoHistory) error {
+	if previous.Path != record.Path {
+		key := util.PathKey(previous.Path)
+		if err := tx.Model(&FileInfoHistory{}).Where("file_key = ?", record.Key).Update("file_key", key).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Xattr{}).Where("file_key = ?", record.Key).Update("file_key", key).Error; err != nil {
+			return err
+		}
+		record.Key = key
+		record.Path = previous.Path
+		record.Name = filepath.Base(previous.Path)
+		record.Ext, record.Mime = util.FileType(record.Name)
+		record.SetVolume()
+	}
+
+	record.MD5 = previous.MD5
+	record.Blake3 = previous.Blake3
+	record.QuickHash = previous.QuickHash
+	record.Size = previous.Size
+	record.MTime = previous.MTime
+	record.SessionID = previous.SessionID
+
+	var err error
+	if record.ContentID, err = contentID(tx, record); err != nil {
+		return err
+	}
+	if err := tx.Save(record).Error; err != nil {
+		return err
+	}
+	return tx.Delete(previous).Error
+}
@@ -0,0 +1,102 @@
+package data
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/baowuhe/go-fsak/util"
+	"gorm.io/gorm"
+)
+
+// Snapshot is the state of a directory tree at a point in time, taken by snapshot create, so it can be
+// compared with a later state of the tree
+type Snapshot struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	Label     string    `gorm:"type:text"`
+	Dir       string    `gorm:"type:text;not null;index"` // Canonical directory of the tree
+	CreatedAt time.Time `gorm:"index"`
+	Files     int64
+	Size      int64
+}
+
+// TableName specifies the table name for Snapshot
+func (Snapshot) TableName() string {
+	return "tb_snapshots"
+}
+
+// SnapshotEntry is a file of the tree of a snapshot
+type SnapshotEntry struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement"`
+	SnapshotID int64     `gorm:"not null;index"`
+	Path       string    `gorm:"type:text;not null"` // Path relative to the directory of the snapshot
+	Size       int64     `gorm:"type:bigint"`
+	MTime      time.Time `gorm:"column:mtime"`
+	MD5        string    `gorm:"type:varchar(32)"`
+	Blake3     string    `gorm:"type:varchar(64)"`
+}
+
+// TableName specifies the table name for SnapshotEntry
+func (SnapshotEntry) TableName() string {
+	return "tb_snapshot_entries"
+}
+
+// CreateSnapshot records a snapshot with the entries of its files, in one transaction
+func (db *DB) CreateSnapshot(snapshot *Snapshot, entries []*SnapshotEntry) error {
+	snapshot.Dir = canonicalPath(snapshot.Dir)
+	snapshot.CreatedAt = time.Now()
+	return db.write(func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(snapshot).Error; err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				entry.SnapshotID = snapshot.ID
+			}
+			if len(entries) == 0 {
+				return nil
+			}
+			return tx.CreateInBatches(entries, 500).Error
+		})
+	})
+}
+
+// GetSnapshots retrieves the snapshots of the trees at or under a directory, or of every tree when dir is
+// empty, newest first
+func (db *DB) GetSnapshots(dir string, snapshots *[]*Snapshot) error {
+	query := db.Order("created_at DESC")
+	if dir != "" {
+		dir = strings.TrimSuffix(canonicalPath(dir), string(filepath.Separator))
+		query = query.Where(`(dir = ? OR dir LIKE ? ESCAPE '\')`, dir, escapeLike(dir+string(filepath.Separator))+"%")
+	}
+	return query.Find(snapshots).Error
+}
+
+// GetSnapshot retrieves a snapshot by ID
+func (db *DB) GetSnapshot(id int64) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := db.First(&snapshot, id).Error; err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// GetSnapshotEntries retrieves the files of a snapshot, ordered by path
+func (db *DB) GetSnapshotEntries(id int64, entries *[]*SnapshotEntry) error {
+	return db.Where("snapshot_id = ?", id).Order("path").Find(entries).Error
+}
+
+// DeleteSnapshot deletes a snapshot with its entries
+func (db *DB) DeleteSnapshot(id int64) error {
+	if util.ReadOnly() {
+		return util.ErrReadOnly
+	}
+	return db.write(func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("snapshot_id = ?", id).Delete(&SnapshotEntry{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&Snapshot{}, id).Error
+		})
+	})
+}
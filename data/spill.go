@@ -0,0 +1,178 @@
+package data
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// memoryCheckInterval is the number of added records between two memory usage checks
+const memoryCheckInterval = 1024
+
+// spillEntry is a grouped record moved out of memory, the record itself is reloaded from the database by path
+type spillEntry struct {
+	ID       int64  `gorm:"primaryKey;autoIncrement"`
+	GroupKey string `gorm:"type:text;not null;index"`
+	Path     string `gorm:"type:text;not null"`
+}
+
+// TableName specifies the table name for spillEntry
+func (spillEntry) TableName() string {
+	return "tb_spill_entries"
+}
+
+// Grouper groups file records by a key, such as their hashes, to find duplicates. When a memory limit
+// is set and the heap approaches it, the groups are moved to a temporary SQLite database instead of
+// growing until the process is killed.
+type Grouper struct {
+	db        *DB
+	maxMemory int64
+	groups    map[string][]*FileInfo
+	added     int
+	spill     *gorm.DB
+	spillPath string
+}
+
+// NewGrouper creates a grouper, a maxMemory of 0 keeps everything in memory.
+// Spilled records are reloaded from db, so all added records must be stored in it.
+func NewGrouper(db *DB, maxMemory int64) *Grouper {
+	if maxMemory > 0 {
+		// Make the garbage collector work harder before the limit is reached
+		debug.SetMemoryLimit(maxMemory)
+	}
+
+	return &Grouper{
+		db:        db,
+		maxMemory: maxMemory,
+		groups:    make(map[string][]*FileInfo),
+	}
+}
+
+// Add adds a record to the group of key
+func (g *Grouper) Add(key string, info *FileInfo) error {
+	g.groups[key] = append(g.groups[key], info)
+	g.added++
+
+	if g.maxMemory > 0 && g.added%memoryCheckInterval == 0 && g.nearMemoryLimit() {
+		return g.flush()
+	}
+	return nil
+}
+
+// Spilled reports whether groups were moved to the temporary database
+func (g *Grouper) Spilled() bool {
+	return g.spill != nil
+}
+
+// nearMemoryLimit checks if the heap uses more than 80% of the memory limit
+func (g *Grouper) nearMemoryLimit() bool {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc > uint64(g.maxMemory)/10*8
+}
+
+// flush moves the in-memory groups to the temporary database
+func (g *Grouper) flush() error {
+	if g.spill == nil {
+		f, err := os.CreateTemp("", "fsak-spill-*.db")
+		if err != nil {
+			return err
+		}
+		f.Close()
+		g.spillPath = f.Name()
+
+		spill, err := gorm.Open(sqlite.Open(g.spillPath+"?_journal_mode=OFF&_sync=0"), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		if err != nil {
+			os.Remove(g.spillPath)
+			return err
+		}
+		g.spill = spill
+
+		if err := g.spill.AutoMigrate(&spillEntry{}); err != nil {
+			return err
+		}
+	}
+
+	var entries []spillEntry
+	for key, infos := range g.groups {
+		for _, info := range infos {
+			entries = append(entries, spillEntry{GroupKey: key, Path: info.Path})
+		}
+	}
+	if len(entries) > 0 {
+		if err := g.spill.CreateInBatches(entries, 500).Error; err != nil {
+			return err
+		}
+	}
+
+	g.groups = make(map[string][]*FileInfo)
+	runtime.GC()
+	return nil
+}
+
+// DuplicateGroups returns the groups containing more than one record
+func (g *Grouper) DuplicateGroups() ([][]*FileInfo, error) {
+	var duplicateGroups [][]*FileInfo
+	if g.spill == nil {
+		for _, group := range g.groups {
+			if len(group) > 1 {
+				duplicateGroups = append(duplicateGroups, group)
+			}
+		}
+		return duplicateGroups, nil
+	}
+
+	// Merge the remaining groups with the spilled ones and let SQLite find the duplicates
+	if err := g.flush(); err != nil {
+		return nil, err
+	}
+
+	rows, err := g.spill.Model(&spillEntry{}).
+		Select("group_key, path").
+		Where("group_key IN (?)", g.spill.Model(&spillEntry{}).Select("group_key").Group("group_key").Having("count(*) > 1")).
+		Order("group_key, path").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lastKey := ""
+	for rows.Next() {
+		var key, path string
+		if err := rows.Scan(&key, &path); err != nil {
+			return nil, err
+		}
+
+		info, err := g.db.GetFileInfoByPath(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(duplicateGroups) == 0 || key != lastKey {
+			duplicateGroups = append(duplicateGroups, nil)
+			lastKey = key
+		}
+		duplicateGroups[len(duplicateGroups)-1] = append(duplicateGroups[len(duplicateGroups)-1], info)
+	}
+
+	return duplicateGroups, rows.Err()
+}
+
+// Close removes the temporary database
+func (g *Grouper) Close() error {
+	if g.spill == nil {
+		return nil
+	}
+
+	if err := closeGorm(g.spill); err != nil {
+		return err
+	}
+	return os.Remove(g.spillPath)
+}
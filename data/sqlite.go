@@ -1,27 +1,134 @@
 package data
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/baowuhe/go-fsak/util"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-// FileInfo represents file information
+// FileInfo represents file information, one record per path. The key is the Blake3 of the path, the
+// contents are identified by the Content record shared by every path holding them.
 type FileInfo struct {
-	ID     int64     `gorm:"primaryKey;autoIncrement"`
-	Key    string    `gorm:"type:varchar(64);not null;unique;index"`
-	Name   string    `gorm:"type:text;not null;index"`
-	Path   string    `gorm:"type:text;not null;index"`
-	Status int       `gorm:"type:tinyint;not null;default:0"`
-	MD5    string    `gorm:"type:varchar(32);index"`
-	Blake3 string    `gorm:"type:varchar(64);index"` // Blake3 hash (64 hex chars for 32-byte hash)
-	Size   int64     `gorm:"type:bigint"`
-	Tag    string    `gorm:"type:varchar(32)"`
-	MTime  time.Time `gorm:"column:mtime"`
-	CTime  time.Time `gorm:"column:ctime"`
+	ID          int64     `gorm:"primaryKey;autoIncrement"`
+	Key         string    `gorm:"type:varchar(64);not null;unique;index"`
+	Name        string    `gorm:"type:text;not null;index"`
+	Ext         string    `gorm:"type:varchar(32);index"`  // Lowercase extension without the dot, derived from Name when recorded
+	Mime        string    `gorm:"type:varchar(128);index"` // MIME type implied by the extension
+	Path        string    `gorm:"type:text;not null;index"`
+	Status      int       `gorm:"type:tinyint;not null;default:0"` // Why the file has no hashes, one of the Status values
+	Reason      string    `gorm:"type:text"`                       // Error of the last attempt to hash the file
+	MD5         string    `gorm:"type:varchar(32);index"`
+	Blake3      string    `gorm:"type:varchar(64);index"` // Blake3 hash (64 hex chars for 32-byte hash)
+	SHA256      string    `gorm:"type:varchar(64);index"` // Only known when imported from a sha256sum manifest
+	QuickHash   string    `gorm:"type:varchar(64);index"` // Blake3 of size and first/last 1MB, matches are probabilistic
+	Size        int64     `gorm:"type:bigint"`
+	DiskSize    int64     `gorm:"type:bigint"` // Space allocated on disk, differs from Size for sparse and compressed files
+	Tag         string    `gorm:"type:varchar(32)"`
+	MTime       time.Time `gorm:"column:mtime"`
+	CTime       time.Time `gorm:"column:ctime"`
+	FinderTags  string    `gorm:"type:text"`               // Comma separated macOS Finder tags, filled by sync info --finder-tags
+	VolumeID    string    `gorm:"type:varchar(255);index"` // Filesystem UUID (or label) of the volume the file lives on, with the directory mounted when it isn't the root
+	VolumeLabel string    `gorm:"type:text"`
+	VolumePath  string    `gorm:"type:text;index"`         // Path relative to the volume's mount point, stays valid when it's mounted elsewhere
+	Owner       string    `gorm:"type:varchar(255);index"` // user@host that created the record, for catalogs shared by several users
+	ContentID   int64     `gorm:"index;default:0"`         // Content record of the MD5 and Blake3 values, 0 until they are known
+	SyncedAt    time.Time `gorm:"index"`                   // When the record was last written, for retention
+	Mode        *uint32   // Permission bits, with the setuid, setgid and sticky bits, as an os.FileMode
+	UID         *int64    `gorm:"column:uid;index"` // User and group IDs owning the file, not known on Windows
+	GID         *int64    `gorm:"column:gid"`
+	ACL         string    `gorm:"column:acl;type:text"` // Owner and DACL as SDDL, only recorded on Windows
+	SessionID   int64     `gorm:"index;default:0"`      // Scan session that last wrote the record, 0 for other commands
+	AddedBy     int64     `gorm:"default:0"`            // Scan session that created the record
+
+	// Extended attributes to record in tb_xattrs, nil when they weren't read
+	Xattrs map[string][]byte `gorm:"-"`
+
+	// Path fsak moved the file from, its record follows the file even to another volume
+	MovedFrom string `gorm:"-"`
+}
+
+// Content is the identity of file contents, shared by the records of every path holding them
+type Content struct {
+	ID     int64  `gorm:"primaryKey;autoIncrement"`
+	Blake3 string `gorm:"type:varchar(64);not null;uniqueIndex:idx_content_hashes"`
+	MD5    string `gorm:"type:varchar(32);not null;uniqueIndex:idx_content_hashes"`
+	Size   int64  `gorm:"type:bigint"`
+}
+
+// TableName specifies the table name for Content
+func (Content) TableName() string {
+	return "tb_contents"
+}
+
+// Status values of a record, why the file couldn't be hashed by the last scan
+const (
+	StatusOK         = 0 // Hashed, or not tried yet
+	StatusPermission = 1 // Reading the file was denied
+	StatusReadError  = 2 // Reading the file failed
+	StatusTooNew     = 3 // The file changed while it was hashed, it's still being written
+	StatusExcluded   = 4 // The file matched the blacklist
+	StatusLocked     = 5 // The file was in use by another program
+)
+
+// StatusNames are the names of the Status values
+var StatusNames = map[int]string{
+	StatusOK:         "ok",
+	StatusPermission: "permission denied",
+	StatusReadError:  "read error",
+	StatusTooNew:     "too new",
+	StatusExcluded:   "excluded",
+	StatusLocked:     "locked",
+}
+
+// HasFullHashes reports whether the MD5 and Blake3 values of the whole file are known
+func (f *FileInfo) HasFullHashes() bool {
+	return f.MD5 != "" && f.Blake3 != ""
+}
+
+// GetDiskSize returns the on-disk usage of the file, falling back to the apparent size for records synced before it was tracked
+func (f *FileInfo) GetDiskSize() int64 {
+	if f.DiskSize > 0 || f.Size == 0 {
+		return f.DiskSize
+	}
+	return f.Size
+}
+
+// SetVolume records the volume the file lives on and its path relative to the volume's mount point
+func (f *FileInfo) SetVolume() {
+	volume, relPath, err := util.GetVolumeLocation(f.Path)
+	if err != nil {
+		// Volume detection is best effort, the absolute path is still recorded
+		return
+	}
+
+	f.VolumeID = volume.ID
+	f.VolumeLabel = volume.Label
+	f.VolumePath = relPath
+}
+
+// SetPermissions records the permission bits, owner and ACL of the file, so they can be queried and
+// reapplied by restore
+func (f *FileInfo) SetPermissions(info os.FileInfo) {
+	mode := uint32(info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky))
+	f.Mode = &mode
+
+	if uid, gid, ok := util.FileOwnerIDs(info); ok {
+		f.UID = &uid
+		f.GID = &gid
+	}
+
+	// The ACL is best effort like the volume, the permission bits are still recorded
+	if acl, err := util.FileACL(f.Path); err == nil {
+		f.ACL = acl
+	}
 }
 
 // TableName specifies the table name for FileInfo
@@ -29,9 +136,67 @@ func (FileInfo) TableName() string {
 	return "tb_file_infos"
 }
 
-// DB is a wrapper around gorm.DB
+// FileInfoHistory is a previous version of a file info record, saved whenever the file changed
+type FileInfoHistory struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement"`
+	FileKey    string    `gorm:"type:varchar(64);not null;index"` // Key of the FileInfo record
+	Path       string    `gorm:"type:text;not null;index"`
+	MD5        string    `gorm:"type:varchar(32)"`
+	Blake3     string    `gorm:"type:varchar(64);index"`
+	QuickHash  string    `gorm:"type:varchar(64)"`
+	Size       int64     `gorm:"type:bigint"`
+	MTime      time.Time `gorm:"column:mtime"`
+	ReplacedAt time.Time `gorm:"index"` // When this version was replaced by a newer one
+	SessionID  int64     // Scan session that recorded this version
+	ReplacedBy int64     `gorm:"index"` // Scan session that replaced this version, 0 for other commands
+}
+
+// TableName specifies the table name for FileInfoHistory
+func (FileInfoHistory) TableName() string {
+	return "tb_file_info_histories"
+}
+
+// ContentChanged reports whether a new record describes different file contents than f.
+// Completing missing hashes of the same contents is not a change.
+func (f *FileInfo) ContentChanged(newer *FileInfo) bool {
+	if f.Size != newer.Size || !SameMTime(f.MTime, newer.MTime) {
+		return true
+	}
+	if f.Blake3 != "" && newer.Blake3 != "" && f.Blake3 != newer.Blake3 {
+		return true
+	}
+	return f.QuickHash != "" && newer.QuickHash != "" && f.QuickHash != newer.QuickHash
+}
+
+// mtimePrecision is the precision the database of the last connection keeps the times with
+var mtimePrecision = time.Nanosecond
+
+// SameMTime checks if a recorded modification time is the one of a file, at the precision the database keeps
+// it with, database servers keep microseconds
+func SameMTime(recorded, actual time.Time) bool {
+	return recorded.Truncate(mtimePrecision).Equal(actual.Truncate(mtimePrecision))
+}
+
+// DB is a wrapper around gorm.DB. The embedded gorm.DB is a pool of read connections,
+// all writes are serialized through a single writer goroutine so reads never wait for a long sync.
 type DB struct {
 	*gorm.DB
+	writer  *gorm.DB
+	writes  chan writeRequest
+	done    chan struct{}
+	paths   *pathCache
+	backend backend
+
+	// Scan session stamped on the records written, with the records it added and updated
+	session        *Session
+	sessionAdded   atomic.Int64
+	sessionUpdated atomic.Int64
+}
+
+// writeRequest is a write operation queued for the writer goroutine
+type writeRequest struct {
+	fn     func(tx *gorm.DB) error
+	result chan error
 }
 
 // GetDBPath returns the path to the database file
@@ -39,74 +204,603 @@ func GetDBPath() (string, error) {
 	return util.GetDBPath()
 }
 
-// Connect connects to the SQLite database
+// Connect connects to the database, the SQLite file of the workspace or the database server of FSAK_DB_DSN
 func Connect() (*DB, error) {
-	dbPath, err := GetDBPath()
+	backend, err := selectBackend()
 	if err != nil {
 		return nil, err
 	}
 
-	// Open database with GORM - configure SQLite for better concurrent access
-	dsn := dbPath + "?_busy_timeout=30000&_journal_mode=WAL&_sync=0&_cache_size=10000"
-	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent), // Silent by default
-	})
+	// Open the single write connection first, it creates the database and switches it to WAL mode
+	writer, err := openWriter(backend)
+	if sqlite, ok := backend.(sqliteBackend); ok && err != nil && isCorruptionError(err) {
+		// Offer to restore a backup or start over instead of failing on every command
+		if err := recoverDatabase(sqlite.path, err); err != nil {
+			return nil, err
+		}
+		writer, err = openWriter(backend)
+	}
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error opening %s: %v", backend, err)
 	}
 
-	// Configure the underlying SQL database for better concurrency
-	sqlDB, err := db.DB()
+	mtimePrecision = backend.timePrecision()
+
+	// Read connections only run queries, SQLite refuses writes on them
+	reader, err := backend.open(runtime.NumCPU(), true)
 	if err != nil {
+		closeGorm(writer)
 		return nil, err
 	}
 
-	// Set connection pool parameters
-	sqlDB.SetMaxOpenConns(1)    // Limit to 1 connection to avoid locking issues
-	sqlDB.SetMaxIdleConns(1)    // Only keep 1 idle connection
-	sqlDB.SetConnMaxLifetime(0) // Connections can live indefinitely
+	db := &DB{
+		DB:      reader,
+		writer:  writer,
+		writes:  make(chan writeRequest),
+		done:    make(chan struct{}),
+		paths:   newPathCache(pathCacheSize),
+		backend: backend,
+	}
+	go db.writeLoop()
+
+	return db, nil
+}
+
+// openWriter opens the write connection, migrates the schema and makes the daily automatic backup
+func openWriter(backend backend) (*gorm.DB, error) {
+	writer, err := backend.open(1, false)
+	if err != nil {
+		return nil, err
+	}
 
 	// Auto-migrate the schema - this creates the table if it doesn't exist and updates it if needed
-	if err := db.AutoMigrate(&FileInfo{}); err != nil {
+	models := []any{&FileInfo{}, &FileInfoHistory{}, &StoreEntry{}, &Content{}, &Run{}, &Xattr{}, &Session{}, &DirScan{}, &ReviewDecision{}, &Snapshot{}, &SnapshotEntry{}, &Operation{}, &OperationEntry{}, &DuplicateScan{}, &DuplicateScanFile{}, &PathForm{}}
+	if err := backend.prepareSchema(writer, models...); err != nil {
+		closeGorm(writer)
+		return nil, err
+	}
+	if err := writer.AutoMigrate(models...); err != nil {
+		closeGorm(writer)
+		return nil, err
+	}
+	if err := migrateContents(writer, backend); err != nil {
+		closeGorm(writer)
+		return nil, err
+	}
+	if err := migrateFileTypes(writer); err != nil {
+		closeGorm(writer)
+		return nil, err
+	}
+	if err := migratePaths(writer, backend); err != nil {
+		closeGorm(writer)
+		return nil, err
+	}
+	if err := migrateVolumeIDs(writer); err != nil {
+		closeGorm(writer)
+		return nil, err
+	}
+	// Records written before sync times were tracked start aging now
+	if err := writer.Model(&FileInfo{}).Where("synced_at IS NULL").Update("synced_at", time.Now()).Error; err != nil {
+		closeGorm(writer)
 		return nil, err
 	}
 
-	return &DB{db}, nil
+	if err := backend.maintain(writer); err != nil {
+		if isCorruptionError(err) {
+			closeGorm(writer)
+			return nil, err
+		}
+		util.PrintWarning("Warning: Could not back up the database: %v\n", err)
+	}
+
+	return writer, nil
+}
+
+// migrateContents links the records hashed before contents were tracked, or by an older version, to
+// their content records
+func migrateContents(db *gorm.DB, backend backend) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		unlinked := "content_id = 0 AND blake3 <> '' AND md5 <> ''"
+		err := tx.Exec(backend.insertIgnore(`INSERT INTO tb_contents (blake3, md5, size)
+			SELECT blake3, md5, max(size) FROM tb_file_infos WHERE ` + unlinked + ` GROUP BY blake3, md5`)).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE tb_file_infos SET content_id = (SELECT id FROM tb_contents
+			WHERE tb_contents.blake3 = tb_file_infos.blake3 AND tb_contents.md5 = tb_file_infos.md5) WHERE ` + unlinked).Error
+	})
+}
+
+// migrateFileTypes fills the extension and MIME type of the records created before they were recorded, which
+// are NULL rather than empty
+func migrateFileTypes(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Only the IDs and names are read, a thousand at a time, so large catalogs don't fill the memory
+		var lastID int64
+		for {
+			var rows []struct {
+				ID   int64
+				Name string
+			}
+			err := tx.Model(&FileInfo{}).Select("id, name").Where("id > ? AND ext IS NULL", lastID).Order("id").Limit(1000).Find(&rows).Error
+			if err != nil || len(rows) == 0 {
+				return err
+			}
+			for _, row := range rows {
+				ext, mimeType := util.FileType(row.Name)
+				if err := tx.Model(&FileInfo{}).Where("id = ?", row.ID).Updates(map[string]interface{}{"ext": ext, "mime": mimeType}).Error; err != nil {
+					return err
+				}
+			}
+			lastID = rows[len(rows)-1].ID
+		}
+	})
+}
+
+// Path forms of the records, kept per owner so the paths are canonicalized again when the case-insensitive-paths
+// setting changes. Older versions kept a single form in the user_version of SQLite databases.
+const (
+	pathsAsGiven = iota
+	pathsCanonical
+	pathsCanonicalFolded
+)
+
+// PathForm is the form of the paths of the records of an owner. The hosts sharing a catalog may fold the case
+// of paths or not, each one only canonicalizes its own records.
+type PathForm struct {
+	Owner string `gorm:"type:varchar(255);primaryKey"`
+	Form  int
+}
+
+// TableName specifies the table name for PathForm
+func (PathForm) TableName() string {
+	return "tb_path_forms"
+}
+
+// migratePaths canonicalizes the paths of the records of the current owner written before paths were
+// canonicalized, or with another case-insensitive-paths setting. Of two records of the owner that turn out to
+// be the same file, the one synced last is kept. Records without an owner were written by versions that didn't
+// record owners, and are taken as the current owner's.
+func migratePaths(db *gorm.DB, backend backend) error {
+	want := pathsCanonical
+	if util.CaseInsensitivePaths() {
+		want = pathsCanonicalFolded
+	}
+	owner := util.CurrentOwner()
+	var form PathForm
+	err := db.Where("owner = ?", owner).First(&form).Error
+	version := form.Form
+	if err == gorm.ErrRecordNotFound {
+		version, err = backend.schemaVersion(db)
+	}
+	if err != nil {
+		return err
+	}
+	if version == want {
+		return nil
+	}
+
+	owned := db.Where("owner = ? OR owner = '' OR owner IS NULL", owner)
+	var count int64
+	if err := db.Model(&FileInfo{}).Where(owned).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		util.PrintProcess("Canonicalizing the paths of %d records...\n", count)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Only the IDs, keys and paths are read, a thousand at a time, so large catalogs don't fill the memory
+		var lastID int64
+		for {
+			var rows []struct {
+				ID       int64
+				Key      string
+				Path     string
+				SyncedAt time.Time
+			}
+			err := tx.Model(&FileInfo{}).Select(`id, "key", path, synced_at`).Where("id > ?", lastID).Where(owned).Order("id").Limit(1000).Find(&rows).Error
+			if err != nil || len(rows) == 0 {
+				return err
+			}
+			for _, row := range rows {
+				path := canonicalPath(row.Path)
+				key := util.PathKey(path)
+				if key == row.Key && path == row.Path {
+					continue
+				}
+
+				if key != row.Key {
+					var other FileInfo
+					err := tx.Select(`id, "key", synced_at, owner`).Where(`"key" = ?`, key).First(&other).Error
+					if err == nil && other.Owner != "" && other.Owner != owner {
+						// The path is recorded by another owner, whose records are left alone
+						continue
+					}
+					if err == nil {
+						// The same file was recorded at two paths
+						loser := other.Key
+						if other.SyncedAt.After(row.SyncedAt) {
+							loser = row.Key
+						}
+						if err := deleteRecord(tx, loser); err != nil {
+							return err
+						}
+						if loser == row.Key {
+							continue
+						}
+					} else if err != gorm.ErrRecordNotFound {
+						return err
+					}
+
+					if err := tx.Model(&FileInfoHistory{}).Where("file_key = ?", row.Key).Update("file_key", key).Error; err != nil {
+						return err
+					}
+					if err := tx.Model(&Xattr{}).Where("file_key = ?", row.Key).Update("file_key", key).Error; err != nil {
+						return err
+					}
+				}
+				err := tx.Model(&FileInfo{}).Where("id = ?", row.ID).Updates(map[string]any{"key": key, "path": path, "name": filepath.Base(path)}).Error
+				if err != nil {
+					return err
+				}
+			}
+			lastID = rows[len(rows)-1].ID
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// The directories scanned are recorded again with their canonical paths
+	if err := clearDirScans(db); err != nil {
+		return err
+	}
+	return db.Save(&PathForm{Owner: owner, Form: want}).Error
+}
+
+// migrateVolumeIDs gives the records of the mounts of a subvolume or a directory of a filesystem the ID of
+// their mount, which includes the directory mounted. Records synced before were given the ID of the whole
+// filesystem, which is no longer the one of any mount. Of the mounts of a filesystem, a record goes to the one
+// with the deepest mount point holding its path, records outside all of them keep their ID.
+func migrateVolumeIDs(db *gorm.DB) error {
+	volumes, err := util.MountedVolumes()
+	if err != nil {
+		// Volume detection is best effort, the records are migrated by a later command
+		return nil
+	}
+	var legacy []*util.VolumeInfo
+	for _, volume := range volumes {
+		if volume.LegacyID != "" {
+			legacy = append(legacy, volume)
+		}
+	}
+	sort.Slice(legacy, func(i, j int) bool {
+		return len(legacy[i].MountPoint) > len(legacy[j].MountPoint)
+	})
+
+	for _, volume := range legacy {
+		query := db.Model(&FileInfo{}).Where("volume_id = ?", volume.LegacyID)
+		if volume.MountPoint != string(filepath.Separator) {
+			// The paths below the mount point sort between its path followed by the separator and by \xff
+			prefix := volume.MountPoint + string(filepath.Separator)
+			query = query.Where("(path = ? OR (path >= ? AND path < ?))", volume.MountPoint, prefix, prefix+"\xff")
+		}
+		result := query.Update("volume_id", volume.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			util.PrintProcess("Moved %d records of %s to the volume ID %s\n", result.RowsAffected, volume.MountPoint, volume.ID)
+		}
+	}
+	return nil
+}
+
+// deleteRecord deletes the record of a key with its history and extended attributes
+func deleteRecord(tx *gorm.DB, key string) error {
+	if err := tx.Where("file_key = ?", key).Delete(&FileInfoHistory{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("file_key = ?", key).Delete(&Xattr{}).Error; err != nil {
+		return err
+	}
+	return tx.Where(`"key" = ?`, key).Delete(&FileInfo{}).Error
+}
+
+// contentID returns the ID of the content record of a file's hashes, creating it when they are new
+func contentID(tx *gorm.DB, fileInfo *FileInfo) (int64, error) {
+	if !fileInfo.HasFullHashes() {
+		return 0, nil
+	}
+	content := Content{Blake3: fileInfo.Blake3, MD5: fileInfo.MD5, Size: fileInfo.Size}
+	if err := tx.Where(Content{Blake3: fileInfo.Blake3, MD5: fileInfo.MD5}).FirstOrCreate(&content).Error; err != nil {
+		return 0, err
+	}
+	return content.ID, nil
+}
+
+// minMovedSize is the size under which a new file isn't matched to a deleted one by its contents alone, small
+// files such as empty ones or license files are often the same without being the same file
+const minMovedSize = 4096
+
+// findMovedRecord returns the record of a file that was renamed or moved to the path of a new record: the
+// one of the path it was moved from when known, otherwise the only record of the current owner with the same
+// contents on the same volume, when its file is gone
+func findMovedRecord(tx *gorm.DB, fileInfo *FileInfo) (*FileInfo, error) {
+	if fileInfo.MovedFrom != "" {
+		var record FileInfo
+		err := tx.Where(`"key" = ?`, util.PathKey(canonicalPath(fileInfo.MovedFrom))).First(&record).Error
+		if err == nil {
+			return &record, nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			return nil, err
+		}
+	}
+	if fileInfo.ContentID == 0 || fileInfo.Size < minMovedSize {
+		return nil, nil
+	}
+
+	// Several records with the contents are ambiguous, none of them is followed
+	var candidates []*FileInfo
+	if err := tx.Where("content_id = ? AND volume_id = ?", fileInfo.ContentID, fileInfo.VolumeID).Limit(2).Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	if len(candidates) != 1 {
+		return nil, nil
+	}
+	candidate := candidates[0]
+	if candidate.Owner != "" && candidate.Owner != util.CurrentOwner() {
+		return nil, nil
+	}
+	if _, err := os.Lstat(candidate.Path); !os.IsNotExist(err) {
+		return nil, nil
+	}
+	return candidate, nil
+}
+
+// writeLoop executes queued writes one at a time on the write connection
+func (db *DB) writeLoop() {
+	defer close(db.done)
+	for req := range db.writes {
+		util.CountDBWrite()
+		req.result <- req.fn(db.writer)
+	}
+}
+
+// write queues a write operation and waits for its result
+func (db *DB) write(fn func(tx *gorm.DB) error) error {
+	result := make(chan error, 1)
+	db.writes <- writeRequest{fn: fn, result: result}
+	return <-result
+}
+
+// Close waits for queued writes to finish and closes all connections
+func (db *DB) Close() error {
+	close(db.writes)
+	<-db.done
+
+	writerErr := closeGorm(db.writer)
+	if err := closeGorm(db.DB); err != nil {
+		return err
+	}
+	return writerErr
+}
+
+// closeGorm closes the connection pool of a gorm.DB
+func closeGorm(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
 }
 
 // GetFileInfoByPath retrieves file info by path
 func (db *DB) GetFileInfoByPath(path string) (*FileInfo, error) {
+	path = canonicalPath(path)
+	if cached, ok := db.paths.Get(path); ok {
+		if cached == nil {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return cached, nil
+	}
+
 	var fileInfo FileInfo
-	result := db.Where("path = ?", path).First(&fileInfo)
+	result := db.Where(`"key" = ?`, util.PathKey(path)).First(&fileInfo)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
+			db.paths.Put(path, nil)
 			return nil, result.Error
 		}
 		return nil, result.Error
 	}
 
+	db.paths.Put(path, &fileInfo)
 	return &fileInfo, nil
 }
 
 // UpsertFileInfo creates or updates file info in the database
 func (db *DB) UpsertFileInfo(fileInfo *FileInfo) error {
-	// For SQLite, we can use the Assign method with FirstOrCreate or use Save
-	// First try to find if the record exists based on the key
-	var existing FileInfo
-	result := db.Where("key = ?", fileInfo.Key).First(&existing)
+	fileInfo.canonicalize()
 
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			// Record doesn't exist, create it
-			return db.Create(fileInfo).Error
+	// Files without a tag get the one of the matching auto-tagging rule
+	if fileInfo.Tag == "" {
+		fileInfo.Tag = util.AutoTag(fileInfo.Path)
+	}
+	fileInfo.Ext, fileInfo.Mime = util.FileType(fileInfo.Name)
+	fileInfo.SyncedAt = time.Now()
+	fileInfo.SessionID = 0
+	if db.session != nil {
+		fileInfo.SessionID = db.session.ID
+	}
+
+	var moved *FileInfo
+	added := false
+	err := db.write(func(tx *gorm.DB) error {
+		// The previous version and the update are saved together
+		return tx.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if fileInfo.ContentID, err = contentID(tx, fileInfo); err != nil {
+				return err
+			}
+
+			// For SQLite, we can use the Assign method with FirstOrCreate or use Save
+			// First try to find if the record exists based on the key
+			var existing FileInfo
+			result := tx.Where(`"key" = ?`, fileInfo.Key).First(&existing)
+
+			if result.Error != nil {
+				if result.Error == gorm.ErrRecordNotFound {
+					// A renamed or moved file keeps its record, with its owner and history
+					if moved, err = findMovedRecord(tx, fileInfo); err != nil {
+						return err
+					}
+					if moved != nil {
+						err := tx.Model(&FileInfoHistory{}).Where("file_key = ?", moved.Key).Update("file_key", fileInfo.Key).Error
+						if err != nil {
+							return err
+						}
+						err = tx.Model(&Xattr{}).Where("file_key = ?", moved.Key).Update("file_key", fileInfo.Key).Error
+						if err != nil {
+							return err
+						}
+						history := &FileInfoHistory{
+							FileKey:    fileInfo.Key,
+							Path:       moved.Path,
+							MD5:        moved.MD5,
+							Blake3:     moved.Blake3,
+							QuickHash:  moved.QuickHash,
+							Size:       moved.Size,
+							MTime:      moved.MTime,
+							ReplacedAt: time.Now(),
+							SessionID:  moved.SessionID,
+							ReplacedBy: fileInfo.SessionID,
+						}
+						if err := tx.Create(history).Error; err != nil {
+							return err
+						}
+						fileInfo.ID = moved.ID
+						fileInfo.Owner = moved.Owner
+						fileInfo.SHA256 = moved.SHA256
+						fileInfo.AddedBy = moved.AddedBy
+						if err := tx.Save(fileInfo).Error; err != nil {
+							return err
+						}
+						return saveXattrs(tx, fileInfo)
+					}
+
+					// Record doesn't exist, create it
+					if fileInfo.Owner == "" {
+						fileInfo.Owner = util.CurrentOwner()
+					}
+					fileInfo.AddedBy = fileInfo.SessionID
+					added = true
+					if err := tx.Create(fileInfo).Error; err != nil {
+						return err
+					}
+					return saveXattrs(tx, fileInfo)
+				}
+				// Some other error occurred
+				return result.Error
+			}
+
+			// Keep the previous version when the file changed
+			if existing.ContentChanged(fileInfo) {
+				history := &FileInfoHistory{
+					FileKey:    existing.Key,
+					Path:       existing.Path,
+					MD5:        existing.MD5,
+					Blake3:     existing.Blake3,
+					QuickHash:  existing.QuickHash,
+					Size:       existing.Size,
+					MTime:      existing.MTime,
+					ReplacedAt: time.Now(),
+					SessionID:  existing.SessionID,
+					ReplacedBy: fileInfo.SessionID,
+				}
+				if err := tx.Create(history).Error; err != nil {
+					return err
+				}
+			}
+
+			// SHA256 values are only imported, they stay valid while the contents don't change
+			if fileInfo.SHA256 == "" && !existing.ContentChanged(fileInfo) {
+				fileInfo.SHA256 = existing.SHA256
+			}
+
+			// The record stays owned by its creator, records synced before owners were tracked are claimed
+			fileInfo.Owner = existing.Owner
+			if fileInfo.Owner == "" {
+				fileInfo.Owner = util.CurrentOwner()
+			}
+
+			// Record exists, update it
+			fileInfo.ID = existing.ID // Keep the existing ID
+			fileInfo.AddedBy = existing.AddedBy
+			if err := tx.Save(fileInfo).Error; err != nil {
+				return err
+			}
+			return saveXattrs(tx, fileInfo)
+		})
+	})
+	if err == nil {
+		db.countSessionWrite(added)
+		if moved != nil {
+			db.paths.RemoveKey(moved.Key)
+			util.PrintProcess("%s was moved to %s, its record follows it\n", moved.Path, fileInfo.Path)
 		}
-		// Some other error occurred
-		return result.Error
+		db.paths.Put(fileInfo.Path, fileInfo)
+	}
+	return err
+}
+
+// GetFileHistory retrieves the previous versions of a file, oldest first, including the ones recorded at
+// the paths it was moved from
+func (db *DB) GetFileHistory(fileInfo *FileInfo, records *[]*FileInfoHistory) error {
+	return db.Where("file_key = ?", fileInfo.Key).Order("replaced_at").Find(records).Error
+}
+
+// GetEarliestMTime returns the earliest modification time recorded for contents, by the records of every path
+// holding them and their previous versions, and whether any was recorded
+func (db *DB) GetEarliestMTime(blake3 string, md5 string, size int64) (time.Time, bool, error) {
+	var mtimes, previous []time.Time
+	if err := db.Model(&FileInfo{}).Where("blake3 = ? AND md5 = ? AND size = ?", blake3, md5, size).Pluck("mtime", &mtimes).Error; err != nil {
+		return time.Time{}, false, err
+	}
+	if err := db.Model(&FileInfoHistory{}).Where("blake3 = ? AND md5 = ? AND size = ?", blake3, md5, size).Pluck("mtime", &previous).Error; err != nil {
+		return time.Time{}, false, err
 	}
 
-	// Record exists, update it
-	fileInfo.ID = existing.ID // Keep the existing ID
-	return db.Save(fileInfo).Error
+	var earliest time.Time
+	for _, mtime := range append(mtimes, previous...) {
+		if !mtime.IsZero() && (earliest.IsZero() || mtime.Before(earliest)) {
+			earliest = mtime
+		}
+	}
+	return earliest, !earliest.IsZero(), nil
+}
+
+// SetMTimeByPath sets the modification time recorded for the file at path, when its record has the hashes given
+func (db *DB) SetMTimeByPath(path string, blake3 string, md5 string, mtime time.Time) error {
+	key := util.PathKey(canonicalPath(path))
+	err := db.write(func(tx *gorm.DB) error {
+		return tx.Model(&FileInfo{}).Where(`"key" = ? AND blake3 = ? AND md5 = ?`, key, blake3, md5).Update("mtime", mtime).Error
+	})
+
+	// The cached record has the previous time
+	db.paths.RemoveKey(key)
+	return err
+}
+
+// GetFileInfosByContent retrieves the records of every path holding the contents of a record, ordered by path
+func (db *DB) GetFileInfosByContent(fileInfo *FileInfo, records *[]*FileInfo) error {
+	if fileInfo.ContentID == 0 {
+		*records = []*FileInfo{fileInfo}
+		return nil
+	}
+	return db.Where("content_id = ?", fileInfo.ContentID).Order("path").Find(records).Error
 }
 
 // CountAllFiles returns the count of all files in the database
@@ -121,7 +815,280 @@ func (db *DB) GetAllFileInfos(records *[]*FileInfo) error {
 	return db.Find(records).Error
 }
 
-// DeleteFileInfo deletes file info by key
+// DeleteFileInfo deletes file info by key, with its history and extended attributes
 func (db *DB) DeleteFileInfo(key string) error {
-	return db.Where("key = ?", key).Delete(&FileInfo{}).Error
+	if util.ReadOnly() {
+		return util.ErrReadOnly
+	}
+	db.paths.RemoveKey(key)
+	return db.write(func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			return deleteRecord(tx, key)
+		})
+	})
+}
+
+// DeleteFileInfos deletes the file info of many keys with their history, by transactions of 500 records
+func (db *DB) DeleteFileInfos(keys []string) error {
+	if util.ReadOnly() {
+		return util.ErrReadOnly
+	}
+	for _, key := range keys {
+		db.paths.RemoveKey(key)
+	}
+	for start := 0; start < len(keys); start += 500 {
+		batch := keys[start:min(start+500, len(keys))]
+		err := db.write(func(tx *gorm.DB) error {
+			return tx.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Where("file_key IN (?)", batch).Delete(&FileInfoHistory{}).Error; err != nil {
+					return err
+				}
+				if err := tx.Where("file_key IN (?)", batch).Delete(&Xattr{}).Error; err != nil {
+					return err
+				}
+				return tx.Where(`"key" IN (?)`, batch).Delete(&FileInfo{}).Error
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetVolumes records the volume of many records, by transactions of 500 records
+func (db *DB) SetVolumes(records []*FileInfo) error {
+	if util.ReadOnly() {
+		return util.ErrReadOnly
+	}
+	for _, record := range records {
+		db.paths.RemoveKey(record.Key)
+	}
+	for start := 0; start < len(records); start += 500 {
+		batch := records[start:min(start+500, len(records))]
+		err := db.write(func(tx *gorm.DB) error {
+			return tx.Transaction(func(tx *gorm.DB) error {
+				for _, record := range batch {
+					volume := map[string]any{"volume_id": record.VolumeID, "volume_label": record.VolumeLabel, "volume_path": record.VolumePath}
+					if err := tx.Model(&FileInfo{}).Where(`"key" = ?`, record.Key).Updates(volume).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetTagByPath sets the tag of the records at or under an absolute path and returns how many were tagged
+func (db *DB) SetTagByPath(path string, tag string) (int64, error) {
+	var tagged int64
+	err := db.write(func(tx *gorm.DB) error {
+		result := DuplicateFilter{PathPrefix: path}.apply(tx.Model(&FileInfo{})).Update("tag", tag)
+		tagged = result.RowsAffected
+		return result.Error
+	})
+
+	// The cached records have the old tag
+	db.paths.Clear()
+	return tagged, err
+}
+
+// GetTags returns the distinct tags of the records
+func (db *DB) GetTags() ([]string, error) {
+	var tags []string
+	result := db.Model(&FileInfo{}).Distinct("tag").Where("tag <> ''").Order("tag").Pluck("tag", &tags)
+	return tags, result.Error
+}
+
+// maxDirLookups bounds the index lookups of GetCatalogedDirs, so a directory with many files stays fast
+const maxDirLookups = 1000
+
+// GetCatalogedDirs returns the cataloged directories directly under an absolute directory. Without a
+// directory, it returns the deepest directory containing all records.
+func (db *DB) GetCatalogedDirs(dir string) ([]string, error) {
+	sep := string(filepath.Separator)
+	if dir == "" {
+		// The common prefix of the first and last paths is shared by all paths
+		var first, last []string
+		if err := db.Model(&FileInfo{}).Order("path").Limit(1).Pluck("path", &first).Error; err != nil {
+			return nil, err
+		}
+		if err := db.Model(&FileInfo{}).Order("path DESC").Limit(1).Pluck("path", &last).Error; err != nil {
+			return nil, err
+		}
+		if len(first) == 0 || len(last) == 0 {
+			return nil, nil
+		}
+
+		common := 0
+		for common < len(first[0]) && common < len(last[0]) && first[0][common] == last[0][common] {
+			common++
+		}
+		root := first[0][:strings.LastIndex(first[0][:common], sep)+1]
+		if root == "" {
+			return nil, nil
+		}
+		root = filepath.Clean(root)
+		return []string{root}, nil
+	}
+
+	// Skip from one subdirectory to the next with the path index instead of reading every record
+	prefix := strings.TrimSuffix(dir, sep) + sep
+	var dirs []string
+	after := prefix
+	for i := 0; i < maxDirLookups; i++ {
+		var paths []string
+		err := db.Model(&FileInfo{}).
+			Where("path > ? AND path < ?", after, prefix+"\xff").
+			Order("path").
+			Limit(1).
+			Pluck("path", &paths).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) == 0 {
+			break
+		}
+
+		rest := paths[0][len(prefix):]
+		end := strings.Index(rest, sep)
+		if end < 0 {
+			// A file directly in the directory
+			after = paths[0]
+			continue
+		}
+
+		child := prefix + rest[:end]
+		dirs = append(dirs, child)
+		after = child + sep + "\xff"
+	}
+	return dirs, nil
+}
+
+// VolumeSummary describes a volume known to the catalog
+type VolumeSummary struct {
+	VolumeID    string
+	VolumeLabel string
+	Files       int64
+	Size        int64
+}
+
+// GetVolumeSummaries returns all volumes recorded in the database with their file counts and sizes
+func (db *DB) GetVolumeSummaries() ([]VolumeSummary, error) {
+	var summaries []VolumeSummary
+	result := db.Model(&FileInfo{}).
+		Select("volume_id, volume_label, count(*) as files, sum(size) as size").
+		Where("volume_id <> ''").
+		Group("volume_id, volume_label").
+		Order("volume_label, volume_id").
+		Scan(&summaries)
+	return summaries, result.Error
+}
+
+// TypeSummary describes the recorded files of an extension
+type TypeSummary struct {
+	Ext   string
+	Mime  string
+	Files int64
+	Size  int64
+}
+
+// GetTypeSummaries returns the file counts and sizes per extension, of a volume when it's not empty, largest first
+func (db *DB) GetTypeSummaries(volume string) ([]TypeSummary, error) {
+	var summaries []TypeSummary
+	query := db.Model(&FileInfo{}).Select("ext, mime, count(*) as files, sum(size) as size")
+	if volume != "" {
+		query = query.Where("volume_id = ? OR volume_label = ?", volume, volume)
+	}
+	result := query.Group("ext, mime").Order("size DESC, ext").Scan(&summaries)
+	return summaries, result.Error
+}
+
+// GetFileInfosByVolume retrieves the file info records of a volume, matched by volume ID or label
+func (db *DB) GetFileInfosByVolume(volume string, records *[]*FileInfo) error {
+	return db.Where("volume_id = ? OR volume_label = ?", volume, volume).Order("volume_path").Find(records).Error
+}
+
+// DuplicateFilter restricts the records considered when looking for duplicates
+type DuplicateFilter struct {
+	Tag        string // Only records synced with this tag
+	PathPrefix string // Only records at or under this absolute path
+	MinSize    int64  // Only records at least this large
+	Ext        string // Only records with this lowercase extension, without the dot
+	Mime       string // Only records of this MIME type, or of all the types starting with it when it ends with a slash
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, for patterns using '\' as the escape character
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// apply adds the filter conditions to a query
+func (f DuplicateFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.Tag != "" {
+		query = query.Where("tag = ?", f.Tag)
+	}
+	if f.PathPrefix != "" {
+		query = wherePathUnder(query, canonicalPath(f.PathPrefix))
+	}
+	if f.MinSize > 0 {
+		query = query.Where("size >= ?", f.MinSize)
+	}
+	if f.Ext != "" {
+		query = query.Where("ext = ?", f.Ext)
+	}
+	if strings.HasSuffix(f.Mime, "/") {
+		query = query.Where(`mime LIKE ? ESCAPE '\'`, escapeLike(f.Mime)+"%")
+	} else if f.Mime != "" {
+		query = query.Where("mime = ?", f.Mime)
+	}
+	return query
+}
+
+// wherePathUnder restricts a query to the paths at or under dir
+func wherePathUnder(query *gorm.DB, dir string) *gorm.DB {
+	// Match whole path components, /data/a must not match /data/ab
+	dir = strings.TrimSuffix(dir, string(filepath.Separator))
+	return query.Where(`(path = ? OR path LIKE ? ESCAPE '\')`, dir, escapeLike(dir+string(filepath.Separator))+"%")
+}
+
+// canonicalPath returns the canonical form of a path, or the path when it can't be made absolute
+func canonicalPath(path string) string {
+	if canonical, err := util.CanonicalPath(path); err == nil {
+		return canonical
+	}
+	return path
+}
+
+// canonicalize puts the path of a record in the form the catalog stores, and keys the record by it
+func (f *FileInfo) canonicalize() {
+	f.Path = canonicalPath(f.Path)
+	f.Key = util.PathKey(f.Path)
+}
+
+// GetDuplicateFileInfos retrieves the records sharing their MD5 and Blake3 values with another record,
+// ordered so that the records of a duplicate group are adjacent
+func (db *DB) GetDuplicateFileInfos(filter DuplicateFilter, records *[]*FileInfo) error {
+	groups := filter.apply(db.Model(&FileInfo{}).Select("blake3, md5").Where("blake3 <> '' AND md5 <> ''")).
+		Group("blake3, md5").
+		Having("count(*) > 1")
+
+	return filter.apply(db.Where("(blake3, md5) IN (?)", groups)).
+		Order("blake3, md5, path").
+		Find(records).Error
+}
+
+// GetFileInfos retrieves the records matching the filter, ordered by path
+func (db *DB) GetFileInfos(filter DuplicateFilter, records *[]*FileInfo) error {
+	return filter.apply(db.Model(&FileInfo{})).Order("path").Find(records).Error
+}
+
+// GetHashedFileInfos retrieves the records with full hashes matching the filter, ordered by path
+func (db *DB) GetHashedFileInfos(filter DuplicateFilter, records *[]*FileInfo) error {
+	return filter.apply(db.Where("blake3 <> '' AND md5 <> ''")).Order("path").Find(records).Error
 }
@@ -0,0 +1,63 @@
+package data
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StoreEntry is a file moved into the content-addressable store of the workspace, recorded at the path it
+// had before so the tree can be materialized again
+type StoreEntry struct {
+	ID       int64     `gorm:"primaryKey;autoIncrement"`
+	Path     string    `gorm:"type:text;not null;unique"` // Logical path, where the file was when it was stored
+	Blake3   string    `gorm:"type:varchar(64);not null;index"`
+	MD5      string    `gorm:"type:varchar(32)"`
+	Size     int64     `gorm:"type:bigint"`
+	Mode     uint32    `gorm:"type:integer"` // Permission bits of the file, restored by copies
+	MTime    time.Time `gorm:"column:mtime"`
+	StoredAt time.Time `gorm:"index"`
+}
+
+// TableName specifies the table name for StoreEntry
+func (StoreEntry) TableName() string {
+	return "tb_store_entries"
+}
+
+// StoreSummary is the number of logical files of the store and the objects holding their contents
+type StoreSummary struct {
+	Entries     int64
+	LogicalSize int64
+	Objects     int64
+	ObjectSize  int64
+}
+
+// UpsertStoreEntry records a stored file, replacing the entry of a file stored earlier at the same path
+func (db *DB) UpsertStoreEntry(entry *StoreEntry) error {
+	return db.write(func(tx *gorm.DB) error {
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "path"}},
+			DoUpdates: clause.AssignmentColumns([]string{"blake3", "md5", "size", "mode", "mtime", "stored_at"}),
+		}).Create(entry).Error
+	})
+}
+
+// GetStoreEntries retrieves the entries at or under a logical path, ordered by path
+func (db *DB) GetStoreEntries(pathPrefix string, entries *[]*StoreEntry) error {
+	// Logical paths aren't paths of the filesystem, they're taken as they are
+	return wherePathUnder(db.Model(&StoreEntry{}), pathPrefix).Order("path").Find(entries).Error
+}
+
+// GetStoreSummary counts the entries of the store and the distinct objects they share
+func (db *DB) GetStoreSummary() (StoreSummary, error) {
+	var summary StoreSummary
+	row := db.Model(&StoreEntry{}).Select("count(*), coalesce(sum(size), 0)").Row()
+	if err := row.Scan(&summary.Entries, &summary.LogicalSize); err != nil {
+		return summary, err
+	}
+	objects := db.Model(&StoreEntry{}).Select("blake3, max(size) AS size").Group("blake3")
+	row = db.Table("(?) AS objects", objects).Select("count(*), coalesce(sum(size), 0)").Row()
+	err := row.Scan(&summary.Objects, &summary.ObjectSize)
+	return summary, err
+}
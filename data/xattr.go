@@ -0,0 +1,48 @@
+package data
+
+import (
+	"gorm.io/gorm"
+)
+
+// Xattr is an extended attribute of a file, captured by sync info --xattrs and backup so restore can
+// reapply it when the backup's filesystem dropped it
+type Xattr struct {
+	ID      int64  `gorm:"primaryKey;autoIncrement"`
+	FileKey string `gorm:"type:varchar(64);not null;uniqueIndex:idx_xattr_name"` // Key of the FileInfo record
+	Name    string `gorm:"type:text;not null;uniqueIndex:idx_xattr_name"`
+	Value   []byte
+}
+
+// TableName specifies the table name for Xattr
+func (Xattr) TableName() string {
+	return "tb_xattrs"
+}
+
+// saveXattrs replaces the extended attributes recorded for a file when they were captured
+func saveXattrs(tx *gorm.DB, fileInfo *FileInfo) error {
+	if fileInfo.Xattrs == nil {
+		return nil
+	}
+	if err := tx.Where("file_key = ?", fileInfo.Key).Delete(&Xattr{}).Error; err != nil {
+		return err
+	}
+	for name, value := range fileInfo.Xattrs {
+		if err := tx.Create(&Xattr{FileKey: fileInfo.Key, Name: name, Value: value}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetXattrs retrieves the extended attributes recorded for a file
+func (db *DB) GetXattrs(fileKey string) (map[string][]byte, error) {
+	var records []*Xattr
+	if err := db.Where("file_key = ?", fileKey).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	attrs := make(map[string][]byte, len(records))
+	for _, record := range records {
+		attrs[record.Name] = record.Value
+	}
+	return attrs, nil
+}
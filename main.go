@@ -2,7 +2,7 @@ package main
 
 import (
 	"os"
-	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/baowuhe/go-fsak/core"
@@ -10,27 +10,16 @@ import (
 )
 
 func main() {
-	// Print workspace directory
-	wsDir, err := util.GetWorkspaceDir()
-	if err != nil {
-		util.PrintError("Error getting workspace directory: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Get current user to show more user-friendly path
-	currentUser, err := os.UserHomeDir()
-	if err == nil {
-		// Replace home directory path with ~ for brevity, but only if the workspace directory is under the home directory
-		relPath, err2 := filepath.Rel(currentUser, wsDir)
-		if err2 == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
-			wsDir = filepath.Join("~", relPath)
-		}
+	// Keep stdout for the NUL-separated paths of --print0, the results of --json, exported file lists,
+	// the paths found by find, completion scripts and the answers to them
+	completion := len(os.Args) > 1 && (os.Args[1] == "completion" || strings.HasPrefix(os.Args[1], "__complete"))
+	export := len(os.Args) > 1 && (os.Args[1] == "export" || os.Args[1] == "find")
+	if completion || export || slices.Contains(os.Args[1:], "--print0") || slices.Contains(os.Args[1:], "--json") {
+		util.MessagesToStderr()
 	}
 
-	util.PrintProcess("Workspace directory: %s\n", wsDir)
-
 	if err := core.Execute(); err != nil {
-		util.PrintError("%v", err)
+		util.PrintError("%v\n", err)
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,77 @@
+package util
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TagRule tags the files whose path matches a glob pattern, set in the [tags] section of the config
+type TagRule struct {
+	Pattern string
+	Tag     string
+	regex   *regexp.Regexp
+}
+
+// newTagRule compiles the pattern of a rule. Patterns with a / match the whole path, where ** matches any
+// number of directories, and patterns without one, such as *.raw, match the file name.
+func newTagRule(pattern string, tag string) TagRule {
+	expanded := filepath.ToSlash(expandHome(pattern))
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !strings.Contains(expanded, "/") {
+		expr.WriteString("(.*/)?")
+	}
+	for i := 0; i < len(expanded); i++ {
+		switch {
+		case strings.HasPrefix(expanded[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(expanded[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case expanded[i] == '*':
+			expr.WriteString("[^/]*")
+		case expanded[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(expanded[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+
+	return TagRule{Pattern: pattern, Tag: tag, regex: regexp.MustCompile(expr.String())}
+}
+
+// Matches reports whether the rule applies to the file at an absolute path
+func (r TagRule) Matches(path string) bool {
+	return r.regex.MatchString(filepath.ToSlash(path))
+}
+
+// sortTagRules orders the rules so the most specific, longest, pattern comes first
+func sortTagRules(rules []TagRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		if len(rules[i].Pattern) != len(rules[j].Pattern) {
+			return len(rules[i].Pattern) > len(rules[j].Pattern)
+		}
+		return rules[i].Pattern < rules[j].Pattern
+	})
+}
+
+// AutoTag returns the tag of the most specific rule of the config matching the file at an absolute path,
+// empty when none does
+func AutoTag(path string) string {
+	config, err := LoadConfig()
+	if err != nil {
+		return ""
+	}
+
+	for _, rule := range config.TagRules {
+		if rule.Matches(path) {
+			return rule.Tag
+		}
+	}
+	return ""
+}
@@ -0,0 +1,228 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+)
+
+// This file implements the small subset of Apple's binary property list format
+// that is needed to read and write Finder tags: a top level array of strings.
+
+const bplistHeader = "bplist00"
+
+// EncodeBplistStrings encodes a list of strings as a binary property list array
+func EncodeBplistStrings(values []string) []byte {
+	numObjects := len(values) + 1
+	refSize := 1
+	if numObjects > 0xff {
+		refSize = 2
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(bplistHeader)
+	offsets := make([]int, 0, numObjects)
+
+	// Object 0 is the array referencing the string objects 1..n
+	offsets = append(offsets, buf.Len())
+	writeBplistMarker(&buf, 0xa0, len(values))
+	for i := range values {
+		writeBplistUint(&buf, uint64(i+1), refSize)
+	}
+
+	// Objects 1..n are the strings, ASCII when possible and UTF-16BE otherwise
+	for _, value := range values {
+		offsets = append(offsets, buf.Len())
+		if isASCII(value) {
+			writeBplistMarker(&buf, 0x50, len(value))
+			buf.WriteString(value)
+		} else {
+			units := utf16.Encode([]rune(value))
+			writeBplistMarker(&buf, 0x60, len(units))
+			for _, unit := range units {
+				writeBplistUint(&buf, uint64(unit), 2)
+			}
+		}
+	}
+
+	// Offset table
+	offsetTableOffset := buf.Len()
+	offsetSize := bplistIntSize(uint64(offsetTableOffset))
+	for _, offset := range offsets {
+		writeBplistUint(&buf, uint64(offset), offsetSize)
+	}
+
+	// Trailer
+	buf.Write(make([]byte, 6))
+	buf.WriteByte(byte(offsetSize))
+	buf.WriteByte(byte(refSize))
+	writeBplistUint(&buf, uint64(numObjects), 8)
+	writeBplistUint(&buf, 0, 8)
+	writeBplistUint(&buf, uint64(offsetTableOffset), 8)
+
+	return buf.Bytes()
+}
+
+// DecodeBplistStrings decodes a binary property list whose top level object is an array of strings
+func DecodeBplistStrings(data []byte) ([]string, error) {
+	if len(data) < len(bplistHeader)+32 || string(data[:len(bplistHeader)]) != bplistHeader {
+		return nil, errors.New("not a binary property list")
+	}
+
+	trailer := data[len(data)-32:]
+	offsetSize := int(trailer[6])
+	refSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:16])
+	topObject := binary.BigEndian.Uint64(trailer[16:24])
+	offsetTableOffset := binary.BigEndian.Uint64(trailer[24:32])
+
+	if offsetTableOffset+numObjects*uint64(offsetSize) > uint64(len(data)) {
+		return nil, errors.New("invalid binary property list offset table")
+	}
+
+	objectOffset := func(ref uint64) (int, error) {
+		if ref >= numObjects {
+			return 0, errors.New("invalid binary property list object reference")
+		}
+		start := offsetTableOffset + ref*uint64(offsetSize)
+		offset := readBplistUint(data[start : start+uint64(offsetSize)])
+		if offset >= uint64(len(data)) {
+			return 0, errors.New("invalid binary property list object offset")
+		}
+		return int(offset), nil
+	}
+
+	pos, err := objectOffset(topObject)
+	if err != nil {
+		return nil, err
+	}
+	if data[pos]&0xf0 != 0xa0 {
+		return nil, errors.New("binary property list top level object is not an array")
+	}
+	count, pos, err := readBplistLength(data, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		refStart := pos + i*refSize
+		if refStart+refSize > len(data) {
+			return nil, errors.New("truncated binary property list array")
+		}
+
+		strPos, err := objectOffset(readBplistUint(data[refStart : refStart+refSize]))
+		if err != nil {
+			return nil, err
+		}
+
+		marker := data[strPos] & 0xf0
+		length, strPos, err := readBplistLength(data, strPos)
+		if err != nil {
+			return nil, err
+		}
+
+		switch marker {
+		case 0x50:
+			if strPos+length > len(data) {
+				return nil, errors.New("truncated binary property list string")
+			}
+			values = append(values, string(data[strPos:strPos+length]))
+		case 0x60:
+			if strPos+length*2 > len(data) {
+				return nil, errors.New("truncated binary property list string")
+			}
+			units := make([]uint16, length)
+			for j := range units {
+				units[j] = binary.BigEndian.Uint16(data[strPos+j*2:])
+			}
+			values = append(values, string(utf16.Decode(units)))
+		default:
+			return nil, errors.New("binary property list array contains a non-string object")
+		}
+	}
+
+	return values, nil
+}
+
+// writeBplistMarker writes an object marker with its length, using an extra integer object for long lengths
+func writeBplistMarker(buf *bytes.Buffer, marker byte, length int) {
+	if length < 0x0f {
+		buf.WriteByte(marker | byte(length))
+		return
+	}
+
+	buf.WriteByte(marker | 0x0f)
+	size := bplistIntSize(uint64(length))
+	switch size {
+	case 1:
+		buf.WriteByte(0x10)
+	case 2:
+		buf.WriteByte(0x11)
+	case 4:
+		buf.WriteByte(0x12)
+	default:
+		buf.WriteByte(0x13)
+	}
+	writeBplistUint(buf, uint64(length), size)
+}
+
+// readBplistLength reads the length of the object at pos and returns the position of its payload
+func readBplistLength(data []byte, pos int) (int, int, error) {
+	length := int(data[pos] & 0x0f)
+	pos++
+	if length != 0x0f {
+		return length, pos, nil
+	}
+
+	if pos >= len(data) || data[pos]&0xf0 != 0x10 {
+		return 0, 0, errors.New("invalid binary property list length")
+	}
+	size := 1 << (data[pos] & 0x0f)
+	pos++
+	if pos+size > len(data) {
+		return 0, 0, errors.New("truncated binary property list length")
+	}
+	return int(readBplistUint(data[pos : pos+size])), pos + size, nil
+}
+
+// bplistIntSize returns the number of bytes needed to store v
+func bplistIntSize(v uint64) int {
+	switch {
+	case v <= 0xff:
+		return 1
+	case v <= 0xffff:
+		return 2
+	case v <= 0xffffffff:
+		return 4
+	default:
+		return 8
+	}
+}
+
+// writeBplistUint writes v as a big-endian unsigned integer of the given size
+func writeBplistUint(buf *bytes.Buffer, v uint64, size int) {
+	for i := size - 1; i >= 0; i-- {
+		buf.WriteByte(byte(v >> (8 * uint(i))))
+	}
+}
+
+// readBplistUint reads a big-endian unsigned integer
+func readBplistUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// isASCII checks if a string only contains ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+//go:build darwin
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// CloneSupported reports whether files can be replaced with APFS clones on this platform
+func CloneSupported() bool {
+	return true
+}
+
+// ReplaceWithClone replaces dst with an APFS clone of src, keeping the permissions, owner and timestamps of dst
+func ReplaceWithClone(src, dst string) error {
+	info, err := os.Lstat(dst)
+	if err != nil {
+		return err
+	}
+
+	// Clone next to the destination first, so dst is only replaced once the clone is complete
+	tmpPath := filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+".fsak-clone")
+	if err := unix.Clonefile(src, tmpPath, unix.CLONE_NOFOLLOW); err != nil {
+		return err
+	}
+
+	// Restore the metadata of the original file on the clone
+	if err := os.Chmod(tmpPath, info.Mode().Perm()); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	atime := info.ModTime()
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		atime = time.Unix(int64(stat.Atimespec.Sec), int64(stat.Atimespec.Nsec))
+		// Changing the owner needs privileges, ignore failures
+		_ = os.Lchown(tmpPath, int(stat.Uid), int(stat.Gid))
+	}
+	if err := os.Chtimes(tmpPath, atime, info.ModTime()); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, dst); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !darwin
+
+package util
+
+import (
+	"errors"
+)
+
+// CloneSupported reports whether files can be replaced with APFS clones on this platform
+func CloneSupported() bool {
+	return false
+}
+
+// ReplaceWithClone replaces dst with an APFS clone of src, which is only available on macOS
+func ReplaceWithClone(src, dst string) error {
+	return errors.New("APFS clones are only supported on macOS")
+}
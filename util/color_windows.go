@@ -0,0 +1,19 @@
+//go:build windows
+
+package util
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// enableColors turns on ANSI escape sequences for a console, older consoles without support report false
+func enableColors(file *os.File) bool {
+	handle := windows.Handle(file.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	return windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
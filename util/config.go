@@ -0,0 +1,305 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// configFileName is the name of the configuration file in the workspace directory
+const configFileName = "fsak.conf"
+
+// Config holds the settings of the configuration file
+type Config struct {
+	Language             string              // Language of the messages, such as en or zh
+	ReadOnly             bool                // Refuse the commands changing files, as --read-only does
+	AllowedPaths         []string            // Directories the commands changing files are restricted to, any when empty
+	CaseInsensitivePaths *bool               // Whether paths differing only in case are the same file, by platform when nil
+	DSN                  string              // PostgreSQL or MySQL server keeping the catalog instead of the SQLite file
+	Aliases              map[string]string   // Directories used as @name in path arguments
+	Profiles             map[string]*Profile // Named sets of settings selected with --profile or FSAK_PROFILE
+	TagRules             []TagRule           // Tags given to the matching files, the most specific rule first
+	Retention            []RetentionRule     // How long the records of tags and volumes are kept by db prune
+	KeepRules            []KeepRule          // Copies clean dup keeps of the duplicates, single extensions first
+
+	// Defaults for the flags of the commands by command path, such as "sync info", "" for every command
+	Defaults map[string]map[string]string
+}
+
+// RetentionRule expires the records of a tag or a volume that weren't synced for a while
+type RetentionRule struct {
+	Tag    string        // Tag of the records, empty for a volume rule
+	Volume string        // Volume ID or label of the records, empty for a tag rule
+	MaxAge time.Duration // Records last synced longer ago are pruned
+}
+
+// Profile holds the settings of a [profile.<name>] section
+type Profile struct {
+	Name      string
+	Workspace string            // Workspace directory used instead of the default one
+	DB        string            // Database file used instead of the one in the workspace
+	DSN       string            // PostgreSQL or MySQL server used instead of the database file
+	Flags     map[string]string // Defaults for the flags of the commands, such as threads or blacklist
+}
+
+var (
+	configOnce    sync.Once
+	loadedConfig  *Config
+	configErr     error
+	activeProfile *Profile
+)
+
+// GetConfigPath returns the path to the configuration file, which is in the default workspace because
+// profiles may move the workspace elsewhere. Without one there, the file in the fsak folder of the user
+// configuration directory, such as ~/.config/fsak, is used when it exists.
+func GetConfigPath() (string, error) {
+	wsDir, err := getDefaultWorkspaceDir()
+	if err != nil {
+		return "", err
+	}
+	configPath := filepath.Join(wsDir, configFileName)
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		if configDir, err := os.UserConfigDir(); err == nil {
+			userConfigPath := filepath.Join(configDir, "fsak", configFileName)
+			if _, err := os.Stat(userConfigPath); err == nil {
+				return userConfigPath, nil
+			}
+		}
+	}
+	return configPath, nil
+}
+
+// LoadConfig reads the configuration file once, a missing file gives an empty configuration
+func LoadConfig() (*Config, error) {
+	configOnce.Do(func() {
+		loadedConfig, configErr = readConfig()
+	})
+	return loadedConfig, configErr
+}
+
+// readConfig reads the configuration file
+func readConfig() (*Config, error) {
+	config := &Config{
+		Aliases:  make(map[string]string),
+		Profiles: make(map[string]*Profile),
+		Defaults: make(map[string]map[string]string),
+	}
+
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	sections, err := readConfigSections(configPath)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading config %s: %v", configPath, err)
+	}
+
+	config.Language = sections["general"]["language"]
+	config.DSN = sections["general"]["dsn"]
+	if value, ok := sections["general"]["read-only"]; ok {
+		if config.ReadOnly, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("invalid read-only setting %s in %s, expected true or false", value, configPath)
+		}
+	}
+	if value, ok := sections["general"]["case-insensitive-paths"]; ok {
+		caseInsensitive, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid case-insensitive-paths setting %s in %s, expected true or false", value, configPath)
+		}
+		config.CaseInsensitivePaths = &caseInsensitive
+	}
+	for _, dir := range filepath.SplitList(sections["general"]["allowed-paths"]) {
+		if dir = strings.TrimSpace(dir); dir == "" {
+			continue
+		}
+		// A relative path would allow a different directory in every working directory
+		absDir := filepath.Clean(expandHome(dir))
+		if !filepath.IsAbs(absDir) {
+			return nil, fmt.Errorf("invalid allowed path %s in %s, expected an absolute path", dir, configPath)
+		}
+		config.AllowedPaths = append(config.AllowedPaths, absDir)
+	}
+
+	for name, dir := range sections["aliases"] {
+		config.Aliases[name] = expandHome(dir)
+	}
+
+	for pattern, tag := range sections["tags"] {
+		config.TagRules = append(config.TagRules, newTagRule(pattern, tag))
+	}
+	sortTagRules(config.TagRules)
+
+	for selector, value := range sections["keep"] {
+		rule, err := newKeepRule(selector, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keep rule %s in %s: %v", selector, configPath, err)
+		}
+		config.KeepRules = append(config.KeepRules, rule)
+	}
+	sortKeepRules(config.KeepRules)
+
+	for key, value := range sections["retention"] {
+		kind, name, _ := strings.Cut(key, ":")
+		maxAge, err := ParseAge(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retention of %s in %s: %v", key, configPath, err)
+		}
+		rule := RetentionRule{MaxAge: maxAge}
+		switch {
+		case kind == "tag" && name != "":
+			rule.Tag = name
+		case kind == "volume" && name != "":
+			rule.Volume = name
+		default:
+			return nil, fmt.Errorf("invalid retention key %s in %s, expected tag:<tag> or volume:<volume>", key, configPath)
+		}
+		config.Retention = append(config.Retention, rule)
+	}
+	sort.Slice(config.Retention, func(i, j int) bool {
+		a, b := config.Retention[i], config.Retention[j]
+		if a.Tag != b.Tag {
+			return a.Tag < b.Tag
+		}
+		return a.Volume < b.Volume
+	})
+
+	for section, settings := range sections {
+		if section == "defaults" {
+			config.Defaults[""] = settings
+		} else if command, ok := strings.CutPrefix(section, "defaults."); ok && command != "" {
+			config.Defaults[strings.Join(strings.Fields(command), " ")] = settings
+		}
+
+		name, ok := strings.CutPrefix(section, "profile.")
+		if !ok || name == "" {
+			continue
+		}
+
+		profile := &Profile{Name: name, Flags: make(map[string]string)}
+		for key, value := range settings {
+			switch key {
+			case "workspace":
+				profile.Workspace = expandHome(value)
+			case "db":
+				profile.DB = expandHome(value)
+			case "dsn":
+				profile.DSN = value
+			default:
+				profile.Flags[key] = value
+			}
+		}
+		config.Profiles[name] = profile
+	}
+
+	return config, nil
+}
+
+// readConfigSections parses an INI-style file into the key = value pairs of its [sections].
+// Lines starting with # or ; are comments.
+func readConfigSections(path string) (map[string]map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sections := make(map[string]map[string]string)
+	section := ""
+	lineNumber := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNumber)
+		}
+		if sections[section] == nil {
+			sections[section] = make(map[string]string)
+		}
+		sections[section][strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return sections, scanner.Err()
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// ExpandPathAlias replaces a leading @name, as in @photos or @photos/2024, with the directory of the alias
+func ExpandPathAlias(path string) (string, error) {
+	if !strings.HasPrefix(path, "@") {
+		return path, nil
+	}
+
+	name, rest := path[1:], ""
+	if i := strings.IndexAny(name, `/\`); i >= 0 {
+		name, rest = name[:i], name[i+1:]
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return "", err
+	}
+
+	dir, ok := config.Aliases[name]
+	if !ok {
+		configPath, _ := GetConfigPath()
+		return "", fmt.Errorf("unknown path alias @%s, define it in the [aliases] section of %s", name, configPath)
+	}
+	if rest == "" {
+		return dir, nil
+	}
+	return filepath.Join(dir, rest), nil
+}
+
+// SetProfile selects the profile used for the workspace and database paths
+func SetProfile(name string) (*Profile, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	profile, ok := config.Profiles[name]
+	if !ok {
+		configPath, _ := GetConfigPath()
+		return nil, fmt.Errorf("unknown profile %s, define it in a [profile.%s] section of %s", name, name, configPath)
+	}
+
+	activeProfile = profile
+	return profile, nil
+}
+
+// GetProfile returns the selected profile, or nil when no profile is selected
+func GetProfile() *Profile {
+	return activeProfile
+}
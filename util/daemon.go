@@ -0,0 +1,11 @@
+package util
+
+// Daemon keeps an fsak command running in the background through the service manager of the system, restarted
+// when it fails
+type Daemon struct {
+	Name   string   // Name of the daemon, part of the names of its units
+	Args   []string // Arguments of the fsak command
+	Env    []string // KEY=value environment of the command
+	Dir    string   // Working directory of the command
+	System bool     // Install for the whole system instead of the current user
+}
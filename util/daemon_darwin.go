@@ -0,0 +1,79 @@
+//go:build darwin
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// daemonLabel returns the label of the launchd job of a daemon, which also names its plist
+func daemonLabel(name string) string {
+	return "com.github.baowuhe.fsakd." + name
+}
+
+// InstallDaemon writes a launchd plist starting the command at load and restarting it when it fails, and
+// loads it. The output goes to the logs directory of the workspace. It returns the path of the plist.
+func InstallDaemon(daemon Daemon) ([]string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("error finding the fsak executable: %v", err)
+	}
+	plistPath, err := launchdPlistPath(daemonLabel(daemon.Name), daemon.System)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(plistPath), 0755); err != nil {
+		return nil, fmt.Errorf("error creating %s: %v", filepath.Dir(plistPath), err)
+	}
+	wsDir, err := GetWorkspaceDir()
+	if err != nil {
+		return nil, err
+	}
+	logDir := filepath.Join(wsDir, "logs")
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating %s: %v", logDir, err)
+	}
+	logPath := filepath.Join(logDir, "daemon-"+daemon.Name+".log")
+
+	// A clean exit, such as after launchctl stop, isn't restarted, and restarts are throttled by 30s
+	keys := "\t<key>RunAtLoad</key>\n\t<true/>\n"
+	keys += "\t<key>KeepAlive</key>\n\t<dict>\n\t\t<key>SuccessfulExit</key>\n\t\t<false/>\n\t</dict>\n"
+	keys += "\t<key>ThrottleInterval</key>\n\t<integer>30</integer>\n"
+	plist := launchdPlist(daemonLabel(daemon.Name), append([]string{executable}, daemon.Args...), daemon.Env, daemon.Dir, logPath, keys)
+
+	// A daemon installed again runs its new command
+	if _, err := os.Stat(plistPath); err == nil {
+		if err := launchctl("unload", plistPath); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.WriteFile(plistPath, []byte(plist), 0644); err != nil {
+		return nil, fmt.Errorf("error writing %s: %v", plistPath, err)
+	}
+	if err := launchctl("load", "-w", plistPath); err != nil {
+		return nil, err
+	}
+	return []string{plistPath}, nil
+}
+
+// RemoveDaemon unloads the launchd job of a daemon, which stops it, and deletes its plist. It returns the
+// path of the plist.
+func RemoveDaemon(name string, system bool) ([]string, error) {
+	plistPath, err := launchdPlistPath(daemonLabel(name), system)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(plistPath); err != nil {
+		return nil, fmt.Errorf("no daemon %s in %s", name, filepath.Dir(plistPath))
+	}
+
+	if err := launchctl("unload", "-w", plistPath); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(plistPath); err != nil {
+		return nil, fmt.Errorf("error removing %s: %v", plistPath, err)
+	}
+	return []string{plistPath}, nil
+}
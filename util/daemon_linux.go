@@ -0,0 +1,94 @@
+//go:build linux
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// daemonUnit returns the name of the systemd service of a daemon
+func daemonUnit(name string) string {
+	return "fsakd-" + name + ".service"
+}
+
+// InstallDaemon writes a systemd service running the command and restarting it when it fails, and enables
+// and starts it. User services start with the first login, system ones at boot. It returns the path of the unit.
+func InstallDaemon(daemon Daemon) ([]string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("error finding the fsak executable: %v", err)
+	}
+	unitDir, err := systemdUnitDir(daemon.System)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(unitDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating %s: %v", unitDir, err)
+	}
+
+	command := []string{quoteSystemdArg(executable)}
+	for _, arg := range daemon.Args {
+		command = append(command, quoteSystemdArg(arg))
+	}
+	target := "default.target"
+	if daemon.System {
+		target = "multi-user.target"
+	}
+
+	var service strings.Builder
+	description := strings.ReplaceAll(strings.Join(daemon.Args, " "), "%", "%%")
+	fmt.Fprintf(&service, "[Unit]\nDescription=fsak daemon %s: %s\n\n", daemon.Name, description)
+	fmt.Fprintf(&service, "[Service]\nType=simple\nWorkingDirectory=%s\n", escapeSystemdSpecifiers(daemon.Dir))
+	for _, env := range daemon.Env {
+		fmt.Fprintf(&service, "Environment=%s\n", quoteSystemdEnv(env))
+	}
+	fmt.Fprintf(&service, "ExecStart=%s\n", strings.Join(command, " "))
+	fmt.Fprintf(&service, "Restart=on-failure\nRestartSec=30\n\n")
+	fmt.Fprintf(&service, "[Install]\nWantedBy=%s\n", target)
+
+	unit := daemonUnit(daemon.Name)
+	servicePath := filepath.Join(unitDir, unit)
+	if err := os.WriteFile(servicePath, []byte(service.String()), 0644); err != nil {
+		return nil, fmt.Errorf("error writing %s: %v", servicePath, err)
+	}
+
+	if err := systemctl(daemon.System, "daemon-reload"); err != nil {
+		return nil, err
+	}
+	// A daemon installed again runs its new command
+	if err := systemctl(daemon.System, "enable", unit); err != nil {
+		return nil, err
+	}
+	if err := systemctl(daemon.System, "restart", unit); err != nil {
+		return nil, err
+	}
+	return []string{servicePath}, nil
+}
+
+// RemoveDaemon stops and disables the service of a daemon and deletes it. It returns the path of the unit.
+func RemoveDaemon(name string, system bool) ([]string, error) {
+	unitDir, err := systemdUnitDir(system)
+	if err != nil {
+		return nil, err
+	}
+
+	unit := daemonUnit(name)
+	servicePath := filepath.Join(unitDir, unit)
+	if _, err := os.Stat(servicePath); err != nil {
+		return nil, fmt.Errorf("no daemon %s in %s", name, unitDir)
+	}
+
+	if err := systemctl(system, "disable", "--now", unit); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(servicePath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error removing %s: %v", servicePath, err)
+	}
+	if err := systemctl(system, "daemon-reload"); err != nil {
+		return nil, err
+	}
+	return []string{servicePath}, nil
+}
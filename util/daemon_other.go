@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !windows
+
+package util
+
+import (
+	"errors"
+)
+
+// InstallDaemon registers a daemon with the service manager, which is only supported with systemd on Linux,
+// launchd on macOS and the service manager of Windows
+func InstallDaemon(daemon Daemon) ([]string, error) {
+	return nil, errors.New("daemons are only supported on Linux (systemd), macOS (launchd) and Windows (services)")
+}
+
+// RemoveDaemon removes a daemon from the service manager, which is only supported with systemd on Linux,
+// launchd on macOS and the service manager of Windows
+func RemoveDaemon(name string, system bool) ([]string, error) {
+	return nil, errors.New("daemons are only supported on Linux (systemd), macOS (launchd) and Windows (services)")
+}
@@ -0,0 +1,174 @@
+//go:build windows
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// daemonService returns the name of the Windows service of a daemon, also the source of its events
+func daemonService(name string) string {
+	return "fsakd-" + name
+}
+
+// serviceCommandLine returns the command line of a service, quoted the way CreateService quotes it
+func serviceCommandLine(executable string, args []string) string {
+	commandLine := syscall.EscapeArg(executable)
+	for _, arg := range args {
+		commandLine += " " + syscall.EscapeArg(arg)
+	}
+	return commandLine
+}
+
+// stopService stops a running service and waits up to a minute for it to stop
+func stopService(service *mgr.Service) error {
+	status, err := service.Query()
+	if err != nil {
+		return err
+	}
+	if status.State == svc.Stopped {
+		return nil
+	}
+	if _, err := service.Control(svc.Stop); err != nil {
+		return fmt.Errorf("error stopping %s: %v", service.Name, err)
+	}
+	for deadline := time.Now().Add(time.Minute); status.State != svc.Stopped; {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s didn't stop within a minute", service.Name)
+		}
+		time.Sleep(500 * time.Millisecond)
+		if status, err = service.Query(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InstallDaemon registers a Windows service running the command, started at boot and restarted 30 seconds
+// after it fails, with its messages in the Application event log, and starts it. Services are installed for
+// the whole system by an administrator and run as LocalSystem, with the workspace of the install.
+func InstallDaemon(daemon Daemon) ([]string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("error finding the fsak executable: %v", err)
+	}
+	wsDir, err := GetWorkspaceDir()
+	if err != nil {
+		return nil, err
+	}
+
+	manager, err := mgr.Connect()
+	if err != nil {
+		if errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+			return nil, errors.New("installing a Windows service needs an administrator")
+		}
+		return nil, fmt.Errorf("error connecting to the service manager: %v", err)
+	}
+	defer manager.Disconnect()
+
+	name := daemonService(daemon.Name)
+	config := mgr.Config{
+		DisplayName:  "fsak daemon " + daemon.Name,
+		Description:  "Keeps the fsak records of watched directories up to date",
+		StartType:    mgr.StartAutomatic,
+		ErrorControl: mgr.ErrorNormal,
+	}
+
+	// A daemon installed again is stopped and runs its new command
+	service, err := manager.OpenService(name)
+	if err == nil {
+		if err := stopService(service); err != nil {
+			service.Close()
+			return nil, err
+		}
+		current, err := service.Config()
+		if err != nil {
+			service.Close()
+			return nil, err
+		}
+		current.DisplayName, current.Description, current.StartType = config.DisplayName, config.Description, config.StartType
+		current.BinaryPathName = serviceCommandLine(executable, daemon.Args)
+		if err := service.UpdateConfig(current); err != nil {
+			service.Close()
+			return nil, fmt.Errorf("error updating %s: %v", name, err)
+		}
+	} else {
+		service, err = manager.CreateService(name, executable, config, daemon.Args...)
+		if err != nil {
+			return nil, fmt.Errorf("error creating %s: %v", name, err)
+		}
+	}
+	defer service.Close()
+
+	// Services can't inherit an environment, it's read from their registry key
+	env := append([]string{serviceEnv + "=" + name, "FSAK_WS_DIR=" + wsDir}, daemon.Env...)
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Services\`+name, registry.SET_VALUE)
+	if err != nil {
+		return nil, fmt.Errorf("error opening the registry key of %s: %v", name, err)
+	}
+	err = key.SetStringsValue("Environment", env)
+	key.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error setting the environment of %s: %v", name, err)
+	}
+
+	// A failure is a non-zero exit of watch, not only a crash
+	recovery := []mgr.RecoveryAction{{Type: mgr.ServiceRestart, Delay: 30 * time.Second}}
+	if err := service.SetRecoveryActions(recovery, uint32((24 * time.Hour).Seconds())); err != nil {
+		return nil, fmt.Errorf("error setting the recovery of %s: %v", name, err)
+	}
+	if err := service.SetRecoveryActionsOnNonCrashFailures(true); err != nil {
+		return nil, fmt.Errorf("error setting the recovery of %s: %v", name, err)
+	}
+
+	eventlog.Remove(name)
+	if err := eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		return nil, fmt.Errorf("error registering the event source %s: %v", name, err)
+	}
+
+	if err := service.Start(); err != nil {
+		return nil, fmt.Errorf("error starting %s: %v", name, err)
+	}
+	return []string{`HKLM\SYSTEM\CurrentControlSet\Services\` + name}, nil
+}
+
+// RemoveDaemon stops the Windows service of a daemon, deletes it and its event source. It returns the
+// registry key of the service.
+func RemoveDaemon(name string, system bool) ([]string, error) {
+	manager, err := mgr.Connect()
+	if err != nil {
+		if errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+			return nil, errors.New("removing a Windows service needs an administrator")
+		}
+		return nil, fmt.Errorf("error connecting to the service manager: %v", err)
+	}
+	defer manager.Disconnect()
+
+	serviceName := daemonService(name)
+	service, err := manager.OpenService(serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("no daemon %s: %v", name, err)
+	}
+	defer service.Close()
+
+	if err := stopService(service); err != nil {
+		return nil, err
+	}
+	if err := service.Delete(); err != nil {
+		return nil, fmt.Errorf("error deleting %s: %v", serviceName, err)
+	}
+	if err := eventlog.Remove(serviceName); err != nil {
+		return nil, fmt.Errorf("error removing the event source %s: %v", serviceName, err)
+	}
+	return []string{`HKLM\SYSTEM\CurrentControlSet\Services\` + serviceName}, nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package util
+
+import (
+	"errors"
+)
+
+// BlockDedupeSupported reports whether block-level dedup is available on this platform
+func BlockDedupeSupported() bool {
+	return false
+}
+
+// DedupeFileRange shares the extents of src with dst, which is only available on Linux
+func DedupeFileRange(src, dst string) (int64, error) {
+	return 0, errors.New("block-level dedup is only supported on Linux")
+}
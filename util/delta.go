@@ -0,0 +1,248 @@
+package util
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"lukechampine.com/blake3"
+)
+
+// deltaBlockSize is the size of the blocks the existing destination file is split into
+const deltaBlockSize = 64 * 1024
+
+// deltaLiteralFlushSize is the amount of literal data buffered before it's written out
+const deltaLiteralFlushSize = 1024 * 1024
+
+// deltaBlock is the signature of a block of the existing destination file
+type deltaBlock struct {
+	Offset int64
+	Length int
+	Strong [16]byte
+}
+
+// rollingChecksum is the rsync weak checksum of a window of bytes
+type rollingChecksum struct {
+	a, b uint32
+	n    uint32
+}
+
+// newRollingChecksum calculates the weak checksum of a window of bytes
+func newRollingChecksum(window []byte) rollingChecksum {
+	var r rollingChecksum
+	r.n = uint32(len(window))
+	for i, c := range window {
+		r.a += uint32(c)
+		r.b += uint32(len(window)-i) * uint32(c)
+	}
+	return r
+}
+
+// Sum returns the 32-bit weak checksum
+func (r rollingChecksum) Sum() uint32 {
+	return (r.a & 0xffff) | (r.b&0xffff)<<16
+}
+
+// Roll removes the first byte of the window and appends a new byte
+func (r *rollingChecksum) Roll(out, in byte) {
+	r.a = r.a - uint32(out) + uint32(in)
+	r.b = r.b - r.n*uint32(out) + r.a
+}
+
+// Shrink removes the first byte of the window without appending a new one
+func (r *rollingChecksum) Shrink(out byte) {
+	r.a -= uint32(out)
+	r.b -= r.n * uint32(out)
+	r.n--
+}
+
+// strongBlockHash returns the strong checksum of a block
+func strongBlockHash(block []byte) [16]byte {
+	var sum [16]byte
+	hash := blake3.New(16, nil)
+	hash.Write(block)
+	copy(sum[:], hash.Sum(nil))
+	return sum
+}
+
+// deltaSignatures splits a file into blocks and indexes their signatures by weak checksum
+func deltaSignatures(f *os.File) (map[uint32][]deltaBlock, error) {
+	signatures := make(map[uint32][]deltaBlock)
+	buf := make([]byte, deltaBlockSize)
+	var offset int64
+	for {
+		n, err := io.ReadFull(f, buf)
+		if n > 0 {
+			weak := newRollingChecksum(buf[:n]).Sum()
+			signatures[weak] = append(signatures[weak], deltaBlock{
+				Offset: offset,
+				Length: n,
+				Strong: strongBlockHash(buf[:n]),
+			})
+			offset += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return signatures, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// DeltaCopy updates dst to match src rsync-style: blocks that already exist in the old dst are reused
+// and only the changed data is read from src. Returns the number of bytes transferred from src.
+// The transfer is restarted when it fails with a transient error, dst is only replaced once it's complete.
+func DeltaCopy(src, dst string) (int64, error) {
+	var transferred int64
+	err := Retry(src, func() error {
+		var err error
+		transferred, err = deltaCopy(src, dst)
+		return err
+	})
+	if err == nil {
+		CountBytesCopied(transferred)
+	}
+	return transferred, err
+}
+
+// deltaCopy performs a delta transfer in a single attempt
+func deltaCopy(src, dst string) (int64, error) {
+	oldFile, err := os.Open(dst)
+	if err != nil {
+		return 0, err
+	}
+	defer oldFile.Close()
+
+	signatures, err := deltaSignatures(oldFile)
+	if err != nil {
+		return 0, err
+	}
+
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer srcFile.Close()
+
+	srcInfo, err := srcFile.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	// Build the new version next to dst, then replace dst once it's complete
+	tmpFile, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".fsak-delta-*")
+	if err != nil {
+		return 0, err
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+	defer tmpFile.Close()
+
+	out := bufio.NewWriterSize(tmpFile, deltaLiteralFlushSize)
+	reader := bufio.NewReaderSize(srcFile, deltaLiteralFlushSize)
+	var transferred int64
+	blockBuf := make([]byte, deltaBlockSize)
+
+	// fillWindow reads up to a full block from src
+	fillWindow := func() ([]byte, error) {
+		window := make([]byte, deltaBlockSize, 2*deltaBlockSize)
+		n, err := io.ReadFull(reader, window)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = nil
+		}
+		return window[:n], err
+	}
+
+	window, err := fillWindow()
+	if err != nil {
+		return 0, err
+	}
+	checksum := newRollingChecksum(window)
+	eof := len(window) < deltaBlockSize
+
+	for len(window) > 0 {
+		// Look for a block of the old file matching the current window
+		if candidates, ok := signatures[checksum.Sum()]; ok {
+			strong := strongBlockHash(window)
+			matched := false
+			for _, block := range candidates {
+				if block.Length != len(window) || block.Strong != strong {
+					continue
+				}
+
+				// Reuse the block from the old file
+				if _, err := oldFile.ReadAt(blockBuf[:block.Length], block.Offset); err != nil && err != io.EOF {
+					return transferred, err
+				}
+				if _, err := out.Write(blockBuf[:block.Length]); err != nil {
+					return transferred, err
+				}
+				matched = true
+				break
+			}
+
+			if matched {
+				if window, err = fillWindow(); err != nil {
+					return transferred, err
+				}
+				checksum = newRollingChecksum(window)
+				eof = len(window) < deltaBlockSize
+				continue
+			}
+		}
+
+		// No match, emit the first byte of the window as literal data and slide the window
+		first := window[0]
+		if err := out.WriteByte(first); err != nil {
+			return transferred, err
+		}
+		transferred++
+
+		if !eof {
+			next, err := reader.ReadByte()
+			if err == nil {
+				window = append(window[1:], next)
+				checksum.Roll(first, next)
+				continue
+			}
+			if err != io.EOF {
+				return transferred, err
+			}
+			eof = true
+		}
+		window = window[1:]
+		checksum.Shrink(first)
+	}
+
+	if err := out.Flush(); err != nil {
+		return transferred, err
+	}
+
+	// Make sure the new version matches the source before replacing dst
+	tmpInfo, err := tmpFile.Stat()
+	if err != nil {
+		return transferred, err
+	}
+	if tmpInfo.Size() != srcInfo.Size() {
+		return transferred, errors.New("delta transfer produced a file of unexpected size")
+	}
+
+	if err := tmpFile.Chmod(srcInfo.Mode().Perm()); err != nil {
+		return transferred, err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		return transferred, err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return transferred, err
+	}
+
+	if err := os.Rename(tmpPath, dst); err != nil {
+		return transferred, err
+	}
+
+	return transferred, nil
+}
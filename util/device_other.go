@@ -0,0 +1,13 @@
+//go:build !unix
+
+package util
+
+import (
+	"os"
+)
+
+// DeviceID returns the ID of the filesystem a file lives on, which isn't known on this platform, so
+// mount points are never detected
+func DeviceID(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
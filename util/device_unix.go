@@ -0,0 +1,16 @@
+//go:build unix
+
+package util
+
+import (
+	"os"
+	"syscall"
+)
+
+// DeviceID returns the ID of the filesystem a file lives on, mount points are where it changes
+func DeviceID(info os.FileInfo) (uint64, bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Dev), true
+	}
+	return 0, false
+}
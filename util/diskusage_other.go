@@ -0,0 +1,13 @@
+//go:build !unix
+
+package util
+
+import (
+	"os"
+)
+
+// GetDiskUsage returns the space a file actually occupies on disk
+// On platforms without allocated block counts, this returns the apparent size
+func GetDiskUsage(info os.FileInfo) int64 {
+	return info.Size()
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package util
+
+import (
+	"os"
+	"syscall"
+)
+
+// GetDiskUsage returns the space a file actually occupies on disk, based on its allocated blocks.
+// This differs from the apparent size for sparse files and on compressed or deduplicated filesystems.
+func GetDiskUsage(info os.FileInfo) int64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		// st_blocks is always counted in 512-byte units
+		return int64(stat.Blocks) * 512
+	}
+
+	// Fallback to the apparent size if we can't get the allocated blocks
+	return info.Size()
+}
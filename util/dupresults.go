@@ -0,0 +1,119 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Formats of the duplicate finder results read by ReadDuplicateResults
+const (
+	ResultsRmlint     = "rmlint JSON"
+	ResultsJdupesJSON = "jdupes JSON"
+	ResultsJdupes     = "jdupes"
+)
+
+// rmlintEntry is an element of the array written by rmlint -o json. The first and the last elements are a
+// header and a footer without a type.
+type rmlintEntry struct {
+	Type     string `json:"type"`
+	Path     string `json:"path"`
+	Checksum string `json:"checksum"`
+}
+
+// jdupesResults is the object written by jdupes -j
+type jdupesResults struct {
+	MatchSets []struct {
+		FileList []struct {
+			FilePath string `json:"filePath"`
+		} `json:"fileList"`
+	} `json:"matchSets"`
+}
+
+// ReadDuplicateResults reads the duplicate groups found by another tool: the JSON output of rmlint
+// (-o json), the JSON output of jdupes (-j), or the plain output of jdupes and fdupes, which lists the
+// paths of each group on their own lines with an empty line between groups. It returns the groups with
+// the detected format.
+func ReadDuplicateResults(name string) ([][]string, string, error) {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		groups, err := parseRmlintResults(trimmed)
+		return groups, ResultsRmlint, err
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		groups, err := parseJdupesJSONResults(trimmed)
+		return groups, ResultsJdupesJSON, err
+	}
+	return parseJdupesResults(content), ResultsJdupes, nil
+}
+
+// parseRmlintResults groups the duplicate files of rmlint results by checksum, in the order of the results
+func parseRmlintResults(content []byte) ([][]string, error) {
+	var entries []rmlintEntry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("invalid rmlint results: %v", err)
+	}
+
+	var groups [][]string
+	index := make(map[string]int)
+	for _, entry := range entries {
+		// Other lint types, such as empty files or bad links, aren't duplicates
+		if entry.Type != "duplicate_file" || entry.Path == "" {
+			continue
+		}
+		i, ok := index[entry.Checksum]
+		if !ok {
+			i = len(groups)
+			index[entry.Checksum] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], entry.Path)
+	}
+	return groups, nil
+}
+
+// parseJdupesJSONResults returns the match sets of jdupes results
+func parseJdupesJSONResults(content []byte) ([][]string, error) {
+	var results jdupesResults
+	if err := json.Unmarshal(content, &results); err != nil {
+		return nil, fmt.Errorf("invalid jdupes results: %v", err)
+	}
+
+	var groups [][]string
+	for _, set := range results.MatchSets {
+		var group []string
+		for _, file := range set.FileList {
+			group = append(group, file.FilePath)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// parseJdupesResults returns the groups of plain jdupes or fdupes results, separated by empty lines
+func parseJdupesResults(content []byte) [][]string {
+	var groups [][]string
+	var group []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			if len(group) > 0 {
+				groups = append(groups, group)
+				group = nil
+			}
+			continue
+		}
+		group = append(group, line)
+	}
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
+	return groups
+}
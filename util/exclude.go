@@ -0,0 +1,15 @@
+package util
+
+// DefaultExcludeDirs contains VCS and package-manager internals, and the file versions kept by merges, that are
+// excluded from scans by default
+var DefaultExcludeDirs = []string{".git", ".hg", ".svn", ".bzr", "_darcs", "CVS", "node_modules", ".pnpm-store", ".yarn", VersionsDirName}
+
+// IsDefaultExcludedDir checks if a directory name is in the default exclusion set
+func IsDefaultExcludedDir(dirName string) bool {
+	for _, excluded := range DefaultExcludeDirs {
+		if dirName == excluded {
+			return true
+		}
+	}
+	return false
+}
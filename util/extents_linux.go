@@ -0,0 +1,108 @@
+//go:build linux
+
+package util
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	fsIocFiemap       = 0xC020660B // FS_IOC_FIEMAP
+	fiemapFlagSync    = 0x1        // FIEMAP_FLAG_SYNC
+	fiemapExtentLast  = 0x1        // FIEMAP_EXTENT_LAST
+	fiemapExtentBatch = 128
+)
+
+// fiemapExtent mirrors struct fiemap_extent from linux/fiemap.h
+type fiemapExtent struct {
+	Logical    uint64
+	Physical   uint64
+	Length     uint64
+	Reserved64 [2]uint64
+	Flags      uint32
+	Reserved   [3]uint32
+}
+
+// fiemapRequest mirrors struct fiemap from linux/fiemap.h with room for a batch of extents
+type fiemapRequest struct {
+	Start         uint64
+	Length        uint64
+	Flags         uint32
+	MappedExtents uint32
+	ExtentCount   uint32
+	Reserved      uint32
+	Extents       [fiemapExtentBatch]fiemapExtent
+}
+
+// ExtentsSupported reports whether file extents can be inspected on this platform
+func ExtentsSupported() bool {
+	return true
+}
+
+// fileExtents returns the physical extents of a file using the FS_IOC_FIEMAP ioctl
+func fileExtents(path string) ([]fiemapExtent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var extents []fiemapExtent
+	var start uint64
+	for {
+		req := fiemapRequest{
+			Start:       start,
+			Length:      ^uint64(0),
+			Flags:       fiemapFlagSync,
+			ExtentCount: fiemapExtentBatch,
+		}
+		_, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), fsIocFiemap, uintptr(unsafe.Pointer(&req)))
+		if errno != 0 {
+			return nil, errno
+		}
+
+		if req.MappedExtents == 0 {
+			return extents, nil
+		}
+
+		for _, extent := range req.Extents[:req.MappedExtents] {
+			extents = append(extents, extent)
+			if extent.Flags&fiemapExtentLast != 0 {
+				return extents, nil
+			}
+		}
+
+		last := req.Extents[req.MappedExtents-1]
+		start = last.Logical + last.Length
+	}
+}
+
+// SharesAllExtents reports whether two files are backed by exactly the same physical extents,
+// as is the case for reflink copies on Btrfs/XFS. Removing one of them frees no space.
+func SharesAllExtents(a, b string) (bool, error) {
+	extentsA, err := fileExtents(a)
+	if err != nil {
+		return false, err
+	}
+	extentsB, err := fileExtents(b)
+	if err != nil {
+		return false, err
+	}
+
+	if len(extentsA) == 0 || len(extentsA) != len(extentsB) {
+		return false, nil
+	}
+
+	for i := range extentsA {
+		if extentsA[i].Logical != extentsB[i].Logical ||
+			extentsA[i].Physical != extentsB[i].Physical ||
+			extentsA[i].Length != extentsB[i].Length {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
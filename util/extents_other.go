@@ -0,0 +1,17 @@
+//go:build !linux
+
+package util
+
+import (
+	"errors"
+)
+
+// ExtentsSupported reports whether file extents can be inspected on this platform
+func ExtentsSupported() bool {
+	return false
+}
+
+// SharesAllExtents reports whether two files are backed by the same physical extents, which is only available on Linux
+func SharesAllExtents(a, b string) (bool, error) {
+	return false, errors.New("extent inspection is only supported on Linux")
+}
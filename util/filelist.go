@@ -0,0 +1,59 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReadFileList reads a list of paths, one per line, from a file or from stdin when the name is "-".
+// With nul, the paths are separated by NUL bytes as written by find -print0, so they may contain newlines.
+// Empty entries are skipped.
+func ReadFileList(name string, nul bool) ([]string, error) {
+	var reader io.Reader = os.Stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var paths []string
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	if nul {
+		scanner.Split(scanNul)
+	}
+	for scanner.Scan() {
+		path := scanner.Text()
+		if !nul {
+			// Lists written on Windows end their lines with \r\n
+			path = strings.TrimSuffix(path, "\r")
+		}
+		if path == "" {
+			continue
+		}
+		paths = append(paths, path)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// scanNul is a bufio.SplitFunc returning the NUL-terminated entries of the input, the last one may lack the NUL
+func scanNul(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,21 @@
+package util
+
+import (
+	"mime"
+	"path/filepath"
+	"strings"
+)
+
+// FileType returns the lowercase extension of a file name, without the dot, and the MIME type it implies,
+// empty when they aren't known. A name starting with its only dot, such as .bashrc, has no extension.
+func FileType(name string) (ext string, mimeType string) {
+	ext = filepath.Ext(name)
+	if ext == name || len(ext) <= 1 {
+		return "", ""
+	}
+	ext = strings.ToLower(ext[1:])
+
+	// Parameters such as the charset of text types are left out
+	mimeType, _, _ = strings.Cut(mime.TypeByExtension("."+ext), ";")
+	return ext, strings.TrimSpace(mimeType)
+}
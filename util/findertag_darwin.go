@@ -0,0 +1,74 @@
+//go:build darwin
+
+package util
+
+import (
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// finderTagsXattr is the extended attribute Finder stores tags in
+const finderTagsXattr = "com.apple.metadata:_kMDItemUserTags"
+
+// FinderTagsSupported reports whether Finder tags can be read and written on this platform
+func FinderTagsSupported() bool {
+	return true
+}
+
+// ReadFinderTags returns the Finder tags of a file, without their color labels
+func ReadFinderTags(path string) ([]string, error) {
+	size, err := unix.Getxattr(path, finderTagsXattr, nil)
+	if err != nil {
+		if err == unix.ENOATTR {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	buf := make([]byte, size)
+	size, err = unix.Getxattr(path, finderTagsXattr, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := DecodeBplistStrings(buf[:size])
+	if err != nil {
+		return nil, err
+	}
+
+	// Tags are stored as "name\ncolor", only keep the name
+	tags := make([]string, 0, len(values))
+	for _, value := range values {
+		name, _, _ := strings.Cut(value, "\n")
+		tags = append(tags, name)
+	}
+
+	return tags, nil
+}
+
+// AddFinderTag adds a Finder tag to a file, keeping its existing tags
+func AddFinderTag(path string, tag string) error {
+	var values []string
+
+	size, err := unix.Getxattr(path, finderTagsXattr, nil)
+	if err == nil && size > 0 {
+		buf := make([]byte, size)
+		if size, err = unix.Getxattr(path, finderTagsXattr, buf); err == nil {
+			values, _ = DecodeBplistStrings(buf[:size])
+		}
+	} else if err != nil && err != unix.ENOATTR {
+		return err
+	}
+
+	for _, value := range values {
+		name, _, _ := strings.Cut(value, "\n")
+		if name == tag {
+			// Already tagged
+			return nil
+		}
+	}
+
+	values = append(values, tag)
+	return unix.Setxattr(path, finderTagsXattr, EncodeBplistStrings(values), 0)
+}
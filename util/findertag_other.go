@@ -0,0 +1,22 @@
+//go:build !darwin
+
+package util
+
+import (
+	"errors"
+)
+
+// FinderTagsSupported reports whether Finder tags can be read and written on this platform
+func FinderTagsSupported() bool {
+	return false
+}
+
+// ReadFinderTags returns the Finder tags of a file, which are only available on macOS
+func ReadFinderTags(path string) ([]string, error) {
+	return nil, errors.New("Finder tags are only supported on macOS")
+}
+
+// AddFinderTag adds a Finder tag to a file, which is only available on macOS
+func AddFinderTag(path string, tag string) error {
+	return errors.New("Finder tags are only supported on macOS")
+}
@@ -2,8 +2,10 @@ package util
 
 import (
 	"crypto/md5"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"hash/crc32"
 	"io"
 	"os"
@@ -84,9 +86,18 @@ func FileMD5CRC32(path string) (md5Str string, crc32Str string, err error) {
 		nil
 }
 
-// FileBlake3MD5 reads a file once and calculates both Blake3 and MD5 values
+// FileBlake3MD5 reads a file once and calculates both Blake3 and MD5 values, retrying on transient errors
 // Returns: Blake3 (hex string), MD5 (hex string), error
 func FileBlake3MD5(path string) (blake3Str string, md5Str string, err error) {
+	err = Retry(path, func() error {
+		blake3Str, md5Str, err = fileBlake3MD5(path)
+		return err
+	})
+	return blake3Str, md5Str, err
+}
+
+// fileBlake3MD5 calculates the Blake3 and MD5 values of a file in a single attempt
+func fileBlake3MD5(path string) (string, string, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return "", "", err
@@ -101,12 +112,95 @@ func FileBlake3MD5(path string) (blake3Str string, md5Str string, err error) {
 	mw := io.MultiWriter(blake3Hash, md5Hash)
 
 	// Copy entire file, underlying read happens only once
-	if _, err = io.Copy(mw, f); err != nil {
+	n, err := io.Copy(mw, f)
+	if err != nil {
 		return "", "", err
 	}
+	CountFileHashed(n)
 
 	// Return results
 	return hex.EncodeToString(blake3Hash.Sum(nil)),
 		hex.EncodeToString(md5Hash.Sum(nil)),
 		nil
 }
+
+// HashingWriter calculates the Blake3 and MD5 values of the bytes written to it, so a copy can hash the stream
+// it writes instead of reading the copy again
+type HashingWriter struct {
+	blake3 *blake3.Hasher
+	md5    hash.Hash
+}
+
+// NewHashingWriter creates a HashingWriter
+func NewHashingWriter() *HashingWriter {
+	return &HashingWriter{blake3: blake3.New(32, nil), md5: md5.New()}
+}
+
+// Write adds p to both hashes
+func (w *HashingWriter) Write(p []byte) (int, error) {
+	w.blake3.Write(p)
+	return w.md5.Write(p)
+}
+
+// Sums returns the Blake3 and MD5 values (hex strings) of the bytes written so far
+func (w *HashingWriter) Sums() (string, string) {
+	return hex.EncodeToString(w.blake3.Sum(nil)), hex.EncodeToString(w.md5.Sum(nil))
+}
+
+// quickHashSampleSize is the number of bytes sampled from the head and the tail of a file for a quick hash
+const quickHashSampleSize = 1024 * 1024
+
+// FileQuickHash calculates a quick Blake3 hash of a file from its size and its first and last 1MB.
+// Files with the same quick hash are only probably identical, as the middle of the files is not read.
+func FileQuickHash(path string) (string, error) {
+	var quickHash string
+	err := Retry(path, func() error {
+		var err error
+		quickHash, err = fileQuickHash(path)
+		return err
+	})
+	return quickHash, err
+}
+
+// fileQuickHash calculates the quick hash of a file in a single attempt
+func fileQuickHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	size := info.Size()
+
+	hash := blake3.New(32, nil) // 32-byte output with no key
+	var sizeBuf [8]byte
+	binary.LittleEndian.PutUint64(sizeBuf[:], uint64(size))
+	hash.Write(sizeBuf[:])
+
+	if size <= 2*quickHashSampleSize {
+		// Small files are hashed completely
+		n, err := io.Copy(hash, f)
+		if err != nil {
+			return "", err
+		}
+		CountFileHashed(n)
+	} else {
+		// Hash the head and the tail of the file
+		if _, err := io.CopyN(hash, f, quickHashSampleSize); err != nil {
+			return "", err
+		}
+		if _, err := f.Seek(size-quickHashSampleSize, io.SeekStart); err != nil {
+			return "", err
+		}
+		if _, err := io.CopyN(hash, f, quickHashSampleSize); err != nil {
+			return "", err
+		}
+		CountFileHashed(2 * quickHashSampleSize)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
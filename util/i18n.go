@@ -0,0 +1,56 @@
+package util
+
+import (
+	"os"
+	"strings"
+)
+
+// locale is the language of the messages, "en" or one of the catalogs
+var locale = "en"
+
+// catalogs maps a language to the translations of the English messages
+var catalogs = map[string]map[string]string{
+	"zh": zhMessages,
+}
+
+// DetectLocale returns the language selected by FSAK_LANG, the language setting of the config or the
+// system locale, in this order
+func DetectLocale() string {
+	if lang := os.Getenv("FSAK_LANG"); lang != "" {
+		return lang
+	}
+
+	// A broken config is reported by the command that needs it
+	if config, err := LoadConfig(); err == nil && config.Language != "" {
+		return config.Language
+	}
+
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if lang := os.Getenv(name); lang != "" {
+			return lang
+		}
+	}
+	return "en"
+}
+
+// SetLocale selects the language of the messages, such as zh or zh_CN.UTF-8.
+// Languages without a catalog fall back to English.
+func SetLocale(lang string) {
+	lang = strings.ToLower(lang)
+	for name := range catalogs {
+		if lang == name || strings.HasPrefix(lang, name+"_") || strings.HasPrefix(lang, name+"-") || strings.HasPrefix(lang, name+".") {
+			locale = name
+			return
+		}
+	}
+	locale = "en"
+}
+
+// T translates an English message or format string to the selected language, messages without a
+// translation are returned unchanged
+func T(message string) string {
+	if translated, ok := catalogs[locale][message]; ok {
+		return translated
+	}
+	return message
+}
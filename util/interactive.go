@@ -2,10 +2,41 @@ package util
 
 import (
 	"errors"
+	"os"
+	"runtime"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"golang.org/x/term"
 )
 
+// promptInput is where the answers to prompts are read from
+var promptInput = os.Stdin
+
+// PromptsFromTerminal reads the answers to prompts from the terminal, for commands reading data from stdin.
+// Without a terminal, prompts keep reading stdin and fail at its end.
+func PromptsFromTerminal() {
+	device := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		device = "CONIN$"
+	}
+
+	// Raw mode needs a handle that can also be written to
+	if tty, err := os.OpenFile(device, os.O_RDWR, 0); err == nil {
+		promptInput = tty
+	}
+}
+
+// CanPrompt reports whether prompts can be answered, which needs a terminal
+func CanPrompt() bool {
+	return term.IsTerminal(int(promptInput.Fd()))
+}
+
+// IsInterrupted reports whether a prompt failed because the user pressed Ctrl-C
+func IsInterrupted(err error) bool {
+	return errors.Is(err, terminal.InterruptErr)
+}
+
 // SelectOne prompts the user to select one option from a list
 func SelectOne(message string, options []string) (string, error) {
 	if len(options) == 0 {
@@ -14,11 +45,11 @@ func SelectOne(message string, options []string) (string, error) {
 
 	var result string
 	prompt := &survey.Select{
-		Message: message,
+		Message: T(message),
 		Options: options,
 	}
 
-	err := survey.AskOne(prompt, &result)
+	err := survey.AskOne(prompt, &result, survey.WithStdio(promptInput, messages, os.Stderr))
 	if err != nil {
 		return "", err
 	}
@@ -34,11 +65,11 @@ func SelectMultiple(message string, options []string) ([]string, error) {
 
 	var result []string
 	prompt := &survey.MultiSelect{
-		Message: message,
+		Message: T(message),
 		Options: options,
 	}
 
-	err := survey.AskOne(prompt, &result)
+	err := survey.AskOne(prompt, &result, survey.WithStdio(promptInput, messages, os.Stderr))
 	if err != nil {
 		return nil, err
 	}
@@ -50,11 +81,11 @@ func SelectMultiple(message string, options []string) ([]string, error) {
 func Confirm(message string, defaultVal bool) (bool, error) {
 	var result bool
 	prompt := &survey.Confirm{
-		Message: message,
+		Message: T(message),
 		Default: defaultVal,
 	}
 
-	err := survey.AskOne(prompt, &result)
+	err := survey.AskOne(prompt, &result, survey.WithStdio(promptInput, messages, os.Stderr))
 	if err != nil {
 		return false, err
 	}
@@ -66,11 +97,11 @@ func Confirm(message string, defaultVal bool) (bool, error) {
 func Input(message string, defaultVal string) (string, error) {
 	var result string
 	prompt := &survey.Input{
-		Message: message,
+		Message: T(message),
 		Default: defaultVal,
 	}
 
-	err := survey.AskOne(prompt, &result)
+	err := survey.AskOne(prompt, &result, survey.WithStdio(promptInput, messages, os.Stderr))
 	if err != nil {
 		return "", err
 	}
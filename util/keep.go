@@ -0,0 +1,121 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Policies of the [keep] section, choosing the copy of a duplicate group clean dup keeps
+const (
+	KeepLargest  = "largest"
+	KeepSmallest = "smallest"
+	KeepNewest   = "newest"
+	KeepOldest   = "oldest"
+	KeepUnder    = "under"
+)
+
+// keepCategories are the groups of extensions a [keep] rule can name instead of a single extension
+var keepCategories = map[string][]string{
+	"images":    {"jpg", "jpeg", "png", "gif", "bmp", "tif", "tiff", "webp", "heic", "heif", "avif", "raw", "cr2", "cr3", "nef", "arw", "dng", "orf", "rw2"},
+	"videos":    {"mp4", "mov", "m4v", "avi", "mkv", "wmv", "webm", "mpg", "mpeg", "mts", "m2ts", "3gp", "flv"},
+	"audio":     {"mp3", "m4a", "aac", "flac", "wav", "ogg", "opus", "wma", "aiff", "alac"},
+	"documents": {"pdf", "doc", "docx", "odt", "rtf", "txt", "md", "xls", "xlsx", "ods", "csv", "ppt", "pptx", "odp", "pages", "numbers", "key", "epub"},
+	"archives":  {"zip", "rar", "7z", "tar", "gz", "tgz", "bz2", "xz", "zst", "iso", "dmg"},
+}
+
+// KeepRule chooses the copy clean dup keeps of the duplicate groups of an extension or a category of
+// extensions, set in the [keep] section of the config
+type KeepRule struct {
+	Selector   string // Extension with its dot, such as .jpg, or a category, such as documents
+	Policy     string // One of the Keep policies
+	Dir        string // Directory of the under policy, canonical
+	extensions []string
+}
+
+// newKeepRule parses a key = policy line of the [keep] section
+func newKeepRule(selector string, value string) (KeepRule, error) {
+	rule := KeepRule{Selector: selector}
+
+	if ext, ok := strings.CutPrefix(selector, "."); ok && ext != "" {
+		rule.extensions = []string{strings.ToLower(ext)}
+	} else if extensions, ok := keepCategories[strings.ToLower(selector)]; ok {
+		rule.extensions = extensions
+	} else {
+		return rule, fmt.Errorf("unknown extension or category %s, expected .<ext> or one of images, videos, audio, documents, archives", selector)
+	}
+
+	policy, dir, _ := strings.Cut(value, ":")
+	switch policy = strings.ToLower(strings.TrimSpace(policy)); policy {
+	case KeepLargest, KeepSmallest, KeepNewest, KeepOldest:
+	case KeepUnder:
+		if dir = strings.TrimSpace(dir); dir == "" {
+			return rule, fmt.Errorf("missing directory in %s, expected under:<dir>", value)
+		}
+		absDir, err := CanonicalPath(expandHome(dir))
+		if err != nil {
+			return rule, fmt.Errorf("invalid directory %s: %v", dir, err)
+		}
+		rule.Dir = absDir
+	default:
+		return rule, fmt.Errorf("unknown policy %s, expected largest, smallest, newest, oldest or under:<dir>", value)
+	}
+	rule.Policy = policy
+
+	return rule, nil
+}
+
+// Matches reports whether the rule applies to a file name
+func (r KeepRule) Matches(name string) bool {
+	ext, _ := FileType(name)
+	for _, candidate := range r.extensions {
+		if ext == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUnderDir reports whether a canonical path is below the directory of an under rule
+func (r KeepRule) IsUnderDir(path string) bool {
+	if r.Policy != KeepUnder {
+		return false
+	}
+	if CaseInsensitivePaths() {
+		return isWithin(strings.ToLower(path), strings.ToLower(r.Dir))
+	}
+	return isWithin(path, r.Dir)
+}
+
+// String describes the rule as it is written in the config
+func (r KeepRule) String() string {
+	if r.Policy == KeepUnder {
+		return fmt.Sprintf("%s = %s:%s", r.Selector, r.Policy, r.Dir)
+	}
+	return fmt.Sprintf("%s = %s", r.Selector, r.Policy)
+}
+
+// sortKeepRules orders the rules so the rules of a single extension come before the categories
+func sortKeepRules(rules []KeepRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		if len(rules[i].extensions) != len(rules[j].extensions) {
+			return len(rules[i].extensions) < len(rules[j].extensions)
+		}
+		return rules[i].Selector < rules[j].Selector
+	})
+}
+
+// KeepRuleFor returns the rule of the config applying to a file name, nil when none does
+func KeepRuleFor(name string) *KeepRule {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil
+	}
+
+	for i := range config.KeepRules {
+		if config.KeepRules[i].Matches(name) {
+			return &config.KeepRules[i]
+		}
+	}
+	return nil
+}
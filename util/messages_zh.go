@@ -0,0 +1,923 @@
+package util
+
+// zhMessages holds the Chinese translations of the messages, prompts and help texts.
+// The keys are the English format strings, the translations must keep their verbs in the same order.
+var zhMessages = map[string]string{
+	// Commands
+	"File System Swiss Army Knife":                                                "文件系统瑞士军刀",
+	"A command-line tool for enhanced file management operations.":                "一个增强文件管理操作的命令行工具。",
+	"Print the version number":                                                    "显示版本号",
+	"Print the version number of fsak.":                                           "显示 fsak 的版本号。",
+	"Calculate MD5 and Blake3 hash values of a file":                              "计算文件的 MD5 和 Blake3 哈希值",
+	"Calculate MD5 and Blake3 hash values of a file with a single read operation": "只读取一次文件，同时计算其 MD5 和 Blake3 哈希值",
+	"Synchronize file information":                                                "同步文件信息",
+	"Commands for synchronizing file information to database.":                    "将文件信息同步到数据库的命令。",
+	"Get file information and sync to database":                                   "获取文件信息并同步到数据库",
+	"Traverse one or more directories and their subdirectories, read file information, calculate MD5 and Blake3 values, and synchronize to SQLite database.": "遍历一个或多个目录及其子目录，读取文件信息，计算 MD5 和 Blake3 值，并同步到 SQLite 数据库。",
+	"Clean operations for database":                                                      "数据库清理操作",
+	"Commands for cleaning database entries and files.":                                  "清理数据库记录和文件的命令。",
+	"Clean file_infos table by removing records where path points to non-existent files": "清理 file_infos 表中路径指向不存在文件的记录",
+	"Traverse the file_infos table and remove records where the path field points to files that no longer exist. Records on volumes that are not mounted are skipped, so the catalog of an unplugged drive is kept.\n\nIn a catalog shared by several users or machines, only the records created by the current user@host are checked, as the files of the others may live on disks this machine can't see, unless --all-owners is given.": "遍历 file_infos 表，删除 path 字段指向已不存在文件的记录。未挂载卷上的记录会被跳过，因此已拔出的磁盘的目录会被保留。\n\n在多个用户或机器共享的目录中，只检查当前 user@host 创建的记录，因为其他人的文件可能位于本机看不到的磁盘上，除非指定 --all-owners。",
+	"Find and remove duplicate files": "查找并删除重复文件",
+	"Find duplicate files in specified folder paths using MD5 and Blake3 values, or handle the duplicate groups found by rmlint or jdupes with --import-results.": "使用 MD5 和 Blake3 值在指定文件夹中查找重复文件，或使用 --import-results 处理 rmlint 或 jdupes 找到的重复文件组。",
+	"Remove dirty files from specified folders": "删除指定文件夹中的垃圾文件",
+	"Remove dirty files from specified folder paths based on user selection. Dirty files are defined as: files with 0 size, files smaller than 1KB (configurable with --small-size), .DS_Store files on macOS, Thumbs.db files on Windows, empty folders, and regenerable build artifacts (node_modules, __pycache__, target, .cache).": "根据用户的选择删除指定文件夹中的垃圾文件。垃圾文件包括：大小为 0 的文件、小于 1KB 的文件（可用 --small-size 设置）、macOS 的 .DS_Store 文件、Windows 的 Thumbs.db 文件、空文件夹，以及可重新生成的构建产物（node_modules、__pycache__、target、.cache）。",
+	"Find and remove reclaimable developer caches": "查找并删除可回收的开发缓存",
+	"Detect developer caches (node_modules, .venv, vendor, Cargo target, Gradle caches) by their project marker files, show the size of each project's cache, and move the selected ones to the deleted folder.": "通过项目标志文件识别开发缓存（node_modules、.venv、vendor、Cargo target、Gradle 缓存），显示每个项目缓存的大小，并将选中的缓存移动到删除文件夹。",
+	"Share storage between duplicate files without removing them": "让重复文件共享存储空间而不删除它们",
+	"Find duplicate files in specified folder paths using MD5 and Blake3 values and let the filesystem share their storage. With --block, the kernel dedup ioctl (FIDEDUPERANGE) shares extents between identical files on Btrfs/XFS. The kernel verifies the contents are identical, so both paths keep working.\n\nWith --symlink, one canonical copy of each content is kept, the one in the earliest folder given, and the other copies are replaced with symlinks to it, which works on any filesystem and keeps every path browseable.": "使用 MD5 和 Blake3 值在指定文件夹中查找重复文件，并让文件系统共享它们的存储空间。使用 --block 时，内核去重 ioctl（FIDEDUPERANGE）会在 Btrfs/XFS 上让相同文件共享数据块。内核会校验内容是否一致，因此两个路径都能继续使用。\n\n使用 --symlink 时，每种内容只保留一个规范副本（位于最先给出的文件夹中），其他副本替换为指向它的符号链接，适用于任何文件系统，且所有路径都能继续浏览。",
+	"Merge files from source directory to target directory": "将源目录中的文件合并到目标目录",
+	"Commands for merging files between directories.":       "在目录之间合并文件的命令。",
+	"Traverse source and target directories, calculate MD5 and Blake3 values, and copy files that don't exist in target based on these values.":                                                                                                        "遍历源目录和目标目录，计算 MD5 和 Blake3 值，并根据这些值复制目标目录中不存在的文件。",
+	"Browse the files of synced volumes, even when they are not mounted":                                                                                                                                                                               "浏览已同步卷上的文件，即使卷未挂载",
+	"Commands for listing, searching and planning deduplication of the files recorded for a volume. Everything is read from the database, so the volume doesn't need to be plugged in. A volume is selected by its ID (filesystem UUID) or its label.": "列出、搜索卷上已记录的文件并规划去重的命令。所有内容都从数据库读取，因此无需插入该卷。卷可以用其 ID（文件系统 UUID）或卷标指定。",
+	"List known volumes, or the files recorded for a volume":                                                                                                                                                                                           "列出已知的卷，或某个卷上已记录的文件",
+	"Search the files recorded for a volume": "搜索某个卷上已记录的文件",
+	"Search the files recorded for a volume by matching a regular expression against their path relative to the volume's mount point.": "用正则表达式匹配文件相对于卷挂载点的路径，搜索该卷上已记录的文件。",
+	"Plan which duplicate files to remove from a volume": "规划要从某个卷中删除的重复文件",
+	"Find duplicate files recorded for a volume using their MD5 and Blake3 values and print which copy of each group to keep and which to remove, so the cleanup can be planned while the drive is shelved.\n\nBy default the copy with the shortest path is kept. With --keep context, the copy in the directory holding the most files of the volume is kept, so the copy inside an organized album wins over a stray one in Downloads.": "使用 MD5 和 Blake3 值查找某个卷上已记录的重复文件，并列出每组中要保留和要删除的副本，这样在磁盘未连接时也能规划清理。\n\n默认保留路径最短的副本。使用 --keep context 时，保留卷中文件最多的目录里的副本，因此整理好的相册中的副本优先于 Downloads 中零散的副本。",
+	"Inspect duplicate files recorded in the database": "查看数据库中记录的重复文件",
+	"Commands for inspecting duplicate files using the MD5 and Blake3 values recorded by sync info. These commands never walk directories, prompt or move files.": "使用 sync info 记录的 MD5 和 Blake3 值查看重复文件的命令。这些命令不会遍历目录、询问或移动文件。",
+	"List duplicate groups from the database": "列出数据库中的重复文件组",
+	"Print the duplicate groups recorded in the database with their paths, sizes and reclaimable bytes. This is read-only: no directories are walked, nothing is asked and no files are moved.": "显示数据库中记录的重复文件组及其路径、大小和可回收的字节数。此命令只读：不遍历目录、不询问、也不移动文件。",
+	"Show the recorded versions of a file": "显示文件的历史版本",
+	"Show the previous versions of a file recorded by sync info, with their hashes, sizes and modification times, to see when a file changed or to investigate bitrot.": "显示 sync info 记录的文件历史版本及其哈希值、大小和修改时间，用于查看文件何时发生变化或排查数据损坏。",
+	"Start an interactive shell for exploratory cleanup sessions": "启动交互式 shell，用于探索式清理",
+	"Start an interactive shell where every fsak command can be run without the fsak prefix, for example catalog search, dup list, history or clean dup. Lines can be recalled with the up and down arrows and commands, flags and paths are completed with Tab. The shell also has the builtins cd, tag, help and exit.": "启动一个交互式 shell，可以不加 fsak 前缀运行所有 fsak 命令，例如 catalog search、dup list、history 或 clean dup。可用上下方向键调出历史输入，用 Tab 补全命令、选项和路径。shell 还提供内置命令 cd、tag、help 和 exit。",
+
+	"Report files with the same name but different content": "报告同名但内容不同的文件",
+	"Report the files under the specified directories that share their name, or with --by path their path relative to the directory, but have different MD5 and Blake3 values. These are probable versioning conflicts, which deduplication by content ignores. The values recorded by sync info are used, nothing is read from disk.": "报告指定目录下文件名相同（使用 --by path 时为相对目录的路径相同）但 MD5 和 Blake3 值不同的文件。这些很可能是版本冲突，按内容去重会忽略它们。只使用 sync info 记录的值，不读取磁盘。",
+
+	// Flags
+	"Compare files by name or by path relative to their directory":                                              "按文件名或相对目录的路径比较文件",
+	"Columns to show, in order (group, size, modified, blake3, path)":                                           "要显示的列及顺序（group、size、modified、blake3、path）",
+	"Number of threads for calculation":                                                                         "计算使用的线程数",
+	"Tag for this batch of sync data":                                                                           "本批同步数据的标签",
+	"Force overwrite existing data":                                                                             "强制覆盖已有数据",
+	"Blacklist file containing paths to exclude (supports regex)":                                               "包含要排除路径的黑名单文件（支持正则表达式）",
+	"Number of records to batch update to SQLite database":                                                      "批量写入 SQLite 数据库的记录数",
+	"Read macOS Finder tags into the database (macOS only)":                                                     "将 macOS Finder 标签读入数据库（仅限 macOS）",
+	"Only calculate a quick hash from the size and the first/last 1MB of each file (matches are probabilistic)": "只根据文件大小和首尾各 1MB 计算快速哈希（匹配结果是概率性的）",
+	"Calculate a quick hash from the size and the first/last 1MB of the file":                                   "根据文件大小和首尾各 1MB 计算快速哈希",
+	"Directory to move deleted files to (default is workspace/deleted)":                                         "删除的文件移动到的目录（默认为 workspace/deleted）",
+	"Directory to move deleted caches to (default is workspace/deleted)":                                        "删除的缓存移动到的目录（默认为 workspace/deleted）",
+	"Directory to move deleted files to (required when not using --list or --recycle-bin)":                      "删除的文件移动到的目录（未使用 --list 或 --recycle-bin 时必填）",
+	"Send deleted files to the Windows Recycle Bin instead of the deleted folder":                               "将删除的文件放入 Windows 回收站，而不是删除文件夹",
+	"Send deleted files to the Windows Recycle Bin instead of the delete directory":                             "将删除的文件放入 Windows 回收站，而不是删除目录",
+	"Send deleted caches to the Windows Recycle Bin instead of the deleted folder":                              "将删除的缓存放入 Windows 回收站，而不是删除文件夹",
+	"Mark selected duplicates with a macOS Finder tag instead of removing them (macOS only)":                    "用 macOS Finder 标签标记选中的重复文件，而不是删除它们（仅限 macOS）",
+	"Replace selected duplicates with APFS clones of a kept file instead of removing them (macOS only)":         "用保留文件的 APFS 克隆替换选中的重复文件，而不是删除它们（仅限 macOS）",
+	"Write the moves to a shell script (PowerShell for .ps1 files) for review instead of performing them":       "将移动操作写入 shell 脚本（.ps1 文件为 PowerShell）以供检查，而不是直接执行",
+	"Skip duplicate groups whose files already share all extents (reflink copies)":                              "跳过所有文件已共享全部数据块的重复组（reflink 副本）",
+	"Group files by size and quick hash (first/last 1MB), selected groups are fully verified before any action": "按大小和快速哈希（首尾各 1MB）分组，选中的组在执行任何操作前都会完整校验",
+	"Memory limit (e.g. 512M, 2G), duplicate groups are moved to a temporary database when it's approached":     "内存上限（如 512M、2G），接近上限时重复组会移到临时数据库中",
+	"Only consider files whose names match this regular expression (e.g. '(?i)\\.(cr2|nef|arw)$')":              "只考虑文件名匹配此正则表达式的文件（如 '(?i)\\.(cr2|nef|arw)$'）",
+	"List dirty files only, don't delete":                                                                       "只列出垃圾文件，不删除",
+	"List developer caches only, don't delete":                                                                  "只列出开发缓存，不删除",
+	"Ask for a separate confirmation for each dirty file type":                                                  "对每种垃圾文件类型分别确认",
+	"Safe-list file containing paths that are never treated as dirty (supports regex)":                          "包含永不视为垃圾文件的路径的白名单文件（支持正则表达式）",
+	"Size in bytes below which files are treated as small files (0 disables the rule)":                          "小于此字节数的文件视为小文件（0 表示禁用此规则）",
+	"With --list, print the dirty paths to stdout separated by NUL bytes for xargs -0, messages go to stderr":   "与 --list 一起使用，将垃圾文件路径以 NUL 分隔输出到标准输出供 xargs -0 使用，提示信息输出到标准错误",
+	"Print the duplicate paths to stdout separated by NUL bytes for xargs -0, messages go to stderr":            "将重复文件路径以 NUL 分隔输出到标准输出供 xargs -0 使用，提示信息输出到标准错误",
+	"Share extents between identical files with the kernel dedup ioctl (Btrfs/XFS, Linux only)":                 "通过内核去重 ioctl 让相同文件共享数据块（Btrfs/XFS，仅限 Linux）",
+	"Source directory to merge from (required)":                                                                 "要合并的源目录（必填）",
+	"Target directory to merge to (required)":                                                                   "合并到的目标目录（必填）",
+	"Update older files at the same path in target from newer ones with different content, transferring only changed blocks and keeping the older version in .fsak-versions": "用较新的文件更新目标中同一路径下内容不同的较旧文件，只传输变化的数据块，并把较旧的版本保留在 .fsak-versions 中",
+	"Only consider files synced with this tag":                                                                "只考虑以此标签同步的文件",
+	"Only consider files under this path":                                                                     "只考虑此路径下的文件",
+	"Only consider files of at least this many bytes":                                                         "只考虑不小于此字节数的文件",
+	"Only show changes since this date (YYYY-MM-DD)":                                                          "只显示此日期（YYYY-MM-DD）之后的变化",
+	"Print messages without colors (colors are also off when NO_COLOR is set or the output isn't a terminal)": "输出不带颜色的信息（设置了 NO_COLOR 或输出不是终端时也不使用颜色）",
+	"Don't exclude VCS and package-manager internals (.git, .hg, .svn, node_modules, ...) from scans":         "扫描时不排除版本控制和包管理器的内部目录（.git、.hg、.svn、node_modules 等）",
+	"Write every skipped or unreadable path with the reason to this file":                                     "将每个跳过或无法读取的路径及原因写入此文件",
+	"Number of times a read or copy failing with a transient I/O error is retried":                            "读取或复制遇到临时 I/O 错误时的重试次数",
+	"Delay before the first retry, doubled for every further retry":                                           "第一次重试前的等待时间，之后每次重试翻倍",
+	"Use the workspace, database and option defaults of this config profile (default $FSAK_PROFILE)":          "使用此配置方案的工作区、数据库和选项默认值（默认为 $FSAK_PROFILE）",
+	"Serve pprof endpoints and runtime metrics on this address (e.g. localhost:6060)":                         "在此地址提供 pprof 端点和运行时指标（如 localhost:6060）",
+	"Write a CPU profile to this file":                                                                        "将 CPU 性能分析写入此文件",
+	"Write a heap profile to this file when the command finishes":                                             "命令结束时将堆内存分析写入此文件",
+
+	"Columns to show, in order (group, size, reclaimable, modified, tag, path)":                       "要显示的列及顺序（group、size、reclaimable、modified、tag、path）",
+	"Columns to show, in order (replaced, modified, size, blake3)":                                    "要显示的列及顺序（replaced、modified、size、blake3）",
+	"Columns to show, in order (volumes: label, id, files, size; files: path, size, modified, owner)": "要显示的列及顺序（卷：label、id、files、size；文件：path、size、modified、owner）",
+	"Columns to show, in order (path, size, modified, owner)":                                         "要显示的列及顺序（path、size、modified、owner）",
+	"Columns to show, in order (group, action, size, path)":                                           "要显示的列及顺序（group、action、size、path）",
+
+	"Columns to show, in order (version, modified, size, path)":                                                             "要显示的列及顺序（version、modified、size、path）",
+	"Keep only the newest N previous versions of each file replaced by --update or --delta in .fsak-versions (0 keeps all)": "在 .fsak-versions 中只为每个被 --update 或 --delta 替换的文件保留最新的 N 个旧版本（0 表示全部保留）",
+	"Version to restore, as shown by versions list (default: the newest)":                                                   "要恢复的版本，即 versions list 显示的名称（默认：最新版本）",
+
+	// Table headers and cells
+	"GROUP":       "组",
+	"SIZE":        "大小",
+	"RECLAIMABLE": "可回收",
+	"MODIFIED":    "修改时间",
+	"OWNER":       "所有者",
+	"TAG":         "标签",
+	"PATH":        "路径",
+	"REPLACED":    "替换时间",
+	"BLAKE3":      "BLAKE3",
+	"LABEL":       "卷标",
+	"ID":          "ID",
+	"FILES":       "文件数",
+	"ACTION":      "操作",
+	"VERSION":     "版本",
+	"CHANGE":      "变化",
+	"added":       "新增",
+	"removed":     "删除",
+	"changed":     "修改",
+	"current":     "当前",
+	"keep":        "保留",
+	"remove":      "删除",
+
+	"Read the files to process from this file, one per line, or from stdin with -":                         "从此文件读取要处理的文件，每行一个，使用 - 时从标准输入读取",
+	"Read the files to process from this file separated by NUL bytes (find -print0), or from stdin with -": "从此文件读取以 NUL 分隔的要处理的文件（find -print0），使用 - 时从标准输入读取",
+
+	"Where copied files go: dated (a FSAK_<YYMMdd> folder) or mirror (their original relative path, renamed on collisions)":             "复制文件的存放方式：dated（FSAK_<YYMMdd> 文件夹）或 mirror（原相对路径，重名时改名）",
+	"For files at the same path in both trees with different content, keep the newer one in target and the older one in .fsak-versions": "对于两边同一路径下内容不同的文件，在目标中保留较新的一个，较旧的放入 .fsak-versions",
+	"Only report the source files missing from the target, without copying anything":                                                    "只报告目标中缺少的源文件，不复制任何文件",
+
+	// Prompts
+	"Select types of dirty files to clean:":                                                 "选择要清理的垃圾文件类型：",
+	"Select files to delete from %s (use space to select multiple, enter to confirm):":      "选择要从 %s 中删除的文件（空格多选，回车确认）：",
+	"Select files to delete (use space to select multiple, enter to confirm):":              "选择要删除的文件（空格多选，回车确认）：",
+	"Select files to tag with %q (use space to select multiple, enter to confirm):":         "选择要标记为 %q 的文件（空格多选，回车确认）：",
+	"Select files to replace with clones (use space to select multiple, enter to confirm):": "选择要替换为克隆的文件（空格多选，回车确认）：",
+	"Select caches to delete (use space to select multiple, enter to confirm):":             "选择要删除的缓存（空格多选，回车确认）：",
+	"Delete %d files from %s? (y/N)":                                                        "删除 %[2]s 中的 %[1]d 个文件？(y/N)",
+	"Do you want to proceed with deletion? (y/N)":                                           "确定要执行删除吗？(y/N)",
+	"Move %d caches (%s) to the deleted folder? (y/N)":                                      "将 %d 个缓存（%s）移动到删除文件夹？(y/N)",
+	"How do you want to recover the database?":                                              "要如何恢复数据库？",
+	"Restore the latest automatic backup (%s)":                                              "恢复最近的自动备份（%s）",
+	"Rebuild an empty database (the corrupted file is kept next to it)":                     "重建一个空数据库（损坏的文件会保留在旁边）",
+	"Abort":                                      "放弃",
+	"%s | (%d bytes, %d bytes on disk)":          "%s | （%d 字节，磁盘占用 %d 字节）",
+	" | shares extents with %s":                  " | 与 %s 共享数据块",
+	"%s | %s (%s, %s on disk)":                   "%s | %s（%s，磁盘占用 %s）",
+	"All %d files":                               "全部 %d 个文件",
+	"Files with size 0":                          "大小为 0 的文件",
+	"Files smaller than %s":                      "小于 %s 的文件",
+	"macOS .DS_Store files":                      "macOS .DS_Store 文件",
+	"Windows Thumbs.db files":                    "Windows Thumbs.db 文件",
+	"Empty folders":                              "空文件夹",
+	"Linux/MacOS hidden files (starting with .)": "Linux/macOS 隐藏文件（以 . 开头）",
+	"Office temporary files":                     "Office 临时文件",
+	"Node.js node_modules folders":               "Node.js node_modules 文件夹",
+	"Python __pycache__ folders":                 "Python __pycache__ 文件夹",
+	"Cargo/Maven target folders":                 "Cargo/Maven target 文件夹",
+	".cache folders":                             ".cache 文件夹",
+
+	// Messages
+	"Workspace directory: %s\n":                    "工作区目录：%s\n",
+	"Workspace directory: %s (profile %s)\n":       "工作区目录：%s（配置方案 %s）\n",
+	"fsak v%s\n":                                   "fsak v%s\n",
+	"Connecting to database...\n":                  "正在连接数据库...\n",
+	"Error connecting to database: %v\n":           "连接数据库出错：%v\n",
+	"Error: %v\n":                                  "错误：%v\n",
+	"Error getting absolute path for %s: %v\n":     "获取 %s 的绝对路径出错：%v\n",
+	"Error getting absolute path for source: %v\n": "获取源目录的绝对路径出错：%v\n",
+	"Error getting absolute path for target: %v\n": "获取目标目录的绝对路径出错：%v\n",
+	"Error getting home directory: %v\n":           "获取主目录出错：%v\n",
+
+	// sync info
+	"Starting to process directories: %v\n":                                            "开始处理目录：%v\n",
+	"Listed files to process: %d\n":                                                    "列表中待处理的文件：%d\n",
+	"Loading blacklist patterns from: %s\n":                                            "正在从 %s 加载黑名单规则\n",
+	"Loaded %d blacklist patterns\n":                                                   "已加载 %d 条黑名单规则\n",
+	"Error reading blacklist: %v\n":                                                    "读取黑名单出错：%v\n",
+	"Counting files in specified directories (this may take a moment)...\n":            "正在统计指定目录中的文件（可能需要一些时间）...\n",
+	"Error counting files: %v\n":                                                       "统计文件出错：%v\n",
+	"Total files to process: %d\n":                                                     "待处理文件总数：%d\n",
+	"Starting %d worker threads to process files and %d for files of at least %s...\n": "启动 %d 个工作线程处理文件，另有 %d 个处理至少 %s 的文件...\n",
+	"Worker %d started and ready to process files\n":                                   "工作线程 %d 已启动，准备处理文件\n",
+	"Worker %d finished processing files\n":                                            "工作线程 %d 已完成文件处理\n",
+	"Walking through directories to collect files for processing...\n":                 "正在遍历目录收集待处理文件...\n",
+	"Scanning directory %d/%d: %s\n":                                                   "正在扫描目录 %d/%d：%s\n",
+	"Finished scanning directory: %s\n":                                                "目录扫描完成：%s\n",
+	"Error walking directory %s: %v\n":                                                 "遍历目录 %s 出错：%v\n",
+	"All files collected, closing processing channels...\n":                            "所有文件已收集，正在关闭处理通道...\n",
+	"Waiting for all workers to complete processing...\n":                              "正在等待所有工作线程完成处理...\n",
+	"Error processing file %s in worker %d: %v\n":                                      "工作线程 %[2]d 处理文件 %[1]s 出错：%[3]v\n",
+	"Error upserting file info: %v\n":                                                  "写入文件信息出错：%v\n",
+	"[ %d / %d files, %s / %s (%.2f%%), ETA %s]: %s\n":                                 "[ %d / %d 个文件，%s / %s（%.2f%%），剩余 %s]：%s\n",
+	"[ %d / %d (%.2f%%)]: Checking %s\n":                                               "[ %d / %d (%.2f%%)]：正在检查 %s\n",
+	"Sync operation completed.":                                                        "同步完成。",
+	"Warning: Could not read Finder tags for %s: %v\n":                                 "警告：无法读取 %s 的 Finder 标签：%v\n",
+	"Error: --finder-tags is only supported on macOS\n":                                "错误：--finder-tags 仅支持 macOS\n",
+
+	// hash
+	"Error calculating quick hash: %v\n":                              "计算快速哈希出错：%v\n",
+	"Error calculating hashes: %v\n":                                  "计算哈希值出错：%v\n",
+	"Quick:  %s (size + first/last 1MB, matches are probabilistic)\n": "快速：%s（大小 + 首尾各 1MB，匹配结果是概率性的）\n",
+
+	// clean info
+	"Error during clean operation: %v\n":                             "清理出错：%v\n",
+	"Found %d records in file_infos table, starting validation...\n": "file_infos 表中共有 %d 条记录，开始校验...\n",
+	"Found %d records pointing to non-existent files\n":              "发现 %d 条记录指向不存在的文件\n",
+	"Cleaning record ID: %d, Path: %s\n":                             "正在清理记录 ID：%d，路径：%s\n",
+	"Clean operation completed. %d records deleted.\n":               "清理完成，共删除 %d 条记录。\n",
+	"Skipped %d records on volumes that are not mounted\n":           "跳过了未挂载卷上的 %d 条记录\n",
+	"Recorded the volume of %d records synced without one\n":         "为 %d 条同步时没有卷的记录记录了卷\n",
+
+	// clean dup and dedupe
+	"Error during duplicate file operation: %v\n":                           "处理重复文件出错：%v\n",
+	"Error during dedupe operation: %v\n":                                   "去重出错：%v\n",
+	"Processing %d files...\n":                                              "正在处理 %d 个文件...\n",
+	"Memory limit reached, grouping files in a temporary database...\n":     "已达到内存上限，改用临时数据库分组文件...\n",
+	"Found %d groups of duplicate files.\n":                                 "找到 %d 组重复文件。\n",
+	"No duplicate files found.\n":                                           "没有找到重复文件。\n",
+	"Duplicate group %d/%d (%d files):\n":                                   "重复组 %d/%d（%d 个文件）：\n",
+	"Duplicate group %d/%d (%d files, quick hash match - probabilistic):\n": "重复组 %d/%d（%d 个文件，快速哈希匹配，结果是概率性的）：\n",
+	"Duplicate group %d/%d (%d files), source: %s\n":                        "重复组 %d/%d（%d 个文件），源文件：%s\n",
+	"Reclaimable space: %s (%s on disk)\n":                                  "可回收空间：%s（磁盘占用 %s）\n",
+	"Found %d duplicate groups (%d files), %s reclaimable.\n":               "找到 %d 组重复文件（%d 个文件），可回收 %s。\n",
+	"Error listing duplicate files: %v\n":                                   "列出重复文件出错：%v\n",
+	"Skipping group %d, all files already share the same extents\n":         "跳过第 %d 组，所有文件已共享相同的数据块\n",
+	"Already sharing extents: %s\n":                                         "已共享数据块：%s\n",
+	"Warning: Files of group %d are not identical, skipping\n":              "警告：第 %d 组的文件内容不一致，已跳过\n",
+	"Verifying %s\n":                                                   "正在校验 %s\n",
+	"No files selected for deletion.\n":                                "没有选择要删除的文件。\n",
+	"Created backup directory: %s\n":                                   "已创建备份目录：%s\n",
+	"Error creating destination directory for %s: %v\n":                "为 %s 创建目标目录出错：%v\n",
+	"Moved %s to %s\n":                                                 "已将 %s 移动到 %s\n",
+	"Moved %s to the Recycle Bin\n":                                    "已将 %s 放入回收站\n",
+	"Error moving %s to %s: %v\n":                                      "将 %s 移动到 %s 出错：%v\n",
+	"Error moving %s to the Recycle Bin: %v\n":                         "将 %s 放入回收站出错：%v\n",
+	"Scripted moving %s to %s\n":                                       "已写入脚本：将 %s 移动到 %s\n",
+	"Warning: Could not delete record for file %s from database: %v\n": "警告：无法从数据库删除文件 %s 的记录：%v\n",
+	"Successfully processed %d duplicate files: moved to deleted folder and removed records from database.\n": "成功处理 %d 个重复文件：已移动到删除文件夹并从数据库删除记录。\n",
+	"Wrote %d moves to %s, review and run it, then run clean info to update the database.\n":                  "已将 %d 个移动操作写入 %s，请检查后运行，然后运行 clean info 更新数据库。\n",
+	"Tagged %s with %q\n":                                                                           "已将 %s 标记为 %q\n",
+	"Successfully tagged %d duplicate files with %q.\n":                                             "成功将 %d 个重复文件标记为 %q。\n",
+	"Replaced %s with a clone of %s\n":                                                              "已用 %[2]s 的克隆替换 %[1]s\n",
+	"Successfully replaced %d duplicate files with APFS clones.\n":                                  "成功用 APFS 克隆替换了 %d 个重复文件。\n",
+	"Warning: All files of group %d were selected, at least one must be kept as the clone source\n": "警告：第 %d 组的所有文件都被选中，至少要保留一个作为克隆源\n",
+	"Deduped %s (%s)\n":                                                                             "已去重 %s（%s）\n",
+	"Successfully deduped %d files, %s of extents shared.\n":                                        "成功去重 %d 个文件，共享了 %s 的数据块。\n",
+	"Warning: Could not dedupe %s: %v\n":                                                            "警告：无法对 %s 去重：%v\n",
+	"Warning: Could not get file stats for %s: %v\n":                                                "警告：无法获取 %s 的文件信息：%v\n",
+	"Warning: Could not calculate hash for %s: %v\n":                                                "警告：无法计算 %s 的哈希值：%v\n",
+	"Error: --recycle-bin is only supported on Windows\n":                                           "错误：--recycle-bin 仅支持 Windows\n",
+	"Error: --finder-tag is only supported on macOS\n":                                              "错误：--finder-tag 仅支持 macOS\n",
+	"Error: --clone is only supported on macOS\n":                                                   "错误：--clone 仅支持 macOS\n",
+	"Error: --block is only supported on Linux\n":                                                   "错误：--block 仅支持 Linux\n",
+	"Error: a dedup mode is required (--block or --symlink)\n":                                      "错误：需要指定去重模式（--block 或 --symlink）\n",
+	"Error: invalid --name-regex %s: %v\n":                                                          "错误：无效的 --name-regex %s：%v\n",
+
+	// clean dirty and clean build
+	"Error during dirty file operation: %v\n":                                               "处理垃圾文件出错：%v\n",
+	"Error during build cache operation: %v\n":                                              "处理构建缓存出错：%v\n",
+	"Error reading safe-list: %v\n":                                                         "读取白名单出错：%v\n",
+	"Error: --delete-to-dir (-d) flag is required when not using --list or --recycle-bin\n": "错误：未使用 --list 或 --recycle-bin 时必须指定 --delete-to-dir (-d)\n",
+	"Error: --print0 can only be used with --list\n":                                        "错误：--print0 只能与 --list 一起使用\n",
+	"No dirty file types selected. Nothing to do.\n":                                        "没有选择垃圾文件类型，无需处理。\n",
+	"\nSelect files to delete from %s category:\n":                                          "\n选择要从 %s 类别中删除的文件：\n",
+	"\n%s (%d, %s, %s on disk):\n":                                                          "\n%s（%d 个，%s，磁盘占用 %s）：\n",
+	"No dirty files found matching your selection.\n":                                       "没有找到符合所选类型的垃圾文件。\n",
+	"\nTotal dirty files found: %d (%s, %s on disk)\n":                                      "\n共找到垃圾文件：%d 个（%s，磁盘占用 %s）\n",
+	"Listing only - no files were deleted.\n":                                               "仅列出，没有删除任何文件。\n",
+	"Operation cancelled by user.\n":                                                        "操作已被用户取消。\n",
+	"Skipping %s\n":                                                                         "跳过 %s\n",
+	"Successfully moved %d dirty files to %s\n":                                             "成功将 %d 个垃圾文件移动到 %s\n",
+	"Successfully moved %d dirty files to the Recycle Bin\n":                                "成功将 %d 个垃圾文件放入回收站\n",
+	"Wrote %d moves to %s, review and run it to move the dirty files to %s\n":               "已将 %d 个移动操作写入 %s，请检查后运行，将垃圾文件移动到 %s\n",
+	"Warning: Could not calculate size of %s: %v\n":                                         "警告：无法计算 %s 的大小：%v\n",
+	"Searching for developer caches...\n":                                                   "正在查找开发缓存...\n",
+	"No developer caches found.\n":                                                          "没有找到开发缓存。\n",
+	"%s | %s | %s (%s, %s on disk)\n":                                                       "%s | %s | %s（%s，磁盘占用 %s）\n",
+	"\nTotal reclaimable: %d caches (%s, %s on disk)\n":                                     "\n共可回收：%d 个缓存（%s，磁盘占用 %s）\n",
+	"Listing only - no caches were deleted.\n":                                              "仅列出，没有删除任何缓存。\n",
+	"No caches selected for deletion.\n":                                                    "没有选择要删除的缓存。\n",
+	"Successfully moved %d developer caches to %s\n":                                        "成功将 %d 个开发缓存移动到 %s\n",
+	"Successfully moved %d developer caches to the Recycle Bin\n":                           "成功将 %d 个开发缓存放入回收站\n",
+
+	// merge
+	"Error during merge: %v\n": "合并出错：%v\n",
+	"Both source (-f) and target (-t) directories must be specified\n": "必须同时指定源目录 (-f) 和目标目录 (-t)\n",
+	"Source directory does not exist: %s\n":                            "源目录不存在：%s\n",
+	"Target directory does not exist: %s\n":                            "目标目录不存在：%s\n",
+	"Starting merge operation from %s to %s\n":                         "开始从 %s 合并到 %s\n",
+	"Found %d files in source directory\n":                             "源目录中有 %d 个文件\n",
+	"Found %d files in target directory\n":                             "目标目录中有 %d 个文件\n",
+	"Found %d files to copy\n":                                         "有 %d 个文件需要复制\n",
+	"Copying %s to %s\n":                                               "正在将 %s 复制到 %s\n",
+	"Updating %s from %s\n":                                            "正在用 %[2]s 更新 %[1]s\n",
+	"Skipping existing file: %s\n":                                     "跳过已存在的文件：%s\n",
+	"Transferred %s of changed data\n":                                 "传输了 %s 的变化数据\n",
+	"Merge operation completed successfully.\n":                        "合并成功完成。\n",
+	"Warning: Could not determine relative path for %s: %v\n":          "警告：无法确定 %s 的相对路径：%v\n",
+
+	"Error: invalid --keep-versions %d, expected 0 or more\n":             "错误：无效的 --keep-versions %d，应为 0 或更大的数\n",
+	"Keeping the previous %s at %s\n":                                     "将之前的 %s 保留到 %s\n",
+	"Removed old version %s\n":                                            "已删除旧版本 %s\n",
+	"Error: invalid --layout %s, expected %s or %s\n":                     "错误：无效的 --layout %s，应为 %s 或 %s\n",
+	"Warning: %s exists with different content, placing the copy at %s\n": "警告：%s 已存在且内容不同，副本放在 %s\n",
+	"Kept newer %s, the older %s is kept at %s\n":                         "保留较新的 %s，较旧的 %s 保存在 %s\n",
+	"Replacing older %s, it's kept at %s\n":                               "正在替换较旧的 %s，它保存在 %s\n",
+	"Checking whether the files of %s are in %s\n":                        "正在检查 %s 中的文件是否都在 %s 中\n",
+	"Error during merge check: %v\n":                                      "检查合并出错：%v\n",
+	"All files of %s are in %s.\n":                                        "%s 中的所有文件都在 %s 中。\n",
+	"%d files (%s) of %s are missing from %s\n":                           "%[3]s 中有 %[1]d 个文件（%[2]s）不在 %[4]s 中\n",
+	"  ... and %d more\n":                                                 "  ……还有 %d 个\n",
+
+	// catalog
+	"Error reading catalog: %v\n":                            "读取目录出错：%v\n",
+	"Error searching catalog: %v\n":                          "搜索目录出错：%v\n",
+	"Error planning deduplication: %v\n":                     "规划去重出错：%v\n",
+	"No volumes recorded yet, run 'fsak sync info' first.\n": "还没有记录任何卷，请先运行 'fsak sync info'。\n",
+	"Found %d volumes.\n":                                    "找到 %d 个卷。\n",
+	"Found %d files (%s).\n":                                 "找到 %d 个文件（%s）。\n",
+	"Plan: remove %d files in %d groups to reclaim %s.\n":    "计划：删除 %[2]d 组中的 %[1]d 个文件，回收 %[3]s。\n",
+	"Warning: %d files have no full hashes and were skipped, run 'fsak sync info' without --quick on the volume to include them\n": "警告：%d 个文件没有完整哈希值，已跳过，请对该卷运行不带 --quick 的 'fsak sync info' 以包含它们\n",
+
+	// dup conflicts
+	"Error: invalid --by %s, expected %s or %s\n":                             "错误：无效的 --by %s，应为 %s 或 %s\n",
+	"Error listing conflicts: %v\n":                                           "列出冲突出错：%v\n",
+	"No conflicts found.\n":                                                   "没有发现冲突。\n",
+	"Found %d conflicts (%d files) with the same %s and different content.\n": "发现 %d 处冲突（%d 个文件）：%s 相同但内容不同。\n",
+
+	// versions
+	"List and restore the kept versions of a file":                                                                       "列出并恢复文件保留的旧版本",
+	"Commands for the previous versions of files kept in .fsak-versions by merge dir --update and --keep-versions.":      "管理 merge dir --update 和 --keep-versions 在 .fsak-versions 中保留的文件旧版本的命令。",
+	"List the kept versions of a file":                                                                                   "列出文件保留的旧版本",
+	"List the previous versions of a file kept in the .fsak-versions directory of the tree it belongs to, newest first.": "列出文件所在目录树的 .fsak-versions 目录中保留的旧版本，最新的在前。",
+	"Restore a kept version of a file":                                                                                   "恢复文件保留的旧版本",
+	"Restore a previous version of a file from the .fsak-versions directory of the tree it belongs to, the newest one unless --version selects another. The current file is kept as a new version first, so a restore can be undone.": "从文件所在目录树的 .fsak-versions 目录中恢复一个旧版本，默认恢复最新的版本，可用 --version 选择其他版本。当前文件会先作为新版本保留，因此恢复可以撤销。",
+	"Error listing versions: %v\n":        "列出版本出错：%v\n",
+	"Error restoring version: %v\n":       "恢复版本出错：%v\n",
+	"Keeping the current %s at %s\n":      "将当前的 %s 保留到 %s\n",
+	"Restored %s from version %s.\n":      "已从版本 %[2]s 恢复 %[1]s。\n",
+	"%d versions of %s are kept in %s.\n": "%[3]s 中保留了 %[2]s 的 %[1]d 个版本。\n",
+
+	// backup
+	"Create a dated snapshot of a directory, hardlinking unchanged files": "为目录创建带日期的快照，未变化的文件使用硬链接",
+	"Create a snapshot of the source directory in a new dated directory of the destination. Files whose MD5 and Blake3 values are in the previous snapshot are hardlinked to it, only new and changed files are copied, so every snapshot is a complete tree while using the space of the changes.\n\nThe hashes recorded by sync info are used for files that didn't change since, the others are hashed and recorded. The files of the snapshot are recorded too, so the next backup and restore find them.": "在目标目录中新建一个带日期的目录，保存源目录的快照。MD5 和 Blake3 值在上一个快照中存在的文件会硬链接到上一个快照，只复制新增和变化的文件，因此每个快照都是完整的目录树，而只占用变化部分的空间。\n\n自 sync info 之后未变化的文件使用其记录的哈希值，其他文件会重新计算并记录。快照中的文件也会被记录，供下次备份和恢复使用。",
+	"Error during backup: %v\n":                                     "备份出错：%v\n",
+	"Error getting absolute path for destination: %v\n":             "获取目标目录的绝对路径出错：%v\n",
+	"Linking unchanged files to snapshot %s\n":                      "未变化的文件将链接到快照 %s\n",
+	"Creating snapshot %s\n":                                        "正在创建快照 %s\n",
+	"Copying %s\n":                                                  "正在复制 %s\n",
+	"Snapshot %s created: %d files linked, %d files copied (%s).\n": "已创建快照 %s：链接了 %d 个文件，复制了 %d 个文件（%s）。\n",
+
+	// restore
+	"Restore files from a backup snapshot": "从备份快照恢复文件",
+	"Restore the files of a snapshot created by backup, or only a subtree of it with --path, to the destination directory, keeping their path relative to the snapshot. Each restored file is verified against the MD5 and Blake3 values recorded when the snapshot was created.": "将 backup 创建的快照中的文件（使用 --path 时只恢复其中的子目录树）恢复到目标目录，保持它们相对快照的路径。每个恢复的文件都会与创建快照时记录的 MD5 和 Blake3 值进行校验。",
+	"Backup directory containing the snapshots (required)":                                                                      "包含快照的备份目录（必需）",
+	"Restore the last snapshot taken on or before this date (YYYY-MM-DD), or the snapshot with this name (default: the latest)": "恢复在此日期（YYYY-MM-DD）或之前创建的最后一个快照，或指定名称的快照（默认：最新的快照）",
+	"Only restore this file or directory, relative to the snapshot":                                                             "只恢复此文件或目录（相对快照的路径）",
+	"Error getting absolute path for backup directory: %v\n":                                                                    "获取备份目录的绝对路径出错：%v\n",
+	"Error: --path %s must be relative to the snapshot\n":                                                                       "错误：--path %s 必须是相对快照的路径\n",
+	"Error during restore: %v\n":                                                                                                "恢复出错：%v\n",
+	"Restoring snapshot %s to %s\n":                                                                                             "正在将快照 %s 恢复到 %s\n",
+	"Restoring %s from snapshot %s to %s\n":                                                                                     "正在将快照 %[2]s 中的 %[1]s 恢复到 %[3]s\n",
+	"Restoring %s\n":                                                                                                            "正在恢复 %s\n",
+	"Warning: no hashes are recorded for %s, it can't be verified\n":                                                            "警告：没有记录 %s 的哈希值，无法校验\n",
+	"Hash mismatch: %s differs from the content recorded for %s\n":                                                              "哈希值不匹配：%s 与 %s 记录的内容不同\n",
+	"Restored %d files (%s) from snapshot %s to %s, %d verified.\n":                                                             "已从快照 %[3]s 恢复 %[1]d 个文件（%[2]s）到 %[4]s，其中 %[5]d 个已校验。\n",
+	"%d existing files were skipped.\n":                                                                                         "跳过了 %d 个已存在的文件。\n",
+	"%d files couldn't be verified.\n":                                                                                          "%d 个文件无法校验。\n",
+	"%d files don't match their recorded hashes.\n":                                                                             "%d 个文件与记录的哈希值不匹配。\n",
+
+	// db
+	"Manage the database":                                        "管理数据库",
+	"Commands for managing the records of the database.":         "管理数据库记录的命令。",
+	"Import checksums from md5sum, sha256sum or b3sum manifests": "从 md5sum、sha256sum 或 b3sum 校验清单导入校验值",
+	"Import the checksums of an existing manifest written by md5sum, sha256sum or b3sum, in the GNU or the BSD-style (--tag) format. Records missing an MD5, SHA256 or Blake3 value get the one of the manifest, and records are created for the listed files that aren't in the database yet, so previously generated checksums become queryable without hashing the files again.\n\nThe paths of the manifest are relative to --base, by default the directory of the manifest. Files modified after the manifest was written are skipped, and values that differ from the ones already recorded are reported and not imported.": "导入 md5sum、sha256sum 或 b3sum 生成的现有校验清单，支持 GNU 格式和 BSD 格式（--tag）。缺少 MD5、SHA256 或 Blake3 值的记录会补上清单中的值，清单中尚未在数据库中的文件会创建记录，这样以前生成的校验值无需重新计算即可查询。\n\n清单中的路径相对于 --base，默认为清单所在的目录。在清单写入之后修改过的文件会被跳过，与已记录的值不同的校验值会被报告且不会导入。",
+	"Directory the paths of the manifest are relative to (default: the directory of the manifest)":             "清单中的路径所相对的目录（默认：清单所在的目录）",
+	"Algorithm of the manifest: md5, sha256 or blake3 (default: guessed from the manifest name)":               "清单的算法：md5、sha256 或 blake3（默认：根据清单文件名推断）",
+	"Tag for the records created by the import":                                                                "导入时创建的记录使用的标签",
+	"Error: invalid --algo %s, expected %s, %s or %s\n":                                                        "错误：无效的 --algo %s，应为 %s、%s 或 %s\n",
+	"Error importing checksums: %v\n":                                                                          "导入校验值出错：%v\n",
+	"Warning: the %s of %s in the manifest differs from the recorded one, keeping the recorded one\n":          "警告：清单中 %[2]s 的 %[1]s 值与已记录的不同，保留已记录的值\n",
+	"Imported %s: %d records created, %d enriched, %d already known.\n":                                        "已导入 %s：创建了 %d 条记录，补充了 %d 条，%d 条已存在。\n",
+	"%d checksums differ from the recorded ones, the files may have changed since the manifest was written.\n": "%d 个校验值与已记录的不同，这些文件可能在清单写入后发生了变化。\n",
+	"%d files were modified after the manifest was written and were skipped.\n":                                "%d 个文件在清单写入后被修改过，已跳过。\n",
+	"%d listed files don't exist under %s.\n":                                                                  "清单中有 %d 个文件在 %s 下不存在。\n",
+	"%d lines of the manifest couldn't be read.\n":                                                             "清单中有 %d 行无法读取。\n",
+
+	// export
+	"Write the files of a directory as a file list for rsync or rclone": "将目录中的文件写成 rsync 或 rclone 使用的文件列表",
+	"Write the files of a directory, relative to it, as a list for the --files-from option of rsync or the --files-from-raw option of rclone. With --missing-from, only the files whose MD5 and Blake3 values aren't in the target directory are listed, as merge dir would copy them, so the transfer can be made by rsync or rclone.\n\nThe list is written to stdout unless --output is given, messages go to stderr.": "将目录中的文件以相对该目录的路径写成列表，供 rsync 的 --files-from 选项或 rclone 的 --files-from-raw 选项使用。使用 --missing-from 时，只列出 MD5 和 Blake3 值不在目标目录中的文件（即 merge dir 会复制的文件），从而由 rsync 或 rclone 完成传输。\n\n除非指定 --output，列表写到标准输出，消息写到标准错误。",
+	"Format of the list: rsync, rsync0 (NUL-separated, for rsync --from0) or rclone": "列表格式：rsync、rsync0（以 NUL 分隔，用于 rsync --from0）或 rclone",
+	"Only list the files whose content isn't in this target directory":               "只列出内容不在此目标目录中的文件",
+	"Write the list to this file instead of stdout":                                  "将列表写入此文件而不是标准输出",
+	"Error: invalid --format %s, expected %s, %s or %s\n":                            "错误：无效的 --format %s，应为 %s、%s 或 %s\n",
+	"Error exporting file list: %v\n":                                                "导出文件列表出错：%v\n",
+	"Warning: %q contains a line break, use --format %s to list it\n":                "警告：%q 包含换行符，请使用 --format %s 列出它\n",
+	"Listed %d files of %s.\n":                                                       "列出了 %[2]s 中的 %[1]d 个文件。\n",
+	"%d files with line breaks in their names were left out.\n":                      "有 %d 个文件名包含换行符的文件未列出。\n",
+
+	// clean dup --import-results
+	"Handle the duplicate groups of rmlint (-o json) or jdupes results instead of scanning folders": "处理 rmlint（-o json）或 jdupes 结果中的重复文件组，而不是扫描文件夹",
+	"Read %d duplicate groups from %s results\n":                                                    "从 %[2]s 结果中读取了 %[1]d 个重复文件组\n",
+
+	// diff
+	"Compare two directory trees": "比较两个目录树",
+	"Compare the files of two directory trees by their path relative to the trees, and list the files only in the first tree (removed), only in the second tree (added), and at the same path with different content (changed). Files are compared by size, and files of the same size by their MD5 and Blake3 values, using the values recorded by sync info for files that didn't change since.\n\nWith --names-only, files are only compared by path and size, nothing is hashed, for quick checks of trees on slow network shares. The command exits with status 1 when the trees differ.": "按相对目录树的路径比较两个目录树中的文件，列出只在第一个目录树中的文件（删除）、只在第二个目录树中的文件（新增）以及路径相同但内容不同的文件（修改）。文件先按大小比较，大小相同的文件再按 MD5 和 Blake3 值比较，自 sync info 之后未变化的文件使用其记录的值。\n\n使用 --names-only 时只按路径和大小比较，不计算哈希，适合在较慢的网络共享上快速检查目录树。目录树不同时命令以状态 1 退出。",
+	"Only compare the paths and sizes of the files, without hashing them": "只比较文件的路径和大小，不计算哈希",
+	"Columns to show, in order (change, size, path)":                      "要显示的列及顺序（change、size、path）",
+	"Error: %s is not a directory\n":                                      "错误：%s 不是目录\n",
+	"Error comparing directories: %v\n":                                   "比较目录出错：%v\n",
+	"Listing the files of %s\n":                                           "正在列出 %s 中的文件\n",
+	"No differences found.\n":                                             "没有发现差异。\n",
+	"%d files only in %s, %d files only in %s, %d files changed.\n":       "%d 个文件只在 %s 中，%d 个文件只在 %s 中，%d 个文件被修改。\n",
+
+	// schedule
+	"Commands installing and removing scheduled runs of fsak commands, as systemd units on Linux, launchd jobs on macOS and scheduled tasks on Windows, so the catalog is kept up to date without hand-written units.": "安装和移除定期运行 fsak 命令的计划的命令，在 Linux 上为 systemd 单元，在 macOS 上为 launchd 任务，在 Windows 上为计划任务，无需手写单元即可保持目录数据最新。",
+	"Install a schedule running an fsak command at the interval given by --every, such as \"schedule install photos --every 6h -- sync info ~/Pictures\". On Linux a systemd service and timer named fsak-<name> are written and the timer is enabled, on macOS a launchd job is written and loaded, and on Windows a task is registered in the fsak folder of Task Scheduler, with the output of the runs in the logs directory of the workspace.\n\nSchedules are installed for the current user unless --system is given, which installs them for the whole system and needs root (SYSTEM tasks and an administrator on Windows). The command runs in the current directory, with the workspace, profile and language of the install.": "安装一个按 --every 指定的间隔运行 fsak 命令的计划，例如 \"schedule install photos --every 6h -- sync info ~/Pictures\"。在 Linux 上会写入名为 fsak-<name> 的 systemd 服务和定时器并启用定时器，在 macOS 上会写入并加载 launchd 任务，在 Windows 上会在任务计划程序的 fsak 文件夹中注册任务，运行输出位于工作区的 logs 目录中。\n\n除非指定 --system，计划只为当前用户安装；--system 为整个系统安装，需要 root 权限（在 Windows 上为 SYSTEM 任务，需要管理员权限）。命令在当前目录中运行，使用安装时的工作区、配置档和语言。",
+	"Stop and remove a schedule installed by schedule install, deleting its systemd units, launchd job or scheduled task.": "停止并移除 schedule install 安装的计划，删除其 systemd 单元、launchd 任务或计划任务。",
+	"Run fsak commands periodically":                                        "定期运行 fsak 命令",
+	"Run an fsak command periodically":                                      "定期运行一个 fsak 命令",
+	"Remove a schedule":                                                     "移除计划",
+	"Interval between runs, such as 30m, 6h or 168h":                        "两次运行之间的间隔，例如 30m、6h 或 168h",
+	"Install for the whole system instead of the current user (needs root)": "为整个系统而不是当前用户安装（需要 root 权限）",
+	"Remove a schedule installed for the whole system":                      "移除为整个系统安装的计划",
+	"Error installing schedule: %v\n":                                       "安装计划出错：%v\n",
+	"Error removing schedule: %v\n":                                         "移除计划出错：%v\n",
+	"Removed %s\n":                                                          "已移除 %s\n",
+	"Wrote %s\n":                                                            "已写入 %s\n",
+	"Schedule %s installed, running %s every %s.\n":                         "计划 %s 已安装，每 %[3]s 运行一次 %[2]s。\n",
+	"Schedule %s removed.\n":                                                "计划 %s 已移除。\n",
+
+	// daemon
+	"Commands installing and removing a daemon running watch in the background, as a systemd service on Linux, a launchd job on macOS and a service on Windows, so the records of directories stay up to date without a terminal left open or hand-written units. Periodic runs of other commands are installed with schedule.": "安装和移除在后台运行 watch 的守护进程的命令，在 Linux 上为 systemd 服务，在 macOS 上为 launchd 任务，在 Windows 上为服务，无需保持终端打开或手写单元即可让目录的记录保持最新。其他命令的定期运行通过 schedule 安装。",
+	"Install a daemon running watch on the directories, started now and again at every login, or at boot with --system, and restarted when it fails. On Linux a systemd service named fsakd-<name> is written, enabled and started, and its output goes to the journal (journalctl --user -u fsakd-<name>). On macOS a launchd job is written and loaded, with its output in the logs directory of the workspace. On Windows a service named fsakd-<name> is registered and started, running as LocalSystem with its messages in the Application event log; it needs an administrator, and is always installed for the whole system.\n\nThe daemon is installed for the current user (--user, the default) unless --system is given, which installs it for the whole system and needs root. It uses the workspace, profile and language of the install. Installing a daemon of the same name again replaces its directories and flags.": "安装一个对这些目录运行 watch 的守护进程，立即启动，并在每次登录时（使用 --system 时在开机时）再次启动，失败时会被重启。在 Linux 上会写入、启用并启动名为 fsakd-<name> 的 systemd 服务，其输出写入日志（journalctl --user -u fsakd-<name>）。在 macOS 上会写入并加载 launchd 任务，输出位于工作区的 logs 目录中。在 Windows 上会注册并启动名为 fsakd-<name> 的服务，以 LocalSystem 身份运行，其消息写入应用程序事件日志；这需要管理员权限，并且总是为整个系统安装。\n\n除非指定 --system，守护进程只为当前用户安装（--user，默认）；--system 为整个系统安装，需要 root 权限。它使用安装时的工作区、配置档和语言。再次安装同名的守护进程会替换其目录和参数。",
+	"Stop a daemon installed by daemon install and remove it, deleting its systemd service, launchd job or Windows service. The pending changes are recorded before it stops.": "停止 daemon install 安装的守护进程并将其移除，删除其 systemd 服务、launchd 任务或 Windows 服务。停止前会先记录待处理的变化。",
+	"Keep directories watched in the background":                    "在后台持续监视目录",
+	"Watch directories in the background":                           "在后台监视目录",
+	"Stop and remove a daemon":                                      "停止并移除守护进程",
+	"Name of the daemon, to run several with different directories": "守护进程的名称，用于以不同目录运行多个守护进程",
+	"Install for the current user, the default":                     "为当前用户安装（默认）",
+	"Error installing daemon: %v\n":                                 "安装守护进程出错：%v\n",
+	"Error removing daemon: %v\n":                                   "移除守护进程出错：%v\n",
+	"Daemon %s installed, watching %s.\n":                           "守护进程 %s 已安装，正在监视 %s。\n",
+	"Service started.\n":                                            "服务已启动。\n",
+	"Daemon %s removed.\n":                                          "守护进程 %s 已移除。\n",
+
+	// shared catalogs
+	"Also check the records created by other users and machines sharing the catalog": "同时检查共享目录的其他用户和机器创建的记录",
+	"Skipped %d records of other users, use --all-owners to check them\n":            "已跳过 %d 条其他用户的记录，使用 --all-owners 检查它们\n",
+
+	// read-only mode
+	"Refuse any command changing files or deleting database records, for safe exploratory runs": "拒绝任何修改文件或删除数据库记录的命令，以便安全地进行探索性运行",
+
+	// dangerous roots
+	"Allow cleaning the filesystem root, the home directory or the workspace":                  "允许清理文件系统根目录、主目录或工作区",
+	"Error: refusing to run %s on %s, %s. Pass --i-know-what-i-am-doing if this is intended\n": "错误：拒绝在 %[2]s 上运行 %[1]s，%[3]s。如确有此意，请指定 --i-know-what-i-am-doing\n",
+	"it is the root of the filesystem":                                                         "它是文件系统的根目录",
+	"it is your home directory":                                                                "它是你的主目录",
+	"it is the workspace":                                                                      "它是工作区",
+	"it is inside the workspace %s":                                                            "它位于工作区 %s 中",
+	"it contains the workspace %s":                                                             "它包含工作区 %s",
+
+	// clean dup --preview
+	"Offer to open the files of each group with their default application before selecting": "在选择之前，提供用默认应用程序打开每组文件的选项",
+	"Go on to the selection": "继续选择",
+	"Open %s":                "打开 %s",
+	"Open a file to look at it, or go on to the selection:": "打开文件查看，或继续选择：",
+	"Warning: Could not open %s: %v\n":                      "警告：无法打开 %s：%v\n",
+
+	// catalog dedupe-plan --keep
+	"Copy of each group to keep: shortest (path) or context (in the directory with the most files)": "每组要保留的副本：shortest（路径最短）或 context（位于文件最多的目录中）",
+	"Error: invalid --keep %s, expected %s or %s\n":                                                 "错误：无效的 --keep %s，应为 %s 或 %s\n",
+
+	// dedupe symlink
+	"Replace duplicates with symlinks to one canonical copy, preferring copies in the earliest folders given": "将重复文件替换为指向同一规范副本的符号链接，优先保留最先给出的文件夹中的副本",
+	"Duplicate group %d/%d (%d files), canonical: %s\n":                                                       "重复组 %d/%d（%d 个文件），规范副本：%s\n",
+	"Warning: %s changed since it was hashed, skipping\n":                                                     "警告：%s 在计算哈希后已被修改，跳过\n",
+	"Warning: Could not replace %s with a symlink: %v\n":                                                      "警告：无法将 %s 替换为符号链接：%v\n",
+	"Linked %s -> %s\n":                            "已链接 %s -> %s\n",
+	"Replaced %d files with symlinks, %s freed.\n": "已将 %d 个文件替换为符号链接，释放 %s。\n",
+
+	// store
+	"Archive files in a deduplicated content-addressable store": "将文件归档到去重的内容寻址存储中",
+	"Commands for the content-addressable store of the workspace, an archive for cold data where every content is kept once in the objects directory, named after its Blake3 value, and the paths the files had are recorded in the database so their trees can be materialized again.": "工作区内容寻址存储的相关命令。它是冷数据的归档，每种内容只在 objects 目录中保存一份，以其 Blake3 值命名，文件原来的路径记录在数据库中，以便之后重新生成目录树。",
+	"Move files into the store": "将文件移入存储",
+	"Move files and the files of directories into the store. Each file is moved to objects/ab/cdef... in the workspace, named after its Blake3 value, unless an object with the same contents is already stored, and its path is recorded as the logical path of the content. The stored objects are read-only.\n\nThe files are removed from their directories once stored, use store checkout to materialize them again.": "将文件及目录中的文件移入存储。每个文件会移动到工作区的 objects/ab/cdef...，以其 Blake3 值命名，除非已存储了内容相同的对象；其路径会记录为该内容的逻辑路径。已存储的对象为只读。\n\n文件存储后会从原目录中删除，使用 store checkout 可重新生成它们。",
+	"Error adding files to the store: %v\n": "将文件加入存储时出错：%v\n",
+	"Materialize a stored tree":             "重新生成已存储的目录树",
+	"Materialize the files stored at or under a logical path in a destination directory, keeping their paths relative to it. Files are hard links to the objects by default, which takes no space but makes them read-only, and copies when the destination is on another filesystem. Use --copy for files that can be edited, or --symlink for links to the objects.\n\nFiles already in the destination are kept.": "在目标目录中重新生成存储在某逻辑路径下的文件，保持其相对路径。默认创建指向对象的硬链接，不占用空间但文件为只读；目标位于其他文件系统时则复制。使用 --copy 得到可编辑的文件，或使用 --symlink 创建指向对象的符号链接。\n\n目标中已存在的文件会被保留。",
+	"Error materializing stored files: %v\n": "重新生成已存储的文件时出错：%v\n",
+	"List the stored files":                  "列出已存储的文件",
+	"List the files stored at or under a logical path, all of them by default, and summarize the space the store saves by keeping every content once.": "列出存储在某逻辑路径下的文件（默认列出全部），并汇总存储通过每种内容只保存一份所节省的空间。",
+	"Error listing stored files: %v\n":                                   "列出已存储的文件时出错：%v\n",
+	"Copy the files instead of hard linking them, so they can be edited": "复制文件而不是创建硬链接，以便编辑",
+	"Create symlinks to the objects instead of hard links":               "创建指向对象的符号链接而不是硬链接",
+	"Columns to show, in order (path, size, modified, blake3)":           "要显示的列，按顺序（path、size、modified、blake3）",
+	"Stored %s\n": "已存储 %s\n",
+	"Stored %s, its contents were already in the store\n":                "已存储 %s，其内容已在存储中\n",
+	"Stored %d files (%s) in %d new objects, %s already in the store.\n": "已存储 %d 个文件（%s），新增 %d 个对象，%s 已在存储中。\n",
+	"Materialized %s\n": "已生成 %s\n",
+	"Materialized %d files in %s (%d linked, %d copied), %d skipped.\n":    "已在 %[2]s 中生成 %[1]d 个文件（链接 %[3]d 个，复制 %[4]d 个），跳过 %[5]d 个。\n",
+	"%d files are stored in %d objects (%s), %s saved by deduplication.\n": "%d 个文件存储在 %d 个对象中（%s），去重节省 %s。\n",
+
+	// content identity
+	"%s was moved to %s, its record follows it\n": "%s 已移动到 %s，其记录随之更新\n",
+	"Previously at %s\n":                          "之前位于 %s\n",
+	"Same contents at %s\n":                       "相同内容位于 %s\n",
+
+	// runs
+	"List the statistics of previous runs": "列出之前运行的统计信息",
+	"List the summaries recorded at the end of the commands that did some work, newest first: elapsed time, files and bytes hashed, bytes copied, database writes, errors and skipped paths, with the version and machine they ran on, so performance can be compared across versions and machines.": "按时间从新到旧列出执行了实际工作的命令结束时记录的摘要：耗时、计算哈希的文件数和字节数、复制的字节数、数据库写入次数、错误数和跳过的路径数，以及运行时的版本和机器，以便比较不同版本和机器的性能。",
+	"Error listing runs: %v\n":                                  "列出运行记录时出错：%v\n",
+	"Only list the runs of this command, such as \"sync info\"": "只列出该命令的运行记录，例如 \"sync info\"",
+	"Number of runs to list":                                    "要列出的运行记录数",
+	"Columns to show, in order (started, command, elapsed, files, hashed, copied, writes, errors, skipped, version, host)": "要显示的列，按顺序（started、command、elapsed、files、hashed、copied、writes、errors、skipped、version、host）",
+	"Finished in %s: %d files hashed (%s), %s copied, %d database writes, %d errors, %d skipped\n":                         "用时 %s 完成：计算了 %d 个文件的哈希（%s），复制 %s，数据库写入 %d 次，错误 %d 个，跳过 %d 个\n",
+	"Warning: Could not write the runs log: %v\n":                                                                          "警告：无法写入运行日志：%v\n",
+	"%d runs listed.\n": "已列出 %d 条运行记录。\n",
+
+	// estimate
+	"Don't ask to proceed after printing how many files and bytes will be processed": "打印将要处理的文件数和字节数后不再询问是否继续",
+	"About to process %d files (%s)\n":                                               "即将处理 %d 个文件（%s）\n",
+	"Process %d files (%s)? (Y/n)":                                                   "处理 %d 个文件（%s）吗？(Y/n)",
+	"Error getting confirmation: %v\n":                                               "获取确认时出错：%v\n",
+
+	// locked files
+	"Try the files that were in use by other programs once more after all the others": "在处理完其他文件后，再次尝试被其他程序占用的文件",
+	"Skipping %s, it's in use by another program\n":                                   "跳过 %s，它正被其他程序占用\n",
+	"Retrying %d files that were in use\n":                                            "正在重试 %d 个曾被占用的文件\n",
+	"Skipping %s again: %v\n":                                                         "再次跳过 %s：%v\n",
+	"Synced %s\n":                                                                     "已同步 %s\n",
+
+	// one file system
+	"Don't descend into directories on other filesystems (mount points) while walking, like du -x": "遍历时不进入其他文件系统（挂载点）中的目录，类似 du -x",
+	"Not crossing into %s, it's on another filesystem\n":                                           "不进入 %s，它位于其他文件系统\n",
+
+	// permissions
+	"List the permissions and owners recorded for the files of a volume": "列出为卷中文件记录的权限和所有者",
+	"List the permission bits, user and group recorded for the files of a volume, or on Windows the owner of their ACL. With --world-writable only files anyone can modify are listed, with --orphaned only files whose owner no longer has an account on this machine, such as the files of a removed user. When both are given, files must match both.\n\nPermissions are recorded when a file is synced, run 'fsak sync info --force' to record them for files synced before they were tracked.": "列出为卷中文件记录的权限位、用户和组，在 Windows 上则列出其 ACL 的所有者。使用 --world-writable 时只列出任何人都能修改的文件，使用 --orphaned 时只列出所有者在本机已没有账户的文件，例如已删除用户的文件。同时指定两者时，文件需同时满足。\n\n权限在同步文件时记录，对于开始记录权限之前同步的文件，运行 'fsak sync info --force' 以记录其权限。",
+	"Columns to show, in order (path, mode, user, group)":                                                                          "要显示的列，按顺序（path、mode、user、group）",
+	"Only list files anyone can modify":                                                                                            "只列出任何人都能修改的文件",
+	"Only list files whose owner no longer has an account on this machine":                                                         "只列出所有者在本机已没有账户的文件",
+	"Warning: %d files have no recorded permissions and were skipped, run 'fsak sync info --force' on the volume to record them\n": "警告：%d 个文件没有记录权限，已跳过，请对该卷运行 'fsak sync info --force' 以记录权限\n",
+	"Warning: Could not restore the owner of %s: %v\n":                                                                             "警告：无法恢复 %s 的所有者：%v\n",
+	"Warning: Could not restore the permissions of %s: %v\n":                                                                       "警告：无法恢复 %s 的权限：%v\n",
+	"Warning: Could not restore the ACL of %s: %v\n":                                                                               "警告：无法恢复 %s 的 ACL：%v\n",
+
+	// extended attributes
+	"Record the extended attributes of files (user.*, security labels, macOS quarantine flags), so restore can reapply them": "记录文件的扩展属性（user.*、安全标签、macOS 隔离标记），以便恢复时重新应用",
+	"Warning: Could not read extended attributes of %s: %v\n":                                                                "警告：无法读取 %s 的扩展属性：%v\n",
+	"Warning: Could not copy the extended attributes of %s: %v\n":                                                            "警告：无法复制 %s 的扩展属性：%v\n",
+	"Warning: Could not restore the extended attributes of %s: %v\n":                                                         "警告：无法恢复 %s 的扩展属性：%v\n",
+
+	// file types
+	"Summarize the recorded files by type": "按类型汇总已记录的文件",
+	"Print the number and total size of the recorded files of each extension, with the MIME type it implies, largest first. Without a volume every record is counted. Extensions and MIME types are recorded with the files, so this only reads the indexes.": "打印每种扩展名的已记录文件数量和总大小，以及对应的 MIME 类型，按大小从大到小排列。未指定卷时统计所有记录。扩展名和 MIME 类型随文件一起记录，因此只需读取索引。",
+	"Columns to show, in order (ext, mime, files, size)": "要显示的列，按顺序（ext、mime、files、size）",
+	"(none)":                        "（无）",
+	"Found %d files of %d types.\n": "共找到 %d 个文件，%d 种类型。\n",
+	"Only consider files with this extension, such as jpg":                                                                  "只考虑具有此扩展名的文件，例如 jpg",
+	"Only consider files of this MIME type, or of all the types starting with it when it ends with a slash, such as image/": "只考虑此 MIME 类型的文件，以斜杠结尾时则考虑所有以其开头的类型，例如 image/",
+
+	// prune
+	"Delete the records of a tag or a volume in bulk": "批量删除某个标签或卷的记录",
+	"Delete the records synced with a tag, or of a volume, with their history and extended attributes, so the catalog of disks that are gone or of data that no longer matters can be expired without writing SQL. With --older-than only the records that weren't synced for that long are deleted, such as 1y, 6m, 2w or 30d. The files themselves are never touched.\n\nWithout --tag and --volume, the rules of the [retention] section of the configuration are applied, such as \"tag:old-usb = 1y\" or \"volume:BACKUP-2019 = 6m\".": "删除使用某个标签同步的记录或某个卷的记录，连同其历史和扩展属性，无需编写 SQL 即可让已不存在的磁盘或不再关心的数据的目录过期。使用 --older-than 时只删除超过该时长未同步的记录，例如 1y、6m、2w 或 30d。文件本身永远不会被改动。\n\n未指定 --tag 和 --volume 时，应用配置中 [retention] 段的规则，例如 \"tag:old-usb = 1y\" 或 \"volume:BACKUP-2019 = 6m\"。",
+	"Delete the records synced with this tag":                                              "删除使用此标签同步的记录",
+	"Delete the records of this volume, by ID or label":                                    "删除此卷的记录，按 ID 或标签指定",
+	"Only delete the records that weren't synced for this long, such as 1y, 6m, 2w or 30d": "只删除超过此时长未同步的记录，例如 1y、6m、2w 或 30d",
+	"Only print how many records would be deleted":                                         "只打印将删除多少条记录",
+	"Error: invalid --older-than: %v\n":                                                    "错误：无效的 --older-than：%v\n",
+	"Error loading config: %v\n":                                                           "加载配置出错：%v\n",
+	"Error: --tag or --volume is required when no [retention] rules are configured\n":      "错误：未配置 [retention] 规则时需要 --tag 或 --volume\n",
+	"Error pruning records: %v\n":                                                          "清理记录出错：%v\n",
+	"tag %s":                                                                               "标签 %s",
+	"volume %s":                                                                            "卷 %s",
+	"last synced before %s":                                                                "最后同步早于 %s",
+	"No records of %s to delete\n":                                                         "没有需要删除的 %s 的记录\n",
+	"%d records (%s) of %s would be deleted\n":                                             "将删除 %[3]s 的 %[1]d 条记录（%[2]s）\n",
+	"Deleted %d records (%s) of %s\n":                                                      "已删除 %[3]s 的 %[1]d 条记录（%[2]s）\n",
+	"Dry run: %d records would be deleted.\n":                                              "试运行：将删除 %d 条记录。\n",
+	"Pruned %d records.\n":                                                                 "已清理 %d 条记录。\n",
+
+	// verify
+	"Only check the records synced with this tag":        "只检查使用此标签同步的记录",
+	"Only list the files recorded with this tag":         "只列出使用此标签记录的文件",
+	"Check recorded files against their recorded hashes": "根据记录的哈希值检查已记录的文件",
+	"Hash the recorded files again and compare them with the MD5 and Blake3 values recorded by sync info, to find silent corruption such as bit rot on an aging disk. Only files whose size and modification time didn't change since they were synced are compared, the others are reported as modified.\n\nSelect the files with --tag, such as every file tagged critical, with paths, or both. Without either, every record with full hashes is verified. Disks of a NAS are read faster with --threads, and --json reports the result of every file. The command exits with status 1 when a file doesn't match.": "重新计算已记录文件的哈希值，并与 sync info 记录的 MD5 和 Blake3 值比较，以发现静默损坏，例如老化磁盘上的位衰减。只比较自同步以来大小和修改时间未变的文件，其他文件报告为已修改。\n\n使用 --tag 选择文件（例如所有标记为 critical 的文件）、使用路径选择，或两者结合。两者都未指定时，验证所有具有完整哈希值的记录。使用 --threads 可更快地读取 NAS 的磁盘，--json 会报告每个文件的结果。有文件不匹配时命令以状态 1 退出。",
+	"Only verify the files synced with this tag":      "只验证使用此标签同步的文件",
+	"Error verifying files: %v\n":                     "验证文件出错：%v\n",
+	"No recorded files with full hashes to verify.\n": "没有具有完整哈希值的已记录文件需要验证。\n",
+	"Missing: %s\n":                                           "缺失：%s\n",
+	"Modified since it was synced: %s\n":                      "同步后已修改：%s\n",
+	"Corrupted: %s doesn't match the hashes recorded on %s\n": "已损坏：%s 与 %s 记录的哈希值不匹配\n",
+	"Verified %d files: %d match, %d corrupted, %d modified since synced, %d missing.\n": "已验证 %d 个文件：%d 个匹配，%d 个损坏，%d 个同步后已修改，%d 个缺失。\n",
+
+	// duplicate scope
+	"Duplicates to find: all, within (copies inside the same folder given) or across (groups with copies in different folders given)": "要查找的重复文件：all、within（同一指定文件夹内的副本）或 across（副本位于不同指定文件夹的组）",
+	"Error: invalid --scope %s, expected %s, %s or %s\n":                                                                              "错误：无效的 --scope %s，应为 %s、%s 或 %s\n",
+	"%d of %d duplicate groups are in scope %s\n":                                                                                     "%[2]d 个重复文件组中有 %[1]d 个在范围 %[3]s 内\n",
+	"Error: --scope can't be combined with --import-results\n":                                                                        "错误：--scope 不能与 --import-results 同时使用\n",
+	// clean dirty flatten
+	"Move the dirty files directly into the delete directory, adding a numeric suffix to names already taken": "将脏文件直接移动到删除目录中，已被占用的名称添加数字后缀",
+	// quarantine manifest
+	"Warning: Could not record %s in the manifest: %v\n": "警告：无法在清单中记录 %s：%v\n",
+	// scan sessions
+	"Label of the scan session, shown by sync sessions":         "扫描会话的标签，由 sync sessions 显示",
+	"Error starting scan session: %v\n":                         "启动扫描会话出错：%v\n",
+	"Scan session %d started\n":                                 "扫描会话 %d 已开始\n",
+	"Error finishing scan session %d: %v\n":                     "结束扫描会话 %d 出错：%v\n",
+	"Scan session %d: %d files added, %d updated, %d skipped\n": "扫描会话 %d：新增 %d 个文件，更新 %d 个，跳过 %d 个\n",
+	"List the scan sessions of sync info":                       "列出 sync info 的扫描会话",
+	"List the runs of sync info, newest first, with their label, tag and directories, when they started and ended, and how many files each added, updated and skipped, to see how each scan changed the catalog.": "列出 sync info 的运行记录（最新的在前），包括标签、标记和目录、开始和结束时间，以及每次新增、更新和跳过的文件数，以查看每次扫描如何改变了目录。",
+	"Error listing scan sessions: %v\n":          "列出扫描会话出错：%v\n",
+	"List the records written by a scan session": "列出扫描会话写入的记录",
+	"List the records last written by a scan session of sync info, and whether the session added them, found them changed or only refreshed them. Records written again by a later command are listed under that command.": "列出 sync info 扫描会话最后写入的记录，以及该会话是新增了它们、发现它们已更改还是仅刷新了它们。被之后的命令再次写入的记录归属于该命令。",
+	"Error showing scan session: %v\n":                     "显示扫描会话出错：%v\n",
+	"Undo the changes a scan session made to the database": "撤销扫描会话对数据库所做的更改",
+	"Undo what a scan session of sync info did to the database: the records it added are deleted, even when later scans refreshed them, and the previous versions of the files it found changed are restored, at the paths they had when the session followed a moved file. Changed records written again by a later command aren't restored, and the files themselves are never touched.": "撤销 sync info 扫描会话对数据库所做的操作：删除它新增的记录（即使之后的扫描刷新过它们），并恢复它发现已更改的文件的先前版本，若会话跟随了移动的文件则恢复到原来的路径。被之后的命令再次写入的已更改记录不会恢复，文件本身从不改动。",
+	"Error rolling back scan session: %v\n": "回滚扫描会话出错：%v\n",
+	"Number of sessions to list":            "要列出的会话数量",
+	"Columns to show, in order (id, started, elapsed, label, tag, dirs, added, updated, skipped, hashed, state)": "要显示的列，按顺序（id、started、elapsed、label、tag、dirs、added、updated、skipped、hashed、state）",
+	"Columns to show, in order (change, path, size)":                                                             "要显示的列，按顺序（change、path、size）",
+	"Only report what would be undone":                                                                           "仅报告将被撤销的内容",
+	"Error: invalid session %s, expected the ID listed by sync sessions\n":                                       "错误：无效的会话 %s，应为 sync sessions 列出的 ID\n",
+	"rolled back":                "已回滚",
+	"interrupted":                "已中断",
+	"done":                       "已完成",
+	"refreshed":                  "已刷新",
+	"%d scan sessions listed.\n": "已列出 %d 个扫描会话。\n",
+	"Session %d (%s): %d files added, %d updated, %d skipped, %d records still written by it.\n": "会话 %d（%s）：新增 %d 个文件，更新 %d 个，跳过 %d 个，仍有 %d 条记录由其写入。\n",
+	"Dry run: %d records would be restored and %d deleted.\n":                                    "试运行：将恢复 %d 条记录，删除 %d 条。\n",
+	"Rolled back scan session %d: %d records restored, %d deleted.\n":                            "已回滚扫描会话 %d：恢复 %d 条记录，删除 %d 条。\n",
+	// failed files
+	"Only sync the files previous scans couldn't hash, under the directories given if any": "仅同步之前的扫描无法计算哈希的文件，如指定了目录则限于这些目录下",
+	"Error getting the files that couldn't be hashed: %v\n":                                "获取无法计算哈希的文件出错：%v\n",
+	"Files previous scans couldn't hash: %d\n":                                             "之前的扫描无法计算哈希的文件：%d\n",
+	"matched the blacklist":                                                                "匹配黑名单",
+	"Warning: Could not record why %s was skipped: %v\n":                                   "警告：无法记录跳过 %s 的原因：%v\n",
+	"List the files sync info couldn't hash":                                               "列出 sync info 无法计算哈希的文件",
+	"List the files the last scans of sync info couldn't hash, with why: permission denied, read error, too new (the file changed while it was hashed), excluded by the blacklist or locked by another program. sync info --retry-failed syncs exactly these files again.": "列出 sync info 最近的扫描无法计算哈希的文件及原因：权限被拒绝、读取错误、太新（文件在计算哈希时发生了变化）、被黑名单排除或被其他程序锁定。sync info --retry-failed 会重新同步这些文件。",
+	"Error listing failed files: %v\n":                       "列出失败的文件出错：%v\n",
+	"Columns to show, in order (status, path, size, reason)": "要显示的列，按顺序（status、path、size、reason）",
+	"%d files couldn't be hashed.\n":                         "%d 个文件无法计算哈希。\n",
+	"ok":                                                     "正常",
+	"permission denied":                                      "权限被拒绝",
+	"read error":                                             "读取错误",
+	"too new":                                                "太新",
+	"excluded":                                               "已排除",
+	"locked":                                                 "已锁定",
+	// touchsync
+	"Restore modification times from the catalog": "从目录数据库恢复修改时间",
+	"Hash the files under the paths given and restore the modification time recorded for their contents, when it's earlier than the one they have now. This repairs the dates a copy tool or a backup program clobbered, such as photos that all got the date they were copied on, while the contents are unchanged.\n\nThe earliest time recorded for the same contents is used, from the records of every path holding them and their previous versions, so files copied to a new location or synced again after their dates were clobbered are repaired too. Files whose contents aren't in the catalog are left alone.": "计算给定路径下文件的哈希，当数据库中为其内容记录的修改时间早于当前时间时恢复该时间。这可以修复被复制工具或备份程序改写的日期，例如全部变成复制日期的照片，而内容未变。\n\n使用为相同内容记录的最早时间，来源包括保存该内容的所有路径的记录及其以前的版本，因此复制到新位置或在日期被改写后再次同步的文件也会被修复。内容不在目录数据库中的文件保持不变。",
+	"Only report the modification times that would be restored":                           "仅报告将要恢复的修改时间",
+	"Error restoring modification times: %v\n":                                            "恢复修改时间出错：%v\n",
+	"Would restore %s from %s to %s\n":                                                    "将把 %s 从 %s 恢复为 %s\n",
+	"Restored %s from %s to %s\n":                                                         "已把 %s 从 %s 恢复为 %s\n",
+	"Error restoring the modification time of %s: %v\n":                                   "恢复 %s 的修改时间出错：%v\n",
+	"Warning: Could not update the record of %s: %v\n":                                    "警告：无法更新 %s 的记录：%v\n",
+	"Dry run: %d modification times would be restored, %d files aren't in the catalog.\n": "试运行：将恢复 %d 个修改时间，%d 个文件不在目录数据库中。\n",
+	"Restored %d modification times, %d files aren't in the catalog.\n":                   "已恢复 %d 个修改时间，%d 个文件不在目录数据库中。\n",
+	// huge files
+	"Files of at least this size are hashed by the --huge-threads workers, such as 512M or 4G": "至少此大小的文件由 --huge-threads 的工作线程计算哈希，例如 512M 或 4G",
+	"Number of threads for the files of at least --huge-size":                                  "处理至少 --huge-size 的文件的线程数",
+	"Error: invalid --huge-size: %v\n":                                                         "错误：无效的 --huge-size：%v\n",
+	"Error: --threads and --huge-threads must be at least 1\n":                                 "错误：--threads 和 --huge-threads 必须至少为 1\n",
+	// fast rescan
+	"Skip the files of directories whose modification time and number of entries didn't change since the last scan": "跳过自上次扫描以来修改时间和条目数都未变化的目录中的文件",
+	"Error getting the directories scanned before: %v\n":                                                            "获取之前扫描的目录出错：%v\n",
+	"Error recording the directories scanned: %v\n":                                                                 "记录扫描的目录出错：%v\n",
+	// workspace exclusion
+	"Don't exclude the workspace, the database and its backups from scans": "扫描时不排除工作区、数据库及其备份",
+	"Not scanning %s, it holds the state of fsak\n":                        "不扫描 %s，其中保存着 fsak 的状态\n",
+	// canonical paths
+	"Canonicalizing the paths of %d records...\n": "正在规范化 %d 条记录的路径...\n",
+	// volume IDs of mounted directories
+	"Moved %d records of %s to the volume ID %s\n": "已将 %d 条记录（%s 上）移到卷 ID %s\n",
+	// copy hashing
+	"Warning: %s changed since it was hashed, the copy has its current contents\n": "警告：%s 在计算哈希后发生了变化，副本为其当前内容\n",
+	// review pages
+	"Number of groups to review before asking whether to go on, 0 reviews all groups at once":                         "每审阅多少组后询问是否继续，0 表示一次审阅所有组",
+	"Skip the groups decided in earlier reviews, to go on with an interrupted review":                                 "跳过之前审阅中已决定的组，以继续中断的审阅",
+	"Reviewed %d of %d groups, go on with the next page?":                                                             "已审阅 %d / %d 组，继续下一页吗？",
+	"Review stopped at group %d of %d, run clean dup again with --resume to go on with the groups not decided yet.\n": "审阅在第 %d / %d 组停止，使用 --resume 再次运行 clean dup 以继续尚未决定的组。\n",
+	"Skipped %d groups decided in earlier reviews.\n":                                                                 "已跳过之前审阅中决定的 %d 组。\n",
+	"Warning: Could not record the decision of group %d: %v\n":                                                        "警告：无法记录第 %d 组的决定：%v\n",
+	// keep rules
+	"Review every group interactively, ignoring the [keep] rules of the config": "交互审阅每一组，忽略配置中的 [keep] 规则",
+	"Keeping %s by the keep rule %s\n":                                          "保留 %s（保留规则 %s）\n",
+	// intake
+	"Inventory a newly attached drive against the catalog":                            "对照目录清点新接入的驱动器",
+	"Tag given to every file of the drive":                                            "为驱动器上每个文件设置的标签",
+	"List the files whose contents are only on the drive":                             "列出内容仅存在于该驱动器上的文件",
+	"Columns of --list-new to show, in order (size, modified, path)":                  "--list-new 显示的列，按顺序（size, modified, path）",
+	"Intake of %s, tagged %q: %d files (%s)\n":                                        "清点 %s，标签 %q：%d 个文件（%s）\n",
+	"Already in the catalog: %d files (%s, %.1f%% of the bytes)\n":                    "已在目录中：%d 个文件（%s，占字节的 %.1f%%）\n",
+	"Only on this drive: %d files (%s, %.1f%% of the bytes)\n":                        "仅在此驱动器上：%d 个文件（%s，占字节的 %.1f%%）\n",
+	"Not hashed: %d files (%s), see sync failed\n":                                    "未计算哈希：%d 个文件（%s），参见 sync failed\n",
+	"%d records are of files no longer on the drive, run clean info to remove them\n": "%d 条记录对应的文件已不在驱动器上，运行 clean info 删除它们\n",
+	"Everything on %s is already in the catalog, the drive can be wiped.\n":           "%s 上的所有内容都已在目录中，可以清空该驱动器。\n",
+	"Keep %s until the files only on it are copied elsewhere.\n":                      "在仅存于 %s 上的文件复制到别处之前，请保留它。\n",
+	"Sync every file of a newly attached drive with a tag, then report how much of its contents is already recorded elsewhere in the catalog, by MD5 and Blake3 values, and how much is only on the drive. When nothing is only on the drive, it can be wiped without losing anything the catalog knows of.\n\nFiles already synced from the drive keep their records and are tagged too. With --list-new, the files only on the drive are listed, so they can be copied before wiping it.": "用标签同步新接入驱动器上的每个文件，然后按 MD5 和 Blake3 值报告其内容有多少已记录在目录的其他位置、有多少仅存在于该驱动器上。当没有内容仅存在于该驱动器上时，可以清空它而不会丢失目录所知的任何内容。\n\n之前已从该驱动器同步的文件保留其记录，同样会被打上标签。使用 --list-new 时会列出仅在该驱动器上的文件，以便在清空前复制它们。",
+	"Error reporting the intake of %s: %v\n": "报告 %s 的清点结果时出错：%v\n",
+	"Error: --threads must be at least 1\n":  "错误：--threads 必须至少为 1\n",
+	// json
+	"Write the results to stdout as JSON lines for jq and other tools, messages go to stderr": "以 JSON 行的形式将结果写到标准输出，供 jq 等工具使用，消息写到标准错误",
+	"Error writing the JSON output: %v\n":                                                     "写入 JSON 输出时出错：%v\n",
+	// dup overlap
+	"Report how much of two datasets have the same content": "报告两个数据集中有多少内容相同",
+	"Report how much of the files of two datasets, tags or directories, have their contents in the other one, by the MD5 and Blake3 values recorded by sync info, such as how many bytes of an old laptop backup are already on the NAS archive. A dataset is tag:<tag> for the records synced with a tag, or a path for the records under it. Both directions are reported, files without full hashes are counted apart.": "按 sync info 记录的 MD5 和 Blake3 值，报告两个数据集（标签或目录）的文件中有多少内容也存在于另一个数据集中，例如旧笔记本备份中有多少字节已在 NAS 归档中。数据集写作 tag:<tag> 表示以该标签同步的记录，或写作路径表示其下的记录。两个方向都会报告，没有完整哈希的文件单独计数。",
+	"Columns to show, in order (set, files, size, shared, shared-size, percent, unhashed)": "要显示的列，按顺序（set, files, size, shared, shared-size, percent, unhashed）",
+	"Error comparing %s and %s: %v\n":                                              "比较 %s 和 %s 时出错：%v\n",
+	"%s vs %s: %.1f%% of the bytes already present\n":                              "%s 对比 %s：%.1f%% 的字节已存在\n",
+	"Files without full hashes aren't compared, sync them without --quick first\n": "没有完整哈希的文件不参与比较，请先不带 --quick 同步它们\n",
+	// undo
+	"Add the entries of a manifest written by undo export to the manifest of a deleted folder, the one of the workspace unless another is given, so undo can put its files back. Files are found at the path they had in the folder, or by their size and Blake3 value when they were moved within it. With --map the original paths are rewritten for this machine.": "将 undo export 写出的清单条目加入删除目录（未指定时为工作区的删除目录）的清单，以便 undo 放回其中的文件。文件按其在目录中的原路径查找，若已在目录内移动，则按大小和 Blake3 值查找。使用 --map 时会为本机改写原始路径。",
+	"Dry run, %d of %d files could be put back.\n":                "试运行，可以放回 %d 个文件（共 %d 个）。\n",
+	"Error exporting the manifest of %s: %v\n":                    "导出 %s 的清单时出错：%v\n",
+	"Error importing %s: %v\n":                                    "导入 %s 时出错：%v\n",
+	"Error putting files back: %v\n":                              "放回文件时出错：%v\n",
+	"Export the manifest of a deleted folder for another machine": "导出删除目录的清单供另一台机器使用",
+	"Exported %d entries to %s\n":                                 "已导出 %d 个条目到 %s\n",
+	"Import a manifest exported on another machine":               "导入在另一台机器上导出的清单",
+	"Imported %d entries into %s, %d files weren't found.\n":      "已导入 %d 个条目到 %s，%d 个文件未找到。\n",
+	"Move the files that clean dup, clean dirty and clean build moved to a deleted folder, the one of the workspace unless another is given, back to their original paths, as recorded in its MANIFEST.tsv. Files whose original path is taken again are left in the deleted folder, and the manifest keeps the files that weren't put back, so undo can be run again.\n\nWhen the deleted folder was copied to another machine, --map rewrites the original paths, such as --map /Users/me=/home/me. A manifest lost on the way can be exported with undo export on the first machine and imported with undo import on the second one.": "将 clean dup、clean dirty 和 clean build 移到删除目录（未指定时为工作区的删除目录）的文件，按其 MANIFEST.tsv 的记录移回原始路径。原始路径已被占用的文件会留在删除目录中，清单保留未放回的文件，因此可以再次运行 undo。\n\n删除目录被复制到另一台机器时，--map 会改写原始路径，例如 --map /Users/me=/home/me。途中丢失的清单可以在第一台机器上用 undo export 导出，再在第二台机器上用 undo import 导入。",
+	"Only report the files that would be put back":                                             "只报告将被放回的文件",
+	"Put %d files back, %d left in %s. Run sync info on their folders to record them again.\n": "已放回 %d 个文件，%d 个留在 %s。请对其所在目录运行 sync info 以重新记录它们。\n",
+	"Put %s back to %s\n": "已将 %s 放回 %s\n",
+	"Put the files moved to a deleted folder back where they came from":                     "将移到删除目录的文件放回原处",
+	"Rewrite the original paths starting with a directory, as <old>=<new>, can be repeated": "改写以某目录开头的原始路径，格式为 <old>=<new>，可重复",
+	"Skipping %s, %s exists\n":            "跳过 %s，%s 已存在\n",
+	"Skipping %s, it isn't in %s\n":       "跳过 %s，它不在 %s 中\n",
+	"Skipping %s, it's no longer in %s\n": "跳过 %s，它已不在 %s 中\n",
+	"Skipping %s: %v\n":                   "跳过 %s：%v\n",
+	"Would put %s back to %s\n":           "将把 %s 放回 %s\n",
+	"Write the manifest of a deleted folder, the one of the workspace unless another is given, to a file with the size and the Blake3 value of every file still in the folder, so undo import can find the files on another machine even after the folder was reorganized there.": "将删除目录（未指定时为工作区的删除目录）的清单写入文件，并附上目录中每个文件的大小和 Blake3 值，以便 undo import 即使在另一台机器上重新整理过目录后也能找到这些文件。",
+	// find
+	"Error finding files: %v\n":                                        "查找文件时出错：%v\n",
+	"Error: invalid --%s date %s, expected YYYY-MM-DD\n":               "错误：无效的 --%s 日期 %s，应为 YYYY-MM-DD\n",
+	"Error: invalid --%s: %v\n":                                        "错误：无效的 --%s：%v\n",
+	"Error: invalid --name pattern %s: %v\n":                           "错误：无效的 --name 模式 %s：%v\n",
+	"Find files recorded in the database":                              "查找数据库中记录的文件",
+	"Only files at least this large, such as 1G":                       "只包括不小于此大小的文件，例如 1G",
+	"Only files at most this large, such as 100M":                      "只包括不大于此大小的文件，例如 100M",
+	"Only files at or under this path":                                 "只包括此路径本身或其下的文件",
+	"Only files modified before this date (YYYY-MM-DD)":                "只包括在此日期之前修改的文件（YYYY-MM-DD）",
+	"Only files modified on or after this date (YYYY-MM-DD)":           "只包括在此日期当天或之后修改的文件（YYYY-MM-DD）",
+	"Only files synced with this tag":                                  "只包括以此标签同步的文件",
+	"Only files whose name matches this glob pattern, such as '*.iso'": "只包括文件名匹配此通配模式的文件，例如 '*.iso'",
+	"Only files with this MD5, Blake3 or SHA-256 value":                "只包括 MD5、Blake3 或 SHA-256 值为此值的文件",
+	"Print the paths of the files recorded in the database matching all the given filters: a glob pattern on the file name, a size range, a hash value, a tag, a directory and a modification date range. Nothing is read from disk, so the files of drives that aren't attached are found too.\n\nSizes are given like 512M or 1.5G, dates as YYYY-MM-DD. --hash matches the MD5, Blake3 or SHA-256 value. The paths are printed one per line, or separated by NUL bytes with --print0.": "打印数据库中记录的、匹配所有给定过滤条件的文件路径：文件名通配模式、大小范围、哈希值、标签、目录和修改日期范围。不会读取磁盘，因此未连接的磁盘上的文件也能找到。\n\n大小写作 512M 或 1.5G，日期写作 YYYY-MM-DD。--hash 匹配 MD5、Blake3 或 SHA-256 值。路径每行打印一个，使用 --print0 时以 NUL 字节分隔。",
+	"Print the paths separated by NUL bytes for xargs -0": "以 NUL 字节分隔打印路径，供 xargs -0 使用",
+	// mv
+	"Move a file or folder like mv, so that its records follow it in the catalog. When the destination is an existing folder, the source is moved into it.\n\nWithin a filesystem the source is renamed. To another filesystem, every file is copied while its Blake3 and MD5 values are calculated, the copy is read back and compared with them and with the values recorded for the source, and flushed to disk, before the source is deleted. The copy keeps the modification time, permissions and extended attributes of the source. A file whose contents don't match is left in place.": "像 mv 一样移动文件或目录，并让其记录在目录库中随之移动。目标是已存在的目录时，源会被移入其中。\n\n在同一文件系统内，源会被重命名。移到另一个文件系统时，每个文件在复制的同时计算 Blake3 和 MD5 值，副本会被读回并与这些值以及源的记录值比较，并刷新到磁盘，然后才删除源。副本保留源的修改时间、权限和扩展属性。内容不匹配的文件会留在原处。",
+	"Move a file or folder, verifying copies to another drive": "移动文件或目录，并校验复制到其他磁盘的副本",
+	"Moved %d files (%s) to %s, verified.\n":                   "已移动 %d 个文件（%s）到 %s，已校验。\n",
+	"Moved %s to %s (%s), verified.\n":                         "已将 %s 移动到 %s（%s），已校验。\n",
+	"Renamed %s to %s, %d records follow it.\n":                "已将 %s 重命名为 %s，%d 条记录随之移动。\n",
+	"Warning: %s still holds files that weren't moved\n":       "警告：%s 中仍有未移动的文件\n",
+	"Warning: Could not set the permissions of %s: %v\n":       "警告：无法设置 %s 的权限：%v\n",
+	"Warning: Skipping %s, it is not a regular file\n":         "警告：跳过 %s，它不是普通文件\n",
+	// verify
+	"Number of files verified in parallel": "并行验证的文件数",
+	// cp
+	"%s is already copied to %s.\n":           "%s 已复制到 %s。\n",
+	"Copied %d files (%s) to %s, verified.\n": "已复制 %d 个文件（%s）到 %s，已校验。\n",
+	"Copied %s to %s (%s), verified.\n":       "已将 %s 复制到 %s（%s），已校验。\n",
+	"Copy a file or folder like cp, verifying every copy and recording it in the catalog. When the destination is an existing folder, the source is copied into it.\n\nEvery file is copied while its Blake3 and MD5 values are calculated, the copy is read back and compared with them and with the values recorded for the source, and flushed to disk, before it appears under its name. The copy keeps the modification time, permissions, owner when allowed, and extended attributes of the source. A file whose contents don't match stops the copy.\n\nAn interrupted copy is resumed by running the same command again: a file copied halfway continues from where it stopped, and the files already copied with the same size and modification time are skipped.": "像 cp 一样复制文件或目录，校验每个副本并将其记录到目录库。目标是已存在的目录时，源会被复制到其中。\n\n每个文件在复制的同时计算 Blake3 和 MD5 值，副本会被读回并与这些值以及源的记录值比较，并刷新到磁盘，然后才以其名称出现。副本保留源的修改时间、权限、（允许时的）所有者和扩展属性。内容不匹配的文件会中止复制。\n\n再次运行同一命令即可恢复中断的复制：复制到一半的文件从中断处继续，大小和修改时间相同的已复制文件会被跳过。",
+	"Copy a file or folder, verifying every copy":                               "复制文件或目录，并校验每个副本",
+	"Error copying %s to %s: %v\n":                                              "将 %s 复制到 %s 时出错：%v\n",
+	"Resuming the copy of %s after %s\n":                                        "从 %[2]s 之后继续复制 %[1]s\n",
+	"Warning: Could not set the ACL of %s: %v\n":                                "警告：无法设置 %s 的 ACL：%v\n",
+	"Warning: The copy of %s resumed from %s doesn't match, copying it again\n": "警告：从 %[2]s 处继续的 %[1]s 副本不匹配，重新复制\n",
+	// snapshot
+	"moved": "移动",
+	"%d files added, %d removed, %d changed, %d moved.\n":              "新增 %d 个文件，删除 %d 个，修改 %d 个，移动 %d 个。\n",
+	"%d snapshots listed.\n":                                           "已列出 %d 个快照。\n",
+	"Columns to show, in order (id, created, label, files, size, dir)": "要显示的列，按顺序（id、created、label、files、size、dir）",
+	"Commands for recording the paths, sizes and hashes of the files of a directory tree at a point in time, and comparing two such snapshots later to see which files were added, removed, changed or moved. Snapshots are stored in the database, the files themselves aren't copied.": "记录某一时刻目录树中文件的路径、大小和哈希值，并在之后比较两个这样的快照，查看哪些文件被新增、删除、修改或移动。快照保存在数据库中，文件本身不会被复制。",
+	"Compare two snapshots": "比较两个快照",
+	"Compare two snapshots by the path of their files relative to their directory, and list the files only in the second one (added), only in the first one (removed), at the same path with different content (changed), and removed from one path and added at another with the same MD5 and Blake3 values (moved). The snapshots are given by the ID listed by snapshot list, and may be of different trees, such as a folder and its backup.\n\nThe command exits with status 1 when the snapshots differ.": "按文件相对于其目录的路径比较两个快照，列出只在第二个快照中的文件（新增）、只在第一个快照中的文件（删除）、路径相同但内容不同的文件（修改），以及从一个路径删除并以相同 MD5 和 Blake3 值出现在另一路径的文件（移动）。快照由 snapshot list 列出的 ID 指定，可以属于不同的目录树，例如一个目录及其备份。\n\n快照不同时命令以状态 1 退出。",
+	"Created snapshot %d of %s: %d files (%s).\n": "已创建 %[2]s 的快照 %[1]d：%[3]d 个文件（%[4]s）。\n",
+	"Delete a snapshot":                           "删除快照",
+	"Delete a snapshot and the entries of its files from the database. The files themselves are never touched.": "从数据库删除快照及其文件条目。文件本身不会被触碰。",
+	"Deleted snapshot %d of %s.\n":                                          "已删除 %[2]s 的快照 %[1]d。\n",
+	"Error comparing snapshots: %v\n":                                       "比较快照时出错：%v\n",
+	"Error creating snapshot of %s: %v\n":                                   "创建 %s 的快照时出错：%v\n",
+	"Error deleting snapshot: %v\n":                                         "删除快照时出错：%v\n",
+	"Error listing snapshots: %v\n":                                         "列出快照时出错：%v\n",
+	"Error: invalid snapshot %s, expected the ID listed by snapshot list\n": "错误：无效的快照 %s，应为 snapshot list 列出的 ID\n",
+	"Label describing the snapshot, shown by snapshot list":                 "描述快照的标签，由 snapshot list 显示",
+	"List the snapshots":                                                    "列出快照",
+	"List the snapshots, newest first, with their ID, label, directory and number of files, optionally only the ones of the trees at or under a directory.": "列出快照（最新的在前），包括其 ID、标签、目录和文件数，可选择只列出某目录本身或其下目录树的快照。",
+	"No differences between snapshot %d of %s and snapshot %d of %s.\n":                                                                                     "%[2]s 的快照 %[1]d 与 %[4]s 的快照 %[3]d 之间没有差异。\n",
+	"Record and compare the state of directory trees over time":                                                                                             "记录并比较目录树随时间变化的状态",
+	"Record the path, size, modification time, MD5 and Blake3 values of every file of a directory tree as a snapshot. The values recorded by sync info are used for the files that didn't change since, the others are hashed and recorded in the catalog too. The same directories as diff are skipped, such as .git and node_modules.": "将目录树中每个文件的路径、大小、修改时间、MD5 和 Blake3 值记录为快照。自同步以来未变化的文件使用 sync info 记录的值，其他文件会被计算哈希并同时记录到目录库。与 diff 一样跳过 .git 和 node_modules 等目录。",
+	"Record the state of a directory tree": "记录目录树的状态",
+	// watch
+	"Error processing file %s: %v\n":                                            "处理文件 %s 时出错：%v\n",
+	"Error watching directories: %v\n":                                          "监视目录时出错：%v\n",
+	"Error: --batch must be at least 1\n":                                       "错误：--batch 至少为 1\n",
+	"Error: --debounce must be positive\n":                                      "错误：--debounce 必须为正数\n",
+	"Keep the records of directories up to date as files change":                "在文件变化时保持目录记录为最新",
+	"Maximum number of records written to the database at once":                 "一次写入数据库的最大记录数",
+	"Removed %s (%d records)\n":                                                 "已移除 %s（%d 条记录）\n",
+	"Stopped watching.":                                                         "已停止监视。",
+	"Tag of the files recorded, files already recorded keep theirs":             "记录文件的标签，已记录的文件保留原标签",
+	"Time without events for a file before it's recorded, such as 500ms or 10s": "文件在记录前需保持无事件的时长，例如 500ms 或 10s",
+	"Warning: %v\n":                    "警告：%v\n",
+	"Warning: Could not read %s: %v\n": "警告：无法读取 %s：%v\n",
+	"Warning: Too many changes at once, some were missed, run sync info on %s\n": "警告：同时发生的变化过多，部分已遗漏，请对 %s 运行 sync info\n",
+	"Watch one or more directories and their subdirectories, and update the database as files are created, modified, renamed or deleted, instead of rescanning them with sync info. Run sync info on the directories first, changes made while they aren't watched are only found by a scan.\n\nA file is hashed once no event was received for it for --debounce, so a file being written is hashed once it's complete, and the records are written by batches. Renamed and moved files keep their records with their history, when they are at least 4 KiB and no other record has their contents on the volume. The records of deleted files are deleted. Stop watching with Ctrl-C, the pending changes are recorded first.": "监视一个或多个目录及其子目录，在文件被创建、修改、重命名或删除时更新数据库，而无需用 sync info 重新扫描。请先对这些目录运行 sync info，未监视期间发生的变化只能通过扫描发现。\n\n文件在 --debounce 时长内没有新事件后才会计算哈希，因此正在写入的文件会在完成后才计算，记录按批写入。重命名和移动的文件保留其记录及历史，前提是文件至少 4 KiB 且该卷上没有其他记录具有相同内容。已删除文件的记录会被删除。按 Ctrl-C 停止监视，待处理的变化会先被记录。",
+	"Watching %d directories under %s, press Ctrl-C to stop\n": "正在监视 %[2]s 下的 %[1]d 个目录，按 Ctrl-C 停止\n",
+	// clean info
+	"Number of directories checked in parallel": "并行检查的目录数",
+	"[ %d / %d (%.2f%%)]: Checked %s\n":         "[ %d / %d (%.2f%%)]：已检查 %s\n",
+	// journal
+	"Recording the changes as operation %d, undo %d reverses them\n":                   "正在将更改记录为操作 %d，运行 undo %d 可撤销\n",
+	"Warning: Could not record %s in the journal: %v\n":                                "警告：无法将 %s 记录到日志：%v\n",
+	"Warning: Could not record the operation in the journal, it can't be undone: %v\n": "警告：无法将操作记录到日志，它将无法撤销：%v\n",
+	"%d operations listed.\n": "已列出 %d 个操作。\n",
+	"Columns to show, in order (id, started, command, files, size, undone)": "要显示的列，按顺序（id、started、command、files、size、undone）",
+	"Deleted the copy %s of %s\n":                                           "已删除 %[2]s 的副本 %[1]s\n",
+	"Dry run, %d of %d files of operation %d could be undone.\n":            "演练：操作 %[3]d 的 %[2]d 个文件中有 %[1]d 个可以撤销。\n",
+	"Error listing operations: %v\n":                                        "列出操作时出错：%v\n",
+	"Error undoing the operation: %v\n":                                     "撤销操作时出错：%v\n",
+	"List the operations of the journal":                                    "列出日志中的操作",
+	"List the operations recorded in the journal, newest first, with their ID, the command, the number and size of the files moved or copied, and when they were undone.": "列出日志中记录的操作，最新的在前，包括 ID、命令、移动或复制的文件数量和大小，以及撤销时间。",
+	"No operation to undo in the journal, putting back the files of %s\n":                                                                                                 "日志中没有可撤销的操作，正在放回 %s 中的文件\n",
+	"Reverse an operation, or put the files of a deleted folder back":                                                                                                     "撤销一个操作，或将已删除文件夹中的文件放回原处",
+	"Reverse the last operation of the journal, or the one given by the ID listed by undo list. Every run of clean dup, clean dirty, clean build and merge dir that moved or copied files is recorded as an operation. The files it moved to a deleted folder are moved back to their original paths, with the records clean dup deleted, and the files merge dir copied are deleted unless they changed since. Files whose original path is taken again are left where they are, and the operation can be undone again once that's resolved.\n\nGiven a deleted folder instead, every file moved to it is moved back to its original path, as recorded in its MANIFEST.tsv, and the manifest keeps the files that weren't put back, so undo can be run again. Without arguments and without operations in the journal, the deleted folder of the workspace is used.\n\nWhen the deleted folder was copied to another machine, --map rewrites the original paths, such as --map /Users/me=/home/me. A manifest lost on the way can be exported with undo export on the first machine and imported with undo import on the second one.": "撤销日志中的最后一个操作，或由 undo list 列出的 ID 指定的操作。clean dup、clean dirty、clean build 和 merge dir 每次移动或复制文件的运行都会被记录为一个操作。移动到已删除文件夹的文件会被移回原路径，并恢复 clean dup 删除的记录；merge dir 复制的文件会被删除，除非它们之后被修改过。原路径已被占用的文件保持原位，问题解决后可以再次撤销该操作。\n\n如果给定的是已删除文件夹，则按其 MANIFEST.tsv 中的记录将其中的每个文件移回原路径，清单会保留未放回的文件，因此可以再次运行 undo。不带参数且日志中没有操作时，使用工作区的已删除文件夹。\n\n当已删除文件夹被复制到另一台机器时，--map 会重写原路径，例如 --map /Users/me=/home/me。途中丢失的清单可以在第一台机器上用 undo export 导出，在第二台机器上用 undo import 导入。",
+	"Undid %d files of operation %d, %d skipped. Run undo %d again once they're resolved.\n": "已撤销操作 %[2]d 的 %[1]d 个文件，跳过 %[3]d 个。问题解决后请再次运行 undo %[4]d。\n",
+	"Undid operation %d, %d files.\n":                                      "已撤销操作 %d，共 %d 个文件。\n",
+	"Undoing operation %d, %s of %s: %d files\n":                           "正在撤销操作 %[1]d，%[3]s 的 %[2]s：%[4]d 个文件\n",
+	"Warning: %s changed since it was moved, run sync info to record it\n": "警告：%s 在移动后已更改，请运行 sync info 记录它\n",
+	"Warning: Could not restore the record of %s: %v\n":                    "警告：无法恢复 %s 的记录：%v\n",
+	"Warning: Could not set the modification time of %s: %v\n":             "警告：无法设置 %s 的修改时间：%v\n",
+	"Warning: Could not update the manifest of %s: %v\n":                   "警告：无法更新 %s 的清单：%v\n",
+	"Would delete the copy %s of %s\n":                                     "将删除 %[2]s 的副本 %[1]s\n",
+	// quarantine
+	"%d files (%s) in %d deleted folders, the oldest moved %s.\n": "%d 个文件（%s）位于 %d 个已删除文件夹中，最早的于 %s 移入。\n",
+	"By deleted folder:":   "按已删除文件夹：",
+	"By source:":           "按来源：",
+	"By time since moved:": "按移入时长：",
+	"Commands for the deleted folders clean dup, clean dirty and clean build move files to.": "用于 clean dup、clean dirty 和 clean build 移入文件的已删除文件夹的命令。",
+	"Error summarizing the deleted folders: %v\n":                                            "汇总已删除文件夹时出错：%v\n",
+	"Error: --depth must be at least 1\n":                                                    "错误：--depth 至少为 1\n",
+	"Inspect the deleted folders":                                                            "查看已删除文件夹",
+	"No files in the deleted folders.\n":                                                     "已删除文件夹中没有文件。\n",
+	"Number of directory levels of the original paths the sources are grouped by":            "按原路径的多少级目录对来源分组",
+	"Skipping %s, it's not a folder\n":                                                       "跳过 %s，它不是文件夹\n",
+	"Summarize the files in the deleted folders by source, age and size":                     "按来源、时长和大小汇总已删除文件夹中的文件",
+	"Summarize the files sitting in deleted folders by the folder they were moved from and by how long ago they were moved, with their number and size, so it can be seen at a glance whether a folder is safe to purge. Without arguments, the deleted folder of the workspace and the ones the journal recorded moves to are summarized.\n\nThe original path and the time of every move are read from the MANIFEST.tsv of the folder. Files missing from it are counted with an unknown source, by their modification time. --depth sets how many directory levels of the original paths the sources are grouped by.": "按文件的来源文件夹和移入时长汇总已删除文件夹中的文件数量和大小，以便一眼看出某个文件夹是否可以安全清空。不带参数时，汇总工作区的已删除文件夹以及日志中记录过移入的文件夹。\n\n每次移动的原路径和时间从文件夹的 MANIFEST.tsv 中读取。清单中没有的文件按修改时间计入，来源未知。--depth 设置按原路径的多少级目录对来源分组。",
+	"unknown":            "未知",
+	"less than a week":   "不到一周",
+	"1 week to 1 month":  "一周到一个月",
+	"1 to 6 months":      "一到六个月",
+	"6 months to 1 year": "六个月到一年",
+	"more than a year":   "超过一年",
+	"FOLDER":             "文件夹",
+	"SOURCE":             "来源",
+	"MOVED":              "移入",
+	"OLDEST":             "最早",
+	// clean dup --diff-last
+	"Only list the groups that are new since the last run over the same folders, without changing anything": "只列出自上次对相同文件夹运行以来新出现的重复组，不做任何更改",
+	"%d of %d duplicate groups are new (%d new files), %s reclaimable.\n":                                   "%[2]d 个重复组中有 %[1]d 个是新的（%[3]d 个新文件），可回收 %[4]s。\n",
+	"Comparing with the %d groups found on %s\n":                                                            "与 %[2]s 找到的 %[1]d 个组进行比较\n",
+	"No earlier run of clean dup over these folders, every group is new\n":                                  "之前未对这些文件夹运行过 clean dup，所有组都是新的\n",
+	"No new duplicate groups since the last run, %d groups found.\n":                                        "自上次运行以来没有新的重复组，共找到 %d 个组。\n",
+	"Warning: Could not record the duplicate groups for --diff-last: %v\n":                                  "警告：无法记录供 --diff-last 使用的重复组：%v\n",
+	"new": "新增",
+	// clean
+	"Skipped %d records on volumes mounted more than once\n": "已跳过 %d 条位于多次挂载的卷上的记录\n",
+	// backend
+	"Warning: Could not convert table %s to %s, its paths are compared ignoring case: %v\n": "警告：无法将表 %s 转换为 %s，其路径比较将忽略大小写：%v\n",
+	// history
+	"Error showing file history: %v\n":                      "显示文件历史出错：%v\n",
+	"Error: invalid --since date %s, expected YYYY-MM-DD\n": "错误：无效的 --since 日期 %s，应为 YYYY-MM-DD\n",
+	"%s changed %d times.\n":                                "%s 共变化 %d 次。\n",
+	"%s changed %d times since %s.\n":                       "%[1]s 自 %[3]s 以来变化 %[2]d 次。\n",
+
+	// shell
+	"Error running shell: %v\n": "运行 shell 出错：%v\n",
+	"Type help for the shell builtins, any other line runs an fsak command. Exit with exit or Ctrl-D.": "输入 help 查看 shell 内置命令，其他输入会作为 fsak 命令运行。输入 exit 或按 Ctrl-D 退出。",
+	"Shell builtins:": "Shell 内置命令：",
+	"  cd <dir>              Change the directory relative paths are resolved against":         "  cd <dir>              切换解析相对路径所用的目录",
+	"  tag <tag> <paths...>  Set the tag of the cataloged files at or under the paths":         "  tag <tag> <paths...>  设置这些路径及其下已记录文件的标签",
+	"  help                  Show this help":                                                   "  help                  显示此帮助",
+	"  exit, quit            Leave the shell (or press Ctrl-D)":                                "  exit, quit            退出 shell（或按 Ctrl-D）",
+	"Any other line runs an fsak command, for example:":                                        "其他输入会作为 fsak 命令运行，例如：",
+	"  catalog search <volume> <pattern>, dup list -p <dir>, history <file>, clean dup <dirs>": "  catalog search <volume> <pattern>、dup list -p <dir>、history <file>、clean dup <dirs>",
+	"Use <command> --help for the options of a command.":                                       "使用 <command> --help 查看命令的选项。",
+	"Usage: tag <tag> <paths...>":                                                              "用法：tag <tag> <paths...>",
+	"Warning: already in the fsak shell":                                                       "警告：已经在 fsak shell 中",
+	"Error changing directory: %v\n":                                                           "切换目录出错：%v\n",
+	"Error running %s: %v\n":                                                                   "运行 %s 出错：%v\n",
+	"Error tagging files: %v\n":                                                                "标记文件出错：%v\n",
+	"Tagged %d files under %s with %s.\n":                                                      "已将 %[2]s 下的 %[1]d 个文件标记为 %[3]s。\n",
+	"Warning: no cataloged files under %s, run sync info first\n":                              "警告：%s 下没有已记录的文件，请先运行 sync info\n",
+
+	// Database health, skipped files and profiling
+	"The database %s is corrupted: %v\n":                                                "数据库 %s 已损坏：%v\n",
+	"Corrupted database moved to %s\n":                                                  "损坏的数据库已移动到 %s\n",
+	"An empty database will be created, run sync info to catalog your files again.\n":   "将创建一个空数据库，请运行 sync info 重新记录文件。\n",
+	"Restored the database from %s, files synced since then need to be synced again.\n": "已从 %s 恢复数据库，此后同步过的文件需要重新同步。\n",
+	"Warning: Could not back up the database: %v\n":                                     "警告：无法备份数据库：%v\n",
+	"Skipped %d files or directories that could not be read (%d in use by other programs, %d transient I/O errors that persisted through retries, %d permanent errors)\n": "跳过了 %d 个无法读取的文件或目录（%d 个被其他程序占用，%d 个重试后仍失败的临时 I/O 错误，%d 个永久错误）\n",
+	"Warning: Transient error on %s, retrying in %v (%d/%d): %v\n":                          "警告：%s 出现临时错误，%v 后重试（%d/%d）：%v\n",
+	"Error report written to %s\n":                                                          "错误报告已写入 %s\n",
+	"Use --errors-to <file> to write the skipped paths and reasons to a file\n":             "使用 --errors-to <file> 将跳过的路径及原因写入文件\n",
+	"Serving pprof on http://%s/debug/pprof/ and runtime metrics on http://%s/debug/vars\n": "pprof 地址为 http://%s/debug/pprof/，运行时指标地址为 http://%s/debug/vars\n",
+	"CPU profile written to %s\n":                                                           "CPU 性能分析已写入 %s\n",
+	"Heap profile written to %s\n":                                                          "堆内存分析已写入 %s\n",
+}
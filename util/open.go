@@ -0,0 +1,27 @@
+package util
+
+import (
+	"os/exec"
+	"runtime"
+)
+
+// OpenWithDefaultApp opens a file with the default application of the desktop, without waiting for it
+func OpenWithDefaultApp(path string) error {
+	var command *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		command = exec.Command("open", path)
+	case "windows":
+		// The empty argument is the window title start expects before a quoted path
+		command = exec.Command("cmd", "/c", "start", "", path)
+	default:
+		command = exec.Command("xdg-open", path)
+	}
+
+	if err := command.Start(); err != nil {
+		return err
+	}
+	// Reap the launcher in the background, the application outlives it
+	go command.Wait()
+	return nil
+}
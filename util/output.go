@@ -1,41 +1,163 @@
 package util
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// ANSI colors of the message prefixes
+const (
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorReset  = "\033[0m"
+)
+
+// messages is where messages and prompts are written, stdout unless it's reserved for machine-readable output
+var messages = os.Stdout
+
+// colors tells whether the message prefixes are colored
+var colors = useColors(os.Stdout)
+
+// MessagesToStderr writes messages and prompts to stderr, so stdout only carries machine-readable output
+func MessagesToStderr() {
+	messages = os.Stderr
+	colors = colors && useColors(os.Stderr)
+}
+
+// eventLogger receives the messages of a process without a console, such as the event log of a Windows service
+type eventLogger interface {
+	Info(eid uint32, msg string) error
+	Warning(eid uint32, msg string) error
+	Error(eid uint32, msg string) error
+}
+
+// messageLog also receives the success, warning and error messages when set, progress messages are left out
+var messageLog eventLogger
+
+// logMessage writes a message to the message log
+func logMessage(write func(uint32, string) error, format string, args []interface{}) {
+	message := T(format)
+	if len(args) > 0 {
+		message = fmt.Sprintf(message, args...)
+	}
+	write(1, strings.TrimSpace(message))
+}
+
+// jsonOutput encodes the results written to stdout by --json, nil when results are printed as messages
+var (
+	jsonOutput *json.Encoder
+	jsonMutex  sync.Mutex
 )
 
+// EnableJSON writes the results of the commands to stdout as JSON lines, one object per line with the kind of
+// result in its "event" field, and the messages and prompts to stderr
+func EnableJSON() {
+	MessagesToStderr()
+	jsonOutput = json.NewEncoder(os.Stdout)
+}
+
+// JSONEnabled reports whether --json is on
+func JSONEnabled() bool {
+	return jsonOutput != nil
+}
+
+// EmitJSON writes a result as a JSON line to stdout when --json is on, with its kind in the "event" field.
+// Workers may emit results concurrently.
+func EmitJSON(event string, fields map[string]any) {
+	if jsonOutput == nil {
+		return
+	}
+	object := make(map[string]any, len(fields)+1)
+	for name, value := range fields {
+		object[name] = value
+	}
+	object["event"] = event
+
+	jsonMutex.Lock()
+	defer jsonMutex.Unlock()
+	if err := jsonOutput.Encode(object); err != nil {
+		PrintError("Error writing the JSON output: %v\n", err)
+	}
+}
+
+// DisableColors prints the message prefixes without colors
+func DisableColors() {
+	colors = false
+}
+
+// useColors reports whether colors can be written to a file, which must be a terminal. NO_COLOR
+// (https://no-color.org) and TERM=dumb turn them off.
+func useColors(file *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	if !term.IsTerminal(int(file.Fd())) {
+		return false
+	}
+	return enableColors(file)
+}
+
+// colorize wraps a message prefix in a color when colors are on
+func colorize(prefix string, color string) string {
+	if !colors {
+		return prefix
+	}
+	return color + prefix + colorReset
+}
+
+// PrintPath0 prints a path terminated by a NUL byte to stdout, for piping into xargs -0
+func PrintPath0(path string) {
+	fmt.Fprintf(os.Stdout, "%s\x00", path)
+}
+
 // PrintProcess prints process information with the "> " prefix
 func PrintProcess(format string, args ...interface{}) {
 	if len(args) == 0 {
-		fmt.Printf("> %s\n", format)
+		fmt.Fprintf(messages, "> %s\n", T(format))
 	} else {
-		fmt.Printf("> "+format, args...)
+		fmt.Fprintf(messages, "> "+T(format), args...)
 	}
 }
 
 // PrintSuccess prints success information with the "[√] " prefix
 func PrintSuccess(format string, args ...interface{}) {
+	if messageLog != nil {
+		logMessage(messageLog.Info, format, args)
+	}
 	if len(args) == 0 {
-		fmt.Printf("[√] %s\n", format)
+		fmt.Fprintf(messages, "%s%s\n", colorize("[√] ", colorGreen), T(format))
 	} else {
-		fmt.Printf("[√] "+format, args...)
+		fmt.Fprintf(messages, colorize("[√] ", colorGreen)+T(format), args...)
 	}
 }
 
 // PrintError prints error information with the "[×] " prefix
 func PrintError(format string, args ...interface{}) {
+	if messageLog != nil {
+		logMessage(messageLog.Error, format, args)
+	}
+	runStats.errors.Add(1)
 	if len(args) == 0 {
-		fmt.Printf("[×] %s\n", format)
+		fmt.Fprintf(messages, "%s%s\n", colorize("[×] ", colorRed), T(format))
 	} else {
-		fmt.Printf("[×] "+format, args...)
+		fmt.Fprintf(messages, colorize("[×] ", colorRed)+T(format), args...)
 	}
 }
 
 // PrintWarning prints warning information with the "[!] " prefix
 func PrintWarning(format string, args ...interface{}) {
+	if messageLog != nil {
+		logMessage(messageLog.Warning, format, args)
+	}
 	if len(args) == 0 {
-		fmt.Printf("[!] %s\n", format)
+		fmt.Fprintf(messages, "%s%s\n", colorize("[!] ", colorYellow), T(format))
 	} else {
-		fmt.Printf("[!] "+format, args...)
+		fmt.Fprintf(messages, colorize("[!] ", colorYellow)+T(format), args...)
 	}
 }
@@ -0,0 +1,29 @@
+package util
+
+import (
+	"os"
+	"os/user"
+	"sync"
+)
+
+var (
+	ownerOnce    sync.Once
+	currentOwner string
+)
+
+// CurrentOwner returns the user@host recorded as the owner of the records created by this run, so the
+// records of a catalog shared by several users and machines can be told apart
+func CurrentOwner() string {
+	ownerOnce.Do(func() {
+		name := os.Getenv("USER")
+		if current, err := user.Current(); err == nil {
+			name = current.Username
+		}
+		host, err := os.Hostname()
+		if err != nil {
+			host = "unknown"
+		}
+		currentOwner = name + "@" + host
+	})
+	return currentOwner
+}
@@ -0,0 +1,65 @@
+package util
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// resolvedDirs caches the directories with their symlinks resolved, by absolute path
+var resolvedDirs sync.Map
+
+// CanonicalPath returns the form of a path the catalog stores and looks up: absolute, cleaned, and with the
+// symlinks of its directories resolved, so a file reached through a relative path or a symlinked directory
+// has one record. The file itself isn't resolved, a symlink to a file is recorded at its own path. The case
+// is kept, see PathKey.
+func CanonicalPath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	dir, name := filepath.Split(absPath)
+	if name == "" {
+		// The root of a filesystem
+		return absPath, nil
+	}
+	return filepath.Join(resolveDir(filepath.Clean(dir)), name), nil
+}
+
+// resolveDir resolves the symlinks of an absolute directory. Of a directory that doesn't exist, such as the
+// one of a record on a disconnected drive, the part that exists is resolved.
+func resolveDir(dir string) string {
+	if resolved, ok := resolvedDirs.Load(dir); ok {
+		return resolved.(string)
+	}
+
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		resolved = filepath.Join(resolveDir(parent), filepath.Base(dir))
+	}
+	resolvedDirs.Store(dir, resolved)
+	return resolved
+}
+
+// PathKey returns the key of the record of a canonical path. When paths are case-insensitive, the paths
+// differing only in case are the same file and share the key.
+func PathKey(path string) string {
+	if CaseInsensitivePaths() {
+		path = strings.ToLower(path)
+	}
+	return CalculateBlake3String(path)
+}
+
+// CaseInsensitivePaths reports whether paths differing only in case are taken as the same file, as they are
+// on the default filesystems of Windows and macOS. The case-insensitive-paths setting overrides it.
+func CaseInsensitivePaths() bool {
+	if config, err := LoadConfig(); err == nil && config.CaseInsensitivePaths != nil {
+		return *config.CaseInsensitivePaths
+	}
+	return runtime.GOOS == "windows" || runtime.GOOS == "darwin"
+}
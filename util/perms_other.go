@@ -0,0 +1,27 @@
+//go:build !unix && !windows
+
+package util
+
+import (
+	"os"
+)
+
+// FileOwnerIDs returns no IDs, file owners aren't known on this platform
+func FileOwnerIDs(info os.FileInfo) (uid, gid int64, ok bool) {
+	return 0, 0, false
+}
+
+// FileACL returns nothing, ACLs aren't known on this platform
+func FileACL(path string) (string, error) {
+	return "", nil
+}
+
+// SetFileOwner does nothing, file owners aren't known on this platform
+func SetFileOwner(path string, uid, gid int64) error {
+	return nil
+}
+
+// SetFileACL does nothing, ACLs aren't known on this platform
+func SetFileACL(path string, acl string) error {
+	return nil
+}
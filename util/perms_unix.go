@@ -0,0 +1,31 @@
+//go:build unix
+
+package util
+
+import (
+	"os"
+	"syscall"
+)
+
+// FileOwnerIDs returns the user and group IDs owning a file
+func FileOwnerIDs(info os.FileInfo) (uid, gid int64, ok bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int64(stat.Uid), int64(stat.Gid), true
+	}
+	return 0, 0, false
+}
+
+// FileACL returns nothing on Unix, where the owner and permission bits describe who can access a file
+func FileACL(path string) (string, error) {
+	return "", nil
+}
+
+// SetFileOwner gives a file to a user and group, which only root is usually allowed to do
+func SetFileOwner(path string, uid, gid int64) error {
+	return os.Lchown(path, int(uid), int(gid))
+}
+
+// SetFileACL does nothing on Unix, where no ACL is recorded
+func SetFileACL(path string, acl string) error {
+	return nil
+}
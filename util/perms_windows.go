@@ -0,0 +1,45 @@
+//go:build windows
+
+package util
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// FileOwnerIDs returns no IDs on Windows, the owner is part of the ACL
+func FileOwnerIDs(info os.FileInfo) (uid, gid int64, ok bool) {
+	return 0, 0, false
+}
+
+// FileACL returns the owner and DACL of a file as an SDDL string
+func FileACL(path string) (string, error) {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION|windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return "", err
+	}
+	return sd.String(), nil
+}
+
+// SetFileOwner does nothing on Windows, the owner is restored with the ACL
+func SetFileOwner(path string, uid, gid int64) error {
+	return nil
+}
+
+// SetFileACL applies the owner and DACL of an SDDL string returned by FileACL to a file
+func SetFileACL(path string, acl string) error {
+	sd, err := windows.SecurityDescriptorFromString(acl)
+	if err != nil {
+		return err
+	}
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return err
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return err
+	}
+	return windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION|windows.DACL_SECURITY_INFORMATION, owner, nil, dacl, nil)
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// Progress tracks the files and bytes processed by a long operation. Percentages and the ETA are computed
+// by bytes, as a count of files is misleading when their sizes vary by orders of magnitude.
+type Progress struct {
+	mu         sync.Mutex
+	totalFiles int
+	totalBytes int64
+	files      int
+	bytes      int64
+	started    time.Time
+}
+
+// NewProgress starts tracking an operation processing totalFiles files of totalBytes bytes
+func NewProgress(totalFiles int, totalBytes int64) *Progress {
+	return &Progress{totalFiles: totalFiles, totalBytes: totalBytes, started: time.Now()}
+}
+
+// Add counts a processed file without reporting it, for skipped files
+func (p *Progress) Add(size int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.files++
+	p.bytes += size
+}
+
+// Report counts a processed file and prints the progress with its path
+func (p *Progress) Report(size int64, path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.files++
+	p.bytes += size
+
+	percentage := 100.0
+	if p.totalBytes > 0 {
+		percentage = min(float64(p.bytes)/float64(p.totalBytes)*100, 100)
+	}
+	PrintProcess("[ %d / %d files, %s / %s (%.2f%%), ETA %s]: %s\n", p.files, p.totalFiles, FormatSize(p.bytes), FormatSize(p.totalBytes), percentage, p.eta(), path)
+}
+
+// eta estimates the time left from the throughput so far
+func (p *Progress) eta() string {
+	if p.bytes == 0 || p.bytes >= p.totalBytes {
+		if p.files >= p.totalFiles {
+			return "0s"
+		}
+		return "?"
+	}
+	elapsed := time.Since(p.started)
+	left := time.Duration(float64(elapsed) * float64(p.totalBytes-p.bytes) / float64(p.bytes))
+	return left.Round(time.Second).String()
+}
@@ -0,0 +1,149 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QuarantineManifestName is the file in a deleted directory mapping the files moved there back to their origins
+const QuarantineManifestName = "MANIFEST.tsv"
+
+// UniqueDestPath returns destPath, or destPath with a numeric suffix before its extension (name_1.ext) when
+// something is already there or the path is taken, so a moved file never replaces another
+func UniqueDestPath(destPath string, taken map[string]bool) string {
+	ext := filepath.Ext(destPath)
+	name := strings.TrimSuffix(destPath, ext)
+	path := destPath
+	for counter := 1; ; counter++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) && !taken[path] {
+			return path
+		}
+		path = fmt.Sprintf("%s_%d%s", name, counter, ext)
+	}
+}
+
+// QuarantineEntry is a line of the manifest of a deleted directory
+type QuarantineEntry struct {
+	MovedAt time.Time
+	Path    string // Path of the file relative to the deleted directory
+	Origin  string // Absolute path the file was moved from
+	Size    int64  // Size of the file, only in exported manifests, -1 when unknown
+	Blake3  string // Blake3 value of the file, only in exported manifests
+}
+
+// String formats the entry as a manifest line, with the size and the Blake3 value when they are known
+func (e QuarantineEntry) String() string {
+	line := fmt.Sprintf("%s\t%q\t%q", e.MovedAt.Format(time.RFC3339), e.Path, e.Origin)
+	if e.Blake3 != "" {
+		line += fmt.Sprintf("\t%d\t%s", e.Size, e.Blake3)
+	}
+	return line
+}
+
+// parseQuarantineEntry parses a manifest line written by RecordQuarantine or WriteQuarantineManifest
+func parseQuarantineEntry(line string) (QuarantineEntry, error) {
+	entry := QuarantineEntry{Size: -1}
+	fields := strings.Split(line, "\t")
+	if len(fields) != 3 && len(fields) != 5 {
+		return entry, fmt.Errorf("expected 3 or 5 tab separated fields, found %d", len(fields))
+	}
+
+	var err error
+	if entry.MovedAt, err = time.Parse(time.RFC3339, fields[0]); err != nil {
+		return entry, fmt.Errorf("invalid time %s", fields[0])
+	}
+	if entry.Path, err = strconv.Unquote(fields[1]); err != nil {
+		return entry, fmt.Errorf("invalid path %s", fields[1])
+	}
+	if entry.Origin, err = strconv.Unquote(fields[2]); err != nil {
+		return entry, fmt.Errorf("invalid original path %s", fields[2])
+	}
+	if len(fields) == 5 {
+		if entry.Size, err = strconv.ParseInt(fields[3], 10, 64); err != nil {
+			return entry, fmt.Errorf("invalid size %s", fields[3])
+		}
+		entry.Blake3 = fields[4]
+	}
+	return entry, nil
+}
+
+// ReadQuarantineManifest reads the entries of a manifest, the one of a deleted directory or an exported one.
+// Lines starting with # are comments.
+func ReadQuarantineManifest(path string) ([]QuarantineEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []QuarantineEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry, err := parseQuarantineEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d of %s: %v", lineNumber, path, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// WriteQuarantineManifest replaces a manifest with the entries, after a header of comments. The manifest is
+// written next to it first, so it's never left half written.
+func WriteQuarantineManifest(path string, header []string, entries []QuarantineEntry) error {
+	partialPath := path + ".partial"
+	f, err := os.Create(partialPath)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, line := range header {
+		fmt.Fprintf(w, "# %s\n", line)
+	}
+	for _, entry := range entries {
+		fmt.Fprintln(w, entry)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(partialPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(partialPath)
+		return err
+	}
+	return os.Rename(partialPath, path)
+}
+
+// RecordQuarantine appends the move of origin to destPath to the manifest of deletedDir, as a line of the
+// time, the path relative to deletedDir and the original path separated by tabs, the paths quoted
+func RecordQuarantine(deletedDir, destPath, origin string) error {
+	relPath, err := filepath.Rel(deletedDir, destPath)
+	if err != nil {
+		relPath = destPath
+	}
+	if absOrigin, err := filepath.Abs(origin); err == nil {
+		origin = absOrigin
+	}
+
+	f, err := os.OpenFile(filepath.Join(deletedDir, QuarantineManifestName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, QuarantineEntry{MovedAt: time.Now(), Path: relPath, Origin: origin}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,24 @@
+package util
+
+import (
+	"errors"
+)
+
+// ReadOnlyEnv enables the read-only mode when set, it's passed to the commands run by the shell
+const ReadOnlyEnv = "FSAK_READ_ONLY"
+
+// ErrReadOnly is returned by the operations refused in read-only mode
+var ErrReadOnly = errors.New("refused in read-only mode")
+
+// readOnly refuses the commands changing files and the deletion of records
+var readOnly bool
+
+// SetReadOnly enables or disables the read-only mode
+func SetReadOnly(enabled bool) {
+	readOnly = enabled
+}
+
+// ReadOnly reports whether the read-only mode is enabled
+func ReadOnly() bool {
+	return readOnly
+}
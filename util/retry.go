@@ -0,0 +1,73 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Retry policy for transient I/O errors, set from the command line
+var (
+	retryAttempts = 2
+	retryDelay    = 500 * time.Millisecond
+)
+
+// SetRetryPolicy sets how many times an operation failing with a transient error is retried,
+// and the delay before the first retry, which doubles with every attempt
+func SetRetryPolicy(attempts int, delay time.Duration) {
+	retryAttempts = attempts
+	retryDelay = delay
+}
+
+// IsTransientError checks if an error is likely to go away when retried, such as a network share
+// hiccup or a USB reset, as opposed to a permanent failure like a missing file or a denied permission
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	for _, transient := range transientErrnos {
+		if errno == transient {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLockedError checks if an error is caused by another program holding the file open exclusively, such
+// as a mailbox or a virtual disk in use on Windows
+func IsLockedError(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	for _, locked := range lockedErrnos {
+		if errno == locked {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry runs an I/O operation on path, retrying it with backoff while it fails with a transient error
+func Retry(path string, fn func() error) error {
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt > retryAttempts || !IsTransientError(err) {
+			return err
+		}
+
+		PrintWarning("Warning: Transient error on %s, retrying in %v (%d/%d): %v\n", path, delay, attempt, retryAttempts, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package util
+
+import (
+	"syscall"
+)
+
+// transientErrnos are the errors of I/O operations that may succeed when retried
+var transientErrnos = []syscall.Errno{
+	syscall.EIO,
+	syscall.EAGAIN,
+	syscall.EINTR,
+	syscall.EBUSY,
+	syscall.ETIMEDOUT,
+	syscall.ESTALE,
+	syscall.ECONNRESET,
+	syscall.ECONNABORTED,
+	syscall.ENETDOWN,
+	syscall.ENETRESET,
+	syscall.ENETUNREACH,
+	syscall.EHOSTDOWN,
+	syscall.EHOSTUNREACH,
+}
+
+// lockedErrnos are the errors of files in use by another program, locks don't keep readers out on Unix
+// but a running executable can't be written
+var lockedErrnos = []syscall.Errno{
+	syscall.ETXTBSY,
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package util
+
+import (
+	"syscall"
+)
+
+// transientErrnos are the errors of I/O operations that may succeed when retried
+var transientErrnos = []syscall.Errno{
+	21,   // ERROR_NOT_READY
+	32,   // ERROR_SHARING_VIOLATION
+	33,   // ERROR_LOCK_VIOLATION
+	51,   // ERROR_REM_NOT_LIST
+	59,   // ERROR_UNEXP_NET_ERR
+	64,   // ERROR_NETNAME_DELETED
+	121,  // ERROR_SEM_TIMEOUT
+	1167, // ERROR_DEVICE_NOT_CONNECTED
+	1231, // ERROR_NETWORK_UNREACHABLE
+}
+
+// lockedErrnos are the errors of files opened exclusively by another program, which may stay locked for
+// as long as that program runs
+var lockedErrnos = []syscall.Errno{
+	32, // ERROR_SHARING_VIOLATION
+	33, // ERROR_LOCK_VIOLATION
+}
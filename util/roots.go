@@ -0,0 +1,107 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isWithin reports whether path is dir or below it
+func isWithin(path, dir string) bool {
+	relPath, err := filepath.Rel(dir, path)
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// DangerousRoot returns why a destructive command shouldn't run on a directory: the root of a filesystem,
+// the home directory, or a directory holding or inside the workspace, where one mistyped path could wipe
+// everything. It returns an empty string for other directories.
+func DangerousRoot(dir string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	// Symlinks and relative paths such as ~/.. reach the same directories
+	if resolved, err := filepath.EvalSymlinks(absDir); err == nil {
+		absDir = resolved
+	}
+
+	if filepath.Dir(absDir) == absDir {
+		return T("it is the root of the filesystem")
+	}
+
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		if resolved, err := filepath.EvalSymlinks(homeDir); err == nil {
+			homeDir = resolved
+		}
+		if absDir == filepath.Clean(homeDir) {
+			return T("it is your home directory")
+		}
+	}
+
+	if wsDir, err := GetWorkspaceDir(); err == nil {
+		if resolved, err := filepath.EvalSymlinks(wsDir); err == nil {
+			wsDir = resolved
+		}
+		if absDir == wsDir {
+			return T("it is the workspace")
+		}
+		if isWithin(absDir, wsDir) {
+			return fmt.Sprintf(T("it is inside the workspace %s"), wsDir)
+		}
+		if isWithin(wsDir, absDir) {
+			return fmt.Sprintf(T("it contains the workspace %s"), wsDir)
+		}
+	}
+	return ""
+}
+
+// CheckAllowedPath returns an error when the configuration restricts the directories fsak may change with
+// the allowed-paths setting and path isn't in one of them
+func CheckAllowedPath(path string) error {
+	config, err := LoadConfig()
+	if err != nil || len(config.AllowedPaths) == 0 {
+		return err
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	resolved, err := resolveMissing(absPath)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range config.AllowedPaths {
+		if resolvedDir, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = resolvedDir
+		}
+		if isWithin(resolved, dir) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is outside the allowed paths (%s) of the configuration", absPath, strings.Join(config.AllowedPaths, string(filepath.ListSeparator)))
+}
+
+// resolveMissing resolves the symlinks of an absolute path that may not exist yet, such as a backup
+// destination: the deepest directory of it that exists is resolved, and the missing names are appended to it
+func resolveMissing(path string) (string, error) {
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		// A dangling symlink leads to a path that can't be checked
+		if _, lstatErr := os.Lstat(path); lstatErr == nil {
+			return "", fmt.Errorf("error resolving %s: %v", path, err)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", fmt.Errorf("error resolving %s: %v", path, err)
+		}
+		missing = append([]string{filepath.Base(path)}, missing...)
+		path = parent
+	}
+}
@@ -0,0 +1,49 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Schedule runs an fsak command periodically through the service manager of the system
+type Schedule struct {
+	Name   string        // Name of the schedule, part of the names of its units
+	Every  time.Duration // Interval between runs
+	Args   []string      // Arguments of the fsak command
+	Env    []string      // KEY=value environment of the command
+	Dir    string        // Working directory of the command, relative paths of its arguments resolve against it
+	System bool          // Install for the whole system instead of the current user
+}
+
+// scheduleNamePattern restricts schedule names to characters valid in unit, plist and task names
+var scheduleNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidateScheduleName checks that a schedule name can be used in the names of its units
+func ValidateScheduleName(name string) error {
+	if !scheduleNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid schedule name %q, only letters, digits, - and _ are allowed", name)
+	}
+	return nil
+}
+
+// ScheduleEnv returns the settings of the current environment a scheduled command needs to use the same
+// workspace, profile and language
+func ScheduleEnv() []string {
+	var env []string
+	for _, name := range []string{"FSAK_WS_DIR", "FSAK_LANG"} {
+		if value := os.Getenv(name); value != "" {
+			if name == "FSAK_WS_DIR" {
+				if wsDir, err := getDefaultWorkspaceDir(); err == nil {
+					value = wsDir
+				}
+			}
+			env = append(env, name+"="+value)
+		}
+	}
+	if profile := GetProfile(); profile != nil {
+		env = append(env, "FSAK_PROFILE="+profile.Name)
+	}
+	return env
+}
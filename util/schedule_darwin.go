@@ -0,0 +1,131 @@
+//go:build darwin
+
+package util
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// launchdLabel returns the label of the launchd job of a schedule, which also names its plist
+func launchdLabel(name string) string {
+	return "com.github.baowuhe.fsak." + name
+}
+
+// launchdPlistPath returns the path of the plist of a launchd job, a launch agent of the user or a launch
+// daemon of the system
+func launchdPlistPath(label string, system bool) (string, error) {
+	if system {
+		return filepath.Join("/Library/LaunchDaemons", label+".plist"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, "Library", "LaunchAgents", label+".plist"), nil
+}
+
+// launchctl runs launchctl with the plist of a schedule
+func launchctl(args ...string) error {
+	output, err := exec.Command("launchctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("launchctl %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// plistString returns a plist string element
+func plistString(value string) string {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(value))
+	return "<string>" + escaped.String() + "</string>"
+}
+
+// launchdPlist returns the plist of a launchd job running a program with its output appended to a log file,
+// with keys the other keys of the job, such as when it runs
+func launchdPlist(label string, args []string, env []string, dir string, logPath string, keys string) string {
+	var plist strings.Builder
+	plist.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	plist.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	plist.WriteString("<plist version=\"1.0\">\n<dict>\n")
+	fmt.Fprintf(&plist, "\t<key>Label</key>\n\t%s\n", plistString(label))
+	plist.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range args {
+		fmt.Fprintf(&plist, "\t\t%s\n", plistString(arg))
+	}
+	plist.WriteString("\t</array>\n")
+	if len(env) > 0 {
+		plist.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+		for _, env := range env {
+			key, value, _ := strings.Cut(env, "=")
+			fmt.Fprintf(&plist, "\t\t<key>%s</key>\n\t\t%s\n", key, plistString(value))
+		}
+		plist.WriteString("\t</dict>\n")
+	}
+	fmt.Fprintf(&plist, "\t<key>WorkingDirectory</key>\n\t%s\n", plistString(dir))
+	plist.WriteString(keys)
+	fmt.Fprintf(&plist, "\t<key>StandardOutPath</key>\n\t%s\n", plistString(logPath))
+	fmt.Fprintf(&plist, "\t<key>StandardErrorPath</key>\n\t%s\n", plistString(logPath))
+	plist.WriteString("</dict>\n</plist>\n")
+	return plist.String()
+}
+
+// InstallSchedule writes a launchd plist running the command at the interval and loads it. The output of
+// the runs goes to the logs directory of the workspace. It returns the path of the plist.
+func InstallSchedule(schedule Schedule) ([]string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("error finding the fsak executable: %v", err)
+	}
+	plistPath, err := launchdPlistPath(launchdLabel(schedule.Name), schedule.System)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(plistPath), 0755); err != nil {
+		return nil, fmt.Errorf("error creating %s: %v", filepath.Dir(plistPath), err)
+	}
+	wsDir, err := GetWorkspaceDir()
+	if err != nil {
+		return nil, err
+	}
+	logDir := filepath.Join(wsDir, "logs")
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating %s: %v", logDir, err)
+	}
+	logPath := filepath.Join(logDir, "schedule-"+schedule.Name+".log")
+
+	keys := fmt.Sprintf("\t<key>StartInterval</key>\n\t<integer>%d</integer>\n", int64(schedule.Every.Seconds()))
+	plist := launchdPlist(launchdLabel(schedule.Name), append([]string{executable}, schedule.Args...), schedule.Env, schedule.Dir, logPath, keys)
+
+	if err := os.WriteFile(plistPath, []byte(plist), 0644); err != nil {
+		return nil, fmt.Errorf("error writing %s: %v", plistPath, err)
+	}
+	if err := launchctl("load", "-w", plistPath); err != nil {
+		return nil, err
+	}
+	return []string{plistPath}, nil
+}
+
+// RemoveSchedule unloads the launchd job of a schedule and deletes its plist. It returns the path of the plist.
+func RemoveSchedule(name string, system bool) ([]string, error) {
+	plistPath, err := launchdPlistPath(launchdLabel(name), system)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(plistPath); err != nil {
+		return nil, fmt.Errorf("no schedule %s in %s", name, filepath.Dir(plistPath))
+	}
+
+	if err := launchctl("unload", "-w", plistPath); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(plistPath); err != nil {
+		return nil, fmt.Errorf("error removing %s: %v", plistPath, err)
+	}
+	return []string{plistPath}, nil
+}
@@ -0,0 +1,141 @@
+//go:build linux
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// systemdUnitDir returns the directory of the systemd units of the user, or of the system
+func systemdUnitDir(system bool) (string, error) {
+	if system {
+		return "/etc/systemd/system", nil
+	}
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		return filepath.Join(configHome, "systemd", "user"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".config", "systemd", "user"), nil
+}
+
+// systemctl runs systemctl for the user manager, or the system one
+func systemctl(system bool, args ...string) error {
+	if !system {
+		args = append([]string{"--user"}, args...)
+	}
+	output, err := exec.Command("systemctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// quoteSystemdArg quotes an argument of an ExecStart line, escaping the specifiers and variables systemd expands
+func quoteSystemdArg(arg string) string {
+	return quoteSystemdEnv(strings.ReplaceAll(arg, "$", "$$"))
+}
+
+// quoteSystemdEnv quotes an assignment of an Environment line, which is split into words like ExecStart but
+// doesn't expand variables, escaping the specifiers systemd expands
+func quoteSystemdEnv(env string) string {
+	env = escapeSystemdSpecifiers(env)
+	if env != "" && !strings.ContainsAny(env, " \t\n\"'\\;") {
+		return env
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(env) + `"`
+}
+
+// escapeSystemdSpecifiers escapes the specifiers systemd expands in a setting taken as it is, such as
+// WorkingDirectory, which is neither unquoted nor split
+func escapeSystemdSpecifiers(value string) string {
+	return strings.ReplaceAll(value, "%", "%%")
+}
+
+// InstallSchedule writes a systemd service running the command and a timer starting it, and enables the
+// timer. It returns the paths of the units.
+func InstallSchedule(schedule Schedule) ([]string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("error finding the fsak executable: %v", err)
+	}
+	unitDir, err := systemdUnitDir(schedule.System)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(unitDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating %s: %v", unitDir, err)
+	}
+
+	command := []string{quoteSystemdArg(executable)}
+	for _, arg := range schedule.Args {
+		command = append(command, quoteSystemdArg(arg))
+	}
+
+	var service strings.Builder
+	description := strings.ReplaceAll(strings.Join(schedule.Args, " "), "%", "%%")
+	fmt.Fprintf(&service, "[Unit]\nDescription=fsak schedule %s: %s\n\n", schedule.Name, description)
+	fmt.Fprintf(&service, "[Service]\nType=oneshot\nWorkingDirectory=%s\n", escapeSystemdSpecifiers(schedule.Dir))
+	for _, env := range schedule.Env {
+		fmt.Fprintf(&service, "Environment=%s\n", quoteSystemdEnv(env))
+	}
+	fmt.Fprintf(&service, "ExecStart=%s\n", strings.Join(command, " "))
+
+	// The first run waits a bit after boot so it doesn't slow it down
+	var timer strings.Builder
+	fmt.Fprintf(&timer, "[Unit]\nDescription=Run fsak schedule %s every %s\n\n", schedule.Name, schedule.Every)
+	fmt.Fprintf(&timer, "[Timer]\nOnBootSec=15min\nOnUnitActiveSec=%ds\n\n", int64(schedule.Every.Seconds()))
+	fmt.Fprintf(&timer, "[Install]\nWantedBy=timers.target\n")
+
+	unit := "fsak-" + schedule.Name
+	servicePath := filepath.Join(unitDir, unit+".service")
+	timerPath := filepath.Join(unitDir, unit+".timer")
+	if err := os.WriteFile(servicePath, []byte(service.String()), 0644); err != nil {
+		return nil, fmt.Errorf("error writing %s: %v", servicePath, err)
+	}
+	if err := os.WriteFile(timerPath, []byte(timer.String()), 0644); err != nil {
+		return nil, fmt.Errorf("error writing %s: %v", timerPath, err)
+	}
+
+	if err := systemctl(schedule.System, "daemon-reload"); err != nil {
+		return nil, err
+	}
+	if err := systemctl(schedule.System, "enable", "--now", unit+".timer"); err != nil {
+		return nil, err
+	}
+	return []string{servicePath, timerPath}, nil
+}
+
+// RemoveSchedule disables the timer of a schedule and deletes its units. It returns the paths of the units.
+func RemoveSchedule(name string, system bool) ([]string, error) {
+	unitDir, err := systemdUnitDir(system)
+	if err != nil {
+		return nil, err
+	}
+
+	unit := "fsak-" + name
+	servicePath := filepath.Join(unitDir, unit+".service")
+	timerPath := filepath.Join(unitDir, unit+".timer")
+	if _, err := os.Stat(timerPath); err != nil {
+		return nil, fmt.Errorf("no schedule %s in %s", name, unitDir)
+	}
+
+	if err := systemctl(system, "disable", "--now", unit+".timer"); err != nil {
+		return nil, err
+	}
+	for _, path := range []string{timerPath, servicePath} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("error removing %s: %v", path, err)
+		}
+	}
+	if err := systemctl(system, "daemon-reload"); err != nil {
+		return nil, err
+	}
+	return []string{servicePath, timerPath}, nil
+}
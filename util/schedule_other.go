@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !windows
+
+package util
+
+import (
+	"errors"
+)
+
+// InstallSchedule registers a schedule with the service manager, which is only supported with systemd on
+// Linux, launchd on macOS and Task Scheduler on Windows
+func InstallSchedule(schedule Schedule) ([]string, error) {
+	return nil, errors.New("schedules are only supported on Linux (systemd), macOS (launchd) and Windows (Task Scheduler)")
+}
+
+// RemoveSchedule removes a schedule from the service manager, which is only supported with systemd on
+// Linux, launchd on macOS and Task Scheduler on Windows
+func RemoveSchedule(name string, system bool) ([]string, error) {
+	return nil, errors.New("schedules are only supported on Linux (systemd), macOS (launchd) and Windows (Task Scheduler)")
+}
@@ -0,0 +1,142 @@
+//go:build windows
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// scheduledTaskName returns the name of the Task Scheduler task of a schedule, in an fsak folder
+func scheduledTaskName(name string) string {
+	return `fsak\` + name
+}
+
+// scheduleScriptPath returns the path of the batch script run by the task of a schedule, in the workspace
+// since a task command line is limited to 261 characters and can't set environment variables
+func scheduleScriptPath(name string) (string, error) {
+	wsDir, err := GetWorkspaceDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(wsDir, "schedules", name+".cmd"), nil
+}
+
+// schtasks runs schtasks.exe
+func schtasks(args ...string) error {
+	output, err := exec.Command("schtasks", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("schtasks %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// quoteBatchArg quotes an argument of a batch script line, doubling the % cmd expands even within quotes
+func quoteBatchArg(arg string) (string, error) {
+	if strings.ContainsAny(arg, "\"\r\n") {
+		return "", fmt.Errorf("argument %q can't be scheduled, it contains a quote or a line break", arg)
+	}
+	return `"` + strings.ReplaceAll(arg, "%", "%%") + `"`, nil
+}
+
+// taskInterval returns the schtasks schedule type and modifier running a task at an interval
+func taskInterval(every time.Duration) ([]string, error) {
+	switch {
+	case every%(24*time.Hour) == 0 && every <= 365*24*time.Hour:
+		return []string{"/SC", "DAILY", "/MO", fmt.Sprint(int64(every / (24 * time.Hour)))}, nil
+	case every%time.Hour == 0 && every < 24*time.Hour:
+		return []string{"/SC", "HOURLY", "/MO", fmt.Sprint(int64(every / time.Hour))}, nil
+	case every%time.Minute == 0 && every < 24*time.Hour:
+		return []string{"/SC", "MINUTE", "/MO", fmt.Sprint(int64(every / time.Minute))}, nil
+	}
+	return nil, fmt.Errorf("Task Scheduler can't run a task every %s, use whole minutes below 24h, whole hours or whole days", every)
+}
+
+// InstallSchedule writes a batch script running the command, with its output appended to the logs
+// directory of the workspace, and registers a Task Scheduler task running it at the interval. System
+// schedules run as SYSTEM. It returns the path of the script.
+func InstallSchedule(schedule Schedule) ([]string, error) {
+	interval, err := taskInterval(schedule.Every)
+	if err != nil {
+		return nil, err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("error finding the fsak executable: %v", err)
+	}
+	scriptPath, err := scheduleScriptPath(schedule.Name)
+	if err != nil {
+		return nil, err
+	}
+	logDir := filepath.Join(filepath.Dir(filepath.Dir(scriptPath)), "logs")
+	for _, dir := range []string{filepath.Dir(scriptPath), logDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("error creating %s: %v", dir, err)
+		}
+	}
+
+	command := make([]string, 0, len(schedule.Args)+1)
+	for _, arg := range append([]string{executable}, schedule.Args...) {
+		quoted, err := quoteBatchArg(arg)
+		if err != nil {
+			return nil, err
+		}
+		command = append(command, quoted)
+	}
+	logPath, err := quoteBatchArg(filepath.Join(logDir, "schedule-"+schedule.Name+".log"))
+	if err != nil {
+		return nil, err
+	}
+	dir, err := quoteBatchArg(schedule.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var script strings.Builder
+	fmt.Fprintf(&script, "@echo off\r\nrem fsak schedule %s, run every %s by Task Scheduler\r\n", schedule.Name, schedule.Every)
+	for _, env := range schedule.Env {
+		quoted, err := quoteBatchArg(env)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&script, "set %s\r\n", quoted)
+	}
+	fmt.Fprintf(&script, "cd /d %s\r\n", dir)
+	fmt.Fprintf(&script, "%s >> %s 2>&1\r\n", strings.Join(command, " "), logPath)
+	if err := os.WriteFile(scriptPath, []byte(script.String()), 0644); err != nil {
+		return nil, fmt.Errorf("error writing %s: %v", scriptPath, err)
+	}
+
+	args := []string{"/Create", "/F", "/TN", scheduledTaskName(schedule.Name), "/TR", `"` + scriptPath + `"`}
+	args = append(args, interval...)
+	if schedule.System {
+		args = append(args, "/RU", "SYSTEM")
+	}
+	if err := schtasks(args...); err != nil {
+		return nil, err
+	}
+	return []string{scriptPath}, nil
+}
+
+// RemoveSchedule deletes the Task Scheduler task of a schedule and its script. It returns the path of the script.
+func RemoveSchedule(name string, system bool) ([]string, error) {
+	scriptPath, err := scheduleScriptPath(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(scriptPath); err != nil {
+		return nil, fmt.Errorf("no schedule %s in %s", name, filepath.Dir(scriptPath))
+	}
+
+	if err := schtasks("/Delete", "/F", "/TN", scheduledTaskName(name)); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(scriptPath); err != nil {
+		return nil, fmt.Errorf("error removing %s: %v", scriptPath, err)
+	}
+	return []string{scriptPath}, nil
+}
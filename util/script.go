@@ -0,0 +1,80 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ScriptWriter writes file operations to a shell script for review and manual execution instead of
+// performing them. Scripts ending in .ps1 are written for PowerShell, all others for POSIX sh.
+type ScriptWriter struct {
+	path       string
+	file       *os.File
+	w          *bufio.Writer
+	powershell bool
+	Count      int // Number of operations written
+}
+
+// NewScriptWriter creates the script file and writes its header
+func NewScriptWriter(path string, command string) (*ScriptWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ScriptWriter{
+		path:       path,
+		file:       f,
+		w:          bufio.NewWriter(f),
+		powershell: strings.EqualFold(filepath.Ext(path), ".ps1"),
+	}
+
+	generated := fmt.Sprintf("Generated by fsak %s on %s, review before running", command, time.Now().Format("2006-01-02 15:04:05"))
+	if s.powershell {
+		fmt.Fprintf(s.w, "# %s\n$ErrorActionPreference = 'Stop'\n\n", generated)
+	} else {
+		fmt.Fprintf(s.w, "#!/bin/sh\n# %s\nset -e\n\n", generated)
+	}
+
+	return s, nil
+}
+
+// quote quotes a path for the script's shell
+func (s *ScriptWriter) quote(path string) string {
+	if s.powershell {
+		return "'" + strings.ReplaceAll(path, "'", "''") + "'"
+	}
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
+
+// Move writes the commands moving src to dst, creating the parent directory of dst
+func (s *ScriptWriter) Move(src, dst string) {
+	if s.powershell {
+		fmt.Fprintf(s.w, "New-Item -ItemType Directory -Force -Path %s | Out-Null\n", s.quote(filepath.Dir(dst)))
+		fmt.Fprintf(s.w, "Move-Item -LiteralPath %s -Destination %s\n", s.quote(src), s.quote(dst))
+	} else {
+		fmt.Fprintf(s.w, "mkdir -p %s\n", s.quote(filepath.Dir(dst)))
+		fmt.Fprintf(s.w, "mv -n -- %s %s\n", s.quote(src), s.quote(dst))
+	}
+	s.Count++
+}
+
+// Close writes the script to disk and makes a shell script executable
+func (s *ScriptWriter) Close() error {
+	if err := s.w.Flush(); err != nil {
+		s.file.Close()
+		return err
+	}
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	if !s.powershell {
+		return os.Chmod(s.path, 0755)
+	}
+	return nil
+}
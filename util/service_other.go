@@ -0,0 +1,14 @@
+//go:build !windows
+
+package util
+
+// RunningAsService reports whether fsak was started by the Windows service manager, never on other systems,
+// where daemons run as regular processes stopped by signals
+func RunningAsService() bool {
+	return false
+}
+
+// RunService runs a long-running command as a Windows service, on other systems it runs until it returns
+func RunService(run func(stop <-chan struct{}) error) error {
+	return run(nil)
+}
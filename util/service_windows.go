@@ -0,0 +1,92 @@
+//go:build windows
+
+package util
+
+import (
+	"os"
+	"sync"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// serviceEnv names the Windows service fsak runs as, set by daemon install, its event source gets the messages
+const serviceEnv = "FSAK_SERVICE"
+
+// runningAsService tells whether fsak was started by the service manager, checked once
+var (
+	runningAsService bool
+	serviceOnce      sync.Once
+)
+
+// RunningAsService reports whether fsak was started by the Windows service manager. From the first call
+// that does, the messages also go to the event log of the service, as it has no console.
+func RunningAsService() bool {
+	serviceOnce.Do(func() {
+		isService, err := svc.IsWindowsService()
+		if err != nil || !isService {
+			return
+		}
+		runningAsService = true
+		if source := os.Getenv(serviceEnv); source != "" {
+			if log, err := eventlog.Open(source); err == nil {
+				messageLog = log
+			}
+		}
+	})
+	return runningAsService
+}
+
+// serviceHandler runs a long-running command as a Windows service
+type serviceHandler struct {
+	run func(stop <-chan struct{}) error
+	err error
+}
+
+// Execute runs the command until it returns, and asks it to stop when the service is stopped or the system
+// shuts down. An error is reported as a service-specific exit code, so the recovery actions restart it.
+func (h *serviceHandler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- h.run(stop)
+	}()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	stopping := false
+	for {
+		select {
+		case err := <-done:
+			h.err = err
+			status <- svc.Status{State: svc.StopPending}
+			if err != nil {
+				return true, 1
+			}
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				if !stopping {
+					stopping = true
+					status <- svc.Status{State: svc.StopPending}
+					close(stop)
+				}
+			}
+		}
+	}
+}
+
+// RunService runs a long-running command as the Windows service fsak was started as, until the service is
+// stopped, when stop is closed and the command is expected to return
+func RunService(run func(stop <-chan struct{}) error) error {
+	handler := &serviceHandler{run: run}
+	PrintSuccess("Service started.\n")
+	// The name is ignored for services running in their own process
+	if err := svc.Run(os.Getenv(serviceEnv), handler); err != nil {
+		return err
+	}
+	return handler.err
+}
@@ -0,0 +1,94 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatSize formats a size in bytes as a human readable string (e.g. 1.50 MB)
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.2f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// ParseSize parses a human readable size such as 512M, 2G or 1.5GB into bytes, plain numbers are bytes
+func ParseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		if exp := strings.IndexByte("KMGTPE", s[n-1]); exp >= 0 {
+			for i := 0; i <= exp; i++ {
+				multiplier *= 1024
+			}
+			s = strings.TrimSpace(s[:n-1])
+		}
+	}
+
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+// ParseAge parses an age such as 12h, 30d, 2w, 6m or 1y, months are 30 days and years 365 days
+func ParseAge(value string) (time.Duration, error) {
+	units := map[byte]time.Duration{
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+		'm': 30 * 24 * time.Hour,
+		'y': 365 * 24 * time.Hour,
+	}
+
+	s := strings.ToLower(strings.TrimSpace(value))
+	if s == "" {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+	unit, ok := units[s[len(s)-1]]
+	if !ok {
+		return 0, fmt.Errorf("invalid age %q, expected a number followed by h, d, w, m or y", value)
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(s[:len(s)-1]), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid age %q, expected a number followed by h, d, w, m or y", value)
+	}
+	return time.Duration(number * float64(unit)), nil
+}
+
+// GetPathSize returns the apparent size and the on-disk usage of a file, or the totals of all files under a directory
+func GetPathSize(path string) (int64, int64, error) {
+	var total, totalDisk int64
+	err := filepath.Walk(path, func(walkPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Skip files that can't be accessed
+			RecordSkipped(walkPath, err)
+			return nil
+		}
+
+		if !info.IsDir() {
+			total += info.Size()
+			totalDisk += GetDiskUsage(info)
+		}
+
+		return nil
+	})
+
+	return total, totalDisk, err
+}
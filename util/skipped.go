@@ -0,0 +1,82 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// SkippedFile is a path a scan couldn't process
+type SkippedFile struct {
+	Path      string
+	Reason    string
+	Transient bool // Whether the error was transient and persisted through all retries
+	Locked    bool // Whether the file was in use by another program
+}
+
+// skippedFiles collects the paths skipped during the current command
+var skippedFiles struct {
+	sync.Mutex
+	files []SkippedFile
+}
+
+// RecordSkipped records a path that was skipped because of an error
+func RecordSkipped(path string, err error) {
+	skippedFiles.Lock()
+	defer skippedFiles.Unlock()
+	skippedFiles.files = append(skippedFiles.files, SkippedFile{Path: path, Reason: err.Error(), Transient: IsTransientError(err), Locked: IsLockedError(err)})
+}
+
+// TakeLockedFiles removes the files skipped because they were in use from the skipped paths and returns
+// them, so they can be tried again
+func TakeLockedFiles() []string {
+	skippedFiles.Lock()
+	defer skippedFiles.Unlock()
+
+	var locked []string
+	remaining := skippedFiles.files[:0]
+	for _, skipped := range skippedFiles.files {
+		if skipped.Locked {
+			locked = append(locked, skipped.Path)
+		} else {
+			remaining = append(remaining, skipped)
+		}
+	}
+	skippedFiles.files = remaining
+	return locked
+}
+
+// GetSkippedFiles returns the paths skipped so far
+func GetSkippedFiles() []SkippedFile {
+	skippedFiles.Lock()
+	defer skippedFiles.Unlock()
+	return append([]SkippedFile(nil), skippedFiles.files...)
+}
+
+// WriteSkippedReport writes the skipped paths to a file, one tab separated path, error kind and reason per line
+func WriteSkippedReport(reportPath string) error {
+	f, err := os.Create(reportPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, skipped := range GetSkippedFiles() {
+		kind := "permanent"
+		switch {
+		case skipped.Locked:
+			kind = "locked"
+		case skipped.Transient:
+			kind = "transient"
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\n", skipped.Path, kind, skipped.Reason); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
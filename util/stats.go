@@ -0,0 +1,57 @@
+package util
+
+import (
+	"sync/atomic"
+)
+
+// RunStats is the work done by the current command, summarized and recorded when it ends
+type RunStats struct {
+	FilesHashed int64
+	BytesHashed int64
+	BytesCopied int64
+	DBWrites    int64
+	Errors      int64
+	Skipped     int64
+}
+
+// runStats counts the work of the current command, from the workers of a scan too
+var runStats struct {
+	filesHashed atomic.Int64
+	bytesHashed atomic.Int64
+	bytesCopied atomic.Int64
+	dbWrites    atomic.Int64
+	errors      atomic.Int64
+}
+
+// CountFileHashed counts a hashed file and the bytes read to hash it
+func CountFileHashed(bytes int64) {
+	runStats.filesHashed.Add(1)
+	runStats.bytesHashed.Add(bytes)
+}
+
+// CountBytesCopied counts bytes written to a copy
+func CountBytesCopied(bytes int64) {
+	runStats.bytesCopied.Add(bytes)
+}
+
+// CountDBWrite counts a write to the database
+func CountDBWrite() {
+	runStats.dbWrites.Add(1)
+}
+
+// GetRunStats returns the work done by the current command so far
+func GetRunStats() RunStats {
+	return RunStats{
+		FilesHashed: runStats.filesHashed.Load(),
+		BytesHashed: runStats.bytesHashed.Load(),
+		BytesCopied: runStats.bytesCopied.Load(),
+		DBWrites:    runStats.dbWrites.Load(),
+		Errors:      runStats.errors.Load(),
+		Skipped:     int64(len(GetSkippedFiles())),
+	}
+}
+
+// Idle reports whether the command did no work worth summarizing
+func (s RunStats) Idle() bool {
+	return s.FilesHashed == 0 && s.BytesCopied == 0 && s.DBWrites == 0 && s.Errors == 0 && s.Skipped == 0
+}
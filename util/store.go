@@ -0,0 +1,28 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// StoreDirName is the directory of the workspace holding the objects of the content-addressable store
+const StoreDirName = "objects"
+
+// GetStoreDir returns the directory of the content-addressable store in the workspace
+func GetStoreDir() (string, error) {
+	wsDir, err := GetWorkspaceDir()
+	if err != nil {
+		return "", err
+	}
+	storeDir := filepath.Join(wsDir, StoreDirName)
+	if err := os.MkdirAll(storeDir, 0755); err != nil {
+		return "", err
+	}
+	return storeDir, nil
+}
+
+// StoreObjectPath returns the path of the object holding the contents with a Blake3 hash, split after the
+// first two characters like git so no directory gets too large
+func StoreObjectPath(storeDir, blake3 string) string {
+	return filepath.Join(storeDir, blake3[:2], blake3[2:])
+}
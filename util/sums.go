@@ -0,0 +1,113 @@
+package util
+
+import (
+	"bufio"
+	"encoding/hex"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Checksum algorithms of the manifests written by md5sum, sha256sum and b3sum
+const (
+	SumMD5    = "md5"
+	SumSHA256 = "sha256"
+	SumBlake3 = "blake3"
+)
+
+// SumEntry is a line of a checksum manifest
+type SumEntry struct {
+	Algorithm string // Named by BSD-style lines, empty otherwise
+	Hash      string // Lowercase hex
+	Name      string // Path as written in the manifest
+}
+
+// taggedSumLine matches the BSD-style lines written with --tag, such as "SHA256 (file) = hash"
+var taggedSumLine = regexp.MustCompile(`^(MD5|SHA256|BLAKE3) \((.*)\) = ([0-9a-fA-F]+)$`)
+
+// ReadSumFile reads the entries of a checksum manifest in the GNU format "hash  name" (or "hash *name" for
+// binary mode) or the BSD-style format, and returns them with the number of lines that couldn't be parsed.
+// Empty lines and # comments are skipped.
+func ReadSumFile(name string) ([]SumEntry, int, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	var entries []SumEntry
+	malformed := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry, ok := parseSumLine(line)
+		if !ok {
+			malformed++
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	return entries, malformed, nil
+}
+
+// parseSumLine parses a manifest line. Names with a backslash or a newline are escaped by the tools, which
+// mark the line with a leading backslash.
+func parseSumLine(line string) (SumEntry, bool) {
+	escaped := strings.HasPrefix(line, `\`)
+	if escaped {
+		line = line[1:]
+	}
+
+	var entry SumEntry
+	if match := taggedSumLine.FindStringSubmatch(line); match != nil {
+		entry = SumEntry{Algorithm: strings.ToLower(match[1]), Name: match[2], Hash: match[3]}
+	} else {
+		hash, rest, found := strings.Cut(line, " ")
+		if !found || len(rest) < 2 || (rest[0] != ' ' && rest[0] != '*') {
+			return SumEntry{}, false
+		}
+		entry = SumEntry{Hash: hash, Name: rest[1:]}
+	}
+
+	if _, err := hex.DecodeString(entry.Hash); err != nil || entry.Name == "" {
+		return SumEntry{}, false
+	}
+	entry.Hash = strings.ToLower(entry.Hash)
+
+	if escaped {
+		entry.Name = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r").Replace(entry.Name)
+	}
+	return entry, true
+}
+
+// SumAlgorithmFromName guesses the algorithm of a manifest from its file name, such as SHA256SUMS or
+// files.b3, empty when the name doesn't tell
+func SumAlgorithmFromName(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.Contains(lower, "sha256"):
+		return SumSHA256
+	case strings.Contains(lower, "md5"):
+		return SumMD5
+	case strings.Contains(lower, "blake3") || strings.Contains(lower, "b3"):
+		return SumBlake3
+	}
+	return ""
+}
+
+// SumHashLength returns the length in hex characters of the hashes of an algorithm
+func SumHashLength(algorithm string) int {
+	if algorithm == SumMD5 {
+		return 32
+	}
+	return 64
+}
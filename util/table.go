@@ -0,0 +1,104 @@
+package util
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/width"
+)
+
+// cellEscaper keeps control characters in file names from breaking the rows
+var cellEscaper = strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// Table prints rows as aligned columns, optionally restricted to a selection of its columns
+type Table struct {
+	columns  []string   // Names of all columns, as accepted by --columns
+	selected []int      // Indexes of the printed columns, in print order
+	rows     [][]string // Cells of every row, one per column
+}
+
+// NewTable creates a table with the named columns, all of which are printed
+func NewTable(columns ...string) *Table {
+	table := &Table{columns: columns}
+	for i := range columns {
+		table.selected = append(table.selected, i)
+	}
+	return table
+}
+
+// SelectColumns prints only the named columns in the given order, no names keep all columns
+func (t *Table) SelectColumns(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	var selected []int
+	for _, name := range names {
+		index := slices.Index(t.columns, strings.ToLower(strings.TrimSpace(name)))
+		if index < 0 {
+			return fmt.Errorf("unknown column %s, available columns are %s", name, strings.Join(t.columns, ", "))
+		}
+		selected = append(selected, index)
+	}
+	t.selected = selected
+	return nil
+}
+
+// AddRow adds a row with a cell for every column
+func (t *Table) AddRow(cells ...string) {
+	for i, cell := range cells {
+		cells[i] = cellEscaper.Replace(cell)
+	}
+	t.rows = append(t.rows, cells)
+}
+
+// Print prints the header and the rows with the columns aligned
+func (t *Table) Print() {
+	lines := make([][]string, 0, len(t.rows)+1)
+	header := make([]string, len(t.selected))
+	for i, index := range t.selected {
+		header[i] = T(strings.ToUpper(t.columns[index]))
+	}
+	lines = append(lines, header)
+	for _, row := range t.rows {
+		cells := make([]string, len(t.selected))
+		for i, index := range t.selected {
+			cells[i] = row[index]
+		}
+		lines = append(lines, cells)
+	}
+
+	widths := make([]int, len(t.selected))
+	for _, cells := range lines {
+		for i, cell := range cells {
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+
+	for _, cells := range lines {
+		var line strings.Builder
+		for i, cell := range cells {
+			line.WriteString(cell)
+			// The last column isn't padded, so lines don't end with spaces
+			if i < len(cells)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-displayWidth(cell)+2))
+			}
+		}
+		fmt.Fprintln(messages, line.String())
+	}
+}
+
+// displayWidth returns the number of terminal columns of a string, wide characters such as Chinese take two
+func displayWidth(s string) int {
+	columns := 0
+	for _, r := range s {
+		switch width.LookupRune(r).Kind() {
+		case width.EastAsianWide, width.EastAsianFullwidth:
+			columns += 2
+		default:
+			columns++
+		}
+	}
+	return columns
+}
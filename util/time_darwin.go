@@ -0,0 +1,20 @@
+//go:build darwin
+
+package util
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// GetCreationTime returns the creation time of a file
+// On macOS, this returns the birth time recorded by the filesystem
+func GetCreationTime(info os.FileInfo) time.Time {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(int64(stat.Birthtimespec.Sec), int64(stat.Birthtimespec.Nsec))
+	}
+
+	// Fallback to ModTime if we can't get the creation time
+	return info.ModTime()
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+
+package util
+
+import (
+	"os"
+	"time"
+)
+
+// GetCreationTime returns the creation time of a file
+// On platforms without a known creation time, this returns the modification time
+func GetCreationTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
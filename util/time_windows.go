@@ -0,0 +1,20 @@
+//go:build windows
+
+package util
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// GetCreationTime returns the creation time of a file
+// On Windows, this returns the actual creation time
+func GetCreationTime(info os.FileInfo) time.Time {
+	if attr, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, attr.CreationTime.Nanoseconds())
+	}
+
+	// Fallback to ModTime if we can't get the creation time
+	return info.ModTime()
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package util
+
+import (
+	"errors"
+)
+
+// RecycleBinSupported reports whether files can be sent to the Recycle Bin on this platform
+func RecycleBinSupported() bool {
+	return false
+}
+
+// MoveToRecycleBin sends a file or directory to the Windows Recycle Bin, which is only available on Windows
+func MoveToRecycleBin(path string) error {
+	return errors.New("the Recycle Bin is only supported on Windows")
+}
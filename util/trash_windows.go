@@ -0,0 +1,68 @@
+//go:build windows
+
+package util
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	foDelete          = 0x0003
+	fofSilent         = 0x0004
+	fofNoConfirmation = 0x0010
+	fofAllowUndo      = 0x0040
+	fofNoErrorUI      = 0x0400
+)
+
+// shFileOpStruct mirrors the Win32 SHFILEOPSTRUCTW structure
+type shFileOpStruct struct {
+	hwnd                  uintptr
+	wFunc                 uint32
+	pFrom                 *uint16
+	pTo                   *uint16
+	fFlags                uint16
+	fAnyOperationsAborted int32
+	hNameMappings         uintptr
+	lpszProgressTitle     *uint16
+}
+
+var procSHFileOperationW = syscall.NewLazyDLL("shell32.dll").NewProc("SHFileOperationW")
+
+// RecycleBinSupported reports whether files can be sent to the Recycle Bin on this platform
+func RecycleBinSupported() bool {
+	return true
+}
+
+// MoveToRecycleBin sends a file or directory to the Windows Recycle Bin
+func MoveToRecycleBin(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	// pFrom must be terminated by a double null character
+	from, err := syscall.UTF16FromString(absPath)
+	if err != nil {
+		return err
+	}
+	from = append(from, 0)
+
+	op := shFileOpStruct{
+		wFunc:  foDelete,
+		pFrom:  &from[0],
+		fFlags: fofAllowUndo | fofNoConfirmation | fofSilent | fofNoErrorUI,
+	}
+
+	ret, _, _ := procSHFileOperationW.Call(uintptr(unsafe.Pointer(&op)))
+	if ret != 0 {
+		return fmt.Errorf("SHFileOperationW failed with code 0x%x", ret)
+	}
+	if op.fAnyOperationsAborted != 0 {
+		return fmt.Errorf("moving %s to the Recycle Bin was aborted", absPath)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// VersionsDirName is the directory in a merge target that keeps the replaced versions of its files
+const VersionsDirName = ".fsak-versions"
+
+// versionTimeFormat names the versions of a file after the time they were kept
+const versionTimeFormat = "20060102-150405"
+
+// NewVersionPath returns the path a version of the file at relPath in root is kept at,
+// .fsak-versions/<relPath>/<time><ext>, and creates its directory
+func NewVersionPath(root string, relPath string) (string, error) {
+	versionDir := filepath.Join(root, VersionsDirName, relPath)
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		return "", fmt.Errorf("error creating versions directory %s: %v", versionDir, err)
+	}
+	return filepath.Join(versionDir, time.Now().Format(versionTimeFormat)+filepath.Ext(relPath)), nil
+}
+
+// Version is a kept version of a file
+type Version struct {
+	Path string    // Path of the kept copy
+	Kept time.Time // Time the version was kept, from its name
+}
+
+// ListVersions returns the versions of the file at relPath in root, newest first. Files in its versions
+// directory not named after a time are ignored.
+func ListVersions(root string, relPath string) ([]Version, error) {
+	versionDir := filepath.Join(root, VersionsDirName, relPath)
+	entries, err := os.ReadDir(versionDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading versions directory %s: %v", versionDir, err)
+	}
+
+	var versions []Version
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(relPath))
+		kept, err := time.ParseInLocation(versionTimeFormat, name, time.Local)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, Version{Path: filepath.Join(versionDir, entry.Name()), Kept: kept})
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Kept.After(versions[j].Kept)
+	})
+	return versions, nil
+}
+
+// PruneVersions removes the versions of the file at relPath in root beyond the newest keep ones and
+// returns the removed paths, a keep of 0 keeps them all
+func PruneVersions(root string, relPath string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+
+	versions, err := ListVersions(root, relPath)
+	if err != nil || len(versions) <= keep {
+		return nil, err
+	}
+
+	var removed []string
+	for _, version := range versions[keep:] {
+		if err := os.Remove(version.Path); err != nil {
+			return removed, fmt.Errorf("error removing version %s: %v", version.Path, err)
+		}
+		removed = append(removed, version.Path)
+	}
+	return removed, nil
+}
+
+// FindVersionsRoot returns the closest directory above the file at path whose versions directory keeps
+// versions of it, and the path of the file relative to that directory
+func FindVersionsRoot(path string) (string, string, bool) {
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		relPath, err := filepath.Rel(dir, path)
+		if err == nil {
+			if info, err := os.Stat(filepath.Join(dir, VersionsDirName, relPath)); err == nil && info.IsDir() {
+				return dir, relPath, true
+			}
+		}
+		if filepath.Dir(dir) == dir {
+			return "", "", false
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// VolumeInfo describes the volume (filesystem) a path lives on
+type VolumeInfo struct {
+	ID         string // Filesystem UUID when available, otherwise the label or the source device
+	Label      string // Human readable volume label or name, may be empty
+	MountPoint string // Where the volume is currently mounted
+	LegacyID   string // ID the records of the mount were synced with before the mounted directory was part of IDs, if it differs
+}
+
+// GetVolumeLocation returns the volume of a path and the path relative to the volume's mount point.
+// Unlike the absolute path, the volume-relative path stays valid when a removable drive is mounted elsewhere.
+func GetVolumeLocation(path string) (*VolumeInfo, string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	volume, err := GetVolumeInfo(absPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	relPath, err := filepath.Rel(volume.MountPoint, absPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return volume, filepath.ToSlash(relPath), nil
+}
+
+// FindMountedVolumes returns the mounts of the volume with the given ID, none if it isn't mounted. A volume
+// mounted several times, or volumes sharing a label, have several.
+func FindMountedVolumes(id string) ([]*VolumeInfo, error) {
+	volumes, err := MountedVolumes()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []*VolumeInfo
+	for _, volume := range volumes {
+		if volume.ID == id {
+			found = append(found, volume)
+		}
+	}
+	return found, nil
+}
+
+// removableMountDirs are the directories removable drives are mounted in, directly or in a folder per user
+var removableMountDirs = []string{"/Volumes", "/media", "/run/media", "/mnt"}
+
+// MountParents returns the directories volumes are mounted in: the ones removable drives are mounted in, their
+// folders per user, and the parents of the current mount points other than the root
+func MountParents() map[string]bool {
+	parents := make(map[string]bool)
+	for _, dir := range removableMountDirs {
+		parents[dir] = true
+		if entries, err := os.ReadDir(dir); err == nil && dir != "/Volumes" && dir != "/mnt" {
+			for _, entry := range entries {
+				if entry.IsDir() {
+					parents[filepath.Join(dir, entry.Name())] = true
+				}
+			}
+		}
+	}
+
+	// Volume detection is best effort, the usual directories are still known
+	volumes, _ := MountedVolumes()
+	for _, volume := range volumes {
+		if parent := filepath.Dir(volume.MountPoint); parent != volume.MountPoint && parent != filepath.Dir(parent) {
+			parents[parent] = true
+		}
+	}
+	return parents
+}
+
+// MaybeUnmounted reports whether the files of a directory may be missing because the volume they were on
+// isn't mounted rather than because they were deleted: when the top-level directory of its path is missing,
+// when the deepest directory of its path still there is one volumes are mounted in, or when it's an empty
+// directory of one, left by a drive unmounted. mountParents are the directories returned by MountParents.
+func MaybeUnmounted(dir string, mountParents map[string]bool) bool {
+	dir = filepath.Clean(dir)
+	root := filepath.VolumeName(dir) + string(filepath.Separator)
+	rest := strings.TrimPrefix(dir[len(filepath.VolumeName(dir)):], string(filepath.Separator))
+	top, _, _ := strings.Cut(rest, string(filepath.Separator))
+	if _, err := os.Stat(filepath.Join(root, top)); err != nil {
+		return true
+	}
+
+	existing := dir
+	for {
+		if _, err := os.Stat(existing); err == nil {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	if existing != dir && mountParents[existing] {
+		return true
+	}
+	if mountParents[filepath.Dir(existing)] {
+		entries, err := os.ReadDir(existing)
+		return err == nil && len(entries) == 0
+	}
+	return false
+}
+
+// unescapeMountPath decodes the octal escapes (e.g. \040 for a space) used in mount tables
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, "\\") {
+		return path
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if c, ok := parseOctalByte(path[i+1 : i+4]); ok {
+				b.WriteByte(c)
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
+
+// parseOctalByte parses a three digit octal number
+func parseOctalByte(s string) (byte, bool) {
+	var v int
+	for _, c := range s {
+		if c < '0' || c > '7' {
+			return 0, false
+		}
+		v = v*8 + int(c-'0')
+	}
+	if v > 0xff {
+		return 0, false
+	}
+	return byte(v), true
+}
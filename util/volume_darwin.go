@@ -0,0 +1,59 @@
+//go:build darwin
+
+package util
+
+import (
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// volumeFromStatfs builds the volume info of a mounted filesystem, identified by its volume name
+func volumeFromStatfs(stat *unix.Statfs_t) *VolumeInfo {
+	mountPoint := unix.ByteSliceToString(stat.Mntonname[:])
+	label := filepath.Base(mountPoint)
+	if mountPoint == "/" {
+		label = "/"
+	}
+
+	return &VolumeInfo{
+		ID:         label,
+		Label:      label,
+		MountPoint: mountPoint,
+	}
+}
+
+// GetVolumeInfo returns the volume a path lives on, identified by its volume name
+func GetVolumeInfo(path string) (*VolumeInfo, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var stat unix.Statfs_t
+	if err := unix.Statfs(absPath, &stat); err != nil {
+		return nil, err
+	}
+
+	return volumeFromStatfs(&stat), nil
+}
+
+// MountedVolumes returns all currently mounted volumes
+func MountedVolumes() ([]*VolumeInfo, error) {
+	n, err := unix.Getfsstat(nil, unix.MNT_NOWAIT)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]unix.Statfs_t, n)
+	n, err = unix.Getfsstat(stats, unix.MNT_NOWAIT)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := make([]*VolumeInfo, 0, n)
+	for i := 0; i < n; i++ {
+		volumes = append(volumes, volumeFromStatfs(&stats[i]))
+	}
+	return volumes, nil
+}
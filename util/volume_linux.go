@@ -0,0 +1,220 @@
+//go:build linux
+
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// mountEntry is a line of /proc/self/mountinfo
+type mountEntry struct {
+	MountPoint string
+	Root       string // Directory of the filesystem mounted, e.g. a btrfs subvolume or the source of a bind mount
+	Dev        uint64
+	Source     string
+}
+
+// mountsMaxAge is how long the parsed mount table is used before it's read again, so long-running commands
+// see volumes mounted and unmounted meanwhile
+const mountsMaxAge = 5 * time.Second
+
+var (
+	mountsMu     sync.Mutex
+	mounts       []mountEntry
+	mountsLoaded time.Time
+	volumesMu    sync.Mutex
+	volumeCache  = make(map[mountEntry]*VolumeInfo)
+)
+
+// loadMounts parses /proc/self/mountinfo, again when it was parsed more than mountsMaxAge ago
+func loadMounts() ([]mountEntry, error) {
+	mountsMu.Lock()
+	defer mountsMu.Unlock()
+	if mounts != nil && time.Since(mountsLoaded) < mountsMaxAge {
+		return mounts, nil
+	}
+
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []mountEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Format: id parent major:minor root mountpoint options [optional...] - fstype source superoptions
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if len(fields) < 5 || sep < 0 || sep+2 >= len(fields) {
+			continue
+		}
+
+		var major, minor uint32
+		if _, err := fmt.Sscanf(fields[2], "%d:%d", &major, &minor); err != nil {
+			continue
+		}
+
+		entries = append(entries, mountEntry{
+			MountPoint: unescapeMountPath(fields[4]),
+			Root:       unescapeMountPath(fields[3]),
+			Dev:        unix.Mkdev(major, minor),
+			Source:     unescapeMountPath(fields[sep+2]),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	mounts, mountsLoaded = entries, time.Now()
+	return mounts, nil
+}
+
+// findMount returns the mount with the longest mount point containing path
+func findMount(path string) (*mountEntry, error) {
+	entries, err := loadMounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var best *mountEntry
+	for i, entry := range entries {
+		if path != entry.MountPoint && entry.MountPoint != "/" && !strings.HasPrefix(path, entry.MountPoint+"/") {
+			continue
+		}
+		// Later entries with the same mount point shadow earlier ones
+		if best == nil || len(entry.MountPoint) >= len(best.MountPoint) {
+			best = &entries[i]
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("no mount point found for %s", path)
+	}
+	return best, nil
+}
+
+// findDeviceLink returns the name of the link in a /dev/disk/by-* directory pointing to the mount's device
+func findDeviceLink(dir string, mount *mountEntry) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	for _, entry := range entries {
+		linkPath := filepath.Join(dir, entry.Name())
+
+		// Match either the device number or the resolved device path
+		if info, err := os.Stat(linkPath); err == nil {
+			if stat, ok := info.Sys().(*syscall.Stat_t); ok && uint64(stat.Rdev) == mount.Dev {
+				return entry.Name()
+			}
+		}
+		if target, err := filepath.EvalSymlinks(linkPath); err == nil && target == mount.Source {
+			return entry.Name()
+		}
+	}
+
+	return ""
+}
+
+// unescapeUdevName decodes the \xNN escapes udev uses in /dev/disk/by-label names
+func unescapeUdevName(name string) string {
+	if !strings.Contains(name, "\\x") {
+		return name
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+3 < len(name) && name[i+1] == 'x' {
+			if c, err := strconv.ParseUint(name[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// GetVolumeInfo returns the volume a path lives on, identified by its filesystem UUID when available
+func GetVolumeInfo(path string) (*VolumeInfo, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mount, err := findMount(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	volumesMu.Lock()
+	defer volumesMu.Unlock()
+	if volume, ok := volumeCache[*mount]; ok {
+		return volume, nil
+	}
+
+	volume := &VolumeInfo{
+		ID:         findDeviceLink("/dev/disk/by-uuid", mount),
+		Label:      unescapeUdevName(findDeviceLink("/dev/disk/by-label", mount)),
+		MountPoint: mount.MountPoint,
+	}
+	if volume.ID == "" {
+		volume.ID = volume.Label
+	}
+	if volume.ID == "" {
+		// Network and virtual filesystems have no UUID, use their source (e.g. server:/export)
+		volume.ID = mount.Source
+	}
+	if mount.Root != "/" {
+		// A subvolume or a directory of the filesystem is mounted, the paths under the mount point are
+		// relative to it rather than to the root of the filesystem
+		volume.LegacyID = volume.ID
+		volume.ID += ":" + mount.Root
+	}
+
+	volumeCache[*mount] = volume
+	return volume, nil
+}
+
+// MountedVolumes returns all currently mounted volumes
+func MountedVolumes() ([]*VolumeInfo, error) {
+	entries, err := loadMounts()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var volumes []*VolumeInfo
+	for _, entry := range entries {
+		if seen[entry.MountPoint] {
+			continue
+		}
+		seen[entry.MountPoint] = true
+
+		volume, err := GetVolumeInfo(entry.MountPoint)
+		if err != nil {
+			continue
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes, nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !windows
+
+package util
+
+import (
+	"errors"
+)
+
+// GetVolumeInfo returns the volume a path lives on, which isn't supported on this platform
+func GetVolumeInfo(path string) (*VolumeInfo, error) {
+	return nil, errors.New("volume detection is not supported on this platform")
+}
+
+// MountedVolumes returns all currently mounted volumes, which isn't supported on this platform
+func MountedVolumes() ([]*VolumeInfo, error) {
+	return nil, errors.New("volume detection is not supported on this platform")
+}
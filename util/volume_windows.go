@@ -0,0 +1,83 @@
+//go:build windows
+
+package util
+
+import (
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	procGetVolumePathNameW                = syscall.NewLazyDLL("kernel32.dll").NewProc("GetVolumePathNameW")
+	procGetVolumeNameForVolumeMountPointW = syscall.NewLazyDLL("kernel32.dll").NewProc("GetVolumeNameForVolumeMountPointW")
+	procGetVolumeInformationW             = syscall.NewLazyDLL("kernel32.dll").NewProc("GetVolumeInformationW")
+	procGetLogicalDrives                  = syscall.NewLazyDLL("kernel32.dll").NewProc("GetLogicalDrives")
+)
+
+// GetVolumeInfo returns the volume a path lives on, identified by its volume GUID
+func GetVolumeInfo(path string) (*VolumeInfo, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pathPtr, err := syscall.UTF16PtrFromString(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the mount point of the volume, e.g. E:\
+	mountBuf := make([]uint16, syscall.MAX_PATH+1)
+	if ret, _, err := procGetVolumePathNameW.Call(uintptr(unsafe.Pointer(pathPtr)), uintptr(unsafe.Pointer(&mountBuf[0])), uintptr(len(mountBuf))); ret == 0 {
+		return nil, err
+	}
+	mountPoint := syscall.UTF16ToString(mountBuf)
+
+	volume := &VolumeInfo{MountPoint: mountPoint}
+
+	// Resolve the volume GUID path, e.g. \\?\Volume{...}\
+	nameBuf := make([]uint16, 50)
+	if ret, _, _ := procGetVolumeNameForVolumeMountPointW.Call(uintptr(unsafe.Pointer(&mountBuf[0])), uintptr(unsafe.Pointer(&nameBuf[0])), uintptr(len(nameBuf))); ret != 0 {
+		name := syscall.UTF16ToString(nameBuf)
+		if start, end := strings.Index(name, "{"), strings.Index(name, "}"); start >= 0 && end > start {
+			volume.ID = name[start+1 : end]
+		}
+	}
+
+	// Read the volume label
+	labelBuf := make([]uint16, syscall.MAX_PATH+1)
+	if ret, _, _ := procGetVolumeInformationW.Call(uintptr(unsafe.Pointer(&mountBuf[0])), uintptr(unsafe.Pointer(&labelBuf[0])), uintptr(len(labelBuf)), 0, 0, 0, 0, 0); ret != 0 {
+		volume.Label = syscall.UTF16ToString(labelBuf)
+	}
+
+	if volume.ID == "" {
+		volume.ID = volume.Label
+	}
+
+	return volume, nil
+}
+
+// MountedVolumes returns the volumes currently mounted at a drive letter
+func MountedVolumes() ([]*VolumeInfo, error) {
+	drives, _, err := procGetLogicalDrives.Call()
+	if drives == 0 {
+		return nil, err
+	}
+
+	var volumes []*VolumeInfo
+	for i := 0; i < 26; i++ {
+		if drives&(1<<uint(i)) == 0 {
+			continue
+		}
+
+		// Drives without media (e.g. empty card readers) fail here and are skipped
+		volume, err := GetVolumeInfo(string(rune('A'+i)) + ":\\")
+		if err != nil {
+			continue
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes, nil
+}
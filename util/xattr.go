@@ -0,0 +1,10 @@
+package util
+
+// CopyXattrs copies the extended attributes of src to dst, so copies don't silently lose them
+func CopyXattrs(src, dst string) error {
+	attrs, err := ReadXattrs(src)
+	if err != nil || len(attrs) == 0 {
+		return err
+	}
+	return WriteXattrs(dst, attrs)
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package util
+
+// ReadXattrs returns nil, extended attributes aren't supported on this platform
+func ReadXattrs(path string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+// WriteXattrs does nothing, extended attributes aren't supported on this platform
+func WriteXattrs(path string, attrs map[string][]byte) error {
+	return nil
+}
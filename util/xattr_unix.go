@@ -0,0 +1,64 @@
+//go:build linux || darwin
+
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// ReadXattrs returns the extended attributes of a file, such as user.* attributes, SELinux labels or the
+// macOS quarantine flag. It returns nil when the filesystem doesn't support them.
+func ReadXattrs(path string) (map[string][]byte, error) {
+	size, err := unix.Listxattr(path, nil)
+	if err == unix.ENOTSUP {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := make(map[string][]byte)
+	if size == 0 {
+		return attrs, nil
+	}
+	buf := make([]byte, size)
+	if size, err = unix.Listxattr(path, buf); err != nil {
+		return nil, err
+	}
+
+	// Names are NUL terminated
+	for _, name := range strings.Split(strings.TrimRight(string(buf[:size]), "\x00"), "\x00") {
+		size, err := unix.Getxattr(path, name, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", name, err)
+		}
+		value := make([]byte, size)
+		if size, err = unix.Getxattr(path, name, value); err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", name, err)
+		}
+		attrs[name] = value[:size]
+	}
+	return attrs, nil
+}
+
+// WriteXattrs sets extended attributes on a file. Every attribute is tried, as some need privileges
+// (security.* on Linux), the first failure is returned.
+func WriteXattrs(path string, attrs map[string][]byte) error {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var firstErr error
+	for _, name := range names {
+		if err := unix.Setxattr(path, name, attrs[name], 0); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("error setting %s: %w", name, err)
+		}
+	}
+	return firstErr
+}